@@ -67,6 +67,12 @@ func ChannelType2APIType(channelType int) (int, bool) {
 		apiType = constant.APITypeJimeng
 	case constant.ChannelTypeMoonshot:
 		apiType = constant.APITypeMoonshot
+	case constant.ChannelTypeBFL:
+		apiType = constant.APITypeBFL
+	case constant.ChannelTypeStability:
+		apiType = constant.APITypeStability
+	case constant.ChannelTypeIdeogram:
+		apiType = constant.APITypeIdeogram
 	}
 	if apiType == -1 {
 		return constant.APITypeOpenAI, false
@@ -22,11 +22,6 @@ var QuotaPerUnit = 500 * 1000.0 // $0.002 / 1K tokens
 var DisplayInCurrencyEnabled = true
 var DisplayTokenStatEnabled = true
 
-// Currency conversion rate (configurable via admin panel)
-// Default: $1 USD = ¥7.2 CNY
-// Administrators can update this value based on current exchange rates
-var USDToCNYRate = 7.2
-
 var DrawingEnabled = true
 var TaskEnabled = true
 var DataExportEnabled = true
@@ -116,6 +111,13 @@ var RetryTimes = 0
 
 var IsMasterNode bool
 
+// MetricsEnabled 是否开启 Prometheus 指标，MetricsToken 非空时访问 /metrics 需要携带该 Bearer Token
+var MetricsEnabled bool
+var MetricsToken string
+
+// TracingEnabled 是否开启 OpenTelemetry 链路追踪，导出地址等通过标准 OTEL_* 环境变量配置
+var TracingEnabled bool
+
 var requestInterval int
 var RequestInterval time.Duration
 
@@ -192,6 +194,7 @@ const (
 	RedemptionCodeStatusEnabled  = 1 // don't use 0, 0 is the default value!
 	RedemptionCodeStatusDisabled = 2 // also don't use 0
 	RedemptionCodeStatusUsed     = 3 // also don't use 0
+	RedemptionCodeStatusReversed = 4 // 已使用后被撤销
 )
 
 const (
@@ -205,4 +208,29 @@ const (
 	TopUpStatusPending = "pending"
 	TopUpStatusSuccess = "success"
 	TopUpStatusExpired = "expired"
+
+	TopUpStatusRefunded          = "refunded"
+	TopUpStatusPartiallyRefunded = "partially_refunded"
+)
+
+const (
+	UserBillingModePrepaid  = "prepaid"
+	UserBillingModePostpaid = "postpaid"
+)
+
+const (
+	PostpaidInvoiceStatusPending = "pending"
+	PostpaidInvoiceStatusPaid    = "paid"
+	PostpaidInvoiceStatusOverdue = "overdue"
+)
+
+const (
+	TaskCallbackStatusSuccess  = "success"
+	TaskCallbackStatusRetrying = "retrying" // 投递失败，等待重试
+	TaskCallbackStatusFailed   = "failed"   // 投递失败，已重试或不再重试
+)
+
+const (
+	TaskCallbackTypeTask       = "task"
+	TaskCallbackTypeMidjourney = "midjourney"
 )
@@ -74,6 +74,9 @@ func InitEnv() {
 	DebugEnabled = os.Getenv("DEBUG") == "true"
 	MemoryCacheEnabled = os.Getenv("MEMORY_CACHE_ENABLED") == "true"
 	IsMasterNode = os.Getenv("NODE_TYPE") != "slave"
+	MetricsEnabled = os.Getenv("METRICS_ENABLED") == "true"
+	MetricsToken = os.Getenv("METRICS_TOKEN")
+	TracingEnabled = os.Getenv("TRACING_ENABLED") == "true"
 
 	// Parse requestInterval and set RequestInterval
 	requestInterval, _ = strconv.Atoi(os.Getenv("POLLING_INTERVAL"))
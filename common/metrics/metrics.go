@@ -15,6 +15,7 @@ const (
 	SubsystemDB    = "database"
 	SubsystemCache = "cache"
 	SubsystemAuth  = "auth"
+	SubsystemTask  = "task"
 )
 
 // ApplicationMetrics holds all the metrics for the application
@@ -32,6 +33,20 @@ type ApplicationMetrics struct {
 	RelayTokensUsed       *prometheus.CounterVec
 	RelayErrorsTotal      *prometheus.CounterVec
 	RelayActiveRequests   prometheus.Gauge
+	RelayTimeToFirstToken *prometheus.HistogramVec
+	RelayTokensPerSecond  *prometheus.HistogramVec
+	RelayRetries          *prometheus.HistogramVec
+	ChannelSelections     *prometheus.CounterVec
+	QuotaOperations       *prometheus.CounterVec
+	QuotaOperationAmount  *prometheus.CounterVec
+	StreamDisconnects     *prometheus.CounterVec
+	PromptCacheTokens     *prometheus.CounterVec
+	PoolCacheOptimization *prometheus.CounterVec
+
+	// Async task metrics
+	TaskQueueDepth        *prometheus.GaugeVec
+	TaskPolls             *prometheus.CounterVec
+	TaskTimeouts          *prometheus.CounterVec
 
 	// Database metrics
 	DBConnections         *prometheus.GaugeVec
@@ -142,6 +157,107 @@ func NewApplicationMetrics() *ApplicationMetrics {
 				Help:      "Number of active relay requests",
 			},
 		),
+		RelayTimeToFirstToken: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "time_to_first_token_seconds",
+				Help:      "Time from request start to the first streamed token",
+				Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+			},
+			[]string{"provider", "model"},
+		),
+		RelayTokensPerSecond: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "completion_tokens_per_second",
+				Help:      "Completion token throughput of relay requests",
+				Buckets:   []float64{1, 5, 10, 20, 50, 100, 200, 500},
+			},
+			[]string{"provider", "model"},
+		),
+		RelayRetries: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "retries_per_request",
+				Help:      "Number of channel retries per relay request",
+				Buckets:   []float64{0, 1, 2, 3, 5, 10},
+			},
+			[]string{"status"},
+		),
+		ChannelSelections: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "channel_selections_total",
+				Help:      "Total number of channel selections by outcome",
+			},
+			[]string{"attempt", "outcome"}, // attempt: first, retry; outcome: success, no_channel, error
+		),
+		QuotaOperations: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "quota_operations_total",
+				Help:      "Total number of quota operations",
+			},
+			[]string{"operation"}, // operation: pre_consume, refund, settle
+		),
+		QuotaOperationAmount: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "quota_amount_total",
+				Help:      "Total quota amount moved by quota operations",
+			},
+			[]string{"operation"},
+		),
+		StreamDisconnects: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "stream_disconnects_total",
+				Help:      "Total number of abnormally terminated streams",
+			},
+			[]string{"provider", "reason"}, // reason: client_disconnected, timeout, upstream_error
+		),
+		PromptCacheTokens: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "prompt_cache_tokens_total",
+				Help:      "Prompt tokens by upstream prompt cache status",
+			},
+			[]string{"provider", "type"}, // type: read, creation, uncached
+		),
+		PoolCacheOptimization: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemRelay,
+				Name:      "pool_cache_optimizations_total",
+				Help:      "Total number of requests rewritten by pool cache optimization",
+			},
+			[]string{"status"}, // status: applied, failed
+		),
+
+		// Async task metrics
+		TaskQueueDepth: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Subsystem: SubsystemTask,
+				Name:      "queue_depth",
+				Help:      "Number of unfinished async tasks",
+			},
+			[]string{"platform", "state"}, // state: due, waiting
+		),
+		TaskPolls: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemTask,
+				Name:      "polls_total",
+				Help:      "Total number of async task status polls",
+			},
+			[]string{"platform"},
+		),
+		TaskTimeouts: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: SubsystemTask,
+				Name:      "timeouts_total",
+				Help:      "Total number of async tasks failed after exceeding the timeout",
+			},
+			[]string{"platform"},
+		),
 
 		// Database metrics
 		DBConnections: promauto.NewGaugeVec(
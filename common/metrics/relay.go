@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+const labelOther = "other"
+
+// labelLimiter bounds the number of distinct values of a label. Values seen
+// after the limit is reached are reported as "other" so that user controlled
+// inputs such as model names cannot create unbounded time series.
+type labelLimiter struct {
+	mu     sync.RWMutex
+	max    int
+	values map[string]struct{}
+}
+
+func newLabelLimiter(max int) *labelLimiter {
+	return &labelLimiter{max: max, values: make(map[string]struct{})}
+}
+
+func (l *labelLimiter) get(value string) string {
+	l.mu.RLock()
+	_, ok := l.values[value]
+	l.mu.RUnlock()
+	if ok {
+		return value
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok = l.values[value]; ok {
+		return value
+	}
+	if len(l.values) >= l.max {
+		return labelOther
+	}
+	l.values[value] = struct{}{}
+	return value
+}
+
+var (
+	modelLabels   = newLabelLimiter(200)
+	channelLabels = newLabelLimiter(500)
+)
+
+// Enabled reports whether metrics collection has been initialized.
+// All helpers below are no-ops until InitMetrics is called.
+func Enabled() bool {
+	return AppMetrics != nil
+}
+
+func providerLabel(channelType int) string {
+	return strconv.Itoa(channelType)
+}
+
+// RelayStarted marks the start of a relay request and returns a function
+// that marks its end.
+func RelayStarted() func() {
+	if !Enabled() {
+		return func() {}
+	}
+	AppMetrics.IncrementActiveRequests()
+	return AppMetrics.DecrementActiveRequests
+}
+
+// ObserveRelayAttempt records a single upstream attempt. errorType is empty
+// for successful attempts.
+func ObserveRelayAttempt(channelType int, model string, channelId int, errorType string, duration time.Duration) {
+	if !Enabled() {
+		return
+	}
+	provider := providerLabel(channelType)
+	model = modelLabels.get(model)
+	channel := channelLabels.get(strconv.Itoa(channelId))
+	if errorType == "" {
+		AppMetrics.RecordRelayRequest(provider, model, channel, "success", duration)
+		return
+	}
+	AppMetrics.RecordRelayRequest(provider, model, channel, "error", duration)
+	AppMetrics.RecordRelayError(provider, model, channel, errorType)
+}
+
+// ObserveRelayRetries records how many retries a relay request needed.
+func ObserveRelayRetries(retries int, success bool) {
+	if !Enabled() {
+		return
+	}
+	status := "success"
+	if !success {
+		status = "error"
+	}
+	AppMetrics.RelayRetries.WithLabelValues(status).Observe(float64(retries))
+}
+
+// RecordChannelSelection records the outcome of choosing a channel.
+func RecordChannelSelection(retry bool, outcome string) {
+	if !Enabled() {
+		return
+	}
+	attempt := "first"
+	if retry {
+		attempt = "retry"
+	}
+	AppMetrics.ChannelSelections.WithLabelValues(attempt, outcome).Inc()
+}
+
+// ObserveRelayUsage records token usage and latency of a finished request.
+// ttft is zero for non-streaming requests, generation is the time spent
+// producing completion tokens.
+func ObserveRelayUsage(channelType int, model string, channelId int, promptTokens int, completionTokens int, ttft time.Duration, generation time.Duration) {
+	if !Enabled() {
+		return
+	}
+	provider := providerLabel(channelType)
+	model = modelLabels.get(model)
+	channel := channelLabels.get(strconv.Itoa(channelId))
+	AppMetrics.RecordTokenUsage(provider, model, channel, "prompt", promptTokens)
+	AppMetrics.RecordTokenUsage(provider, model, channel, "completion", completionTokens)
+	if ttft > 0 {
+		AppMetrics.RelayTimeToFirstToken.WithLabelValues(provider, model).Observe(ttft.Seconds())
+	}
+	if completionTokens > 0 && generation > 0 {
+		AppMetrics.RelayTokensPerSecond.WithLabelValues(provider, model).Observe(float64(completionTokens) / generation.Seconds())
+	}
+}
+
+// RecordQuotaOperation records a quota pre-consume, refund or settlement.
+func RecordQuotaOperation(operation string, quota int) {
+	if !Enabled() {
+		return
+	}
+	AppMetrics.QuotaOperations.WithLabelValues(operation).Inc()
+	if quota < 0 {
+		quota = -quota
+	}
+	AppMetrics.QuotaOperationAmount.WithLabelValues(operation).Add(float64(quota))
+}
+
+// RecordStreamDisconnect records a stream that did not finish normally.
+func RecordStreamDisconnect(channelType int, reason string) {
+	if !Enabled() {
+		return
+	}
+	AppMetrics.StreamDisconnects.WithLabelValues(providerLabel(channelType), reason).Inc()
+}
+
+// RecordPromptCacheTokens records prompt tokens by upstream cache status.
+func RecordPromptCacheTokens(channelType int, readTokens int, creationTokens int, uncachedTokens int) {
+	if !Enabled() {
+		return
+	}
+	provider := providerLabel(channelType)
+	AppMetrics.PromptCacheTokens.WithLabelValues(provider, "read").Add(float64(readTokens))
+	AppMetrics.PromptCacheTokens.WithLabelValues(provider, "creation").Add(float64(creationTokens))
+	AppMetrics.PromptCacheTokens.WithLabelValues(provider, "uncached").Add(float64(uncachedTokens))
+}
+
+// RecordPoolCacheOptimization records whether pool cache padding was applied.
+func RecordPoolCacheOptimization(applied bool) {
+	if !Enabled() {
+		return
+	}
+	status := "applied"
+	if !applied {
+		status = "failed"
+	}
+	AppMetrics.PoolCacheOptimization.WithLabelValues(status).Inc()
+}
+
+// RecordCacheOperation records a lookup against one of the cache layers.
+func RecordCacheOperation(operation string, cacheType string, hit bool) {
+	if !Enabled() {
+		return
+	}
+	status := "hit"
+	if !hit {
+		status = "miss"
+	}
+	AppMetrics.CacheOperationsTotal.WithLabelValues(operation, cacheType, status).Inc()
+}
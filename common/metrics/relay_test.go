@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelLimiter(t *testing.T) {
+	limiter := newLabelLimiter(2)
+
+	assert.Equal(t, "a", limiter.get("a"))
+	assert.Equal(t, "b", limiter.get("b"))
+	assert.Equal(t, labelOther, limiter.get("c"))
+	// values already seen are kept after the limit is reached
+	assert.Equal(t, "a", limiter.get("a"))
+	assert.Equal(t, labelOther, limiter.get("d"))
+}
+
+func TestHelpersWithoutInit(t *testing.T) {
+	saved := AppMetrics
+	AppMetrics = nil
+	defer func() { AppMetrics = saved }()
+
+	assert.False(t, Enabled())
+	assert.NotPanics(t, func() {
+		RelayStarted()()
+		ObserveRelayAttempt(1, "gpt-4o", 1, "", 0)
+		RecordQuotaOperation("refund", -100)
+		RecordCacheOperation("get_channel", "memory", true)
+	})
+}
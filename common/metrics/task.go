@@ -0,0 +1,27 @@
+package metrics
+
+// SetTaskQueueDepth records the number of unfinished tasks of a platform that
+// are due for polling and that are waiting for their next poll.
+func SetTaskQueueDepth(platform string, due int, waiting int) {
+	if !Enabled() {
+		return
+	}
+	AppMetrics.TaskQueueDepth.WithLabelValues(platform, "due").Set(float64(due))
+	AppMetrics.TaskQueueDepth.WithLabelValues(platform, "waiting").Set(float64(waiting))
+}
+
+// RecordTaskPolls records how many tasks of a platform were polled.
+func RecordTaskPolls(platform string, count int) {
+	if !Enabled() {
+		return
+	}
+	AppMetrics.TaskPolls.WithLabelValues(platform).Add(float64(count))
+}
+
+// RecordTaskTimeout records a task failed by the poller after timing out.
+func RecordTaskTimeout(platform string) {
+	if !Enabled() {
+		return
+	}
+	AppMetrics.TaskTimeouts.WithLabelValues(platform).Inc()
+}
@@ -16,6 +16,9 @@ var (
 		"prefix:imagen-",
 		"flux-",
 		"flux.1-",
+		"prefix:sd3",
+		"prefix:stable-image-",
+		"prefix:ideogram-",
 	}
 )
 
@@ -11,7 +11,7 @@ import (
 // - Quota (system internal unit)
 // - Tokens (approximate input tokens)
 // - USD (US dollars)
-// - CNY (Chinese Yuan)
+// Other currencies are converted by operation_setting.QuotaToCurrency with configurable exchange rates
 
 // QuotaToUSD converts quota to USD
 // Formula: USD = quota / QuotaPerUnit
@@ -23,13 +23,6 @@ func QuotaToUSD(quota float64) float64 {
 	return quota / QuotaPerUnit
 }
 
-// QuotaToCNY converts quota to CNY
-// Formula: CNY = (quota / QuotaPerUnit) * USDToCNYRate
-// Example: 500,000 quota = $1.00 = ¥7.2 CNY (at default rate)
-func QuotaToCNY(quota float64) float64 {
-	return QuotaToUSD(quota) * USDToCNYRate
-}
-
 // QuotaToTokens converts quota to approximate input tokens
 // For most Claude models, 1 quota ≈ 1 input token
 // Note: This is an approximation as actual token costs vary by model
@@ -43,15 +36,6 @@ func USDToQuota(usd float64) float64 {
 	return usd * QuotaPerUnit
 }
 
-// CNYToQuota converts CNY to quota
-// Formula: quota = (CNY / USDToCNYRate) * QuotaPerUnit
-func CNYToQuota(cny float64) float64 {
-	if USDToCNYRate == 0 {
-		return 0
-	}
-	return (cny / USDToCNYRate) * QuotaPerUnit
-}
-
 // TokensToQuota converts approximate tokens to quota
 // For most Claude models, 1 input token ≈ 1 quota
 func TokensToQuota(tokens int) float64 {
@@ -59,13 +43,11 @@ func TokensToQuota(tokens int) float64 {
 }
 
 // FormatQuotaWithUnit formats quota value with specified unit
-// Supported units: "quota", "usd", "cny", "tokens"
+// Supported units: "quota", "usd", "tokens"
 func FormatQuotaWithUnit(quota float64, unit string) string {
 	switch unit {
 	case "usd":
 		return "$" + formatFloat(QuotaToUSD(quota), 4)
-	case "cny":
-		return "¥" + formatFloat(QuotaToCNY(quota), 4)
 	case "tokens":
 		return formatInt(QuotaToTokens(quota)) + " tokens"
 	case "quota":
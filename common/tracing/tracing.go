@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName         = "one-api"
+	defaultServiceName = "new-api"
+)
+
+// Span attribute keys shared by the relay pipeline.
+var (
+	AttrRequestId        = attribute.Key("newapi.request_id")
+	AttrUserId           = attribute.Key("newapi.user_id")
+	AttrTokenId          = attribute.Key("newapi.token_id")
+	AttrGroup            = attribute.Key("newapi.group")
+	AttrModel            = attribute.Key("newapi.model")
+	AttrChannelId        = attribute.Key("newapi.channel.id")
+	AttrChannelType      = attribute.Key("newapi.channel.type")
+	AttrRetryIndex       = attribute.Key("newapi.retry_index")
+	AttrIsStream         = attribute.Key("newapi.stream")
+	AttrPromptTokens     = attribute.Key("newapi.usage.prompt_tokens")
+	AttrCompletionTokens = attribute.Key("newapi.usage.completion_tokens")
+	AttrQuota            = attribute.Key("newapi.quota")
+)
+
+var (
+	provider *sdktrace.TracerProvider
+	enabled  atomic.Bool
+)
+
+// Init configures a tracer provider that exports spans over OTLP/HTTP.
+// The exporter endpoint, headers, sampler and resource attributes are read
+// from the standard OTEL_* environment variables.
+func Init(ctx context.Context, serviceVersion string) error {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := newResource(ctx, serviceVersion)
+	if err != nil {
+		return err
+	}
+	setProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	))
+	return nil
+}
+
+// InitWithExporter configures a tracer provider that exports every span
+// synchronously to the given exporter. It is intended for tests, e.g. with
+// tracetest.NewInMemoryExporter.
+func InitWithExporter(exporter sdktrace.SpanExporter) {
+	setProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+}
+
+// Shutdown flushes pending spans and stops the tracer provider.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	enabled.Store(false)
+	return provider.Shutdown(ctx)
+}
+
+func newResource(ctx context.Context, serviceVersion string) (*resource.Resource, error) {
+	// WithFromEnv comes last so OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+	// override the defaults.
+	return resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(
+			semconv.ServiceName(defaultServiceName),
+			semconv.ServiceVersion(serviceVersion),
+		),
+		resource.WithFromEnv(),
+	)
+}
+
+func setProvider(tp *sdktrace.TracerProvider) {
+	provider = tp
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	enabled.Store(true)
+}
+
+// Enabled reports whether a tracer provider has been configured. Without one
+// all spans are no-ops.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start starts a span as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts a server span for an incoming request, continuing the
+// trace from its W3C trace-context headers if present.
+func StartServer(r *http.Request, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// StartClient starts a client span for an outgoing request and injects its
+// trace context into header.
+func StartClient(ctx context.Context, name string, header http.Header, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	return ctx, span
+}
+
+// SetAttributes adds attributes to the span in ctx.
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+// RecordError marks span as failed. A nil err is ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// TraceId returns the trace id of the span in ctx, or an empty string.
+func TraceId(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	InitWithExporter(exporter)
+	defer func() { _ = Shutdown(context.Background()) }()
+	require.True(t, Enabled())
+
+	const incomingTraceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("traceparent", "00-"+incomingTraceId+"-00f067aa0ba902b7-01")
+
+	ctx, server := StartServer(req, "POST /v1/chat/completions")
+	attemptCtx, attempt := Start(ctx, "relay.attempt", AttrChannelId.Int(3), AttrRetryIndex.Int(1))
+	upstream := http.Header{}
+	_, client := StartClient(attemptCtx, "relay.upstream_request", upstream)
+	RecordError(client, errors.New("upstream failed"))
+	client.End()
+	SetAttributes(attemptCtx, AttrPromptTokens.Int(10), AttrCompletionTokens.Int(20))
+	attempt.End()
+	server.End()
+
+	assert.Equal(t, incomingTraceId, TraceId(ctx))
+	assert.Contains(t, upstream.Get("traceparent"), incomingTraceId)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	byName := make(map[string]tracetest.SpanStub, len(spans))
+	for _, span := range spans {
+		assert.Equal(t, incomingTraceId, span.SpanContext.TraceID().String())
+		byName[span.Name] = span
+	}
+
+	serverSpan := byName["POST /v1/chat/completions"]
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind)
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+
+	attemptSpan := byName["relay.attempt"]
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), attemptSpan.Parent.SpanID())
+	attrs := make(map[string]int64)
+	for _, kv := range attemptSpan.Attributes {
+		attrs[string(kv.Key)] = kv.Value.AsInt64()
+	}
+	assert.Equal(t, int64(3), attrs[string(AttrChannelId)])
+	assert.Equal(t, int64(1), attrs[string(AttrRetryIndex)])
+	assert.Equal(t, int64(10), attrs[string(AttrPromptTokens)])
+	assert.Equal(t, int64(20), attrs[string(AttrCompletionTokens)])
+
+	clientSpan := byName["relay.upstream_request"]
+	assert.Equal(t, trace.SpanKindClient, clientSpan.SpanKind)
+	assert.Equal(t, attemptSpan.SpanContext.SpanID(), clientSpan.Parent.SpanID())
+	assert.Contains(t, upstream.Get("traceparent"), clientSpan.SpanContext.SpanID().String())
+	assert.Equal(t, codes.Error, clientSpan.Status.Code)
+}
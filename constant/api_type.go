@@ -31,6 +31,9 @@ const (
 	APITypeXai
 	APITypeCoze
 	APITypeJimeng
+	APITypeBFL
+	APITypeStability
+	APITypeIdeogram
 	APITypeMoonshot // this one is only for count, do not add any channel after this
 	APITypeDummy    // this one is only for count, do not add any channel after this
 )
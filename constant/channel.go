@@ -50,6 +50,12 @@ const (
 	ChannelTypeKling          = 50
 	ChannelTypeJimeng         = 51
 	ChannelTypeVidu           = 52
+	ChannelTypeRunway         = 53
+	ChannelTypeLuma           = 54
+	ChannelTypeBFL            = 55
+	ChannelTypeStability      = 56
+	ChannelTypeIdeogram       = 57
+	ChannelTypeUdio           = 58
 	ChannelTypeDummy          // this one is only for count, do not add any channel after this
 
 )
@@ -108,4 +114,10 @@ var ChannelBaseURLs = []string{
 	"https://api.klingai.com",                   //50
 	"https://visual.volcengineapi.com",          //51
 	"https://api.vidu.cn",                       //52
+	"https://api.dev.runwayml.com",              //53
+	"https://api.lumalabs.ai",                   //54
+	"https://api.bfl.ai",                        //55
+	"https://api.stability.ai",                  //56
+	"https://api.ideogram.ai",                   //57
+	"https://api.piapi.ai",                      //58
 }
@@ -46,5 +46,16 @@ const (
 	ContextKeyUsingGroup  ContextKey = "group"
 	ContextKeyUserName    ContextKey = "username"
 
+	ContextKeyUserCreditLimit ContextKey = "user_credit_limit"
+
 	ContextKeySystemPromptOverride ContextKey = "system_prompt_override"
+
+	ContextKeyCostTags ContextKey = "cost_tags"
+
+	ContextKeySkipRemoteMedia ContextKey = "skip_remote_media" // 估算费用时不下载请求中的远程文件
+
+	/* task related keys */
+	ContextKeyTaskRemixFrom ContextKey = "task_remix_from" // remix 请求所基于的任务 ID
+	ContextKeyTask          ContextKey = "task"            // 提交成功后写入数据库的任务
+	ContextKeyQueuedTask    ContextKey = "queued_task"     // 从网关队列调度执行时对应的占位任务
 )
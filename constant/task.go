@@ -1,5 +1,10 @@
 package constant
 
+import (
+	"slices"
+	"strconv"
+)
+
 type TaskPlatform string
 
 const (
@@ -15,9 +20,18 @@ const (
 	TaskActionTextGenerate      = "textGenerate"
 	TaskActionFirstTailGenerate = "firstTailGenerate"
 	TaskActionReferenceGenerate = "referenceGenerate"
+	TaskActionRemix             = "remix"
+	TaskActionMusicGenerate     = "musicGenerate"
 )
 
 var SunoModel2Action = map[string]string{
 	"suno_music":  SunoActionMusic,
 	"suno_lyrics": SunoActionLyrics,
 }
+
+// MusicTaskPlatforms 音乐生成任务所属的平台，音乐任务不出现在视频接口中
+var MusicTaskPlatforms = []TaskPlatform{TaskPlatformSuno, TaskPlatform(strconv.Itoa(ChannelTypeUdio))}
+
+func IsMusicTaskPlatform(platform TaskPlatform) bool {
+	return slices.Contains(MusicTaskPlatforms, platform)
+}
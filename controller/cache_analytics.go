@@ -6,7 +6,9 @@ import (
 	"one-api/common"
 	"one-api/model"
 	"one-api/service"
+	"one-api/setting/operation_setting"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -65,40 +67,29 @@ func GetCacheMetricsOverview(c *gin.Context) {
 	}
 	netSavings := totalCostSaved - actualWarmupCost
 
+	data := gin.H{
+		"total_requests":         summary["total_requests"],
+		"cache_hit_rate":         summary["avg_cache_hit_rate"],
+		"active_warmup_channels": activeWarmupChannels,
+		"period":                 period,
+		"start_time":             startTime.Unix(),
+		"end_time":               endTime.Unix(),
+
+		// 🔥 Unit conversion metadata
+		"conversion_rates": gin.H{
+			"quota_per_usd":  common.QuotaPerUnit,
+			"exchange_rates": operation_setting.GetExchangeRates(),
+		},
+		"display_currency": getDisplayCurrency(c),
+	}
+	// 🔥 Multi-unit support for cost_saved, net_savings and warmup cost (using actual data instead of estimation)
+	setQuotaInAllUnits(data, "cost_saved", totalCostSaved)
+	setQuotaInAllUnits(data, "net_savings", netSavings)
+	setQuotaInAllUnits(data, "warmup_cost", actualWarmupCost)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"total_requests":         summary["total_requests"],
-			"cache_hit_rate":         summary["avg_cache_hit_rate"],
-			"active_warmup_channels": activeWarmupChannels,
-			"period":                 period,
-			"start_time":             startTime.Unix(),
-			"end_time":               endTime.Unix(),
-
-			// 🔥 Multi-unit support for cost_saved
-			"cost_saved_quota":  totalCostSaved,
-			"cost_saved_usd":    common.QuotaToUSD(totalCostSaved),
-			"cost_saved_cny":    common.QuotaToCNY(totalCostSaved),
-			"cost_saved_tokens": common.QuotaToTokens(totalCostSaved),
-
-			// 🔥 Multi-unit support for net_savings
-			"net_savings_quota":  netSavings,
-			"net_savings_usd":    common.QuotaToUSD(netSavings),
-			"net_savings_cny":    common.QuotaToCNY(netSavings),
-			"net_savings_tokens": common.QuotaToTokens(netSavings),
-
-			// 🔥 Warmup cost breakdown (using actual data instead of estimation)
-			"warmup_cost_quota":  actualWarmupCost,
-			"warmup_cost_usd":    common.QuotaToUSD(actualWarmupCost),
-			"warmup_cost_cny":    common.QuotaToCNY(actualWarmupCost),
-			"warmup_cost_tokens": common.QuotaToTokens(actualWarmupCost),
-
-			// 🔥 Unit conversion metadata
-			"conversion_rates": gin.H{
-				"quota_per_usd": common.QuotaPerUnit,
-				"usd_to_cny":    common.USDToCNYRate,
-			},
-		},
+		"data":    data,
 	})
 }
 
@@ -183,50 +174,63 @@ func GetCacheMetricsChart(c *gin.Context) {
 		}
 		costSaved = append(costSaved, saved)
 
-		// 🔥 Add multi-unit cost data for chart
-		costSavedUSD := common.QuotaToUSD(saved)
-		costSavedCNY := common.QuotaToCNY(saved)
-		costSavedTokens := float64(common.QuotaToTokens(saved))
+		currentTime = bucketEnd
+	}
 
-		// Store in separate arrays (we'll add to response later)
-		_ = costSavedUSD
-		_ = costSavedCNY
-		_ = costSavedTokens
+	data := gin.H{
+		"timestamps":       timestamps,
+		"cache_hit_rates":  cacheHitRates,
+		"period":           period,
+		"interval":         interval,
+		"display_currency": getDisplayCurrency(c),
 
-		currentTime = bucketEnd
+		// 🔥 Multi-unit cost data
+		"cost_saved_quota":  costSaved,
+		"cost_saved_tokens": convertArrayToTokens(costSaved),
+	}
+	for currency := range operation_setting.GetExchangeRates() {
+		data["cost_saved_"+strings.ToLower(currency)] = convertArrayToCurrency(costSaved, currency)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"timestamps":      timestamps,
-			"cache_hit_rates": cacheHitRates,
+		"data":    data,
+	})
+}
 
-			// 🔥 Multi-unit cost data
-			"cost_saved_quota":  costSaved,
-			"cost_saved_usd":    convertArrayToUSD(costSaved),
-			"cost_saved_cny":    convertArrayToCNY(costSaved),
-			"cost_saved_tokens": convertArrayToTokens(costSaved),
+// getDisplayCurrency 当前用户选择的展示货币
+func getDisplayCurrency(c *gin.Context) string {
+	userCurrency := ""
+	if user, err := model.GetUserCache(c.GetInt("id")); err == nil {
+		userCurrency = user.GetSetting().Currency
+	}
+	return operation_setting.ResolveDisplayCurrency(userCurrency)
+}
 
-			"period":            period,
-			"interval":          interval,
-		},
-	})
+// quotaInAllUnits 将额度换算为全部计量单位，货币以小写代码为键，如 usd、cny
+func quotaInAllUnits(quota float64) gin.H {
+	values := gin.H{
+		"quota":  quota,
+		"tokens": common.QuotaToTokens(quota),
+	}
+	for currency := range operation_setting.GetExchangeRates() {
+		values[strings.ToLower(currency)] = operation_setting.QuotaToCurrency(quota, currency)
+	}
+	return values
 }
 
-// Helper functions for array conversion
-func convertArrayToUSD(quotaArray []float64) []float64 {
-	result := make([]float64, len(quotaArray))
-	for i, quota := range quotaArray {
-		result[i] = common.QuotaToUSD(quota)
+// setQuotaInAllUnits 以 prefix_单位 为键写入换算结果，如 cost_saved_usd
+func setQuotaInAllUnits(data gin.H, prefix string, quota float64) {
+	for unit, value := range quotaInAllUnits(quota) {
+		data[prefix+"_"+unit] = value
 	}
-	return result
 }
 
-func convertArrayToCNY(quotaArray []float64) []float64 {
+// Helper functions for array conversion
+func convertArrayToCurrency(quotaArray []float64, currency string) []float64 {
 	result := make([]float64, len(quotaArray))
 	for i, quota := range quotaArray {
-		result[i] = common.QuotaToCNY(quota)
+		result[i] = operation_setting.QuotaToCurrency(quota, currency)
 	}
 	return result
 }
@@ -295,10 +299,7 @@ func GetCacheMetricsByChannels(c *gin.Context) {
 		if cost, ok := cm["total_cost_saved"].(float64); ok {
 			totalCostSaved = cost
 		}
-		channelMetrics[i]["cost_saved_quota"] = totalCostSaved
-		channelMetrics[i]["cost_saved_usd"] = common.QuotaToUSD(totalCostSaved)
-		channelMetrics[i]["cost_saved_cny"] = common.QuotaToCNY(totalCostSaved)
-		channelMetrics[i]["cost_saved_tokens"] = common.QuotaToTokens(totalCostSaved)
+		setQuotaInAllUnits(channelMetrics[i], "cost_saved", totalCostSaved)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -505,24 +506,9 @@ func GetCachePerformanceAnalysis(c *gin.Context) {
 			"avg_cache_hit_rate": roiMetrics["avg_cache_hit_rate"],
 
 			// Cost analysis (multi-unit)
-			"cost_saved": gin.H{
-				"quota":  totalCostSaved,
-				"usd":    common.QuotaToUSD(totalCostSaved),
-				"cny":    common.QuotaToCNY(totalCostSaved),
-				"tokens": common.QuotaToTokens(totalCostSaved),
-			},
-			"warmup_cost": gin.H{
-				"quota":  warmupCost,
-				"usd":    common.QuotaToUSD(warmupCost),
-				"cny":    common.QuotaToCNY(warmupCost),
-				"tokens": common.QuotaToTokens(warmupCost),
-			},
-			"net_savings": gin.H{
-				"quota":  netSavings,
-				"usd":    common.QuotaToUSD(netSavings),
-				"cny":    common.QuotaToCNY(netSavings),
-				"tokens": common.QuotaToTokens(netSavings),
-			},
+			"cost_saved": quotaInAllUnits(totalCostSaved),
+			"warmup_cost": quotaInAllUnits(warmupCost),
+			"net_savings": quotaInAllUnits(netSavings),
 
 			// ROI indicators
 			"roi":                roi * 100, // Convert to percentage
@@ -76,6 +76,42 @@ func testChannel(channel *model.Channel, testModel string) testResult {
 			newAPIError: nil,
 		}
 	}
+	if channel.Type == constant.ChannelTypeRunway {
+		return testResult{
+			localErr:    errors.New("runway channel test is not supported"),
+			newAPIError: nil,
+		}
+	}
+	if channel.Type == constant.ChannelTypeLuma {
+		return testResult{
+			localErr:    errors.New("luma channel test is not supported"),
+			newAPIError: nil,
+		}
+	}
+	if channel.Type == constant.ChannelTypeBFL {
+		return testResult{
+			localErr:    errors.New("bfl channel test is not supported"),
+			newAPIError: nil,
+		}
+	}
+	if channel.Type == constant.ChannelTypeStability {
+		return testResult{
+			localErr:    errors.New("stability channel test is not supported"),
+			newAPIError: nil,
+		}
+	}
+	if channel.Type == constant.ChannelTypeIdeogram {
+		return testResult{
+			localErr:    errors.New("ideogram channel test is not supported"),
+			newAPIError: nil,
+		}
+	}
+	if channel.Type == constant.ChannelTypeUdio {
+		return testResult{
+			localErr:    errors.New("udio channel test is not supported"),
+			newAPIError: nil,
+		}
+	}
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 
@@ -107,7 +107,9 @@ func GetLogsStat(c *gin.Context) {
 	modelName := c.Query("model_name")
 	channel, _ := strconv.Atoi(c.Query("channel"))
 	group := c.Query("group")
-	stat := model.SumUsedQuota(logType, startTimestamp, endTimestamp, modelName, username, tokenName, channel, group)
+	tagKey := c.Query("tag_key")
+	tagValue := c.Query("tag_value")
+	stat := model.SumUsedQuota(logType, startTimestamp, endTimestamp, modelName, username, tokenName, channel, group, tagKey, tagValue)
 	//tokenNum := model.SumUsedToken(logType, startTimestamp, endTimestamp, modelName, username, "")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -130,7 +132,9 @@ func GetLogsSelfStat(c *gin.Context) {
 	modelName := c.Query("model_name")
 	channel, _ := strconv.Atoi(c.Query("channel"))
 	group := c.Query("group")
-	quotaNum := model.SumUsedQuota(logType, startTimestamp, endTimestamp, modelName, username, tokenName, channel, group)
+	tagKey := c.Query("tag_key")
+	tagValue := c.Query("tag_value")
+	quotaNum := model.SumUsedQuota(logType, startTimestamp, endTimestamp, modelName, username, tokenName, channel, group, tagKey, tagValue)
 	//tokenNum := model.SumUsedToken(logType, startTimestamp, endTimestamp, modelName, username, tokenName)
 	c.JSON(200, gin.H{
 		"success": true,
@@ -145,6 +149,31 @@ func GetLogsSelfStat(c *gin.Context) {
 	return
 }
 
+// GetLogsTagStat 按标签值汇总某个标签键下的消费，用于成本分摊
+func GetLogsTagStat(c *gin.Context) {
+	getLogsTagStat(c, c.Query("username"))
+}
+
+func GetLogsSelfTagStat(c *gin.Context) {
+	getLogsTagStat(c, c.GetString("username"))
+}
+
+func getLogsTagStat(c *gin.Context, username string) {
+	tagKey := c.Query("tag_key")
+	if tagKey == "" {
+		common.ApiErrorMsg(c, "缺少标签键")
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	stats, err := model.SumQuotaByTag(tagKey, startTimestamp, endTimestamp, username)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, stats)
+}
+
 func DeleteHistoryLogs(c *gin.Context) {
 	targetTimestamp, _ := strconv.ParseInt(c.Query("target_timestamp"), 10, 64)
 	if targetTimestamp == 0 {
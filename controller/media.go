@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMedia 通过签名地址访问转存的生成结果，无需登录
+func GetMedia(c *gin.Context) {
+	key := c.Param("key")
+	if !service.VerifyMediaSignature(key, c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "invalid_or_expired_signature",
+		})
+		return
+	}
+	media, err := model.GetMediaByKey(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "media_not_found",
+		})
+		return
+	}
+	relay.ServeMedia(c, media)
+}
@@ -11,6 +11,7 @@ import (
 	"one-api/dto"
 	"one-api/logger"
 	"one-api/model"
+	"one-api/relay"
 	"one-api/service"
 	"one-api/setting"
 	"one-api/setting/system_setting"
@@ -22,8 +23,12 @@ import (
 func UpdateMidjourneyTaskBulk() {
 	//imageModel := "midjourney"
 	ctx := context.TODO()
+	elector := service.GetLeaderElector("midjourney_poller")
 	for {
 		time.Sleep(time.Duration(15) * time.Second)
+		if !elector.IsLeader() {
+			continue
+		}
 
 		tasks := model.GetAllUnFinishTasks()
 		if len(tasks) == 0 {
@@ -66,13 +71,16 @@ func UpdateMidjourneyTaskBulk() {
 			midjourneyChannel, err := model.CacheGetChannel(channelId)
 			if err != nil {
 				logger.LogError(ctx, fmt.Sprintf("CacheGetChannel: %v", err))
+				failReason := fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId)
 				err := model.MjBulkUpdate(taskIds, map[string]any{
-					"fail_reason": fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId),
+					"fail_reason": failReason,
 					"status":      "FAILURE",
 					"progress":    "100%",
 				})
 				if err != nil {
 					logger.LogInfo(ctx, fmt.Sprintf("UpdateMidjourneyTask error: %v", err))
+				} else {
+					notifyMidjourneyTasksFailed(taskIds, taskM, failReason)
 				}
 				continue
 			}
@@ -129,6 +137,7 @@ func UpdateMidjourneyTaskBulk() {
 				if !checkMjTaskNeedUpdate(task, responseItem) {
 					continue
 				}
+				previousStatus := task.Status
 				task.Code = 1
 				task.Progress = responseItem.Progress
 				task.PromptEn = responseItem.PromptEn
@@ -171,10 +180,11 @@ func UpdateMidjourneyTaskBulk() {
 						shouldReturnQuota = true
 					}
 				}
-				err = task.Update()
+				// 快照读取后任务可能已被取消并退款，只有本次更新成功时才退款和回调，避免重复退款或覆盖取消原因
+				updated, err := task.UpdateUnfinished()
 				if err != nil {
 					logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
-				} else {
+				} else if updated {
 					if shouldReturnQuota {
 						err = model.IncreaseUserQuota(task.UserId, task.Quota, false)
 						if err != nil {
@@ -183,12 +193,29 @@ func UpdateMidjourneyTaskBulk() {
 						logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, logger.LogQuota(task.Quota))
 						model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
 					}
+					if previousStatus != task.Status {
+						relay.NotifyMidjourneyFinished(task)
+					}
 				}
 			}
 		}
 	}
 }
 
+// notifyMidjourneyTasksFailed 批量标记失败后，为指定了回调地址的任务推送失败结果
+func notifyMidjourneyTasksFailed(mjIds []string, taskM map[string]*model.Midjourney, failReason string) {
+	for _, mjId := range mjIds {
+		task := taskM[mjId]
+		if task == nil || task.CallbackUrl == "" {
+			continue
+		}
+		task.Status = "FAILURE"
+		task.Progress = "100%"
+		task.FailReason = failReason
+		relay.NotifyMidjourneyFinished(task)
+	}
+}
+
 func checkMjTaskNeedUpdate(oldTask *model.Midjourney, newTask dto.MidjourneyDto) bool {
 	if oldTask.Code != 1 {
 		return true
@@ -251,6 +278,8 @@ func GetAllMidjourney(c *gin.Context) {
 	queryParams := model.TaskQueryParams{
 		ChannelID:      c.Query("channel_id"),
 		MjID:           c.Query("mj_id"),
+		Status:         c.Query("status"),
+		Action:         c.Query("action"),
 		StartTimestamp: c.Query("start_timestamp"),
 		EndTimestamp:   c.Query("end_timestamp"),
 	}
@@ -276,6 +305,8 @@ func GetUserMidjourney(c *gin.Context) {
 
 	queryParams := model.TaskQueryParams{
 		MjID:           c.Query("mj_id"),
+		Status:         c.Query("status"),
+		Action:         c.Query("action"),
 		StartTimestamp: c.Query("start_timestamp"),
 		EndTimestamp:   c.Query("end_timestamp"),
 	}
@@ -293,3 +324,43 @@ func GetUserMidjourney(c *gin.Context) {
 	pageInfo.SetItems(items)
 	common.ApiSuccess(c, pageInfo)
 }
+
+type retryMidjourneyResult struct {
+	Id      int    `json:"id"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// RetryMidjourneyTasks 管理员批量重试失败的绘图任务，逐个返回重试结果
+func RetryMidjourneyTasks(c *gin.Context) {
+	var req struct {
+		Ids []int `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Ids) == 0 {
+		common.ApiErrorMsg(c, "请选择要重试的任务")
+		return
+	}
+	if len(req.Ids) > 100 {
+		common.ApiErrorMsg(c, "一次最多重试 100 个任务")
+		return
+	}
+	tasks := model.GetMjByIds(req.Ids)
+	found := make(map[int]*model.Midjourney, len(tasks))
+	for _, task := range tasks {
+		found[task.Id] = task
+	}
+	results := make([]retryMidjourneyResult, 0, len(req.Ids))
+	for _, id := range req.Ids {
+		result := retryMidjourneyResult{Id: id}
+		task, ok := found[id]
+		if !ok {
+			result.Message = "任务不存在"
+		} else if err := service.RetryMidjourneyTask(c, task); err != nil {
+			result.Message = err.Error()
+		} else {
+			result.Success = true
+		}
+		results = append(results, result)
+	}
+	common.ApiSuccess(c, results)
+}
@@ -80,6 +80,8 @@ func GetStatus(c *gin.Context) {
 		"usd_exchange_rate": operation_setting.USDExchangeRate,
 		"price":             operation_setting.Price,
 		"stripe_unit_price": setting.StripeUnitPrice,
+		"display_currency":  operation_setting.ResolveDisplayCurrency(""),
+		"exchange_rates":    operation_setting.GetExchangeRates(),
 
 		// 面板启用开关
 		"api_info_enabled":      cs.ApiInfoEnabled,
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/relay/helper"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelayMusic
+// @Summary 生成音乐
+// @Description 统一的音乐生成接口，根据分发到的渠道转换为 Suno 或 Udio 的请求，按生成的歌曲数计费
+// @Tags Music
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.MusicRequest true "音乐生成请求参数"
+// @Success 200 {object} dto.MusicTask "音乐任务"
+// @Failure 400 {object} dto.TaskError "请求参数错误"
+// @Router /v1/music/generations [post]
+func RelayMusic(c *gin.Context) {
+	writer := helper.NewBufferedResponseWriter(c.Writer)
+	c.Writer = writer
+	RelayTask(c)
+	c.Writer = writer.ResponseWriter
+
+	if v, ok := common.GetContextKey(c, constant.ContextKeyTask); ok {
+		// 适配器可能已复制上游响应头，改写响应体后长度不再一致
+		c.Writer.Header().Del("Content-Length")
+		c.JSON(http.StatusOK, relay.TaskModel2Music(v.(*model.Task)))
+		return
+	}
+	statusCode := writer.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	c.Data(statusCode, "application/json", writer.Body.Bytes())
+}
+
+// RetrieveMusic
+// @Summary 查询音乐任务
+// @Tags Music
+// @Produce json
+// @Security BearerAuth
+// @Param task_id path string true "任务 ID"
+// @Success 200 {object} dto.MusicTask "音乐任务"
+// @Router /v1/music/generations/{task_id} [get]
+func RetrieveMusic(c *gin.Context) {
+	task, exist, err := model.GetByTaskId(c.GetInt("id"), c.Param("task_id"))
+	if err != nil {
+		taskErr := service.TaskErrorWrapper(err, "get_task_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if !exist || !constant.IsMusicTaskPlatform(task.Platform) {
+		taskErr := service.TaskErrorWrapperLocal(fmt.Errorf("music task %s not found", c.Param("task_id")), "task_not_exist", http.StatusNotFound)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	c.JSON(http.StatusOK, relay.TaskModel2Music(task))
+}
@@ -155,6 +155,51 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "TieredRatio":
+		err = ratio_setting.CheckTieredRatio(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "分段倍率设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "VideoPrice":
+		err = ratio_setting.CheckVideoPrice(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "视频按秒计费设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "ImageSizeRatio":
+		err = ratio_setting.CheckImageSizeRatio(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "图片尺寸倍率设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "ImageQualityRatio":
+		err = ratio_setting.CheckImageQualityRatio(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "图片质量倍率设置失败: " + err.Error(),
+			})
+			return
+		}
+	case "PricingSchedule":
+		err = ratio_setting.CheckPricingSchedule(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "分时定价设置失败: " + err.Error(),
+			})
+			return
+		}
 	case "ModelRequestRateLimitGroup":
 		err = setting.CheckModelRequestRateLimitGroup(option.Value.(string))
 		if err != nil {
@@ -0,0 +1,262 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service/payment"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/thanhpk/randstr"
+)
+
+type PaymentRequest struct {
+	Provider      string `json:"provider"`
+	Amount        int64  `json:"amount"`
+	PaymentMethod string `json:"payment_method"`
+	Currency      string `json:"currency"`
+}
+
+// genTradeNo 生成充值订单号，Stripe 沿用 ref_ 前缀以兼容未记录入账额度的旧订单
+func genTradeNo(provider string, userId int) string {
+	if provider == payment.ProviderStripe {
+		reference := fmt.Sprintf("new-api-ref-%d-%d-%s", userId, time.Now().UnixMilli(), randstr.String(4))
+		return "ref_" + common.Sha1([]byte(reference))
+	}
+	return fmt.Sprintf("USR%dNO%s%d", userId, common.GetRandomString(6), time.Now().Unix())
+}
+
+// getPaymentMoney 校验充值数量与结算货币并计算实付金额，返回实际使用的结算货币
+func getPaymentMoney(provider payment.Provider, userId int, amount int64, currency string) (float64, string, error) {
+	minTopUp := payment.GetMinTopUp(provider)
+	if amount < minTopUp {
+		return 0, "", fmt.Errorf("充值数量不能小于 %d", minTopUp)
+	}
+	currency, err := payment.ResolveCurrency(provider, currency)
+	if err != nil {
+		return 0, "", err
+	}
+	group, err := model.GetUserGroup(userId, true)
+	if err != nil {
+		return 0, "", errors.New("获取用户分组失败")
+	}
+	payMoney, err := payment.GetPayMoney(provider, amount, group, currency)
+	if err != nil {
+		return 0, "", err
+	}
+	if payMoney < 0.01 {
+		return 0, "", errors.New("充值金额过低")
+	}
+	return payMoney, currency, nil
+}
+
+// createPaymentOrder 向支付渠道下单并创建待支付的充值订单
+func createPaymentOrder(provider payment.Provider, userId int, amount int64, paymentMethod string, currency string) (*payment.PayResult, *model.TopUp, error) {
+	if !provider.Enabled() {
+		return nil, nil, errors.New("当前管理员未配置支付信息")
+	}
+	payMoney, currency, err := getPaymentMoney(provider, userId, amount, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		return nil, nil, errors.New("获取用户信息失败")
+	}
+	topUp := &model.TopUp{
+		UserId:          userId,
+		Amount:          payment.NormalizeAmount(amount),
+		Money:           payMoney,
+		Currency:        currency,
+		Quota:           payment.GetQuota(amount),
+		TradeNo:         genTradeNo(provider.Name(), userId),
+		PaymentProvider: provider.Name(),
+		CreateTime:      time.Now().Unix(),
+		Status:          common.TopUpStatusPending,
+	}
+	result, err := provider.CreateOrder(&payment.Order{
+		TradeNo:       topUp.TradeNo,
+		UserId:        userId,
+		Email:         user.Email,
+		CustomerId:    user.StripeCustomer,
+		PaymentMethod: paymentMethod,
+		Amount:        amount,
+		Money:         payMoney,
+		Currency:      currency,
+		Subject:       fmt.Sprintf("TUC%d", amount),
+	})
+	if err != nil {
+		log.Printf("%s 下单失败: %v", provider.Name(), err)
+		return nil, nil, errors.New("拉起支付失败")
+	}
+	topUp.ExternalId = result.ExternalId
+	if err = topUp.Insert(); err != nil {
+		return nil, nil, errors.New("创建订单失败")
+	}
+	return result, topUp, nil
+}
+
+// RequestPayment 通过指定的支付渠道发起充值
+func RequestPayment(c *gin.Context) {
+	var req PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "参数错误"})
+		return
+	}
+	provider, err := payment.GetProvider(req.Provider)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": err.Error()})
+		return
+	}
+	result, topUp, err := createPaymentOrder(provider, c.GetInt("id"), req.Amount, req.PaymentMethod, req.Currency)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{
+		"message": "success",
+		"data": gin.H{
+			"trade_no": topUp.TradeNo,
+			"pay_link": result.PayLink,
+			"params":   result.Params,
+		},
+	})
+}
+
+// RequestPaymentAmount 查询指定支付渠道的实付金额
+func RequestPaymentAmount(c *gin.Context) {
+	var req PaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "参数错误"})
+		return
+	}
+	provider, err := payment.GetProvider(req.Provider)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": err.Error()})
+		return
+	}
+	payMoney, currency, err := getPaymentMoney(provider, c.GetInt("id"), req.Amount, req.Currency)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "success", "data": strconv.FormatFloat(payMoney, 'f', 2, 64), "currency": currency})
+}
+
+// PaymentNotify 支付渠道的统一回调入口
+func PaymentNotify(c *gin.Context) {
+	provider, err := payment.GetProvider(c.Param("provider"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	handlePaymentWebhook(c, provider)
+}
+
+func handlePaymentWebhook(c *gin.Context, provider payment.Provider) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Printf("读取 %s 回调失败: %v", provider.Name(), err)
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+	notification, err := provider.VerifyWebhook(c.Request, body)
+	if err == nil {
+		if notification.Status != "" {
+			err = applyPaymentNotification(provider, notification)
+		} else if event, ok := notification.Event.(stripe.Event); ok {
+			handleStripeEvent(event)
+		}
+	}
+	if err != nil {
+		log.Printf("%s 回调处理失败: %v", provider.Name(), err)
+	}
+	status, response := provider.WebhookResponse(err == nil)
+	c.String(status, response)
+}
+
+// applyPaymentNotification 按支付结果更新充值订单，订单锁与幂等入账对所有支付渠道统一处理
+func applyPaymentNotification(provider payment.Provider, notification *payment.Notification) error {
+	tradeNo := notification.TradeNo
+	if tradeNo == "" {
+		return errors.New("未提供支付单号")
+	}
+	LockOrder(tradeNo)
+	defer UnlockOrder(tradeNo)
+
+	topUp := model.GetTopUpByTradeNo(tradeNo)
+	if topUp == nil {
+		log.Printf("%s 回调未找到订单: %s", provider.Name(), tradeNo)
+		return nil
+	}
+	if topUp.PaymentProvider != "" && topUp.PaymentProvider != provider.Name() {
+		return fmt.Errorf("订单 %s 不属于支付渠道 %s", tradeNo, provider.Name())
+	}
+
+	switch notification.Status {
+	case payment.OrderStatusPaid:
+		_, credited, err := model.CompleteTopUp(tradeNo, notification.ExternalId, notification.CustomerId)
+		if err != nil {
+			return err
+		}
+		if !credited {
+			return nil
+		}
+		if err = model.SettlePostpaidInvoiceByTradeNo(tradeNo); err != nil {
+			log.Println("结清后付费账单失败", tradeNo, ", err:", err.Error())
+		}
+		log.Printf("%s 充值订单支付成功: %s, %.2f", provider.Name(), tradeNo, topUp.Money)
+	case payment.OrderStatusExpired:
+		if topUp.Status != common.TopUpStatusPending {
+			return nil
+		}
+		if err := model.ExpireTopUp(tradeNo); err != nil {
+			return err
+		}
+		log.Println("充值订单已过期", tradeNo)
+	}
+	return nil
+}
+
+// QueryPaymentOrder 用户主动向支付渠道查询待支付订单，用于回调丢失时补单
+func QueryPaymentOrder(c *gin.Context) {
+	tradeNo := c.Param("trade_no")
+	topUp := model.GetTopUpByTradeNo(tradeNo)
+	if topUp == nil || topUp.UserId != c.GetInt("id") {
+		common.ApiErrorMsg(c, "充值订单不存在")
+		return
+	}
+	if topUp.Status == common.TopUpStatusPending {
+		providerName := topUp.PaymentProvider
+		if providerName == "" {
+			providerName = payment.ProviderEpay
+			if strings.HasPrefix(topUp.TradeNo, "ref_") {
+				providerName = payment.ProviderStripe
+			}
+		}
+		provider, err := payment.GetProvider(providerName)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		notification, err := provider.QueryOrder(topUp)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if err = applyPaymentNotification(provider, notification); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		topUp = model.GetTopUpByTradeNo(tradeNo)
+	}
+	common.ApiSuccess(c, topUp)
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"one-api/service/payment"
+	"one-api/setting"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thanhpk/randstr"
+)
+
+type UpdateUserBillingRequest struct {
+	UserId      int    `json:"user_id"`
+	BillingMode string `json:"billing_mode"`
+	CreditLimit int    `json:"credit_limit"`
+}
+
+// UpdateUserBilling 管理员设置用户的计费模式与信用额度
+func UpdateUserBilling(c *gin.Context) {
+	var req UpdateUserBillingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.UserId == 0 {
+		common.ApiErrorMsg(c, "无效的用户 ID")
+		return
+	}
+	if err := model.UpdateUserBilling(req.UserId, req.BillingMode, req.CreditLimit); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(req.UserId, model.LogTypeManage, fmt.Sprintf("管理员将用户计费模式设置为 %s，信用额度 %d", req.BillingMode, req.CreditLimit))
+	common.ApiSuccess(c, nil)
+}
+
+func GetAllPostpaidInvoices(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	invoices, total, err := model.GetPostpaidInvoices(userId, c.Query("status"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(invoices)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetUserPostpaidInvoices(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	invoices, total, err := model.GetPostpaidInvoices(c.GetInt("id"), c.Query("status"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(invoices)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// RunPostpaidBilling 管理员手动触发出账与逾期检查
+func RunPostpaidBilling(c *gin.Context) {
+	now := time.Now()
+	if err := service.IssuePostpaidInvoices(now); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := service.CheckPostpaidInvoices(now); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+type PostpaidPayRequest struct {
+	InvoiceId int `json:"invoice_id"`
+}
+
+// RequestPostpaidPay 通过 Stripe 支付后付费账单，支付完成后由 Stripe Webhook 入账并结清账单
+func RequestPostpaidPay(c *gin.Context) {
+	var req PostpaidPayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "参数错误"})
+		return
+	}
+	if setting.StripeApiSecret == "" || setting.StripeWebhookSecret == "" || setting.StripePriceId == "" {
+		c.JSON(200, gin.H{"message": "error", "data": "当前管理员未配置支付信息"})
+		return
+	}
+	id := c.GetInt("id")
+	invoice, err := model.GetPostpaidInvoiceById(req.InvoiceId)
+	if err != nil || invoice.UserId != id {
+		c.JSON(200, gin.H{"message": "error", "data": "账单不存在"})
+		return
+	}
+	if !invoice.IsOpen() {
+		c.JSON(200, gin.H{"message": "error", "data": "账单已结清"})
+		return
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "获取用户信息失败"})
+		return
+	}
+
+	reference := fmt.Sprintf("new-api-invoice-%d-%d-%s", invoice.Id, time.Now().UnixMilli(), randstr.String(4))
+	referenceId := "ref_" + common.Sha1([]byte(reference))
+	provider, _ := payment.GetProvider(payment.ProviderStripe)
+	result, err := provider.CreateOrder(&payment.Order{
+		TradeNo:    referenceId,
+		UserId:     id,
+		Email:      user.Email,
+		CustomerId: user.StripeCustomer,
+		Amount:     invoice.Amount,
+		Money:      float64(invoice.Amount),
+	})
+	if err != nil {
+		log.Println("获取Stripe Checkout支付链接失败", err)
+		c.JSON(200, gin.H{"message": "error", "data": "拉起支付失败"})
+		return
+	}
+
+	// 账单按原始数量入账，不叠加充值分组倍率
+	topUp := &model.TopUp{
+		UserId:          id,
+		Amount:          invoice.Amount,
+		Money:           float64(invoice.Amount),
+		Quota:           int(float64(invoice.Amount) * common.QuotaPerUnit),
+		TradeNo:         referenceId,
+		PaymentProvider: payment.ProviderStripe,
+		ExternalId:      result.ExternalId,
+		CreateTime:      time.Now().Unix(),
+		Status:          common.TopUpStatusPending,
+	}
+	if err = topUp.Insert(); err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "创建订单失败"})
+		return
+	}
+	invoice.TradeNo = referenceId
+	if err = invoice.Update(); err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "更新账单失败"})
+		return
+	}
+	c.JSON(200, gin.H{
+		"message": "success",
+		"data": gin.H{
+			"pay_link": result.PayLink,
+		},
+	})
+}
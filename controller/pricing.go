@@ -4,6 +4,7 @@ import (
 	"one-api/model"
 	"one-api/setting"
 	"one-api/setting/ratio_setting"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +18,9 @@ func GetPricing(c *gin.Context) {
 		groupRatio[s] = f
 	}
 	var group string
+	priceOverrides := make([]model.UserPriceOverride, 0)
 	if exists {
+		priceOverrides = model.GetUserPriceOverridesCopy(userId.(int))
 		user, err := model.GetUserCache(userId.(int))
 		if err == nil {
 			group = user.Group
@@ -38,6 +41,15 @@ func GetPricing(c *gin.Context) {
 		}
 	}
 
+	now := time.Now()
+	schedules := make([]gin.H, 0)
+	for _, schedule := range ratio_setting.GetPricingSchedules() {
+		schedules = append(schedules, gin.H{
+			"schedule": schedule,
+			"active":   schedule.IsActive(now),
+		})
+	}
+
 	c.JSON(200, gin.H{
 		"success":            true,
 		"data":               pricing,
@@ -46,6 +58,8 @@ func GetPricing(c *gin.Context) {
 		"usable_group":       usableGroup,
 		"supported_endpoint": model.GetSupportedEndpointMap(),
 		"auto_groups":        setting.AutoGroups,
+		"pricing_schedules":  schedules,
+		"price_overrides":    priceOverrides,
 	})
 }
 
@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/setting"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultEstimateEndpoint  = "/v1/chat/completions"
+	maxEstimateBatchLines    = 5000
+	maxEstimateBatchLineSize = 16 << 20
+	maxEstimateBodySize      = 16 << 20
+	maxEstimateBatchSize     = 128 << 20
+)
+
+// PricingEstimate 单个请求的费用估算，CompletionQuota 按请求中的 max_tokens 估算补全费用上限
+type PricingEstimate struct {
+	Model               string               `json:"model"`
+	Group               string               `json:"group"`
+	PromptTokens        int                  `json:"prompt_tokens"`
+	MaxCompletionTokens int                  `json:"max_completion_tokens"`
+	UsePrice            bool                 `json:"use_price"`
+	ModelPrice          float64              `json:"model_price"`
+	ModelRatio          float64              `json:"model_ratio"`
+	CompletionRatio     float64              `json:"completion_ratio"`
+	GroupRatio          float64              `json:"group_ratio"`
+	PricingTier         int                  `json:"pricing_tier,omitempty"`
+	PricingSchedule     string               `json:"pricing_schedule,omitempty"`
+	TimeMultiplier      float64              `json:"time_multiplier,omitempty"`
+	PriceOverride       *types.PriceOverride `json:"price_override,omitempty"`
+	PromptQuota         int                  `json:"prompt_quota"`
+	CompletionQuota     int                  `json:"completion_quota"`
+	Quota               int                  `json:"quota"`
+	PreConsumedQuota    int                  `json:"pre_consumed_quota"`
+	Cost                float64              `json:"cost"`
+}
+
+// estimateRelayFormat 根据中继路径判断请求格式
+func estimateRelayFormat(endpoint string) (types.RelayFormat, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "/v1beta/models/"):
+		return types.RelayFormatGemini, nil
+	case endpoint == "/v1/chat/completions", endpoint == "/v1/completions":
+		return types.RelayFormatOpenAI, nil
+	case endpoint == "/v1/messages":
+		return types.RelayFormatClaude, nil
+	case endpoint == "/v1/responses":
+		return types.RelayFormatOpenAIResponses, nil
+	case endpoint == "/v1/embeddings":
+		return types.RelayFormatEmbedding, nil
+	case endpoint == "/v1/images/generations":
+		return types.RelayFormatOpenAIImage, nil
+	case endpoint == "/v1/rerank":
+		return types.RelayFormatRerank, nil
+	}
+	return "", fmt.Errorf("不支持估算的接口: %s", endpoint)
+}
+
+// estimateModelName Gemini 格式的模型名在路径中，其余格式取请求体中的 model
+func estimateModelName(endpoint string, body []byte) string {
+	if rest, ok := strings.CutPrefix(endpoint, "/v1beta/models/"); ok {
+		name, _, _ := strings.Cut(rest, ":")
+		return name
+	}
+	return gjson.GetBytes(body, "model").String()
+}
+
+// estimateRequestCost 在独立的上下文中按中继流程校验请求、计算 token 与价格，不会请求上游也不会预扣额度
+// 请求中的远程图片等文件不会被下载，按固定 token 数估算
+func estimateRequestCost(c *gin.Context, user *model.UserBase, group string, endpoint string, body []byte) (*PricingEstimate, error) {
+	format, err := estimateRelayFormat(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	modelName := estimateModelName(endpoint, body)
+	if modelName == "" {
+		return nil, errors.New("请求中缺少模型名称")
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	ec, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ec.Request = req
+	user.WriteContext(ec)
+	common.SetContextKey(ec, constant.ContextKeySkipRemoteMedia, true)
+	common.SetContextKey(ec, constant.ContextKeyUserId, user.Id)
+	common.SetContextKey(ec, constant.ContextKeyUsingGroup, group)
+	common.SetContextKey(ec, constant.ContextKeyOriginalModel, modelName)
+
+	request, err := helper.GetAndValidateRequest(ec, format)
+	if err != nil {
+		return nil, err
+	}
+	info, err := relaycommon.GenRelayInfo(ec, format, request, nil)
+	if err != nil {
+		return nil, err
+	}
+	meta := request.GetTokenCountMeta()
+	tokens, err := service.CountRequestToken(ec, meta, info)
+	if err != nil {
+		return nil, err
+	}
+	// 关闭了媒体 token 统计时仍按文本估算，否则估算结果恒为 0
+	if tokens == 0 && meta.CombineText != "" {
+		tokens = service.CountTextToken(meta.CombineText, modelName)
+	}
+	info.SetPromptTokens(tokens)
+	priceData, err := helper.ModelPriceHelper(ec, info, tokens, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	groupRatio := priceData.GroupRatioInfo.GroupRatio
+	estimate := &PricingEstimate{
+		Model:               modelName,
+		Group:               info.UsingGroup,
+		PromptTokens:        tokens,
+		MaxCompletionTokens: meta.MaxTokens,
+		UsePrice:            priceData.UsePrice,
+		ModelPrice:          priceData.ModelPrice,
+		ModelRatio:          priceData.ModelRatio,
+		CompletionRatio:     priceData.CompletionRatio,
+		GroupRatio:          groupRatio,
+		PricingTier:         priceData.PricingTier,
+		PricingSchedule:     priceData.GroupRatioInfo.PricingSchedule,
+		PriceOverride:       priceData.GroupRatioInfo.PriceOverride,
+		PreConsumedQuota:    priceData.ShouldPreConsumedQuota,
+	}
+	if estimate.PricingSchedule != "" {
+		estimate.TimeMultiplier = priceData.GroupRatioInfo.TimeMultiplier
+	}
+	if priceData.UsePrice {
+		estimate.Quota = int(math.Round(priceData.ModelPrice * common.QuotaPerUnit * groupRatio))
+	} else {
+		ratio := priceData.ModelRatio * groupRatio
+		estimate.PromptQuota = int(math.Round(float64(tokens) * ratio))
+		estimate.CompletionQuota = int(math.Round(float64(meta.MaxTokens) * priceData.CompletionRatio * ratio))
+		estimate.Quota = estimate.PromptQuota + estimate.CompletionQuota
+	}
+	estimate.Cost = float64(estimate.Quota) / common.QuotaPerUnit
+	return estimate, nil
+}
+
+// getEstimateUserAndGroup 校验调用者可以使用 group 参数指定的分组，未指定时使用用户所在分组
+func getEstimateUserAndGroup(c *gin.Context) (*model.UserBase, string, error) {
+	user, err := model.GetUserCache(c.GetInt("id"))
+	if err != nil {
+		return nil, "", err
+	}
+	group := c.Query("group")
+	if group == "" {
+		return user, user.Group, nil
+	}
+	if _, ok := setting.GetUserUsableGroups(user.Group)[group]; !ok {
+		return nil, "", fmt.Errorf("无权使用分组 %s", group)
+	}
+	return user, group, nil
+}
+
+// EstimatePricing 估算单个中继请求的费用，endpoint 参数指定请求对应的中继路径
+func EstimatePricing(c *gin.Context) {
+	user, group, err := getEstimateUserAndGroup(c)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxEstimateBodySize))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	endpoint := c.DefaultQuery("endpoint", defaultEstimateEndpoint)
+	estimate, err := estimateRequestCost(c, user, group, endpoint, body)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, estimate)
+}
+
+// batchEstimateLine 兼容 OpenAI Batch 输入文件格式，没有 body 字段时整行视为请求体
+type batchEstimateLine struct {
+	CustomId string          `json:"custom_id"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type batchEstimateResult struct {
+	Line     int              `json:"line"`
+	CustomId string           `json:"custom_id,omitempty"`
+	Estimate *PricingEstimate `json:"estimate,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// EstimatePricingBatch 估算 JSONL 文件中全部请求的费用，文件通过 multipart 的 file 字段或直接作为请求体上传
+func EstimatePricingBatch(c *gin.Context) {
+	user, group, err := getEstimateUserAndGroup(c)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxEstimateBatchSize)
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	defaultEndpoint := c.DefaultQuery("endpoint", defaultEstimateEndpoint)
+	results := make([]batchEstimateResult, 0)
+	totalQuota := 0
+	failed := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEstimateBatchLineSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(results) >= maxEstimateBatchLines {
+			common.ApiErrorMsg(c, fmt.Sprintf("单次最多估算 %d 个请求", maxEstimateBatchLines))
+			return
+		}
+		result := batchEstimateResult{Line: lineNo}
+		var entry batchEstimateLine
+		if err := common.Unmarshal(line, &entry); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.CustomId = entry.CustomId
+			endpoint, body := defaultEndpoint, line
+			if len(entry.Body) > 0 {
+				body = entry.Body
+				if entry.Url != "" {
+					endpoint = entry.Url
+				}
+			}
+			result.Estimate, err = estimateRequestCost(c, user, group, endpoint, body)
+			if err != nil {
+				result.Error = err.Error()
+			}
+		}
+		if result.Estimate != nil {
+			totalQuota += result.Estimate.Quota
+		} else {
+			failed++
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"items":  results,
+		"total":  len(results),
+		"failed": failed,
+		"quota":  totalQuota,
+		"cost":   float64(totalQuota) / common.QuotaPerUnit,
+	})
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/checkout/session"
+)
+
+func GetAllTopUps(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	topUps, total, err := model.GetAllTopUps(userId, c.Query("status"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(topUps)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetQuotaReversals(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	reversals, total, err := model.GetQuotaReversals(userId, c.Query("source_type"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(reversals)
+	common.ApiSuccess(c, pageInfo)
+}
+
+type RefundTopUpRequest struct {
+	Quota  int    `json:"quota"` // 0 表示退还全部剩余额度
+	Reason string `json:"reason"`
+}
+
+// RefundTopUp 管理员对充值订单退款，扣回对应额度
+func RefundTopUp(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req RefundTopUpRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Quota < 0 {
+		common.ApiErrorMsg(c, "退款额度不能为负数")
+		return
+	}
+	topUp := model.GetTopUpById(id)
+	if topUp == nil {
+		common.ApiErrorMsg(c, "充值订单不存在")
+		return
+	}
+	LockOrder(topUp.TradeNo)
+	defer UnlockOrder(topUp.TradeNo)
+	reversal, err := model.RefundTopUp(id, req.Quota, false, req.Reason, c.GetInt("id"), "")
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, reversal)
+}
+
+type ReverseRedemptionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReverseRedemption 管理员撤销已使用的兑换码，扣回对应额度
+func ReverseRedemption(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req ReverseRedemptionRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	reversal, err := model.ReverseRedemption(id, req.Reason, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, reversal)
+}
+
+// chargeRefunded Stripe 退款（含争议退款）后按退款比例扣回充值额度
+// 同一笔 Charge 可能多次部分退款，每次事件中的 amount_refunded 为累计值
+func chargeRefunded(event stripe.Event) {
+	var charge stripe.Charge
+	if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+		log.Println("解析Stripe Charge失败", err)
+		return
+	}
+	if charge.PaymentIntent == nil || charge.PaymentIntent.ID == "" || charge.Amount <= 0 {
+		return
+	}
+	if err := setStripeKey(); err != nil {
+		log.Println(err.Error())
+		return
+	}
+	params := &stripe.CheckoutSessionListParams{PaymentIntent: stripe.String(charge.PaymentIntent.ID)}
+	iter := session.List(params)
+	referenceId := ""
+	for iter.Next() {
+		if ref := iter.CheckoutSession().ClientReferenceID; ref != "" {
+			referenceId = ref
+			break
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Println("查询Stripe Checkout会话失败", charge.ID, err)
+		return
+	}
+	if referenceId == "" {
+		log.Println("退款未关联充值订单", charge.ID)
+		return
+	}
+
+	LockOrder(referenceId)
+	defer UnlockOrder(referenceId)
+
+	topUp := model.GetTopUpByTradeNo(referenceId)
+	if topUp == nil {
+		log.Println("充值订单不存在", referenceId)
+		return
+	}
+	reversed, err := model.GetReversedQuota(model.QuotaReversalSourceTopUp, topUp.Id)
+	if err != nil {
+		log.Println("查询已扣回额度失败", referenceId, err)
+		return
+	}
+	target := int(float64(topUp.CreditedQuota()) * float64(charge.AmountRefunded) / float64(charge.Amount))
+	delta := target - reversed
+	if delta <= 0 {
+		return
+	}
+	reason := fmt.Sprintf("Stripe 退款 %.2f(%s)", float64(charge.AmountRefunded)/100, charge.Currency)
+	if _, err = model.RefundTopUp(topUp.Id, delta, charge.Refunded, reason, 0, charge.ID); err != nil {
+		log.Println("扣回充值额度失败", referenceId, ", err:", err.Error())
+		return
+	}
+	log.Printf("充值订单已退款：%s, %.2f(%s)", referenceId, float64(charge.AmountRefunded)/100, charge.Currency)
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/logger"
@@ -20,6 +22,7 @@ import (
 	"one-api/setting"
 	"one-api/types"
 	"strings"
+	"time"
 
 	"github.com/bytedance/gopkg/util/gopool"
 
@@ -65,6 +68,7 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	requestId := c.GetString(common.RequestIdKey)
 	group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
 	originalModel := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+	defer metrics.RelayStarted()()
 
 	var (
 		newAPIError *types.NewAPIError
@@ -163,6 +167,16 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		requestBody, _ := common.GetRequestBody(c)
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 
+		attemptStart := time.Now()
+		requestCtx := c.Request.Context()
+		attemptCtx, span := tracing.Start(requestCtx, "relay.attempt",
+			tracing.AttrChannelId.Int(channel.Id),
+			tracing.AttrChannelType.Int(channel.Type),
+			tracing.AttrModel.String(originalModel),
+			tracing.AttrRetryIndex.Int(i),
+			tracing.AttrIsStream.Bool(relayInfo.IsStream),
+		)
+		c.Request = c.Request.WithContext(attemptCtx)
 		switch relayFormat {
 		case types.RelayFormatOpenAIRealtime:
 			newAPIError = relay.WssHelper(c, relayInfo)
@@ -174,7 +188,14 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 			newAPIError = relayHandler(c, relayInfo)
 		}
 
+		observeRelayAttempt(channel, originalModel, newAPIError, attemptStart)
+		if newAPIError != nil {
+			tracing.RecordError(span, newAPIError)
+		}
+		span.End()
+		c.Request = c.Request.WithContext(requestCtx)
 		if newAPIError == nil {
+			metrics.ObserveRelayRetries(i, true)
 			return
 		}
 
@@ -186,6 +207,7 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	}
 
 	useChannel := c.GetStringSlice("use_channel")
+	metrics.ObserveRelayRetries(max(len(useChannel)-1, 0), false)
 	if len(useChannel) > 1 {
 		retryLogStr := fmt.Sprintf("重试：%s", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(useChannel)), "->"), "[]"))
 		logger.LogInfo(c, retryLogStr)
@@ -205,6 +227,14 @@ func addUsedChannel(c *gin.Context, channelId int) {
 	c.Set("use_channel", useChannel)
 }
 
+func observeRelayAttempt(channel *model.Channel, modelName string, newAPIError *types.NewAPIError, start time.Time) {
+	errorType := ""
+	if newAPIError != nil {
+		errorType = string(newAPIError.GetErrorCode())
+	}
+	metrics.ObserveRelayAttempt(channel.Type, modelName, channel.Id, errorType, time.Since(start))
+}
+
 func getChannel(c *gin.Context, group, originalModel string, retryCount int) (*model.Channel, *types.NewAPIError) {
 	if retryCount == 0 {
 		autoBan := c.GetBool("auto_ban")
@@ -221,11 +251,14 @@ func getChannel(c *gin.Context, group, originalModel string, retryCount int) (*m
 	}
 	channel, selectGroup, err := model.CacheGetRandomSatisfiedChannel(c, group, originalModel, retryCount)
 	if err != nil {
+		metrics.RecordChannelSelection(true, "error")
 		return nil, types.NewError(fmt.Errorf("获取分组 %s 下模型 %s 的可用渠道失败（retry）: %s", selectGroup, originalModel, err.Error()), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry())
 	}
 	if channel == nil {
+		metrics.RecordChannelSelection(true, "no_channel")
 		return nil, types.NewError(fmt.Errorf("分组 %s 下模型 %s 的可用渠道不存在（数据库一致性已被破坏，retry）", selectGroup, originalModel), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry())
 	}
+	metrics.RecordChannelSelection(true, "success")
 	newAPIError := middleware.SetupContextForSelectedChannel(c, channel, originalModel)
 	if newAPIError != nil {
 		return nil, newAPIError
@@ -333,9 +366,16 @@ func RelayMidjourney(c *gin.Context) {
 		mjErr = relay.RelayMidjourneyTask(c, relayInfo.RelayMode)
 	case relayconstant.RelayModeMidjourneyTaskImageSeed:
 		mjErr = relay.RelayMidjourneyTaskImageSeed(c)
+	case relayconstant.RelayModeMidjourneyTaskCancel:
+		mjErr = relay.RelayMidjourneyTaskCancel(c)
 	case relayconstant.RelayModeSwapFace:
 		mjErr = relay.RelaySwapFace(c, relayInfo)
 	default:
+		queued, release := queueMidjourneySubmit(c, relayInfo)
+		if queued {
+			return
+		}
+		defer release()
 		mjErr = relay.RelayMidjourneySubmit(c, relayInfo)
 	}
 	//err = relayMidjourneySubmit(c, relayMode)
@@ -390,6 +430,11 @@ func RelayTask(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	queued, release := queueTaskSubmit(c, relayInfo)
+	if queued {
+		return
+	}
+	defer release()
 	taskErr := taskRelayHandler(c, relayInfo)
 	if taskErr == nil {
 		retryTimes = 0
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GenerateStatementRequest struct {
+	UserId  int    `json:"user_id"`
+	TokenId int    `json:"token_id"`
+	Period  string `json:"period"`
+}
+
+// GenerateStatement 生成月度账单；user_id 为 0 时为该月所有有消费的用户批量生成
+func GenerateStatement(c *gin.Context) {
+	var req GenerateStatementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Period == "" {
+		common.ApiErrorMsg(c, "账单周期不能为空")
+		return
+	}
+	if req.UserId == 0 {
+		if req.TokenId != 0 {
+			common.ApiErrorMsg(c, "按令牌生成账单时必须指定用户")
+			return
+		}
+		created, err := service.GenerateStatementsForPeriod(req.Period)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		common.ApiSuccess(c, gin.H{"created": created})
+		return
+	}
+	statement, err := service.GenerateStatement(req.UserId, req.TokenId, req.Period)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, statement)
+}
+
+func GetAllStatements(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	period := c.Query("period")
+	statements, total, err := model.GetAllStatements(userId, period, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(statements)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetUserStatements(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId := c.GetInt("id")
+	period := c.Query("period")
+	statements, total, err := model.GetAllStatements(userId, period, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(statements)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func DownloadStatement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	statement, err := model.GetStatementById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	writeStatement(c, statement)
+}
+
+func DownloadUserStatement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	statement, err := model.GetUserStatementById(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	writeStatement(c, statement)
+}
+
+func writeStatement(c *gin.Context, statement *model.Statement) {
+	format := c.DefaultQuery("format", "csv")
+	var (
+		data        []byte
+		err         error
+		contentType string
+	)
+	switch format {
+	case "csv":
+		data, err = service.RenderStatementCSV(statement)
+		contentType = "text/csv; charset=utf-8"
+	case "pdf":
+		data, err = service.RenderStatementPDF(statement)
+		contentType = "application/pdf"
+	default:
+		common.ApiErrorMsg(c, "不支持的账单格式: "+format)
+		return
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	filename := fmt.Sprintf("statement-%s-%d", statement.Period, statement.UserId)
+	if statement.TokenId != 0 {
+		filename += fmt.Sprintf("-%d", statement.TokenId)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", filename, format))
+	c.Data(http.StatusOK, contentType, data)
+}
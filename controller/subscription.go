@@ -0,0 +1,438 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting"
+	"one-api/setting/system_setting"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/checkout/session"
+	"github.com/stripe/stripe-go/v81/subscription"
+)
+
+// GetSubscriptionPlans 管理员获取全部套餐
+func GetSubscriptionPlans(c *gin.Context) {
+	plans, err := model.GetSubscriptionPlans(false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, plans)
+}
+
+// GetEnabledSubscriptionPlans 用户获取可订阅的套餐
+func GetEnabledSubscriptionPlans(c *gin.Context) {
+	plans, err := model.GetSubscriptionPlans(true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	for _, plan := range plans {
+		plan.StripePriceId = ""
+	}
+	common.ApiSuccess(c, plans)
+}
+
+func validateSubscriptionPlan(plan *model.SubscriptionPlan) error {
+	if plan.Name == "" {
+		return errors.New("套餐名称不能为空")
+	}
+	if plan.StripePriceId == "" {
+		return errors.New("Stripe Price ID 不能为空")
+	}
+	if plan.MonthlyQuota < 0 || plan.MaxRolloverQuota < 0 {
+		return errors.New("额度不能为负数")
+	}
+	if plan.RolloverPercent < 0 || plan.RolloverPercent > 100 {
+		return errors.New("结转比例必须在 0 到 100 之间")
+	}
+	return nil
+}
+
+func CreateSubscriptionPlan(c *gin.Context) {
+	var plan model.SubscriptionPlan
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := validateSubscriptionPlan(&plan); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := plan.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &plan)
+}
+
+func UpdateSubscriptionPlan(c *gin.Context) {
+	var plan model.SubscriptionPlan
+	if err := c.ShouldBindJSON(&plan); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if plan.Id == 0 {
+		common.ApiErrorMsg(c, "缺少套餐 ID")
+		return
+	}
+	origin, err := model.GetSubscriptionPlanById(plan.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err = validateSubscriptionPlan(&plan); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	plan.CreatedTime = origin.CreatedTime
+	if err = plan.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &plan)
+}
+
+func DeleteSubscriptionPlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err = model.DeleteSubscriptionPlanById(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func GetAllUserSubscriptions(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	subs, total, err := model.GetAllUserSubscriptions(userId, c.Query("status"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(subs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetSelfSubscription 获取当前用户生效中的订阅，没有订阅时返回 null
+func GetSelfSubscription(c *gin.Context) {
+	sub, err := model.GetUserActiveSubscription(c.GetInt("id"))
+	if err != nil {
+		common.ApiSuccess(c, nil)
+		return
+	}
+	if sub.Plan != nil {
+		sub.Plan.StripePriceId = ""
+	}
+	common.ApiSuccess(c, sub)
+}
+
+type SubscriptionRequest struct {
+	PlanId int `json:"plan_id"`
+}
+
+// RequestSubscription 创建 Stripe 订阅模式的 Checkout 链接
+func RequestSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "参数错误"})
+		return
+	}
+	plan, err := model.GetSubscriptionPlanById(req.PlanId)
+	if err != nil || !plan.Enabled {
+		c.JSON(200, gin.H{"message": "error", "data": "订阅套餐不存在"})
+		return
+	}
+	id := c.GetInt("id")
+	if sub, err := model.GetUserActiveSubscription(id); err == nil && sub != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "已存在生效中的订阅，请切换套餐"})
+		return
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "获取用户信息失败"})
+		return
+	}
+	payLink, err := genStripeSubscriptionLink(user, plan)
+	if err != nil {
+		log.Println("获取Stripe订阅链接失败", err)
+		c.JSON(200, gin.H{"message": "error", "data": "拉起支付失败"})
+		return
+	}
+	c.JSON(200, gin.H{
+		"message": "success",
+		"data": gin.H{
+			"pay_link": payLink,
+		},
+	})
+}
+
+// SwitchSubscription 切换套餐，Stripe 按比例计费，新的套餐额度在下一周期发放
+func SwitchSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	plan, err := model.GetSubscriptionPlanById(req.PlanId)
+	if err != nil || !plan.Enabled {
+		common.ApiErrorMsg(c, "订阅套餐不存在")
+		return
+	}
+	sub, err := model.GetUserActiveSubscription(c.GetInt("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "当前没有生效中的订阅")
+		return
+	}
+	if sub.PlanId == plan.Id {
+		common.ApiErrorMsg(c, "已是当前套餐")
+		return
+	}
+	if err = setStripeKey(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	stripeSub, err := subscription.Get(sub.StripeSubscriptionId, nil)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if stripeSub.Items == nil || len(stripeSub.Items.Data) == 0 {
+		common.ApiErrorMsg(c, "Stripe 订阅缺少订阅项")
+		return
+	}
+	_, err = subscription.Update(sub.StripeSubscriptionId, &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				ID:    stripe.String(stripeSub.Items.Data[0].ID),
+				Price: stripe.String(plan.StripePriceId),
+			},
+		},
+		CancelAtPeriodEnd: stripe.Bool(false),
+		Metadata: map[string]string{
+			"plan_id": strconv.Itoa(plan.Id),
+		},
+	})
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	sub.CancelAtPeriodEnd = false
+	if err = model.SwitchUserSubscriptionPlan(sub, plan); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(sub.UserId, model.LogTypeSystem, fmt.Sprintf("切换订阅套餐为 %s", plan.Name))
+	common.ApiSuccess(c, nil)
+}
+
+// CancelSubscription 在当前周期结束时取消订阅
+func CancelSubscription(c *gin.Context) {
+	sub, err := model.GetUserActiveSubscription(c.GetInt("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "当前没有生效中的订阅")
+		return
+	}
+	if err = setStripeKey(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	_, err = subscription.Update(sub.StripeSubscriptionId, &stripe.SubscriptionParams{
+		CancelAtPeriodEnd: stripe.Bool(true),
+	})
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	sub.CancelAtPeriodEnd = true
+	if err = sub.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func setStripeKey() error {
+	if !strings.HasPrefix(setting.StripeApiSecret, "sk_") && !strings.HasPrefix(setting.StripeApiSecret, "rk_") {
+		return fmt.Errorf("无效的Stripe API密钥")
+	}
+	stripe.Key = setting.StripeApiSecret
+	return nil
+}
+
+func genStripeSubscriptionLink(user *model.User, plan *model.SubscriptionPlan) (string, error) {
+	if err := setStripeKey(); err != nil {
+		return "", err
+	}
+	metadata := map[string]string{
+		"user_id": strconv.Itoa(user.Id),
+		"plan_id": strconv.Itoa(plan.Id),
+	}
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(fmt.Sprintf("sub_%d_%d", user.Id, plan.Id)),
+		SuccessURL:        stripe.String(system_setting.ServerAddress + "/console/log"),
+		CancelURL:         stripe.String(system_setting.ServerAddress + "/topup"),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price:    stripe.String(plan.StripePriceId),
+				Quantity: stripe.Int64(1),
+			},
+		},
+		Mode: stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		SubscriptionData: &stripe.CheckoutSessionSubscriptionDataParams{
+			Metadata: metadata,
+		},
+		Metadata:            metadata,
+		AllowPromotionCodes: stripe.Bool(setting.StripePromotionCodesEnabled),
+	}
+	if user.StripeCustomer != "" {
+		params.Customer = stripe.String(user.StripeCustomer)
+	} else if user.Email != "" {
+		params.CustomerEmail = stripe.String(user.Email)
+	}
+	result, err := session.New(params)
+	if err != nil {
+		return "", err
+	}
+	return result.URL, nil
+}
+
+func subscriptionMetadataIds(metadata map[string]string) (int, int, error) {
+	userId, err := strconv.Atoi(metadata["user_id"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("订阅缺少 user_id 元数据")
+	}
+	planId, err := strconv.Atoi(metadata["plan_id"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("订阅缺少 plan_id 元数据")
+	}
+	return userId, planId, nil
+}
+
+// subscriptionCheckoutCompleted 订阅模式的 Checkout 完成，创建订阅记录；额度在 invoice.paid 时发放
+func subscriptionCheckoutCompleted(event stripe.Event) {
+	var checkoutSession stripe.CheckoutSession
+	if err := json.Unmarshal(event.Data.Raw, &checkoutSession); err != nil {
+		log.Println("解析Stripe订阅Checkout失败", err)
+		return
+	}
+	if checkoutSession.Subscription == nil {
+		log.Println("Stripe订阅Checkout缺少订阅ID", checkoutSession.ID)
+		return
+	}
+	userId, planId, err := subscriptionMetadataIds(checkoutSession.Metadata)
+	if err != nil {
+		log.Println(err.Error(), checkoutSession.ID)
+		return
+	}
+	if _, err = model.CreateUserSubscription(userId, planId, checkoutSession.Subscription.ID); err != nil {
+		log.Println("创建订阅记录失败", checkoutSession.Subscription.ID, err)
+		return
+	}
+	if checkoutSession.Customer != nil && checkoutSession.Customer.ID != "" {
+		if err = model.UpdateUserStripeCustomer(userId, checkoutSession.Customer.ID); err != nil {
+			log.Println("更新Stripe客户失败", err)
+		}
+	}
+}
+
+// subscriptionInvoicePaid 订阅首期或续期扣费成功，发放套餐额度
+func subscriptionInvoicePaid(event stripe.Event) {
+	var invoice stripe.Invoice
+	if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
+		log.Println("解析Stripe Invoice失败", err)
+		return
+	}
+	if invoice.Subscription == nil || invoice.Subscription.ID == "" {
+		return
+	}
+	LockOrder(invoice.Subscription.ID)
+	defer UnlockOrder(invoice.Subscription.ID)
+
+	sub, err := model.GetUserSubscriptionByStripeId(invoice.Subscription.ID)
+	if err != nil {
+		// invoice.paid 可能先于 checkout.session.completed 到达
+		if invoice.SubscriptionDetails == nil {
+			log.Println("未找到订阅记录", invoice.Subscription.ID)
+			return
+		}
+		userId, planId, err := subscriptionMetadataIds(invoice.SubscriptionDetails.Metadata)
+		if err != nil {
+			log.Println(err.Error(), invoice.Subscription.ID)
+			return
+		}
+		sub, err = model.CreateUserSubscription(userId, planId, invoice.Subscription.ID)
+		if err != nil {
+			log.Println("创建订阅记录失败", invoice.Subscription.ID, err)
+			return
+		}
+	}
+	periodStart, periodEnd := invoice.PeriodStart, invoice.PeriodEnd
+	if invoice.Lines != nil && len(invoice.Lines.Data) > 0 && invoice.Lines.Data[0].Period != nil {
+		periodStart = invoice.Lines.Data[0].Period.Start
+		periodEnd = invoice.Lines.Data[0].Period.End
+	}
+	if err = model.RenewUserSubscription(sub, invoice.ID, periodStart, periodEnd); err != nil {
+		log.Println("发放订阅额度失败", invoice.Subscription.ID, err)
+		return
+	}
+	log.Printf("订阅续期成功：%s, %.2f(%s)", invoice.Subscription.ID, float64(invoice.AmountPaid)/100, strings.ToUpper(string(invoice.Currency)))
+}
+
+// subscriptionUpdated 同步订阅状态、周期与取消标记
+func subscriptionUpdated(event stripe.Event) {
+	var stripeSub stripe.Subscription
+	if err := json.Unmarshal(event.Data.Raw, &stripeSub); err != nil {
+		log.Println("解析Stripe订阅失败", err)
+		return
+	}
+	sub, err := model.GetUserSubscriptionByStripeId(stripeSub.ID)
+	if err != nil {
+		log.Println("未找到订阅记录", stripeSub.ID)
+		return
+	}
+	if stripeSub.Status == stripe.SubscriptionStatusCanceled {
+		if err = model.CancelUserSubscription(sub); err != nil {
+			log.Println("取消订阅失败", stripeSub.ID, err)
+		}
+		return
+	}
+	switch stripeSub.Status {
+	case stripe.SubscriptionStatusActive, stripe.SubscriptionStatusTrialing:
+		sub.Status = model.SubscriptionStatusActive
+	case stripe.SubscriptionStatusPastDue, stripe.SubscriptionStatusUnpaid:
+		sub.Status = model.SubscriptionStatusPastDue
+	}
+	sub.CancelAtPeriodEnd = stripeSub.CancelAtPeriodEnd
+	sub.CurrentPeriodStart = stripeSub.CurrentPeriodStart
+	sub.CurrentPeriodEnd = stripeSub.CurrentPeriodEnd
+	if err = sub.Update(); err != nil {
+		log.Println("更新订阅记录失败", stripeSub.ID, err)
+	}
+}
+
+// subscriptionDeleted 订阅结束，恢复用户原分组
+func subscriptionDeleted(event stripe.Event) {
+	stripeSubId := event.GetObjectValue("id")
+	sub, err := model.GetUserSubscriptionByStripeId(stripeSubId)
+	if err != nil {
+		log.Println("未找到订阅记录", stripeSubId)
+		return
+	}
+	if err = model.CancelUserSubscription(sub); err != nil {
+		log.Println("取消订阅失败", stripeSubId, err)
+	}
+}
@@ -8,11 +8,14 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/logger"
 	"one-api/model"
 	"one-api/relay"
+	"one-api/service"
+	"one-api/setting/operation_setting"
 	"sort"
 	"strconv"
 	"time"
@@ -21,52 +24,137 @@ import (
 	"github.com/samber/lo"
 )
 
+// taskPollTick 轮询调度的检查周期，每个任务实际的轮询时间由 next_poll_at 决定
+const taskPollTick = 5 * time.Second
+
 func UpdateTaskBulk() {
-	//revocer
-	//imageModel := "midjourney"
+	// 多节点部署时仅由选举出的 leader 轮询，避免重复请求上游和重复退款
+	elector := service.GetLeaderElector("task_poller")
 	for {
-		time.Sleep(time.Duration(15) * time.Second)
-		common.SysLog("任务进度轮询开始")
-		ctx := context.TODO()
-		allTasks := model.GetAllUnFinishSyncTasks(500)
-		platformTask := make(map[constant.TaskPlatform][]*model.Task)
-		for _, t := range allTasks {
-			platformTask[t.Platform] = append(platformTask[t.Platform], t)
+		time.Sleep(taskPollTick)
+		if !elector.IsLeader() {
+			continue
 		}
-		for platform, tasks := range platformTask {
-			if len(tasks) == 0 {
+		updateTaskRound(context.TODO())
+	}
+}
+
+// updateTaskRound 执行一轮任务轮询：处理超时任务、轮询到期任务并安排下次轮询
+func updateTaskRound(ctx context.Context) {
+	pollSetting := operation_setting.GetTaskPollSetting()
+	now := time.Now().Unix()
+	failTimeoutTasks(ctx, pollSetting.TimeoutMinutes, now)
+
+	if depths, err := model.GetUnFinishSyncTaskDepth(now); err == nil {
+		for _, depth := range depths {
+			metrics.SetTaskQueueDepth(string(depth.Platform), depth.Due, depth.Waiting)
+		}
+	}
+
+	allTasks := model.GetDueUnFinishSyncTasks(now, pollSetting.BatchSize)
+	if len(allTasks) == 0 {
+		return
+	}
+	common.SysLog(fmt.Sprintf("任务进度轮询开始，到期任务数: %d", len(allTasks)))
+	platformTask := make(map[constant.TaskPlatform][]*model.Task)
+	for _, t := range allTasks {
+		platformTask[t.Platform] = append(platformTask[t.Platform], t)
+	}
+	for platform, tasks := range platformTask {
+		if len(tasks) == 0 {
+			continue
+		}
+		metrics.RecordTaskPolls(string(platform), len(tasks))
+		taskChannelM := make(map[int][]string)
+		taskM := make(map[string]*model.Task)
+		nullTasks := make([]*model.Task, 0)
+		nullTaskIds := make([]int64, 0)
+		previousProgress := make(map[int64]string, len(tasks))
+		for _, task := range tasks {
+			if task.TaskID == "" {
+				// 统计失败的未完成任务
+				nullTasks = append(nullTasks, task)
+				nullTaskIds = append(nullTaskIds, task.ID)
 				continue
 			}
-			taskChannelM := make(map[int][]string)
-			taskM := make(map[string]*model.Task)
-			nullTaskIds := make([]int64, 0)
-			for _, task := range tasks {
-				if task.TaskID == "" {
-					// 统计失败的未完成任务
-					nullTaskIds = append(nullTaskIds, task.ID)
-					continue
-				}
-				taskM[task.TaskID] = task
-				taskChannelM[task.ChannelId] = append(taskChannelM[task.ChannelId], task.TaskID)
-			}
-			if len(nullTaskIds) > 0 {
-				err := model.TaskBulkUpdateByID(nullTaskIds, map[string]any{
-					"status":   "FAILURE",
-					"progress": "100%",
-				})
-				if err != nil {
-					logger.LogError(ctx, fmt.Sprintf("Fix null task_id task error: %v", err))
-				} else {
-					logger.LogInfo(ctx, fmt.Sprintf("Fix null task_id task success: %v", nullTaskIds))
+			previousProgress[task.ID] = string(task.Status) + "|" + task.Progress
+			taskM[task.TaskID] = task
+			taskChannelM[task.ChannelId] = append(taskChannelM[task.ChannelId], task.TaskID)
+		}
+		if len(nullTaskIds) > 0 {
+			err := model.TaskBulkUpdateByID(nullTaskIds, map[string]any{
+				"status":   "FAILURE",
+				"progress": "100%",
+			})
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("Fix null task_id task error: %v", err))
+			} else {
+				logger.LogInfo(ctx, fmt.Sprintf("Fix null task_id task success: %v", nullTaskIds))
+				for _, task := range nullTasks {
+					task.Status = model.TaskStatusFailure
+					task.Progress = "100%"
+					settleAndNotifyTask(ctx, task)
 				}
 			}
-			if len(taskChannelM) == 0 {
-				continue
-			}
+		}
+		if len(taskChannelM) == 0 {
+			continue
+		}
+
+		UpdateTaskByPlatform(platform, taskChannelM, taskM)
+		scheduleNextPoll(ctx, platform, taskM, previousProgress)
+	}
+	common.SysLog("任务进度轮询完成")
+}
+
+// scheduleNextPoll 为仍未完成的任务安排下次轮询，进度有变化时恢复基础间隔，否则按次数退避
+func scheduleNextPoll(ctx context.Context, platform constant.TaskPlatform, taskM map[string]*model.Task, previousProgress map[int64]string) {
+	type schedule struct {
+		nextPollAt int64
+		pollCount  int
+	}
+	now := time.Now().Unix()
+	scheduleIds := make(map[schedule][]int64)
+	for _, task := range taskM {
+		if task.Progress == "100%" {
+			continue
+		}
+		pollCount := task.PollCount + 1
+		if previousProgress[task.ID] != string(task.Status)+"|"+task.Progress {
+			pollCount = 0
+		}
+		next := schedule{
+			nextPollAt: now + int64(service.TaskPollInterval(string(platform), pollCount)),
+			pollCount:  pollCount,
+		}
+		scheduleIds[next] = append(scheduleIds[next], task.ID)
+	}
+	for next, ids := range scheduleIds {
+		if err := model.TaskUpdatePollSchedule(ids, next.nextPollAt, next.pollCount); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("Update task poll schedule error: %v", err))
+		}
+	}
+}
 
-			UpdateTaskByPlatform(platform, taskChannelM, taskM)
+// failTimeoutTasks 将提交后超过 timeoutMinutes 仍未完成的任务判定失败并退还额度
+func failTimeoutTasks(ctx context.Context, timeoutMinutes int, now int64) {
+	if timeoutMinutes <= 0 {
+		return
+	}
+	tasks := model.GetTimeoutUnFinishSyncTasks(now-int64(timeoutMinutes)*60, 100)
+	for _, task := range tasks {
+		failReason := fmt.Sprintf("任务超时：提交后 %d 分钟内未完成", timeoutMinutes)
+		failed, err := model.TaskFailIfUnfinished(task, failReason, now)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("Fail timeout task %s error: %v", task.TaskID, err))
+			continue
+		}
+		if !failed {
+			continue
 		}
-		common.SysLog("任务进度轮询完成")
+		metrics.RecordTaskTimeout(string(task.Platform))
+		logger.LogInfo(ctx, fmt.Sprintf("Task %s timeout after %d minutes", task.TaskID, timeoutMinutes))
+		settleAndNotifyTask(ctx, task)
 	}
 }
 
@@ -101,13 +189,16 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 	channel, err := model.CacheGetChannel(channelId)
 	if err != nil {
 		common.SysLog(fmt.Sprintf("CacheGetChannel: %v", err))
+		failReason := fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId)
 		err = model.TaskBulkUpdate(taskIds, map[string]any{
-			"fail_reason": fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId),
+			"fail_reason": failReason,
 			"status":      "FAILURE",
 			"progress":    "100%",
 		})
 		if err != nil {
 			common.SysLog(fmt.Sprintf("UpdateMidjourneyTask error2: %v", err))
+		} else {
+			notifyTasksFailed(taskIds, taskM, failReason)
 		}
 		return err
 	}
@@ -149,6 +240,8 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 			continue
 		}
 
+		previousStatus := task.Status
+		previousProgress := task.Progress
 		task.Status = lo.If(model.TaskStatus(responseItem.Status) != "", model.TaskStatus(responseItem.Status)).Else(task.Status)
 		task.FailReason = lo.If(responseItem.FailReason != "", responseItem.FailReason).Else(task.FailReason)
 		task.SubmitTime = lo.If(responseItem.SubmitTime != 0, responseItem.SubmitTime).Else(task.SubmitTime)
@@ -157,20 +250,6 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		if responseItem.FailReason != "" || task.Status == model.TaskStatusFailure {
 			logger.LogInfo(ctx, task.TaskID+" 构建失败，"+task.FailReason)
 			task.Progress = "100%"
-			//err = model.CacheUpdateUserQuota(task.UserId) ?
-			if err != nil {
-				logger.LogError(ctx, "error update user quota cache: "+err.Error())
-			} else {
-				quota := task.Quota
-				if quota != 0 {
-					err = model.IncreaseUserQuota(task.UserId, quota, false)
-					if err != nil {
-						logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-					}
-					logContent := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, logger.LogQuota(quota))
-					model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
-				}
-			}
 		}
 		if responseItem.Status == model.TaskStatusSuccess {
 			task.Progress = "100%"
@@ -180,11 +259,47 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		err = task.Update()
 		if err != nil {
 			common.SysLog("UpdateMidjourneyTask task error: " + err.Error())
+		} else if previousStatus != task.Status || previousProgress != task.Progress {
+			// 上游返回失败原因但状态未置为失败时同样已结束，由结算全额退还
+			settleAndNotifyTask(ctx, task)
 		}
 	}
 	return nil
 }
 
+// notifyTasksFailed 批量标记失败后，退还任务额度并推送失败结果
+func notifyTasksFailed(taskIds []string, taskM map[string]*model.Task, failReason string) {
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		if task == nil {
+			continue
+		}
+		task.Status = model.TaskStatusFailure
+		task.Progress = "100%"
+		task.FailReason = failReason
+		settleAndNotifyTask(context.Background(), task)
+	}
+}
+
+// settleAndNotifyTask 任务状态变化后结算额度，进入终态时推送回调
+func settleAndNotifyTask(ctx context.Context, task *model.Task) {
+	if task.Status == model.TaskStatusSuccess && task.Properties.SongCount > 0 {
+		// 按首计费的音乐任务按实际生成的歌曲数结算
+		if songs, err := relay.MusicTaskSongs(task); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("Failed to parse songs of task %s: %s", task.TaskID, err.Error()))
+		} else if len(songs) > 0 {
+			task.Properties.UsageSongCount = len(songs)
+			if err = model.TaskUpdateProperties(task.ID, task.Properties); err != nil {
+				logger.LogError(ctx, fmt.Sprintf("Failed to update task %s properties: %s", task.TaskID, err.Error()))
+			}
+		}
+	}
+	if _, err := service.SettleTask(task); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("Failed to settle task %s: %s", task.TaskID, err.Error()))
+	}
+	relay.NotifyTaskFinished(task)
+}
+
 func checkTaskNeedUpdate(oldTask *model.Task, newTask dto.SunoDataResponse) bool {
 
 	if oldTask.SubmitTime != newTask.SubmitTime {
@@ -272,3 +387,31 @@ func GetUserTask(c *gin.Context) {
 	pageInfo.SetItems(items)
 	common.ApiSuccess(c, pageInfo)
 }
+
+// SettleTask 手动重新结算任务额度，仅退还尚未退还的差额
+func SettleTask(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	task, err := model.GetTaskById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	// 上游返回失败原因的任务（如 Suno）结束时状态不一定是失败，以进度判断是否结束
+	if task.Progress != "100%" && task.Status != model.TaskStatusSuccess && task.Status != model.TaskStatusFailure {
+		common.ApiErrorMsg(c, "任务尚未结束，无法结算")
+		return
+	}
+	refund, err := service.SettleTask(task)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"refund":       refund,
+		"refund_quota": task.RefundQuota,
+	})
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"strconv"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+func GetAllTaskCallbacks(c *gin.Context) {
+	getTaskCallbacks(c, 0)
+}
+
+func GetUserTaskCallbacks(c *gin.Context) {
+	getTaskCallbacks(c, c.GetInt("id"))
+}
+
+func getTaskCallbacks(c *gin.Context, userId int) {
+	pageInfo := common.GetPageQuery(c)
+	callbacks, total, err := model.GetTaskCallbacks(userId, c.Query("task_id"), c.Query("status"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(callbacks)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// RetryTaskCallback 手动重新投递一次回调，投递结果作为新的记录保存
+func RetryTaskCallback(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	callback, err := model.GetTaskCallbackById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if callback.Status == common.TaskCallbackStatusRetrying {
+		// 与定时重试竞争领取，避免重复投递
+		claimed, err := model.ClaimTaskCallbackRetry(callback.Id)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if !claimed {
+			common.ApiErrorMsg(c, "该回调正在重试中")
+			return
+		}
+	}
+	gopool.Go(func() {
+		service.RetryTaskCallback(callback)
+	})
+	common.ApiSuccess(c, nil)
+}
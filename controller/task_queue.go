@@ -0,0 +1,431 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/logger"
+	"one-api/model"
+	"one-api/relay"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// taskQueueTick 队列调度的检查周期
+	taskQueueTick = 2 * time.Second
+	// taskQueueHeartbeatTimeout 入队节点超过该时间（秒）未更新心跳，其排队请求判定失败
+	taskQueueHeartbeatTimeout = 60
+)
+
+// queuedSubmission 在入队节点内存中等待调度的提交请求
+type queuedSubmission struct {
+	item     *model.TaskQueueItem
+	ctx      *gin.Context
+	recorder *httptest.ResponseRecorder
+	handler  gin.HandlerFunc
+}
+
+var (
+	queuedSubmissions     = make(map[int]*queuedSubmission)
+	queuedSubmissionsLock sync.Mutex
+)
+
+// queueTaskSubmit 用户或渠道的并发任务数已满时将异步任务提交放入网关队列并直接响应，返回是否已排队
+// 未排队时调用方需要在提交结束后调用返回的 release 释放并发名额
+func queueTaskSubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) (bool, func()) {
+	if relayInfo.RelayMode == relayconstant.RelayModeSunoFetch || relayInfo.RelayMode == relayconstant.RelayModeSunoFetchByID ||
+		relayInfo.RelayMode == relayconstant.RelayModeVideoFetchByID {
+		return false, func() {}
+	}
+	queue, release := reserveTaskSubmit(c)
+	if !queue {
+		return false, release
+	}
+	if taskQueueFull(c) {
+		c.JSON(http.StatusTooManyRequests, service.TaskErrorWrapperLocal(errors.New("too many queued tasks, please try again later"), "task_queue_full", http.StatusTooManyRequests))
+		return true, func() {}
+	}
+
+	platform := constant.TaskPlatform(c.GetString("platform"))
+	if platform == "" {
+		platform = relay.GetTaskPlatform(c)
+	}
+	task := model.InitTask(platform, relayInfo)
+	task.ChannelId = c.GetInt("channel_id")
+	task.Status = model.TaskStatusQueued
+	task.QueueId = newTaskQueueId()
+	task.TaskID = task.QueueId
+	task.Properties.Model = c.GetString("original_model")
+	task.CallbackUrl, _ = service.GetTaskCallbackUrl(c)
+	if err := task.Insert(); err != nil {
+		c.JSON(http.StatusInternalServerError, service.TaskErrorWrapper(err, "insert_task_failed", http.StatusInternalServerError))
+		return true, func() {}
+	}
+	position, err := enqueueSubmission(c, model.TaskQueueKindTask, task.ID, task, RelayTask)
+	if err != nil {
+		failQueuedRecord(model.TaskQueueKindTask, task.ID, "加入队列失败")
+		c.JSON(http.StatusInternalServerError, service.TaskErrorWrapper(err, "enqueue_task_failed", http.StatusInternalServerError))
+		return true, func() {}
+	}
+	logger.LogInfo(c, fmt.Sprintf("任务 %s 进入网关队列，排队位置 %d", task.QueueId, position))
+
+	// OpenAI 视频与统一音乐接口根据该任务生成各自格式的响应
+	common.SetContextKey(c, constant.ContextKeyTask, task)
+	if relayInfo.RelayMode == relayconstant.RelayModeSunoSubmit {
+		c.JSON(http.StatusOK, dto.TaskResponse[string]{
+			Code: "success",
+			Data: task.TaskID,
+		})
+		return true, func() {}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":        task.TaskID,
+		"status":         "queued",
+		"queue_position": position,
+	})
+	return true, func() {}
+}
+
+// queueMidjourneySubmit 用户或渠道的并发任务数已满时将 Midjourney 提交放入网关队列并直接响应，返回是否已排队
+// 未排队时调用方需要在提交结束后调用返回的 release 释放并发名额
+func queueMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) (bool, func()) {
+	// 上传图片立即完成，不占用并发名额
+	if relayInfo.RelayMode == relayconstant.RelayModeMidjourneyUpload {
+		return false, func() {}
+	}
+	var midjRequest dto.MidjourneyRequest
+	if err := common.UnmarshalBodyReusable(c, &midjRequest); err != nil {
+		// 请求体错误由提交流程返回
+		return false, func() {}
+	}
+	queue, release := reserveTaskSubmit(c)
+	if !queue {
+		return false, release
+	}
+	if taskQueueFull(c) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"description": "队列已满，请稍后尝试",
+			"type":        "upstream_error",
+			"code":        23,
+		})
+		return true, func() {}
+	}
+
+	queueId := newTaskQueueId()
+	task := &model.Midjourney{
+		UserId:      c.GetInt("id"),
+		Action:      midjRequest.Action,
+		MjId:        queueId,
+		QueueId:     queueId,
+		Prompt:      midjRequest.Prompt,
+		Description: "排队中",
+		SubmitTime:  time.Now().UnixNano() / int64(time.Millisecond),
+		Status:      model.TaskStatusQueued,
+		Progress:    "0%",
+		ChannelId:   c.GetInt("channel_id"),
+	}
+	task.CallbackUrl, _ = service.GetTaskCallbackUrl(c)
+	if err := task.Insert(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"description": "insert_midjourney_task_failed",
+			"type":        "upstream_error",
+			"code":        4,
+		})
+		return true, func() {}
+	}
+	position, err := enqueueSubmission(c, model.TaskQueueKindMidjourney, int64(task.Id), task, RelayMidjourney)
+	if err != nil {
+		failQueuedRecord(model.TaskQueueKindMidjourney, int64(task.Id), "加入队列失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"description": "enqueue_midjourney_task_failed",
+			"type":        "upstream_error",
+			"code":        4,
+		})
+		return true, func() {}
+	}
+	logger.LogInfo(c, fmt.Sprintf("Midjourney 任务 %s 进入网关队列，排队位置 %d", queueId, position))
+
+	// 与上游排队（code 22）一致，以提交成功返回，客户端按任务 ID 轮询
+	c.JSON(http.StatusOK, dto.MidjourneyResponse{
+		Code:        1,
+		Description: fmt.Sprintf("排队中，前面还有%d个任务", position-1),
+		Result:      queueId,
+		Properties: map[string]any{
+			"numberOfQueues": position - 1,
+		},
+	})
+	return true, func() {}
+}
+
+// reserveTaskSubmit 为直接提交占用并发名额，名额已满时返回 queue=true
+func reserveTaskSubmit(c *gin.Context) (queue bool, release func()) {
+	release = func() {}
+	// 从队列调度执行的请求已分配名额
+	if _, ok := common.GetContextKey(c, constant.ContextKeyQueuedTask); ok {
+		return false, release
+	}
+	if !operation_setting.GetTaskQueueSetting().Enabled {
+		return false, release
+	}
+	userId := c.GetInt("id")
+	channelId := c.GetInt("channel_id")
+	reserved, err := service.TaskQueueReserve(userId, channelId)
+	if err != nil {
+		// 统计失败时不阻塞提交
+		logger.LogError(c, "failed to check task queue: "+err.Error())
+		return false, release
+	}
+	if !reserved {
+		return true, release
+	}
+	return false, func() {
+		service.TaskQueueRelease(userId, channelId)
+	}
+}
+
+func taskQueueFull(c *gin.Context) bool {
+	maxQueued := operation_setting.GetTaskQueueSetting().MaxQueuedPerUser
+	if maxQueued <= 0 {
+		return false
+	}
+	count, err := model.CountUserTaskQueueItems(c.GetInt("id"))
+	if err != nil {
+		logger.LogError(c, "failed to count queued tasks: "+err.Error())
+		return false
+	}
+	return int(count) >= maxQueued
+}
+
+func newTaskQueueId() string {
+	return "queue_" + common.GetUUID()
+}
+
+// enqueueSubmission 复制请求上下文保存到本节点内存，并写入队列记录，返回排队位置
+func enqueueSubmission(c *gin.Context, kind string, recordId int64, placeholder any, handler gin.HandlerFunc) (int, error) {
+	ctx, recorder, err := cloneQueuedContext(c)
+	if err != nil {
+		return 0, err
+	}
+	common.SetContextKey(ctx, constant.ContextKeyQueuedTask, placeholder)
+	item := &model.TaskQueueItem{
+		Kind:      kind,
+		RecordId:  recordId,
+		UserId:    c.GetInt("id"),
+		ChannelId: c.GetInt("channel_id"),
+		Priority:  operation_setting.GetTaskQueueSetting().GetGroupPriority(common.GetContextKeyString(c, constant.ContextKeyUsingGroup)),
+		NodeId:    service.TaskQueueNodeId,
+	}
+	if err = item.Insert(); err != nil {
+		return 0, err
+	}
+	queuedSubmissionsLock.Lock()
+	queuedSubmissions[item.Id] = &queuedSubmission{
+		item:     item,
+		ctx:      ctx,
+		recorder: recorder,
+		handler:  handler,
+	}
+	queuedSubmissionsLock.Unlock()
+	return model.GetTaskQueuePosition(kind, recordId), nil
+}
+
+// cloneQueuedContext 复制请求上下文，原请求响应后仍可在调度时重新执行提交
+func cloneQueuedContext(c *gin.Context) (*gin.Context, *httptest.ResponseRecorder, error) {
+	requestBody, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = c.Request.Clone(context.Background())
+	ctx.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+	ctx.Params = append(gin.Params(nil), c.Params...)
+	ctx.Keys = maps.Clone(c.Keys)
+	return ctx, recorder, nil
+}
+
+// RunTaskQueueDispatcher 定期调度网关队列，每个节点只执行自己入队的请求，需要在所有节点启动
+func RunTaskQueueDispatcher() {
+	for {
+		time.Sleep(taskQueueTick)
+		dispatchTaskQueue()
+	}
+}
+
+func dispatchTaskQueue() {
+	setting := operation_setting.GetTaskQueueSetting()
+	now := common.GetTimestamp()
+
+	queuedSubmissionsLock.Lock()
+	local := len(queuedSubmissions)
+	queuedSubmissionsLock.Unlock()
+	if local > 0 {
+		if err := model.TouchTaskQueueItems(service.TaskQueueNodeId, now); err != nil {
+			common.SysError("failed to touch task queue items: " + err.Error())
+		}
+	}
+
+	// 入队节点失效（请求上下文已丢失）或排队超时的请求判定失败
+	var createdBefore int64
+	if setting.MaxWaitMinutes > 0 {
+		createdBefore = now - int64(setting.MaxWaitMinutes)*60
+	}
+	staleItems, err := model.GetStaleTaskQueueItems(now-taskQueueHeartbeatTimeout, createdBefore)
+	if err != nil {
+		common.SysError("failed to get stale task queue items: " + err.Error())
+		return
+	}
+	for _, item := range staleItems {
+		removed, err := model.DeleteTaskQueueItem(item.Id)
+		if err != nil || !removed {
+			continue
+		}
+		takeQueuedSubmission(item.Id)
+		reason := "排队超时"
+		if item.HeartbeatAt < now-taskQueueHeartbeatTimeout {
+			reason = "排队节点已失效"
+		}
+		failQueuedRecord(item.Kind, item.RecordId, reason)
+	}
+
+	listedAt := common.GetTimestamp()
+	items, err := model.GetTaskQueueItems()
+	if err != nil {
+		common.SysError("failed to get task queue items: " + err.Error())
+		return
+	}
+	pruneQueuedSubmissions(items, listedAt)
+	if len(items) == 0 {
+		return
+	}
+	userIds := make([]int, 0, len(items))
+	channelIds := make([]int, 0, len(items))
+	for _, item := range items {
+		userIds = append(userIds, item.UserId)
+		channelIds = append(channelIds, item.ChannelId)
+	}
+	usage, err := service.LoadTaskQueueUsage(userIds, channelIds)
+	if err != nil {
+		common.SysError("failed to load task queue usage: " + err.Error())
+		return
+	}
+	// 其他节点的请求同样参与名额分配，由各自节点执行
+	for _, item := range service.PlanTaskQueueDispatch(items, usage, setting) {
+		if item.NodeId == service.TaskQueueNodeId {
+			dispatchQueuedSubmission(item)
+		}
+	}
+}
+
+func dispatchQueuedSubmission(item *model.TaskQueueItem) {
+	removed, err := model.DeleteTaskQueueItem(item.Id)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to remove task queue item %d: %s", item.Id, err.Error()))
+		return
+	}
+	submission := takeQueuedSubmission(item.Id)
+	if !removed {
+		return
+	}
+	if submission == nil {
+		failQueuedRecord(item.Kind, item.RecordId, "排队请求已丢失")
+		return
+	}
+	service.TaskQueueAcquire(item.UserId, item.ChannelId)
+	gopool.Go(func() {
+		defer service.TaskQueueRelease(item.UserId, item.ChannelId)
+		submission.handler(submission.ctx)
+		// 提交失败时占位任务不会被替换，使用提交返回的错误信息将其判定失败
+		failQueuedRecord(item.Kind, item.RecordId, queuedSubmitFailReason(submission.recorder))
+	})
+}
+
+func takeQueuedSubmission(id int) *queuedSubmission {
+	queuedSubmissionsLock.Lock()
+	defer queuedSubmissionsLock.Unlock()
+	submission := queuedSubmissions[id]
+	delete(queuedSubmissions, id)
+	return submission
+}
+
+// pruneQueuedSubmissions 清理已被其他节点移出队列（例如用户取消）的本地请求
+// 只清理入队早于本次查询的请求，避免误删查询之后才入队的请求
+func pruneQueuedSubmissions(items []*model.TaskQueueItem, listedAt int64) {
+	ids := make(map[int]struct{}, len(items))
+	for _, item := range items {
+		ids[item.Id] = struct{}{}
+	}
+	queuedSubmissionsLock.Lock()
+	defer queuedSubmissionsLock.Unlock()
+	for id, submission := range queuedSubmissions {
+		if _, ok := ids[id]; !ok && submission.item.CreatedAt < listedAt-5 {
+			delete(queuedSubmissions, id)
+		}
+	}
+}
+
+func queuedSubmitFailReason(recorder *httptest.ResponseRecorder) string {
+	var resp struct {
+		Message     string `json:"message"`
+		Description string `json:"description"`
+	}
+	if err := common.Unmarshal(recorder.Body.Bytes(), &resp); err == nil {
+		if resp.Message != "" {
+			return resp.Message
+		}
+		if resp.Description != "" {
+			return resp.Description
+		}
+	}
+	return "提交任务失败"
+}
+
+// failQueuedRecord 将仍在网关排队（尚未提交上游）的占位任务判定失败，占位任务未扣费，无需退款
+func failQueuedRecord(kind string, recordId int64, reason string) {
+	switch kind {
+	case model.TaskQueueKindTask:
+		task, err := model.GetTaskById(recordId)
+		if err != nil || !task.IsGatewayQueued() {
+			return
+		}
+		failed, err := model.TaskFailIfUnfinished(task, reason, common.GetTimestamp())
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to fail queued task %s: %s", task.QueueId, err.Error()))
+			return
+		}
+		if failed {
+			relay.NotifyTaskFinished(task)
+		}
+	case model.TaskQueueKindMidjourney:
+		task := model.GetMjByuId(int(recordId))
+		if task == nil || !task.IsGatewayQueued() {
+			return
+		}
+		cancelled, err := model.CancelMjTask(task.Id, reason)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to fail queued midjourney task %s: %s", task.QueueId, err.Error()))
+			return
+		}
+		if cancelled {
+			if task = model.GetMjByuId(task.Id); task != nil {
+				relay.NotifyMidjourneyFinished(task)
+			}
+		}
+	}
+}
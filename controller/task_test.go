@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestSettleTask(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Task{}, &model.User{}, &model.Log{}))
+	originDB, originLogDB, usingSQLite := model.DB, model.LOG_DB, common.UsingSQLite
+	redisEnabled, batchUpdateEnabled := common.RedisEnabled, common.BatchUpdateEnabled
+	model.DB, model.LOG_DB, common.UsingSQLite = db, db, true
+	common.RedisEnabled, common.BatchUpdateEnabled = false, false
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB, common.UsingSQLite = originDB, originLogDB, usingSQLite
+		common.RedisEnabled, common.BatchUpdateEnabled = redisEnabled, batchUpdateEnabled
+		sqlDB.Close()
+	})
+
+	require.NoError(t, db.Create(&model.User{Id: 1, Username: "settle_user"}).Error)
+	require.NoError(t, db.Create(&model.Task{ID: 1, UserId: 1, TaskID: "video_running", Quota: 1000, Status: model.TaskStatusInProgress, Progress: "50%"}).Error)
+	// Suno 任务失败时状态不是 FAILURE，但进度为 100% 且带有失败原因
+	require.NoError(t, db.Create(&model.Task{ID: 2, UserId: 1, TaskID: "song_failed", Quota: 1000, Status: model.TaskStatusInProgress, Progress: "100%", FailReason: "content moderated"}).Error)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/task/:id/settle", SettleTask)
+	settle := func(id string) (bool, int) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/task/"+id+"/settle", nil))
+		var resp struct {
+			Success bool `json:"success"`
+			Data    struct {
+				Refund int `json:"refund"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Success, resp.Data.Refund
+	}
+
+	success, _ := settle("1")
+	assert.False(t, success)
+
+	success, refund := settle("2")
+	assert.True(t, success)
+	assert.Equal(t, 1000, refund)
+	success, refund = settle("2")
+	assert.True(t, success)
+	assert.Equal(t, 0, refund)
+	user, err := model.GetUserById(1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, user.Quota)
+}
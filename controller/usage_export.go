@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/logger"
+	"one-api/model"
+	"one-api/service"
+	"one-api/service/export"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ExportLogs(c *gin.Context) {
+	exportUsage(c, service.UsageDatasetLogs)
+}
+
+func ExportQuotaData(c *gin.Context) {
+	exportUsage(c, service.UsageDatasetQuotaData)
+}
+
+// exportUsage 以流的方式导出用量数据，format 支持 csv、ndjson 与 parquet
+func exportUsage(c *gin.Context, dataset string) {
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.IsValidFormat(format) {
+		common.ApiErrorMsg(c, "不支持的导出格式: "+format)
+		return
+	}
+	filter := model.UsageExportFilter{
+		Username:  c.Query("username"),
+		TokenName: c.Query("token_name"),
+		ModelName: c.Query("model_name"),
+		Group:     c.Query("group"),
+	}
+	filter.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	filter.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	filter.LogType, _ = strconv.Atoi(c.Query("type"))
+	filter.Channel, _ = strconv.Atoi(c.Query("channel"))
+
+	filename := fmt.Sprintf("%s-%s%s", dataset, time.Now().Format("20060102150405"), export.FileExtension(format))
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	if _, err := service.ExportUsage(c.Writer, dataset, format, filter); err != nil {
+		// 响应已经开始写出，只能记录错误
+		logger.LogError(c, "failed to export usage: "+err.Error())
+	}
+}
+
+func GetUsageReports(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	reports, total, err := model.GetUsageReports(pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(reports)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GenerateUsageReports 手动生成指定日期的报表，未指定时为前一天
+func GenerateUsageReports(c *gin.Context) {
+	period := c.DefaultQuery("period", time.Now().AddDate(0, 0, -1).Format(time.DateOnly))
+	reports, err := service.GenerateUsageReports(period)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, reports)
+}
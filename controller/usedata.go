@@ -13,6 +13,16 @@ func GetAllQuotaDates(c *gin.Context) {
 	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
 	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
 	username := c.Query("username")
+	// 指定标签键时按标签值聚合
+	if tagKey := c.Query("tag_key"); tagKey != "" {
+		dates, err := model.GetQuotaDataByTag(tagKey, startTimestamp, endTimestamp, 0, username)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		common.ApiSuccess(c, dates)
+		return
+	}
 	dates, err := model.GetAllQuotaDates(startTimestamp, endTimestamp, username)
 	if err != nil {
 		common.ApiError(c, err)
@@ -38,6 +48,15 @@ func GetUserQuotaDates(c *gin.Context) {
 		})
 		return
 	}
+	if tagKey := c.Query("tag_key"); tagKey != "" {
+		dates, err := model.GetQuotaDataByTag(tagKey, startTimestamp, endTimestamp, userId, "")
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		common.ApiSuccess(c, dates)
+		return
+	}
 	dates, err := model.GetQuotaDataByUserId(userId, startTimestamp, endTimestamp)
 	if err != nil {
 		common.ApiError(c, err)
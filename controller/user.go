@@ -10,6 +10,7 @@ import (
 	"one-api/logger"
 	"one-api/model"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"strconv"
 	"strings"
 	"sync"
@@ -1104,6 +1105,7 @@ type UpdateUserSettingRequest struct {
 	BarkUrl                    string  `json:"bark_url,omitempty"`
 	AcceptUnsetModelRatioModel bool    `json:"accept_unset_model_ratio_model"`
 	RecordIpLog                bool    `json:"record_ip_log"`
+	Currency                   string  `json:"currency,omitempty"`
 }
 
 func UpdateUserSetting(c *gin.Context) {
@@ -1192,6 +1194,16 @@ func UpdateUserSetting(c *gin.Context) {
 		}
 	}
 
+	if req.Currency != "" {
+		if _, ok := operation_setting.GetExchangeRate(req.Currency); !ok {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "不支持的货币: " + req.Currency,
+			})
+			return
+		}
+	}
+
 	userId := c.GetInt("id")
 	user, err := model.GetUserById(userId, true)
 	if err != nil {
@@ -1205,6 +1217,7 @@ func UpdateUserSetting(c *gin.Context) {
 		QuotaWarningThreshold: req.QuotaWarningThreshold,
 		AcceptUnsetRatioModel: req.AcceptUnsetModelRatioModel,
 		RecordIpLog:           req.RecordIpLog,
+		Currency:              strings.ToUpper(req.Currency),
 	}
 
 	// 如果是webhook类型,添加webhook相关设置
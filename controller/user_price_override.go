@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetUserPriceOverrides(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	overrides, total, err := model.GetUserPriceOverrides(userId, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(overrides)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func CreateUserPriceOverride(c *gin.Context) {
+	var override model.UserPriceOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := override.Validate(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if _, err := model.GetUserById(override.UserId, false); err != nil {
+		common.ApiErrorMsg(c, "用户不存在")
+		return
+	}
+	if err := override.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &override)
+}
+
+func UpdateUserPriceOverride(c *gin.Context) {
+	var override model.UserPriceOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if override.Id == 0 {
+		common.ApiErrorMsg(c, "缺少协议价 ID")
+		return
+	}
+	origin, err := model.GetUserPriceOverrideById(override.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err = override.Validate(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	override.CreatedTime = origin.CreatedTime
+	if err = override.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &override)
+}
+
+func DeleteUserPriceOverride(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err = model.DeleteUserPriceOverrideById(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/logger"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/types"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func openAIVideoError(c *gin.Context, statusCode int, code string, message string) {
+	c.JSON(statusCode, gin.H{
+		"error": types.OpenAIError{
+			Message: common.MessageWithRequestId(message, c.GetString(common.RequestIdKey)),
+			Type:    "new_api_error",
+			Code:    code,
+		},
+	})
+}
+
+// getUserVideo 获取当前用户的视频任务，不存在时直接写出 404
+func getUserVideo(c *gin.Context) *model.Task {
+	task, exist, err := model.GetByTaskId(c.GetInt("id"), c.Param("video_id"))
+	if err != nil {
+		openAIVideoError(c, http.StatusInternalServerError, "get_task_failed", err.Error())
+		return nil
+	}
+	if !exist || constant.IsMusicTaskPlatform(task.Platform) {
+		openAIVideoError(c, http.StatusNotFound, "video_not_found", fmt.Sprintf("video %s not found", c.Param("video_id")))
+		return nil
+	}
+	return task
+}
+
+// RelayOpenAIVideo
+// @Summary 创建视频（OpenAI 兼容）
+// @Description 兼容 OpenAI /v1/videos 接口，通过已配置的视频渠道生成视频；请求路径带 video_id 时为 remix
+// @Tags Video
+// @Accept json,mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.OpenAIVideoRequest true "视频生成请求参数"
+// @Success 200 {object} dto.OpenAIVideo "视频对象"
+// @Failure 400 {object} dto.OpenAIError "请求参数错误"
+// @Router /v1/videos [post]
+// @Router /v1/videos/{video_id}/remix [post]
+func RelayOpenAIVideo(c *gin.Context) {
+	writer := helper.NewBufferedResponseWriter(c.Writer)
+	c.Writer = writer
+	RelayTask(c)
+	c.Writer = writer.ResponseWriter
+
+	if v, ok := common.GetContextKey(c, constant.ContextKeyTask); ok {
+		c.JSON(http.StatusOK, relay.TaskModel2OpenAIVideo(v.(*model.Task)))
+		return
+	}
+	var taskErr dto.TaskError
+	if err := common.Unmarshal(writer.Body.Bytes(), &taskErr); err != nil || taskErr.Message == "" {
+		taskErr.Message = "failed to create video"
+	}
+	statusCode := writer.StatusCode
+	if statusCode < http.StatusBadRequest {
+		statusCode = http.StatusInternalServerError
+	}
+	openAIVideoError(c, statusCode, taskErr.Code, taskErr.Message)
+}
+
+// ListOpenAIVideos
+// @Summary 列出视频（OpenAI 兼容）
+// @Tags Video
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "返回数量，默认 20，最大 100"
+// @Param after query string false "从该视频 ID 之后开始"
+// @Param order query string false "按创建时间排序，asc 或 desc，默认 desc"
+// @Success 200 {object} dto.OpenAIVideoList "视频列表"
+// @Router /v1/videos [get]
+func ListOpenAIVideos(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	userId := c.GetInt("id")
+	var afterId int64
+	if after := c.Query("after"); after != "" {
+		task, exist, err := model.GetByTaskId(userId, after)
+		if err != nil {
+			openAIVideoError(c, http.StatusInternalServerError, "get_task_failed", err.Error())
+			return
+		}
+		if !exist {
+			openAIVideoError(c, http.StatusBadRequest, "invalid_request", fmt.Sprintf("video %s not found", after))
+			return
+		}
+		afterId = task.ID
+	}
+	tasks, err := model.TaskGetUserVideos(userId, afterId, limit+1, c.Query("order") == "asc")
+	if err != nil {
+		openAIVideoError(c, http.StatusInternalServerError, "get_tasks_failed", err.Error())
+		return
+	}
+	list := dto.OpenAIVideoList{
+		Object: "list",
+		Data:   make([]*dto.OpenAIVideo, 0, len(tasks)),
+	}
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		list.HasMore = true
+	}
+	for _, task := range tasks {
+		list.Data = append(list.Data, relay.TaskModel2OpenAIVideo(task))
+	}
+	if len(list.Data) > 0 {
+		list.FirstId = &list.Data[0].Id
+		list.LastId = &list.Data[len(list.Data)-1].Id
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// RetrieveOpenAIVideo
+// @Summary 查询视频（OpenAI 兼容）
+// @Tags Video
+// @Produce json
+// @Security BearerAuth
+// @Param video_id path string true "视频 ID"
+// @Success 200 {object} dto.OpenAIVideo "视频对象"
+// @Router /v1/videos/{video_id} [get]
+func RetrieveOpenAIVideo(c *gin.Context) {
+	task := getUserVideo(c)
+	if task == nil {
+		return
+	}
+	c.JSON(http.StatusOK, relay.TaskModel2OpenAIVideo(task))
+}
+
+// DeleteOpenAIVideo
+// @Summary 删除视频（OpenAI 兼容）
+// @Description 仅可删除已结束的视频，删除后无法再查询或下载
+// @Tags Video
+// @Produce json
+// @Security BearerAuth
+// @Param video_id path string true "视频 ID"
+// @Success 200 {object} dto.OpenAIVideoDeleted "删除结果"
+// @Router /v1/videos/{video_id} [delete]
+func DeleteOpenAIVideo(c *gin.Context) {
+	task := getUserVideo(c)
+	if task == nil {
+		return
+	}
+	if task.Status != model.TaskStatusSuccess && task.Status != model.TaskStatusFailure {
+		openAIVideoError(c, http.StatusBadRequest, "invalid_request", "video is still being generated")
+		return
+	}
+	if err := task.Delete(); err != nil {
+		openAIVideoError(c, http.StatusInternalServerError, "delete_task_failed", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, dto.OpenAIVideoDeleted{
+		Id:      task.TaskID,
+		Object:  "video.deleted",
+		Deleted: true,
+	})
+}
+
+// GetOpenAIVideoContent
+// @Summary 下载视频内容（OpenAI 兼容）
+// @Description 由服务端代理下载，不暴露上游地址与密钥，目前仅支持 variant=video
+// @Tags Video
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param video_id path string true "视频 ID"
+// @Param variant query string false "内容类型，默认 video"
+// @Router /v1/videos/{video_id}/content [get]
+func GetOpenAIVideoContent(c *gin.Context) {
+	if variant := c.DefaultQuery("variant", "video"); variant != "video" {
+		openAIVideoError(c, http.StatusBadRequest, "invalid_request", fmt.Sprintf("variant %s is not supported", variant))
+		return
+	}
+	task := getUserVideo(c)
+	if task == nil {
+		return
+	}
+	if task.Status == model.TaskStatusSuccess && task.Properties.MediaKey != "" {
+		if media, err := model.GetMediaByKey(task.Properties.MediaKey); err == nil {
+			relay.ServeMedia(c, media)
+			return
+		}
+	}
+	// 成功任务的结果地址保存在 FailReason 中
+	contentUrl := task.FailReason
+	if task.Status != model.TaskStatusSuccess || contentUrl == "" {
+		openAIVideoError(c, http.StatusNotFound, "video_content_not_ready", "video content is not available")
+		return
+	}
+	if strings.HasPrefix(contentUrl, "data:") {
+		mimeType, data, found := strings.Cut(strings.TrimPrefix(contentUrl, "data:"), ",")
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if !found || err != nil {
+			openAIVideoError(c, http.StatusBadGateway, "invalid_video_content", "failed to decode video content")
+			return
+		}
+		c.Data(http.StatusOK, strings.TrimSuffix(mimeType, ";base64"), decoded)
+		return
+	}
+	resp, err := service.DoDownloadRequest(contentUrl, "video content")
+	if err != nil {
+		// 错误信息可能包含上游地址，仅记录日志
+		logger.LogError(c, fmt.Sprintf("download video %s failed: %s", task.TaskID, err.Error()))
+		openAIVideoError(c, http.StatusBadGateway, "download_video_failed", "failed to download video content")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		openAIVideoError(c, http.StatusBadGateway, "download_video_failed", fmt.Sprintf("upstream status code %d", resp.StatusCode))
+		return
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+	c.DataFromReader(http.StatusOK, resp.ContentLength, contentType, resp.Body, nil)
+}
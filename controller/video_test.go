@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestDeleteOpenAIVideo(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Task{}))
+	originDB, usingSQLite := model.DB, common.UsingSQLite
+	model.DB, common.UsingSQLite = db, true
+	t.Cleanup(func() {
+		model.DB, common.UsingSQLite = originDB, usingSQLite
+		sqlDB.Close()
+	})
+
+	require.NoError(t, db.Create(&model.Task{UserId: 1, TaskID: "video_running", Platform: "kling", Status: model.TaskStatusInProgress}).Error)
+	require.NoError(t, db.Create(&model.Task{UserId: 1, TaskID: "video_done", Platform: "kling", Status: model.TaskStatusSuccess}).Error)
+	require.NoError(t, db.Create(&model.Task{UserId: 2, TaskID: "video_other", Platform: "kling", Status: model.TaskStatusSuccess}).Error)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/v1/videos/:video_id", func(c *gin.Context) {
+		c.Set("id", 1)
+	}, DeleteOpenAIVideo)
+	deleteVideo := func(videoId string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/videos/"+videoId, nil))
+		return w.Code
+	}
+
+	// 生成中的视频不能删除
+	assert.Equal(t, http.StatusBadRequest, deleteVideo("video_running"))
+	_, exist, err := model.GetByTaskId(1, "video_running")
+	require.NoError(t, err)
+	assert.True(t, exist)
+
+	assert.Equal(t, http.StatusOK, deleteVideo("video_done"))
+	_, exist, err = model.GetByTaskId(1, "video_done")
+	require.NoError(t, err)
+	assert.False(t, exist)
+	assert.Equal(t, http.StatusNotFound, deleteVideo("video_done"))
+
+	// 不能删除其他用户的视频
+	assert.Equal(t, http.StatusNotFound, deleteVideo("video_other"))
+}
@@ -45,26 +45,27 @@ type MidjourneyResponseWithStatusCode struct {
 }
 
 type MidjourneyDto struct {
-	MjId        string      `json:"id"`
-	Action      string      `json:"action"`
-	CustomId    string      `json:"customId"`
-	BotType     string      `json:"botType"`
-	Prompt      string      `json:"prompt"`
-	PromptEn    string      `json:"promptEn"`
-	Description string      `json:"description"`
-	State       string      `json:"state"`
-	SubmitTime  int64       `json:"submitTime"`
-	StartTime   int64       `json:"startTime"`
-	FinishTime  int64       `json:"finishTime"`
-	ImageUrl    string      `json:"imageUrl"`
-	VideoUrl    string      `json:"videoUrl"`
-	VideoUrls   []ImgUrls   `json:"videoUrls"`
-	Status      string      `json:"status"`
-	Progress    string      `json:"progress"`
-	FailReason  string      `json:"failReason"`
-	Buttons     any         `json:"buttons"`
-	MaskBase64  string      `json:"maskBase64"`
-	Properties  *Properties `json:"properties"`
+	MjId          string      `json:"id"`
+	Action        string      `json:"action"`
+	CustomId      string      `json:"customId"`
+	BotType       string      `json:"botType"`
+	Prompt        string      `json:"prompt"`
+	PromptEn      string      `json:"promptEn"`
+	Description   string      `json:"description"`
+	State         string      `json:"state"`
+	SubmitTime    int64       `json:"submitTime"`
+	StartTime     int64       `json:"startTime"`
+	FinishTime    int64       `json:"finishTime"`
+	ImageUrl      string      `json:"imageUrl"`
+	VideoUrl      string      `json:"videoUrl"`
+	VideoUrls     []ImgUrls   `json:"videoUrls"`
+	Status        string      `json:"status"`
+	Progress      string      `json:"progress"`
+	FailReason    string      `json:"failReason"`
+	Buttons       any         `json:"buttons"`
+	MaskBase64    string      `json:"maskBase64"`
+	Properties    *Properties `json:"properties"`
+	QueuePosition int         `json:"queuePosition,omitempty"` // 在网关排队时的位置，从 1 开始
 }
 
 type ImgUrls struct {
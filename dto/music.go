@@ -0,0 +1,43 @@
+package dto
+
+// MusicRequest /v1/music/generations 统一的音乐生成请求，由各平台适配器转换为上游请求
+type MusicRequest struct {
+	Model        string         `json:"model,omitempty" example:"suno_music"`
+	Prompt       string         `json:"prompt,omitempty" example:"a cheerful summer pop song"` // 歌曲描述，未提供歌词时由上游生成歌词
+	Lyrics       string         `json:"lyrics,omitempty"`                                      // 自定义歌词
+	Style        string         `json:"style,omitempty" example:"pop, upbeat"`                 // 风格标签
+	Title        string         `json:"title,omitempty"`
+	Duration     int            `json:"duration,omitempty"`     // 期望时长（秒），仅部分平台支持
+	Instrumental bool           `json:"instrumental,omitempty"` // 纯音乐，不生成人声
+	Metadata     map[string]any `json:"metadata,omitempty"`     // 透传给上游的平台参数
+}
+
+// MusicTask /v1/music/generations 接口返回的音乐任务
+type MusicTask struct {
+	TaskId        string      `json:"task_id"`
+	Object        string      `json:"object"`
+	Model         string      `json:"model"`
+	Status        string      `json:"status"` // queued, in_progress, completed, failed
+	Progress      string      `json:"progress,omitempty"`
+	CreatedAt     int64       `json:"created_at"`
+	FinishedAt    int64       `json:"finished_at,omitempty"`
+	Songs         []MusicSong `json:"songs"`
+	Error         *MusicError `json:"error,omitempty"`
+	QueuePosition int         `json:"queue_position,omitempty"` // 在网关排队时的位置，从 1 开始
+}
+
+// MusicSong 生成的单首歌曲
+type MusicSong struct {
+	Id       string  `json:"id"`
+	Title    string  `json:"title,omitempty"`
+	AudioUrl string  `json:"audio_url"`
+	ImageUrl string  `json:"image_url,omitempty"`
+	Lyrics   string  `json:"lyrics,omitempty"`
+	Style    string  `json:"style,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+type MusicError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -79,15 +79,16 @@ func (t *TaskResponse[T]) IsSuccess() bool {
 }
 
 type TaskDto struct {
-	TaskID     string          `json:"task_id"` // 第三方id，不一定有/ song id\ Task id
-	Action     string          `json:"action"`  // 任务类型, song, lyrics, description-mode
-	Status     string          `json:"status"`  // 任务状态, submitted, queueing, processing, success, failed
-	FailReason string          `json:"fail_reason"`
-	SubmitTime int64           `json:"submit_time"`
-	StartTime  int64           `json:"start_time"`
-	FinishTime int64           `json:"finish_time"`
-	Progress   string          `json:"progress"`
-	Data       json.RawMessage `json:"data"`
+	TaskID        string          `json:"task_id"` // 第三方id，不一定有/ song id\ Task id
+	Action        string          `json:"action"`  // 任务类型, song, lyrics, description-mode
+	Status        string          `json:"status"`  // 任务状态, submitted, queueing, processing, success, failed
+	FailReason    string          `json:"fail_reason"`
+	SubmitTime    int64           `json:"submit_time"`
+	StartTime     int64           `json:"start_time"`
+	FinishTime    int64           `json:"finish_time"`
+	Progress      string          `json:"progress"`
+	Data          json.RawMessage `json:"data"`
+	QueuePosition int             `json:"queue_position,omitempty"` // 在网关排队时的位置，从 1 开始
 }
 
 type SunoGoAPISubmitReq struct {
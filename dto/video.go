@@ -45,3 +45,55 @@ type VideoTaskError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
+
+// OpenAIVideoRequest OpenAI /v1/videos 创建视频的请求，也支持 multipart/form-data 上传 input_reference
+type OpenAIVideoRequest struct {
+	Model          string         `json:"model,omitempty" form:"model" example:"sora-2"`
+	Prompt         string         `json:"prompt" form:"prompt" example:"A calico cat playing a piano on stage"`
+	Seconds        string         `json:"seconds,omitempty" form:"seconds" example:"4"`  // 视频时长（秒）
+	Size           string         `json:"size,omitempty" form:"size" example:"720x1280"` // 分辨率，宽x高
+	InputReference string         `json:"input_reference,omitempty" form:"-"`            // 参考图 URL 或 Base64，multipart 请求中为文件
+	Metadata       map[string]any `json:"metadata,omitempty"`                            // 透传给上游的厂商参数
+}
+
+// OpenAIVideoRemixRequest OpenAI /v1/videos/{video_id}/remix 的请求
+type OpenAIVideoRemixRequest struct {
+	Prompt   string         `json:"prompt" example:"Extend the scene with the cat taking a bow"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// OpenAIVideo OpenAI /v1/videos 接口返回的视频对象
+type OpenAIVideo struct {
+	Id                 string            `json:"id"`
+	Object             string            `json:"object"`
+	Model              string            `json:"model"`
+	Status             string            `json:"status"` // queued, in_progress, completed, failed
+	Progress           int               `json:"progress"`
+	CreatedAt          int64             `json:"created_at"`
+	CompletedAt        *int64            `json:"completed_at"`
+	ExpiresAt          *int64            `json:"expires_at"`
+	Seconds            string            `json:"seconds,omitempty"`
+	Size               string            `json:"size,omitempty"`
+	RemixedFromVideoId *string           `json:"remixed_from_video_id"`
+	Error              *OpenAIVideoError `json:"error"`
+	QueuePosition      int               `json:"queue_position,omitempty"` // 在网关排队时的位置，从 1 开始
+}
+
+type OpenAIVideoError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type OpenAIVideoList struct {
+	Object  string         `json:"object"`
+	Data    []*OpenAIVideo `json:"data"`
+	FirstId *string        `json:"first_id"`
+	LastId  *string        `json:"last_id"`
+	HasMore bool           `json:"has_more"`
+}
+
+type OpenAIVideoDeleted struct {
+	Id      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
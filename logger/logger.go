@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"one-api/common"
+	"one-api/setting/operation_setting"
 	"os"
 	"path/filepath"
 	"sync"
@@ -93,7 +94,7 @@ func logHelper(ctx context.Context, level string, msg string) {
 
 func LogQuota(quota int) string {
 	if common.DisplayInCurrencyEnabled {
-		return fmt.Sprintf("＄%.6f 额度", float64(quota)/common.QuotaPerUnit)
+		return FormatQuota(quota) + " 额度"
 	} else {
 		return fmt.Sprintf("%d 点额度", quota)
 	}
@@ -101,7 +102,7 @@ func LogQuota(quota int) string {
 
 func FormatQuota(quota int) string {
 	if common.DisplayInCurrencyEnabled {
-		return fmt.Sprintf("＄%.6f", float64(quota)/common.QuotaPerUnit)
+		return operation_setting.FormatQuotaInCurrency(quota, operation_setting.ResolveDisplayCurrency(""))
 	} else {
 		return fmt.Sprintf("%d", quota)
 	}
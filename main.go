@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
 	"log"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/controller"
 	"one-api/logger"
@@ -52,6 +55,10 @@ func main() {
 		common.SysLog("running in debug mode")
 	}
 
+	defer func() {
+		_ = tracing.Shutdown(context.Background())
+	}()
+
 	defer func() {
 		err := model.CloseDB()
 		if err != nil {
@@ -88,6 +95,13 @@ func main() {
 	// 热更新配置
 	go model.SyncOptions(common.SyncFrequency)
 
+	// 用户协议价
+	model.InitUserPriceOverrideCache()
+	go model.SyncUserPriceOverrideCache(common.SyncFrequency)
+
+	// 汇率文件
+	go service.SyncExchangeRateFile()
+
 	// 数据看板
 	go model.UpdateQuotaData()
 
@@ -105,7 +119,8 @@ func main() {
 	service.GetCacheWarmerService().Start()
 	common.SysLog("Cache Warmer service started for intelligent pool cache keep-alive")
 
-	if common.IsMasterNode && constant.UpdateTask {
+	// 任务轮询在所有节点启动，启用 Redis 时由选举决定执行节点，否则仅主节点执行
+	if constant.UpdateTask {
 		gopool.Go(func() {
 			controller.UpdateMidjourneyTaskBulk()
 		})
@@ -113,6 +128,24 @@ func main() {
 			controller.UpdateTaskBulk()
 		})
 	}
+	// 排队请求的上下文保存在入队节点内存中，队列调度需要在所有节点启动
+	gopool.Go(func() {
+		controller.RunTaskQueueDispatcher()
+	})
+	if common.IsMasterNode {
+		gopool.Go(func() {
+			service.StartPostpaidBillingTask()
+		})
+		gopool.Go(func() {
+			service.StartUsageReportTask()
+		})
+		gopool.Go(func() {
+			service.StartTaskCallbackRetryTask()
+		})
+		gopool.Go(func() {
+			service.StartMediaCleanupTask()
+		})
+	}
 	if os.Getenv("BATCH_UPDATE_ENABLED") == "true" {
 		common.BatchUpdateEnabled = true
 		common.SysLog("batch update enabled with interval " + strconv.Itoa(common.BatchUpdateInterval) + "s")
@@ -145,6 +178,7 @@ func main() {
 	// This will cause SSE not to work!!!
 	//server.Use(gzip.Gzip(gzip.DefaultCompression))
 	server.Use(middleware.RequestId())
+	server.Use(middleware.Tracing())
 	middleware.SetUpLogger(server)
 	// Initialize session store
 	store := cookie.NewStore([]byte(common.SessionSecret))
@@ -255,6 +289,18 @@ func InitResources() error {
 
 	service.InitHttpClient()
 
+	if common.MetricsEnabled {
+		metrics.InitMetrics()
+	}
+
+	if common.TracingEnabled {
+		if err := tracing.Init(context.Background(), common.Version); err != nil {
+			common.SysError("failed to initialize tracing: " + err.Error())
+		} else {
+			common.SysLog("opentelemetry tracing enabled")
+		}
+	}
+
 	service.InitTokenEncoders()
 
 	// Initialize SQL Database
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/common"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/model"
 	"one-api/setting"
@@ -148,6 +149,8 @@ func WssAuth(c *gin.Context) {
 
 func TokenAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		_, span := tracing.Start(c.Request.Context(), "middleware.token_auth")
+		defer span.End()
 		// 先检测是否为ws
 		if c.Request.Header.Get("Sec-WebSocket-Protocol") != "" {
 			// Sec-WebSocket-Protocol: realtime, openai-insecure-api-key.sk-xxx, openai-beta.realtime-v1
@@ -206,9 +209,11 @@ func TokenAuth() func(c *gin.Context) {
 			}
 		}
 		if err != nil {
+			tracing.RecordError(span, err)
 			abortWithOpenAiMessage(c, http.StatusUnauthorized, err.Error())
 			return
 		}
+		span.SetAttributes(tracing.AttrTokenId.Int(token.Id), tracing.AttrUserId.Int(token.UserId))
 
 		allowIpsMap := token.GetIpLimitsMap()
 		if len(allowIpsMap) != 0 {
@@ -229,6 +234,10 @@ func TokenAuth() func(c *gin.Context) {
 			abortWithOpenAiMessage(c, http.StatusForbidden, "用户已被封禁")
 			return
 		}
+		if userCache.BillingLocked {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "账户存在逾期未结清的账单，已暂停使用")
+			return
+		}
 
 		userCache.WriteContext(c)
 
@@ -250,11 +259,13 @@ func TokenAuth() func(c *gin.Context) {
 			userGroup = tokenGroup
 		}
 		common.SetContextKey(c, constant.ContextKeyUsingGroup, userGroup)
+		span.SetAttributes(tracing.AttrGroup.String(userGroup))
 
 		err = SetupContextForToken(c, token, parts...)
 		if err != nil {
 			return
 		}
+		span.End()
 		c.Next()
 	}
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/model"
@@ -27,6 +29,8 @@ type ModelRequest struct {
 
 func Distribute() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		_, span := tracing.Start(c.Request.Context(), "middleware.distribute")
+		defer span.End()
 		var channel *model.Channel
 		channelId, ok := common.GetContextKey(c, constant.ContextKeyTokenSpecificChannelId)
 		modelRequest, shouldSelectChannel, err := getModelRequest(c)
@@ -34,6 +38,14 @@ func Distribute() func(c *gin.Context) {
 			abortWithOpenAiMessage(c, http.StatusBadRequest, "Invalid request, "+err.Error())
 			return
 		}
+		costTags, err := service.ParseCostTags(c)
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, "Invalid cost tags, "+err.Error())
+			return
+		}
+		if len(costTags) > 0 {
+			common.SetContextKey(c, constant.ContextKeyCostTags, costTags)
+		}
 		if ok {
 			id, err := strconv.Atoi(channelId.(string))
 			if err != nil {
@@ -97,6 +109,7 @@ func Distribute() func(c *gin.Context) {
 				}
 				channel, selectGroup, err = model.CacheGetRandomSatisfiedChannel(c, userGroup, modelRequest.Model, 0)
 				if err != nil {
+					metrics.RecordChannelSelection(false, "error")
 					showGroup := userGroup
 					if userGroup == "auto" {
 						showGroup = fmt.Sprintf("auto(%s)", selectGroup)
@@ -111,13 +124,20 @@ func Distribute() func(c *gin.Context) {
 					return
 				}
 				if channel == nil {
+					metrics.RecordChannelSelection(false, "no_channel")
 					abortWithOpenAiMessage(c, http.StatusServiceUnavailable, fmt.Sprintf("分组 %s 下模型 %s 无可用渠道（distributor）", userGroup, modelRequest.Model), string(types.ErrorCodeModelNotFound))
 					return
 				}
+				metrics.RecordChannelSelection(false, "success")
 			}
 		}
 		common.SetContextKey(c, constant.ContextKeyRequestStartTime, time.Now())
 		SetupContextForSelectedChannel(c, channel, modelRequest.Model)
+		span.SetAttributes(tracing.AttrModel.String(modelRequest.Model))
+		if channel != nil {
+			span.SetAttributes(tracing.AttrChannelId.Int(channel.Id), tracing.AttrChannelType.Int(channel.Type))
+		}
+		span.End()
 		c.Next()
 	}
 }
@@ -131,7 +151,8 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 		if relayMode == relayconstant.RelayModeMidjourneyTaskFetch ||
 			relayMode == relayconstant.RelayModeMidjourneyTaskFetchByCondition ||
 			relayMode == relayconstant.RelayModeMidjourneyNotify ||
-			relayMode == relayconstant.RelayModeMidjourneyTaskImageSeed {
+			relayMode == relayconstant.RelayModeMidjourneyTaskImageSeed ||
+			relayMode == relayconstant.RelayModeMidjourneyTaskCancel {
 			shouldSelectChannel = false
 		} else {
 			midjourneyRequest := dto.MidjourneyRequest{}
@@ -177,6 +198,10 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 		if _, ok := c.Get("relay_mode"); !ok {
 			c.Set("relay_mode", relayMode)
 		}
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/music/generations") {
+		err = common.UnmarshalBodyReusable(c, &modelRequest)
+		modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "suno_music")
+		c.Set("relay_mode", relayconstant.RelayModeMusicSubmit)
 	} else if strings.HasPrefix(c.Request.URL.Path, "/v1beta/models/") || strings.HasPrefix(c.Request.URL.Path, "/v1/models/") {
 		// Gemini API 路径处理: /v1beta/models/gemini-2.0-flash:generateContent
 		relayMode := relayconstant.RelayModeGemini
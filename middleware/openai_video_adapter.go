@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultOpenAIVideoModel = "sora-2"
+
+// OpenAIVideoRequestConvert 将 OpenAI /v1/videos 的创建与 remix 请求转换为统一的视频任务请求
+func OpenAIVideoRequestConvert() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var unifiedReq map[string]interface{}
+		var err error
+		if videoId := c.Param("video_id"); videoId != "" {
+			unifiedReq, err = convertOpenAIVideoRemixRequest(c, videoId)
+		} else {
+			unifiedReq, err = convertOpenAIVideoRequest(c)
+		}
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, err.Error(), "invalid_request")
+			return
+		}
+
+		jsonData, err := json.Marshal(unifiedReq)
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// Rewrite request body and path
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonData))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.URL.Path = "/v1/video/generations"
+		c.Set(common.KeyRequestBody, jsonData)
+		c.Next()
+	}
+}
+
+func convertOpenAIVideoRequest(c *gin.Context) (map[string]interface{}, error) {
+	var req dto.OpenAIVideoRequest
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.ShouldBind(&req); err != nil {
+			return nil, err
+		}
+		// multipart 请求中的 input_reference 为文件，转为 data URL 交给上游
+		if fileHeader, err := c.FormFile("input_reference"); err == nil {
+			file, err := fileHeader.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return nil, err
+			}
+			mimeType := fileHeader.Header.Get("Content-Type")
+			if mimeType == "" || mimeType == "application/octet-stream" {
+				mimeType = http.DetectContentType(data)
+			}
+			req.InputReference = "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		} else {
+			req.InputReference = c.PostForm("input_reference")
+		}
+	} else if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, errors.New("prompt is required")
+	}
+
+	unifiedReq := map[string]interface{}{
+		"model":  common.GetStringIfEmpty(req.Model, defaultOpenAIVideoModel),
+		"prompt": req.Prompt,
+	}
+	if req.Size != "" {
+		unifiedReq["size"] = req.Size
+	}
+	if req.Seconds != "" {
+		seconds, err := strconv.Atoi(req.Seconds)
+		if err != nil {
+			return nil, err
+		}
+		unifiedReq["duration"] = seconds
+	}
+	if req.InputReference != "" {
+		unifiedReq["image"] = req.InputReference
+	} else {
+		c.Set("action", constant.TaskActionTextGenerate)
+	}
+	if len(req.Metadata) > 0 {
+		unifiedReq["metadata"] = req.Metadata
+	}
+	return unifiedReq, nil
+}
+
+func convertOpenAIVideoRemixRequest(c *gin.Context, videoId string) (map[string]interface{}, error) {
+	var req dto.OpenAIVideoRemixRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, errors.New("prompt is required")
+	}
+	originTask, exist, err := model.GetByTaskId(c.GetInt("id"), videoId)
+	if err != nil {
+		return nil, err
+	}
+	if !exist || originTask.Properties.Model == "" {
+		return nil, fmt.Errorf("video %s does not exist or cannot be remixed", videoId)
+	}
+	common.SetContextKey(c, constant.ContextKeyTaskRemixFrom, originTask.TaskID)
+	unifiedReq := map[string]interface{}{
+		"model":  originTask.Properties.Model,
+		"prompt": req.Prompt,
+	}
+	if len(req.Metadata) > 0 {
+		unifiedReq["metadata"] = req.Metadata
+	}
+	return unifiedReq, nil
+}
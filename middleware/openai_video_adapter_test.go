@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"one-api/constant"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runOpenAIVideoConvert 经过转换中间件后返回改写后的路径、请求体与 action
+func runOpenAIVideoConvert(t *testing.T, contentType string, body io.Reader) (*httptest.ResponseRecorder, string, map[string]any, string) {
+	gin.SetMode(gin.TestMode)
+	var path, action string
+	var converted map[string]any
+	router := gin.New()
+	router.POST("/v1/videos", OpenAIVideoRequestConvert(), func(c *gin.Context) {
+		path = c.Request.URL.Path
+		action = c.GetString("action")
+		data, err := io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &converted))
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/videos", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w, path, converted, action
+}
+
+func TestOpenAIVideoRequestConvert(t *testing.T) {
+	w, path, converted, action := runOpenAIVideoConvert(t, "application/json",
+		strings.NewReader(`{"prompt":"a cat","seconds":"8","size":"720x1280","metadata":{"style":"anime"}}`))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/v1/video/generations", path)
+	assert.Equal(t, constant.TaskActionTextGenerate, action)
+	assert.Equal(t, map[string]any{
+		"model":    defaultOpenAIVideoModel,
+		"prompt":   "a cat",
+		"size":     "720x1280",
+		"duration": float64(8),
+		"metadata": map[string]any{"style": "anime"},
+	}, converted)
+
+	// multipart 上传的参考图转为 data URL，按图生视频处理
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	require.NoError(t, form.WriteField("model", "kling-v1"))
+	require.NoError(t, form.WriteField("prompt", "a dog"))
+	part, err := form.CreateFormFile("input_reference", "ref.png")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("\x89PNG\r\n\x1a\n"))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	w, _, converted, action = runOpenAIVideoConvert(t, form.FormDataContentType(), &buf)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, action)
+	assert.Equal(t, "kling-v1", converted["model"])
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", converted["image"])
+
+	w, _, _, _ = runOpenAIVideoConvert(t, "application/json", strings.NewReader(`{"prompt":" "}`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _, _, _ = runOpenAIVideoConvert(t, "application/json", strings.NewReader(`{"prompt":"a cat","seconds":"eight"}`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"one-api/common"
+	"one-api/common/metrics"
 	"one-api/constant"
 	"one-api/dto"
 	relay_constant "one-api/relay/constant"
@@ -56,6 +57,7 @@ func PoolCacheOptimizer() gin.HandlerFunc {
 
 		// Apply cache optimization
 		err := applyPoolCacheOptimization(c, &channelSetting)
+		metrics.RecordPoolCacheOptimization(err == nil)
 		if err != nil {
 			common.SysError("Failed to apply pool cache optimization: " + err.Error())
 			// Continue even if optimization fails - don't break user requests
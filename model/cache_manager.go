@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"one-api/common"
+	"one-api/common/metrics"
 	"strconv"
 	"strings"
 	"sync"
@@ -221,7 +222,9 @@ func (cm *LayeredCacheManager) GetChannel(id int) (*Channel, error) {
 
 	// Try L1 cache first
 	if cm.l1Cache != nil {
-		if entry, found := cm.l1Cache.Get(key); found {
+		entry, found := cm.l1Cache.Get(key)
+		metrics.RecordCacheOperation("get_channel", "memory", found)
+		if found {
 			atomic.AddInt64(&cm.metrics.L1Hits, 1)
 			if channel, ok := entry.Data.(*Channel); ok {
 				return channel, nil
@@ -231,7 +234,9 @@ func (cm *LayeredCacheManager) GetChannel(id int) (*Channel, error) {
 
 	// Try L2 cache
 	if cm.l2Cache != nil {
-		if entry, err := cm.l2Cache.Get(context.Background(), key); err == nil && entry != nil {
+		entry, err := cm.l2Cache.Get(context.Background(), key)
+		metrics.RecordCacheOperation("get_channel", "redis", err == nil && entry != nil)
+		if err == nil && entry != nil {
 			atomic.AddInt64(&cm.metrics.L2Hits, 1)
 			if channel, ok := entry.Data.(*Channel); ok {
 				// Populate L1 cache
@@ -288,7 +293,9 @@ func (cm *LayeredCacheManager) GetRandomSatisfiedChannel(ctx *gin.Context, group
 
 	// Try L1 cache first
 	if cm.l1Cache != nil {
-		if entry, found := cm.l1Cache.Get(key); found {
+		entry, found := cm.l1Cache.Get(key)
+		metrics.RecordCacheOperation("get_channel_selection", "memory", found)
+		if found {
 			atomic.AddInt64(&cm.metrics.L1Hits, 1)
 			if result, ok := entry.Data.(*ChannelSelectionResult); ok {
 				return result.Channel, result.SelectedGroup, nil
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"one-api/common"
+	"one-api/common/tracing"
 	"one-api/logger"
 	"one-api/types"
 	"os"
@@ -151,11 +152,26 @@ type RecordConsumeLogParams struct {
 }
 
 func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams) {
+	// 用量记录在当前 relay 尝试的 span 上，不受是否记录消费日志影响
+	tracing.SetAttributes(c.Request.Context(),
+		tracing.AttrPromptTokens.Int(params.PromptTokens),
+		tracing.AttrCompletionTokens.Int(params.CompletionTokens),
+		tracing.AttrQuota.Int(params.Quota),
+	)
 	if !common.LogConsumeEnabled {
 		return
 	}
+	_, span := tracing.Start(c.Request.Context(), "model.record_consume_log")
+	defer span.End()
 	logger.LogInfo(c, fmt.Sprintf("record consume log: userId=%d, params=%s", userId, common.GetJsonString(params)))
 	username := c.GetString("username")
+	costTags := getContextCostTags(c)
+	if len(costTags) > 0 {
+		if params.Other == nil {
+			params.Other = make(map[string]interface{})
+		}
+		params.Other["cost_tags"] = costTags
+	}
 	otherStr := common.MapToJsonStr(params.Other)
 	// 判断是否需要记录 IP
 	needRecordIp := false
@@ -191,6 +207,10 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 	err := LOG_DB.Create(log).Error
 	if err != nil {
 		logger.LogError(c, "failed to record log: "+err.Error())
+	} else if len(costTags) > 0 {
+		if err = recordLogTags(log, costTags); err != nil {
+			logger.LogError(c, "failed to record log tags: "+err.Error())
+		}
 	}
 	if common.DataExportEnabled {
 		gopool.Go(func() {
@@ -317,7 +337,7 @@ type Stat struct {
 	Tpm   int `json:"tpm"`
 }
 
-func SumUsedQuota(logType int, startTimestamp int64, endTimestamp int64, modelName string, username string, tokenName string, channel int, group string) (stat Stat) {
+func SumUsedQuota(logType int, startTimestamp int64, endTimestamp int64, modelName string, username string, tokenName string, channel int, group string, tagKey string, tagValue string) (stat Stat) {
 	tx := LOG_DB.Table("logs").Select("sum(quota) quota")
 
 	// 为rpm和tpm创建单独的查询
@@ -349,6 +369,10 @@ func SumUsedQuota(logType int, startTimestamp int64, endTimestamp int64, modelNa
 		tx = tx.Where(logGroupCol+" = ?", group)
 		rpmTpmQuery = rpmTpmQuery.Where(logGroupCol+" = ?", group)
 	}
+	if tagKey != "" {
+		tx = tx.Where("id in (?)", logTagFilter(tagKey, tagValue))
+		rpmTpmQuery = rpmTpmQuery.Where("id in (?)", logTagFilter(tagKey, tagValue))
+	}
 
 	tx = tx.Where("type = ?", LogTypeConsume)
 	rpmTpmQuery = rpmTpmQuery.Where("type = ?", LogTypeConsume)
@@ -404,5 +428,8 @@ func DeleteOldLog(ctx context.Context, targetTimestamp int64, limit int) (int64,
 		}
 	}
 
+	if err := deleteOldLogTags(ctx, targetTimestamp, limit); err != nil {
+		return total, err
+	}
 	return total, nil
 }
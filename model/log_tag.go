@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+	"one-api/common"
+	"one-api/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogTag 消费日志的成本归属标签，每个标签一行，冗余记录额度与 token 便于按标签聚合
+type LogTag struct {
+	Id        int    `json:"id"`
+	LogId     int    `json:"log_id" gorm:"index"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;index:idx_log_tag_key_created_at,priority:2"`
+	TagKey    string `json:"tag_key" gorm:"size:64;index:idx_log_tag_key_created_at,priority:1"`
+	TagValue  string `json:"tag_value" gorm:"size:255"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	Username  string `json:"username" gorm:"index;default:''"`
+	ModelName string `json:"model_name" gorm:"default:''"`
+	Quota     int    `json:"quota" gorm:"default:0"`
+	TokenUsed int    `json:"token_used" gorm:"default:0"`
+}
+
+// TagQuotaData 按标签值聚合的消费数据，CreatedAt 仅在按小时聚合时返回
+type TagQuotaData struct {
+	TagValue  string `json:"tag_value"`
+	CreatedAt int64  `json:"created_at,omitempty"`
+	Count     int    `json:"count"`
+	Quota     int    `json:"quota"`
+	TokenUsed int    `json:"token_used"`
+}
+
+const logTagHourExpr = "created_at - created_at % 3600"
+
+func recordLogTags(log *Log, tags map[string]string) error {
+	logTags := make([]*LogTag, 0, len(tags))
+	for key, value := range tags {
+		logTags = append(logTags, &LogTag{
+			LogId:     log.Id,
+			CreatedAt: log.CreatedAt,
+			TagKey:    key,
+			TagValue:  value,
+			UserId:    log.UserId,
+			Username:  log.Username,
+			ModelName: log.ModelName,
+			Quota:     log.Quota,
+			TokenUsed: log.PromptTokens + log.CompletionTokens,
+		})
+	}
+	return LOG_DB.Create(&logTags).Error
+}
+
+// SumQuotaByTag 统计时间范围内某个标签键下各标签值的消费，username 为空时统计全部用户
+func SumQuotaByTag(tagKey string, startTimestamp int64, endTimestamp int64, username string) (stats []*TagQuotaData, err error) {
+	tx := LOG_DB.Table("log_tags").Select("tag_value, count(*) as count, sum(quota) as quota, sum(token_used) as token_used").Where("tag_key = ?", tagKey)
+	if username != "" {
+		tx = tx.Where("username = ?", username)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	err = tx.Group("tag_value").Order("quota desc").Find(&stats).Error
+	return stats, err
+}
+
+// GetQuotaDataByTag 按标签值与小时聚合消费数据，userId 与 username 为空值时不过滤
+func GetQuotaDataByTag(tagKey string, startTime int64, endTime int64, userId int, username string) (quotaData []*TagQuotaData, err error) {
+	tx := LOG_DB.Table("log_tags").Select("tag_value, "+logTagHourExpr+" as created_at, count(*) as count, sum(quota) as quota, sum(token_used) as token_used").
+		Where("tag_key = ? and created_at >= ? and created_at <= ?", tagKey, startTime, endTime)
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if username != "" {
+		tx = tx.Where("username = ?", username)
+	}
+	err = tx.Group("tag_value, " + logTagHourExpr).Find(&quotaData).Error
+	return quotaData, err
+}
+
+// logTagFilter 返回带有指定标签的日志 ID 子查询
+func logTagFilter(tagKey string, tagValue string) interface{} {
+	return LOG_DB.Table("log_tags").Select("log_id").Where("tag_key = ? and tag_value = ?", tagKey, tagValue)
+}
+
+func deleteOldLogTags(ctx context.Context, targetTimestamp int64, limit int) error {
+	for {
+		if nil != ctx.Err() {
+			return ctx.Err()
+		}
+		result := LOG_DB.Where("created_at < ?", targetTimestamp).Limit(limit).Delete(&LogTag{})
+		if nil != result.Error {
+			return result.Error
+		}
+		if result.RowsAffected < int64(limit) {
+			return nil
+		}
+	}
+}
+
+func getContextCostTags(c *gin.Context) map[string]string {
+	tags, ok := common.GetContextKey(c, constant.ContextKeyCostTags)
+	if !ok {
+		return nil
+	}
+	costTags, _ := tags.(map[string]string)
+	return costTags
+}
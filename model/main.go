@@ -266,6 +266,8 @@ func InitLogDB() (err error) {
 }
 
 func migrateDB() error {
+	// 新增 refund_quota 之前失败的任务已由旧的轮询逻辑全额退还，迁移后需要回填，避免重新结算时重复退还
+	backfillRefundQuota := DB.Migrator().HasTable(&Task{}) && !DB.Migrator().HasColumn(&Task{}, "refund_quota")
 	err := DB.AutoMigrate(
 		&Channel{},
 		&Token{},
@@ -278,6 +280,9 @@ func migrateDB() error {
 		&TopUp{},
 		&QuotaData{},
 		&Task{},
+		&TaskCallback{},
+		&TaskQueueItem{},
+		&Media{},
 		&Model{},
 		&Vendor{},
 		&PrefillGroup{},
@@ -285,10 +290,23 @@ func migrateDB() error {
 		&TwoFA{},
 		&TwoFABackupCode{},
 		&PromptCacheMetrics{},
+		&Statement{},
+		&PostpaidInvoice{},
+		&SubscriptionPlan{},
+		&UserSubscription{},
+		&QuotaReversal{},
+		&UserPriceOverride{},
+		&UsageReport{},
 	)
 	if err != nil {
 		return err
 	}
+	if backfillRefundQuota {
+		err = DB.Model(&Task{}).Where("status = ?", TaskStatusFailure).Update("refund_quota", gorm.Expr("quota")).Error
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -311,12 +329,22 @@ func migrateDBFast() error {
 		{&TopUp{}, "TopUp"},
 		{&QuotaData{}, "QuotaData"},
 		{&Task{}, "Task"},
+		{&TaskCallback{}, "TaskCallback"},
+		{&TaskQueueItem{}, "TaskQueueItem"},
+		{&Media{}, "Media"},
 		{&Model{}, "Model"},
 		{&Vendor{}, "Vendor"},
 		{&PrefillGroup{}, "PrefillGroup"},
 		{&Setup{}, "Setup"},
 		{&TwoFA{}, "TwoFA"},
 		{&TwoFABackupCode{}, "TwoFABackupCode"},
+		{&Statement{}, "Statement"},
+		{&PostpaidInvoice{}, "PostpaidInvoice"},
+		{&SubscriptionPlan{}, "SubscriptionPlan"},
+		{&UserSubscription{}, "UserSubscription"},
+		{&QuotaReversal{}, "QuotaReversal"},
+		{&UserPriceOverride{}, "UserPriceOverride"},
+		{&UsageReport{}, "UsageReport"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -347,7 +375,7 @@ func migrateDBFast() error {
 
 func migrateLOGDB() error {
 	var err error
-	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
+	if err = LOG_DB.AutoMigrate(&Log{}, &LogTag{}); err != nil {
 		return err
 	}
 	return nil
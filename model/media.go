@@ -0,0 +1,57 @@
+package model
+
+import (
+	"one-api/common"
+)
+
+// Media 转存到网关存储的生成结果，通过签名地址对外提供访问
+type Media struct {
+	Id          int    `json:"id"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	Key         string `json:"key" gorm:"type:varchar(64);uniqueIndex"` // 对外访问地址中使用的随机标识
+	SourceType  string `json:"source_type" gorm:"type:varchar(20);index:idx_media_source,priority:1"`
+	SourceId    string `json:"source_id" gorm:"type:varchar(191);index:idx_media_source,priority:2"`
+	ContentType string `json:"content_type" gorm:"type:varchar(128)"`
+	Size        int64  `json:"size"`
+	Backend     string `json:"backend" gorm:"type:varchar(20)"`
+	Path        string `json:"path" gorm:"type:varchar(512)"` // 本地文件路径或 S3 对象 key
+	CreatedAt   int64  `json:"created_at" gorm:"bigint;index"`
+	ExpiresAt   int64  `json:"expires_at" gorm:"bigint;index"` // 0 表示永久保留
+}
+
+func (media *Media) Insert() error {
+	media.CreatedAt = common.GetTimestamp()
+	return DB.Create(media).Error
+}
+
+func (media *Media) Delete() error {
+	return DB.Delete(media).Error
+}
+
+func GetMediaByKey(key string) (*Media, error) {
+	var media Media
+	err := DB.First(&media, commonKeyCol+" = ?", key).Error
+	return &media, err
+}
+
+// GetMediaBySource 获取来源对象最近转存的结果，不存在时返回 nil
+func GetMediaBySource(sourceType string, sourceId string) *Media {
+	var media Media
+	err := DB.Where("source_type = ? AND source_id = ?", sourceType, sourceId).Order("id desc").First(&media).Error
+	if err != nil {
+		return nil
+	}
+	return &media
+}
+
+// GetUserMediaSize 统计用户当前占用的存储空间（字节）
+func GetUserMediaSize(userId int) (size int64, err error) {
+	err = DB.Model(&Media{}).Where("user_id = ?", userId).Select("COALESCE(SUM(size), 0)").Scan(&size).Error
+	return size, err
+}
+
+// GetExpiredMedia 获取已超过保留期的记录
+func GetExpiredMedia(now int64, limit int) (medias []*Media, err error) {
+	err = DB.Where("expires_at > 0 AND expires_at <= ?", now).Order("id").Limit(limit).Find(&medias).Error
+	return medias, err
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Midjourney struct {
 	Id          int    `json:"id"`
 	Code        int    `json:"code"`
@@ -23,16 +25,36 @@ type Midjourney struct {
 	Quota       int    `json:"quota"`
 	Buttons     string `json:"buttons"`
 	Properties  string `json:"properties"`
+	CallbackUrl string `json:"callback_url,omitempty" gorm:"type:varchar(1024)"`
+	Seed        string `json:"seed,omitempty" gorm:"type:varchar(64)"`
+	QueueId     string `json:"queue_id,omitempty" gorm:"type:varchar(64);index"` // 在网关排队时返回给用户的任务 ID
+	TokenId     int    `json:"-" gorm:"default:0"`                               // 提交任务所用的令牌，管理员重试时据此扣除令牌额度
+}
+
+// IsGatewayQueued 是否为网关排队中、尚未提交上游的占位任务
+func (midjourney *Midjourney) IsGatewayQueued() bool {
+	return midjourney.QueueId != "" && midjourney.MjId == midjourney.QueueId
 }
 
 // TaskQueryParams 用于包含所有搜索条件的结构体，可以根据需求添加更多字段
 type TaskQueryParams struct {
 	ChannelID      string
 	MjID           string
+	Status         string
+	Action         string
 	StartTimestamp string
 	EndTimestamp   string
 }
 
+// MjTaskCondition 用户按条件查询任务，指定 Ids 时只按 Ids 查询
+type MjTaskCondition struct {
+	Ids    []string
+	Status string
+	Action string
+	Offset int
+	Limit  int
+}
+
 func GetAllUserTask(userId int, startIdx int, num int, queryParams TaskQueryParams) []*Midjourney {
 	var tasks []*Midjourney
 	var err error
@@ -43,6 +65,12 @@ func GetAllUserTask(userId int, startIdx int, num int, queryParams TaskQueryPara
 	if queryParams.MjID != "" {
 		query = query.Where("mj_id = ?", queryParams.MjID)
 	}
+	if queryParams.Status != "" {
+		query = query.Where("status = ?", queryParams.Status)
+	}
+	if queryParams.Action != "" {
+		query = query.Where("action = ?", queryParams.Action)
+	}
 	if queryParams.StartTimestamp != "" {
 		// 假设您已将前端传来的时间戳转换为数据库所需的时间格式，并处理了时间戳的验证和解析
 		query = query.Where("submit_time >= ?", queryParams.StartTimestamp)
@@ -74,6 +102,12 @@ func GetAllTasks(startIdx int, num int, queryParams TaskQueryParams) []*Midjourn
 	if queryParams.MjID != "" {
 		query = query.Where("mj_id = ?", queryParams.MjID)
 	}
+	if queryParams.Status != "" {
+		query = query.Where("status = ?", queryParams.Status)
+	}
+	if queryParams.Action != "" {
+		query = query.Where("action = ?", queryParams.Action)
+	}
 	if queryParams.StartTimestamp != "" {
 		query = query.Where("submit_time >= ?", queryParams.StartTimestamp)
 	}
@@ -94,7 +128,7 @@ func GetAllUnFinishTasks() []*Midjourney {
 	var tasks []*Midjourney
 	var err error
 	// get all tasks progress is not 100%
-	err = DB.Where("progress != ?", "100%").Find(&tasks).Error
+	err = DB.Where("progress != ?", "100%").Where(submittedMjCondition).Find(&tasks).Error
 	if err != nil {
 		return nil
 	}
@@ -114,7 +148,10 @@ func GetByOnlyMJId(mjId string) *Midjourney {
 func GetByMJId(userId int, mjId string) *Midjourney {
 	var mj *Midjourney
 	var err error
-	err = DB.Where("user_id = ? and mj_id = ?", userId, mjId).First(&mj).Error
+	if mjId == "" {
+		return nil
+	}
+	err = DB.Where("user_id = ? and (mj_id = ? or queue_id = ?)", userId, mjId, mjId).First(&mj).Error
 	if err != nil {
 		return nil
 	}
@@ -124,13 +161,42 @@ func GetByMJId(userId int, mjId string) *Midjourney {
 func GetByMJIds(userId int, mjIds []string) []*Midjourney {
 	var mj []*Midjourney
 	var err error
-	err = DB.Where("user_id = ? and mj_id in (?)", userId, mjIds).Find(&mj).Error
+	err = DB.Where("user_id = ? and (mj_id in (?) or queue_id in (?))", userId, mjIds, mjIds).Find(&mj).Error
 	if err != nil {
 		return nil
 	}
 	return mj
 }
 
+// GetUserMjTasksByCondition 按状态、操作分页查询用户自己的任务
+func GetUserMjTasksByCondition(userId int, condition MjTaskCondition) []*Midjourney {
+	if len(condition.Ids) != 0 {
+		return GetByMJIds(userId, condition.Ids)
+	}
+	var tasks []*Midjourney
+	query := DB.Where("user_id = ?", userId)
+	if condition.Status != "" {
+		query = query.Where("status = ?", condition.Status)
+	}
+	if condition.Action != "" {
+		query = query.Where("action = ?", condition.Action)
+	}
+	err := query.Order("id desc").Limit(condition.Limit).Offset(condition.Offset).Find(&tasks).Error
+	if err != nil {
+		return nil
+	}
+	return tasks
+}
+
+func GetMjByIds(ids []int) []*Midjourney {
+	var tasks []*Midjourney
+	err := DB.Where("id in (?)", ids).Find(&tasks).Error
+	if err != nil {
+		return nil
+	}
+	return tasks
+}
+
 func GetMjByuId(id int) *Midjourney {
 	var mj *Midjourney
 	var err error
@@ -157,6 +223,44 @@ func (midjourney *Midjourney) Update() error {
 	return err
 }
 
+// UpdateUnfinished 仅在任务尚未结束时保存全部字段，返回是否由本次调用更新，避免覆盖取消等并发操作已写入的结束状态
+func (midjourney *Midjourney) UpdateUnfinished() (bool, error) {
+	result := DB.Model(&Midjourney{}).
+		Where("id = ? and progress != ?", midjourney.Id, "100%").
+		Select("*").
+		Updates(midjourney)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CancelMjTask 将未结束的任务标记为失败，返回是否由本次调用完成取消，避免与轮询并发时重复退款
+func CancelMjTask(id int, failReason string) (bool, error) {
+	result := DB.Model(&Midjourney{}).
+		Where("id = ? and progress != ?", id, "100%").
+		Updates(map[string]any{
+			"status":      "FAILURE",
+			"progress":    "100%",
+			"fail_reason": failReason,
+			"finish_time": time.Now().UnixMilli(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ClaimMjTaskForRetry 将失败的任务标记为已提交，返回是否由本次调用抢占，避免并发重试重复提交与扣费
+// 进度仍为 100%，提交上游前不会被轮询按旧的任务 ID 更新
+func ClaimMjTaskForRetry(id int) (bool, error) {
+	result := DB.Model(&Midjourney{}).
+		Where("id = ? and status = ?", id, TaskStatusFailure).
+		Update("status", TaskStatusSubmitted)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseMjTaskRetry 重试提交上游失败时恢复为失败状态，之后可以再次重试
+func ReleaseMjTaskRetry(id int) error {
+	return DB.Model(&Midjourney{}).
+		Where("id = ? and status = ? and progress = ?", id, TaskStatusSubmitted, "100%").
+		Update("status", TaskStatusFailure).Error
+}
+
 func MjBulkUpdate(mjIds []string, params map[string]any) error {
 	return DB.Model(&Midjourney{}).
 		Where("mj_id in (?)", mjIds).
@@ -179,6 +283,12 @@ func CountAllTasks(queryParams TaskQueryParams) int64 {
 	if queryParams.MjID != "" {
 		query = query.Where("mj_id = ?", queryParams.MjID)
 	}
+	if queryParams.Status != "" {
+		query = query.Where("status = ?", queryParams.Status)
+	}
+	if queryParams.Action != "" {
+		query = query.Where("action = ?", queryParams.Action)
+	}
 	if queryParams.StartTimestamp != "" {
 		query = query.Where("submit_time >= ?", queryParams.StartTimestamp)
 	}
@@ -196,6 +306,12 @@ func CountAllUserTask(userId int, queryParams TaskQueryParams) int64 {
 	if queryParams.MjID != "" {
 		query = query.Where("mj_id = ?", queryParams.MjID)
 	}
+	if queryParams.Status != "" {
+		query = query.Where("status = ?", queryParams.Status)
+	}
+	if queryParams.Action != "" {
+		query = query.Where("action = ?", queryParams.Action)
+	}
 	if queryParams.StartTimestamp != "" {
 		query = query.Where("submit_time >= ?", queryParams.StartTimestamp)
 	}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelMjTaskRaceWithPolling(t *testing.T) {
+	setupSQLiteTestDB(t, &Midjourney{})
+	require.NoError(t, DB.Create(&Midjourney{Id: 1, UserId: 1, MjId: "mj_1", Status: "IN_PROGRESS", Progress: "50%", Quota: 100}).Error)
+
+	// 轮询读取快照后任务被取消，轮询结果不能覆盖取消写入的失败状态
+	polled := GetMjByuId(1)
+	require.NotNil(t, polled)
+	cancelled, err := CancelMjTask(1, "cancelled")
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	polled.Status, polled.Progress = "SUCCESS", "100%"
+	updated, err := polled.UpdateUnfinished()
+	require.NoError(t, err)
+	assert.False(t, updated)
+	task := GetMjByuId(1)
+	assert.Equal(t, "FAILURE", task.Status)
+	assert.Equal(t, "cancelled", task.FailReason)
+
+	// 再次取消不会重复生效，调用方据此只退款一次
+	cancelled, err = CancelMjTask(1, "cancelled")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+
+	// 轮询先完成任务时取消不生效
+	require.NoError(t, DB.Create(&Midjourney{Id: 2, UserId: 1, MjId: "mj_2", Status: "IN_PROGRESS", Progress: "50%", Quota: 100}).Error)
+	polled = GetMjByuId(2)
+	polled.Status, polled.Progress = "SUCCESS", "100%"
+	updated, err = polled.UpdateUnfinished()
+	require.NoError(t, err)
+	assert.True(t, updated)
+	cancelled, err = CancelMjTask(2, "cancelled")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.Equal(t, "SUCCESS", GetMjByuId(2).Status)
+}
+
+func TestGetUserMjTasksByCondition(t *testing.T) {
+	setupSQLiteTestDB(t, &Midjourney{})
+	for i := 1; i <= 5; i++ {
+		status := "SUCCESS"
+		if i%2 == 0 {
+			status = "FAILURE"
+		}
+		require.NoError(t, DB.Create(&Midjourney{Id: i, UserId: 1, MjId: fmt.Sprintf("mj_%d", i), Action: "IMAGINE", Status: status}).Error)
+	}
+	require.NoError(t, DB.Create(&Midjourney{Id: 6, UserId: 1, MjId: "mj_6", Action: "UPSCALE", Status: "SUCCESS"}).Error)
+	require.NoError(t, DB.Create(&Midjourney{Id: 7, UserId: 2, MjId: "mj_7", Action: "IMAGINE", Status: "SUCCESS"}).Error)
+
+	taskIds := func(tasks []*Midjourney) []int {
+		ids := make([]int, 0, len(tasks))
+		for _, task := range tasks {
+			ids = append(ids, task.Id)
+		}
+		return ids
+	}
+
+	// 按 ID 倒序分页，不包含其他用户的任务
+	assert.Equal(t, []int{6, 5, 4}, taskIds(GetUserMjTasksByCondition(1, MjTaskCondition{Offset: 0, Limit: 3})))
+	assert.Equal(t, []int{3, 2, 1}, taskIds(GetUserMjTasksByCondition(1, MjTaskCondition{Offset: 3, Limit: 3})))
+	assert.Empty(t, GetUserMjTasksByCondition(1, MjTaskCondition{Offset: 6, Limit: 3}))
+
+	assert.Equal(t, []int{4, 2}, taskIds(GetUserMjTasksByCondition(1, MjTaskCondition{Status: "FAILURE", Limit: 10})))
+	assert.Equal(t, []int{6}, taskIds(GetUserMjTasksByCondition(1, MjTaskCondition{Action: "UPSCALE", Limit: 10})))
+	assert.Equal(t, []int{3, 1}, taskIds(GetUserMjTasksByCondition(1, MjTaskCondition{Status: "SUCCESS", Action: "IMAGINE", Offset: 1, Limit: 2})))
+
+	// 指定任务 ID 时忽略其他条件，且只返回自己的任务
+	tasks := GetUserMjTasksByCondition(1, MjTaskCondition{Ids: []string{"mj_2", "mj_7"}, Status: "SUCCESS"})
+	assert.Equal(t, []int{2}, taskIds(tasks))
+}
+
+func TestClaimMjTaskForRetry(t *testing.T) {
+	setupSQLiteTestDB(t, &Midjourney{})
+	require.NoError(t, DB.Create(&Midjourney{Id: 1, MjId: "mj_1", Status: TaskStatusFailure, Progress: "100%"}).Error)
+	require.NoError(t, DB.Create(&Midjourney{Id: 2, MjId: "mj_2", Status: "SUCCESS", Progress: "100%"}).Error)
+
+	claimed, err := ClaimMjTaskForRetry(1)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	// 已被抢占的任务不能再次抢占
+	claimed, err = ClaimMjTaskForRetry(1)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	claimed, err = ClaimMjTaskForRetry(2)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	// 释放后恢复为失败状态，可以再次重试
+	require.NoError(t, ReleaseMjTaskRetry(1))
+	assert.Equal(t, TaskStatusFailure, GetMjByuId(1).Status)
+	claimed, err = ClaimMjTaskForRetry(1)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
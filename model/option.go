@@ -83,6 +83,21 @@ func InitOptionMap() {
 	common.OptionMap["StripePriceId"] = setting.StripePriceId
 	common.OptionMap["StripeUnitPrice"] = strconv.FormatFloat(setting.StripeUnitPrice, 'f', -1, 64)
 	common.OptionMap["StripePromotionCodesEnabled"] = strconv.FormatBool(setting.StripePromotionCodesEnabled)
+	common.OptionMap["StripeCurrencies"] = setting.StripeCurrencies
+	common.OptionMap["PayPalClientId"] = setting.PayPalClientId
+	common.OptionMap["PayPalClientSecret"] = setting.PayPalClientSecret
+	common.OptionMap["PayPalWebhookId"] = setting.PayPalWebhookId
+	common.OptionMap["PayPalSandbox"] = strconv.FormatBool(setting.PayPalSandbox)
+	common.OptionMap["PayPalCurrency"] = setting.PayPalCurrency
+	common.OptionMap["PayPalUnitPrice"] = strconv.FormatFloat(setting.PayPalUnitPrice, 'f', -1, 64)
+	common.OptionMap["PayPalMinTopUp"] = strconv.Itoa(setting.PayPalMinTopUp)
+	common.OptionMap["PaymentWebhookName"] = setting.PaymentWebhookName
+	common.OptionMap["PaymentWebhookCreateUrl"] = setting.PaymentWebhookCreateUrl
+	common.OptionMap["PaymentWebhookQueryUrl"] = setting.PaymentWebhookQueryUrl
+	common.OptionMap["PaymentWebhookSecret"] = setting.PaymentWebhookSecret
+	common.OptionMap["PaymentWebhookUnitPrice"] = strconv.FormatFloat(setting.PaymentWebhookUnitPrice, 'f', -1, 64)
+	common.OptionMap["PaymentWebhookMinTopUp"] = strconv.Itoa(setting.PaymentWebhookMinTopUp)
+	common.OptionMap["PaymentWebhookCurrencies"] = setting.PaymentWebhookCurrencies
 	common.OptionMap["TopupGroupRatio"] = common.TopupGroupRatio2JSONString()
 	common.OptionMap["Chats"] = setting.Chats2JsonString()
 	common.OptionMap["AutoGroups"] = setting.AutoGroups2JsonString()
@@ -107,6 +122,11 @@ func InitOptionMap() {
 	common.OptionMap["ModelRequestRateLimitSuccessCount"] = strconv.Itoa(setting.ModelRequestRateLimitSuccessCount)
 	common.OptionMap["ModelRequestRateLimitGroup"] = setting.ModelRequestRateLimitGroup2JSONString()
 	common.OptionMap["ModelRatio"] = ratio_setting.ModelRatio2JSONString()
+	common.OptionMap["TieredRatio"] = ratio_setting.TieredRatio2JSONString()
+	common.OptionMap["VideoPrice"] = ratio_setting.VideoPrice2JSONString()
+	common.OptionMap["ImageSizeRatio"] = ratio_setting.ImageSizeRatio2JSONString()
+	common.OptionMap["ImageQualityRatio"] = ratio_setting.ImageQualityRatio2JSONString()
+	common.OptionMap["PricingSchedule"] = ratio_setting.PricingSchedule2JSONString()
 	common.OptionMap["ModelPrice"] = ratio_setting.ModelPrice2JSONString()
 	common.OptionMap["CacheRatio"] = ratio_setting.CacheRatio2JSONString()
 	common.OptionMap["GroupRatio"] = ratio_setting.GroupRatio2JSONString()
@@ -333,6 +353,36 @@ func updateOptionMap(key string, value string) (err error) {
 		setting.StripeMinTopUp, _ = strconv.Atoi(value)
 	case "StripePromotionCodesEnabled":
 		setting.StripePromotionCodesEnabled = value == "true"
+	case "StripeCurrencies":
+		setting.StripeCurrencies = value
+	case "PayPalClientId":
+		setting.PayPalClientId = value
+	case "PayPalClientSecret":
+		setting.PayPalClientSecret = value
+	case "PayPalWebhookId":
+		setting.PayPalWebhookId = value
+	case "PayPalSandbox":
+		setting.PayPalSandbox = value == "true"
+	case "PayPalCurrency":
+		setting.PayPalCurrency = value
+	case "PayPalUnitPrice":
+		setting.PayPalUnitPrice, _ = strconv.ParseFloat(value, 64)
+	case "PayPalMinTopUp":
+		setting.PayPalMinTopUp, _ = strconv.Atoi(value)
+	case "PaymentWebhookName":
+		setting.PaymentWebhookName = value
+	case "PaymentWebhookCreateUrl":
+		setting.PaymentWebhookCreateUrl = value
+	case "PaymentWebhookQueryUrl":
+		setting.PaymentWebhookQueryUrl = value
+	case "PaymentWebhookSecret":
+		setting.PaymentWebhookSecret = value
+	case "PaymentWebhookUnitPrice":
+		setting.PaymentWebhookUnitPrice, _ = strconv.ParseFloat(value, 64)
+	case "PaymentWebhookMinTopUp":
+		setting.PaymentWebhookMinTopUp, _ = strconv.Atoi(value)
+	case "PaymentWebhookCurrencies":
+		setting.PaymentWebhookCurrencies = value
 	case "TopupGroupRatio":
 		err = common.UpdateTopupGroupRatioByJSONString(value)
 	case "GitHubClientId":
@@ -391,6 +441,16 @@ func updateOptionMap(key string, value string) (err error) {
 		common.DataExportDefaultTime = value
 	case "ModelRatio":
 		err = ratio_setting.UpdateModelRatioByJSONString(value)
+	case "TieredRatio":
+		err = ratio_setting.UpdateTieredRatioByJSONString(value)
+	case "VideoPrice":
+		err = ratio_setting.UpdateVideoPriceByJSONString(value)
+	case "ImageSizeRatio":
+		err = ratio_setting.UpdateImageSizeRatioByJSONString(value)
+	case "ImageQualityRatio":
+		err = ratio_setting.UpdateImageQualityRatioByJSONString(value)
+	case "PricingSchedule":
+		err = ratio_setting.UpdatePricingScheduleByJSONString(value)
 	case "GroupRatio":
 		err = ratio_setting.UpdateGroupRatioByJSONString(value)
 	case "GroupGroupRatio":
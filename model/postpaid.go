@@ -0,0 +1,156 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/logger"
+
+	"gorm.io/gorm"
+)
+
+// PostpaidInvoice 后付费账单，记录出账时用户的欠款
+type PostpaidInvoice struct {
+	Id          int    `json:"id"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	Quota       int    `json:"quota"`  // 出账时的欠款额度
+	Amount      int64  `json:"amount"` // 需要支付的充值数量，与 TopUp.Amount 单位一致
+	TradeNo     string `json:"trade_no" gorm:"type:varchar(255);index"`
+	Status      string `json:"status" gorm:"type:varchar(16);index"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+	DueTime     int64  `json:"due_time" gorm:"bigint;index"`
+	PaidTime    int64  `json:"paid_time" gorm:"bigint"`
+}
+
+func (invoice *PostpaidInvoice) Insert() error {
+	invoice.CreatedTime = common.GetTimestamp()
+	return DB.Create(invoice).Error
+}
+
+func (invoice *PostpaidInvoice) Update() error {
+	return DB.Save(invoice).Error
+}
+
+func (invoice *PostpaidInvoice) IsOpen() bool {
+	return invoice.Status == common.PostpaidInvoiceStatusPending || invoice.Status == common.PostpaidInvoiceStatusOverdue
+}
+
+func GetPostpaidInvoiceById(id int) (*PostpaidInvoice, error) {
+	var invoice PostpaidInvoice
+	err := DB.Where("id = ?", id).First(&invoice).Error
+	return &invoice, err
+}
+
+func GetPostpaidInvoices(userId int, status string, startIdx int, num int) (invoices []*PostpaidInvoice, total int64, err error) {
+	tx := DB.Model(&PostpaidInvoice{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&invoices).Error
+	return invoices, total, err
+}
+
+// GetOpenPostpaidInvoices 返回所有未结清（待支付或已逾期）的账单
+func GetOpenPostpaidInvoices() (invoices []*PostpaidInvoice, err error) {
+	err = DB.Where("status IN ?", []string{common.PostpaidInvoiceStatusPending, common.PostpaidInvoiceStatusOverdue}).
+		Find(&invoices).Error
+	return invoices, err
+}
+
+func HasOpenPostpaidInvoice(userId int) (bool, error) {
+	var count int64
+	err := DB.Model(&PostpaidInvoice{}).Where("user_id = ? AND status IN ?", userId,
+		[]string{common.PostpaidInvoiceStatusPending, common.PostpaidInvoiceStatusOverdue}).Count(&count).Error
+	return count > 0, err
+}
+
+// GetPostpaidDebtors 返回余额为负的后付费用户
+func GetPostpaidDebtors() (users []*User, err error) {
+	err = DB.Select("id", "quota").Where("billing_mode = ? AND quota < 0", common.UserBillingModePostpaid).Find(&users).Error
+	return users, err
+}
+
+// UpdateUserBilling 设置用户的计费模式与信用额度
+func UpdateUserBilling(userId int, billingMode string, creditLimit int) error {
+	if billingMode != common.UserBillingModePrepaid && billingMode != common.UserBillingModePostpaid {
+		return fmt.Errorf("无效的计费模式: %s", billingMode)
+	}
+	if creditLimit < 0 {
+		return errors.New("信用额度不能为负数")
+	}
+	updates := map[string]interface{}{
+		"billing_mode": billingMode,
+		"credit_limit": creditLimit,
+	}
+	if billingMode == common.UserBillingModePrepaid {
+		updates["credit_limit"] = 0
+	}
+	if err := DB.Model(&User{}).Where("id = ?", userId).Updates(updates).Error; err != nil {
+		return err
+	}
+	return invalidateUserCache(userId)
+}
+
+// SetUserBillingLocked 锁定或解锁用户的 API 调用
+func SetUserBillingLocked(userId int, locked bool) error {
+	if err := DB.Model(&User{}).Where("id = ?", userId).Update("billing_locked", locked).Error; err != nil {
+		return err
+	}
+	return invalidateUserCache(userId)
+}
+
+// MarkPostpaidInvoicePaid 将账单标记为已结清，若用户已没有逾期账单则解除锁定
+func MarkPostpaidInvoicePaid(invoice *PostpaidInvoice) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PostpaidInvoice{}).Where("id = ? AND status IN ?", invoice.Id,
+			[]string{common.PostpaidInvoiceStatusPending, common.PostpaidInvoiceStatusOverdue}).
+			Updates(map[string]interface{}{"status": common.PostpaidInvoiceStatusPaid, "paid_time": common.GetTimestamp()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("账单状态错误")
+		}
+		var overdue int64
+		err := tx.Model(&PostpaidInvoice{}).Where("user_id = ? AND status = ?", invoice.UserId, common.PostpaidInvoiceStatusOverdue).
+			Count(&overdue).Error
+		if err != nil {
+			return err
+		}
+		if overdue == 0 {
+			return tx.Model(&User{}).Where("id = ?", invoice.UserId).Update("billing_locked", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	invoice.Status = common.PostpaidInvoiceStatusPaid
+	RecordLog(invoice.UserId, LogTypeTopup, fmt.Sprintf("后付费账单 #%d 已结清，欠款额度: %s", invoice.Id, logger.LogQuota(invoice.Quota)))
+	return invalidateUserCache(invoice.UserId)
+}
+
+// SettlePostpaidInvoiceByTradeNo 在支付回调成功后结清对应的账单，非账单订单直接忽略
+func SettlePostpaidInvoiceByTradeNo(tradeNo string) error {
+	if tradeNo == "" {
+		return nil
+	}
+	var invoice PostpaidInvoice
+	err := DB.Where("trade_no = ?", tradeNo).First(&invoice).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !invoice.IsOpen() {
+		return nil
+	}
+	return MarkPostpaidInvoicePaid(&invoice)
+}
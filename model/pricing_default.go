@@ -35,6 +35,16 @@ var defaultVendorRules = map[string]string{
 	"kling":    "快手",
 	"jimeng":   "即梦",
 	"vidu":     "Vidu",
+	"gen4_":    "Runway",
+	"gen3a_":   "Runway",
+	"ray-":     "Luma",
+	"hailuo":   "MiniMax",
+	"flux":     "BFL",
+	"sd3":      "Stability",
+	"stable-":  "Stability",
+	"ideogram": "Ideogram",
+	"music-u":  "Udio",
+	"suno_":    "Suno",
 }
 
 // 供应商默认图标映射
@@ -62,6 +72,13 @@ var defaultVendorIcons = map[string]string{
 	"快手":         "Kling.Color",
 	"即梦":         "Jimeng.Color",
 	"Vidu":       "Vidu",
+	"Runway":     "Runway",
+	"Luma":       "LumaLabs",
+	"BFL":        "Bfl",
+	"Stability":  "Stability.Color",
+	"Ideogram":   "Ideogram",
+	"Udio":       "Udio",
+	"Suno":       "Suno",
 	"微软":         "AzureAI",
 	"Microsoft":  "AzureAI",
 	"Azure":      "AzureAI",
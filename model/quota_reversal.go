@@ -0,0 +1,218 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/logger"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+const (
+	QuotaReversalSourceTopUp      = "topup"
+	QuotaReversalSourceRedemption = "redemption"
+)
+
+// QuotaReversal 额度扣回的审计记录，对应充值退款或兑换码撤销
+// Quota 为应扣回的额度，DeductedQuota 为按余额不足策略实际扣回的额度
+type QuotaReversal struct {
+	Id            int    `json:"id"`
+	UserId        int    `json:"user_id" gorm:"index"`
+	SourceType    string `json:"source_type" gorm:"type:varchar(16);index:idx_reversal_source,priority:1"`
+	SourceId      int    `json:"source_id" gorm:"index:idx_reversal_source,priority:2"`
+	Quota         int    `json:"quota"`
+	DeductedQuota int    `json:"deducted_quota"`
+	Reason        string `json:"reason" gorm:"type:varchar(255)"`
+	OperatorId    int    `json:"operator_id"` // 0 表示由支付回调自动发起
+	ExternalId    string `json:"external_id" gorm:"type:varchar(128);default:''"`
+	CreatedTime   int64  `json:"created_time" gorm:"bigint"`
+}
+
+// CreditedQuota 返回该充值订单实际入账的额度
+// 未记录 Quota 的旧订单：易支付按 Amount 入账，Stripe（订单号以 ref_ 开头）按 Money 入账
+func (topUp *TopUp) CreditedQuota() int {
+	if topUp.Quota > 0 {
+		return topUp.Quota
+	}
+	if strings.HasPrefix(topUp.TradeNo, "ref_") {
+		return int(topUp.Money * common.QuotaPerUnit)
+	}
+	return int(float64(topUp.Amount) * common.QuotaPerUnit)
+}
+
+func GetAllTopUps(userId int, status string, startIdx int, num int) (topUps []*TopUp, total int64, err error) {
+	tx := DB.Model(&TopUp{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&topUps).Error
+	return topUps, total, err
+}
+
+func GetQuotaReversals(userId int, sourceType string, startIdx int, num int) (reversals []*QuotaReversal, total int64, err error) {
+	tx := DB.Model(&QuotaReversal{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if sourceType != "" {
+		tx = tx.Where("source_type = ?", sourceType)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&reversals).Error
+	return reversals, total, err
+}
+
+// GetReversedQuota 返回某个充值订单或兑换码已经记录的扣回额度
+func GetReversedQuota(sourceType string, sourceId int) (quota int, err error) {
+	return getReversedQuota(DB, sourceType, sourceId)
+}
+
+func getReversedQuota(tx *gorm.DB, sourceType string, sourceId int) (quota int, err error) {
+	err = tx.Model(&QuotaReversal{}).Select("COALESCE(SUM(quota), 0)").
+		Where("source_type = ? AND source_id = ?", sourceType, sourceId).Scan(&quota).Error
+	return quota, err
+}
+
+// deductQuotaWithPolicy 在事务中从用户余额扣回额度，余额不足时按配置的策略处理
+func deductQuotaWithPolicy(tx *gorm.DB, userId int, quota int) (int, error) {
+	var user User
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Select("id", "quota").Where("id = ?", userId).First(&user).Error; err != nil {
+		return 0, err
+	}
+	deducted := quota
+	if user.Quota < quota {
+		switch operation_setting.GetRefundSetting().NegativeBalancePolicy {
+		case operation_setting.RefundPolicyAllowNegative:
+		case operation_setting.RefundPolicyClamp:
+			deducted = max(user.Quota, 0)
+		default:
+			return 0, fmt.Errorf("用户余额 %s 不足以扣回 %s", logger.FormatQuota(user.Quota), logger.FormatQuota(quota))
+		}
+	}
+	if deducted == 0 {
+		return 0, nil
+	}
+	err := tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota - ?", deducted)).Error
+	return deducted, err
+}
+
+func syncReversedUserQuotaCache(userId int, deducted int) {
+	if deducted == 0 {
+		return
+	}
+	gopool.Go(func() {
+		if err := cacheDecrUserQuota(userId, int64(deducted)); err != nil {
+			common.SysLog("failed to decrease user quota cache: " + err.Error())
+		}
+	})
+}
+
+// RefundTopUp 退款充值订单并扣回对应额度，quota 为 0 时扣回全部剩余额度
+// fullRefund 为 true 时订单标记为已退款，否则按累计扣回额度判断是否为部分退款
+func RefundTopUp(topUpId int, quota int, fullRefund bool, reason string, operatorId int, externalId string) (*QuotaReversal, error) {
+	reversal := &QuotaReversal{}
+	topUp := &TopUp{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", topUpId).First(topUp).Error; err != nil {
+			return errors.New("充值订单不存在")
+		}
+		if topUp.Status != common.TopUpStatusSuccess && topUp.Status != common.TopUpStatusPartiallyRefunded {
+			return errors.New("充值订单状态错误")
+		}
+		credited := topUp.CreditedQuota()
+		reversed, err := getReversedQuota(tx, QuotaReversalSourceTopUp, topUp.Id)
+		if err != nil {
+			return err
+		}
+		remaining := credited - reversed
+		if quota == 0 {
+			quota = remaining
+		}
+		if quota <= 0 || quota > remaining {
+			return fmt.Errorf("可退款额度为 %s", logger.FormatQuota(remaining))
+		}
+		deducted, err := deductQuotaWithPolicy(tx, topUp.UserId, quota)
+		if err != nil {
+			return err
+		}
+		if fullRefund || reversed+quota >= credited {
+			topUp.Status = common.TopUpStatusRefunded
+		} else {
+			topUp.Status = common.TopUpStatusPartiallyRefunded
+		}
+		if err = tx.Save(topUp).Error; err != nil {
+			return err
+		}
+		reversal = &QuotaReversal{
+			UserId:        topUp.UserId,
+			SourceType:    QuotaReversalSourceTopUp,
+			SourceId:      topUp.Id,
+			Quota:         quota,
+			DeductedQuota: deducted,
+			Reason:        reason,
+			OperatorId:    operatorId,
+			ExternalId:    externalId,
+			CreatedTime:   common.GetTimestamp(),
+		}
+		return tx.Create(reversal).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	syncReversedUserQuotaCache(reversal.UserId, reversal.DeductedQuota)
+	RecordLog(reversal.UserId, LogTypeManage, fmt.Sprintf("充值订单 %s 退款，扣回额度 %s（应扣回 %s），原因：%s",
+		topUp.TradeNo, logger.LogQuota(reversal.DeductedQuota), logger.LogQuota(reversal.Quota), reason))
+	return reversal, nil
+}
+
+// ReverseRedemption 撤销已使用的兑换码并扣回对应额度
+func ReverseRedemption(redemptionId int, reason string, operatorId int) (*QuotaReversal, error) {
+	reversal := &QuotaReversal{}
+	redemption := &Redemption{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", redemptionId).First(redemption).Error; err != nil {
+			return errors.New("兑换码不存在")
+		}
+		if redemption.Status != common.RedemptionCodeStatusUsed || redemption.UsedUserId == 0 {
+			return errors.New("只能撤销已使用的兑换码")
+		}
+		deducted, err := deductQuotaWithPolicy(tx, redemption.UsedUserId, redemption.Quota)
+		if err != nil {
+			return err
+		}
+		redemption.Status = common.RedemptionCodeStatusReversed
+		if err = tx.Model(redemption).Select("status").Updates(redemption).Error; err != nil {
+			return err
+		}
+		reversal = &QuotaReversal{
+			UserId:        redemption.UsedUserId,
+			SourceType:    QuotaReversalSourceRedemption,
+			SourceId:      redemption.Id,
+			Quota:         redemption.Quota,
+			DeductedQuota: deducted,
+			Reason:        reason,
+			OperatorId:    operatorId,
+			CreatedTime:   common.GetTimestamp(),
+		}
+		return tx.Create(reversal).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	syncReversedUserQuotaCache(reversal.UserId, reversal.DeductedQuota)
+	RecordLog(reversal.UserId, LogTypeManage, fmt.Sprintf("兑换码ID %d 已被撤销，扣回额度 %s（应扣回 %s），原因：%s",
+		redemption.Id, logger.LogQuota(reversal.DeductedQuota), logger.LogQuota(reversal.Quota), reason))
+	return reversal, nil
+}
@@ -0,0 +1,181 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"one-api/common"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Statement 月度账单，生成后不可修改
+// TokenId 为 0 表示用户维度的账单，否则为令牌维度的账单
+type Statement struct {
+	Id                  int     `json:"id"`
+	UserId              int     `json:"user_id" gorm:"index;uniqueIndex:uk_statement_scope,priority:1"`
+	Username            string  `json:"username" gorm:"default:''"`
+	TokenId             int     `json:"token_id" gorm:"default:0;uniqueIndex:uk_statement_scope,priority:2"`
+	TokenName           string  `json:"token_name" gorm:"default:''"`
+	Period              string  `json:"period" gorm:"type:varchar(7);index;uniqueIndex:uk_statement_scope,priority:3"`
+	StartTime           int64   `json:"start_time" gorm:"bigint"`
+	EndTime             int64   `json:"end_time" gorm:"bigint"`
+	RequestCount        int     `json:"request_count" gorm:"default:0"`
+	PromptTokens        int     `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens    int     `json:"completion_tokens" gorm:"default:0"`
+	CacheTokens         int     `json:"cache_tokens" gorm:"default:0"`
+	CacheCreationTokens int     `json:"cache_creation_tokens" gorm:"default:0"`
+	Quota               int     `json:"quota" gorm:"default:0"`
+	Amount              float64 `json:"amount"`
+	Currency            string  `json:"currency" gorm:"type:varchar(8);default:'USD'"`
+	QuotaPerUnit        float64 `json:"quota_per_unit"`
+	Items               string  `json:"items" gorm:"type:text"`
+	CreatedTime         int64   `json:"created_time" gorm:"bigint"`
+}
+
+// StatementItem 账单中按模型拆分的明细
+type StatementItem struct {
+	ModelName           string  `json:"model_name"`
+	RequestCount        int     `json:"request_count"`
+	PromptTokens        int     `json:"prompt_tokens"`
+	CompletionTokens    int     `json:"completion_tokens"`
+	CacheTokens         int     `json:"cache_tokens"`
+	CacheCreationTokens int     `json:"cache_creation_tokens"`
+	Quota               int     `json:"quota"`
+	Amount              float64 `json:"amount"`
+}
+
+var ErrStatementExists = errors.New("该周期的账单已存在")
+
+func (statement *Statement) GetItems() []StatementItem {
+	var items []StatementItem
+	if statement.Items == "" {
+		return items
+	}
+	if err := json.Unmarshal([]byte(statement.Items), &items); err != nil {
+		common.SysError("failed to unmarshal statement items: " + err.Error())
+	}
+	return items
+}
+
+// Insert 写入账单，同一用户/令牌同一周期只允许存在一份
+func (statement *Statement) Insert() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&Statement{}).Where("user_id = ? AND token_id = ? AND period = ?",
+			statement.UserId, statement.TokenId, statement.Period).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrStatementExists
+		}
+		statement.CreatedTime = common.GetTimestamp()
+		return tx.Create(statement).Error
+	})
+}
+
+func GetStatementById(id int) (*Statement, error) {
+	var statement Statement
+	err := DB.Where("id = ?", id).First(&statement).Error
+	return &statement, err
+}
+
+func GetUserStatementById(id int, userId int) (*Statement, error) {
+	var statement Statement
+	err := DB.Where("id = ? AND user_id = ?", id, userId).First(&statement).Error
+	return &statement, err
+}
+
+func GetAllStatements(userId int, period string, startIdx int, num int) (statements []*Statement, total int64, err error) {
+	tx := DB.Model(&Statement{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if period != "" {
+		tx = tx.Where("period = ?", period)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	// 列表不返回明细，下载时再读取
+	err = tx.Omit("items").Order("id desc").Limit(num).Offset(startIdx).Find(&statements).Error
+	return statements, total, err
+}
+
+// GetConsumeUserIds 返回时间范围内产生过消费日志的用户
+func GetConsumeUserIds(startTimestamp int64, endTimestamp int64) (userIds []int, err error) {
+	err = LOG_DB.Model(&Log{}).Where("type = ? AND created_at >= ? AND created_at < ?", LogTypeConsume, startTimestamp, endTimestamp).
+		Distinct().Pluck("user_id", &userIds).Error
+	return userIds, err
+}
+
+// AggregateConsumeLogs 按模型汇总时间范围内的消费日志，tokenId 为 0 时汇总用户全部令牌
+// 缓存 token 存放在 Other 字段中，无法直接在 SQL 中求和，因此分批读取后在内存中累加
+func AggregateConsumeLogs(userId int, tokenId int, startTimestamp int64, endTimestamp int64) ([]StatementItem, error) {
+	type consumeRow struct {
+		Id               int
+		ModelName        string
+		Quota            int
+		PromptTokens     int
+		CompletionTokens int
+		Other            string
+	}
+	tx := LOG_DB.Model(&Log{}).Select("id, model_name, quota, prompt_tokens, completion_tokens, other").
+		Where("user_id = ? AND type = ? AND created_at >= ? AND created_at < ?", userId, LogTypeConsume, startTimestamp, endTimestamp)
+	if tokenId != 0 {
+		tx = tx.Where("token_id = ?", tokenId)
+	}
+
+	itemMap := make(map[string]*StatementItem)
+	var rows []consumeRow
+	result := tx.FindInBatches(&rows, 1000, func(_ *gorm.DB, _ int) error {
+		for _, row := range rows {
+			item, ok := itemMap[row.ModelName]
+			if !ok {
+				item = &StatementItem{ModelName: row.ModelName}
+				itemMap[row.ModelName] = item
+			}
+			item.RequestCount++
+			item.Quota += row.Quota
+			item.PromptTokens += row.PromptTokens
+			item.CompletionTokens += row.CompletionTokens
+			if row.Other == "" {
+				continue
+			}
+			other, _ := common.StrToMap(row.Other)
+			item.CacheTokens += otherInt(other, "cache_tokens")
+			item.CacheCreationTokens += otherInt(other, "cache_creation_tokens")
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	items := make([]StatementItem, 0, len(itemMap))
+	for _, item := range itemMap {
+		item.Amount = common.QuotaToUSD(float64(item.Quota))
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Quota == items[j].Quota {
+			return items[i].ModelName < items[j].ModelName
+		}
+		return items[i].Quota > items[j].Quota
+	})
+	return items, nil
+}
+
+func otherInt(other map[string]interface{}, key string) int {
+	if other == nil {
+		return 0
+	}
+	switch v := other[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
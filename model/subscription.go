@@ -0,0 +1,296 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/logger"
+
+	"gorm.io/gorm"
+)
+
+// SubscriptionPlan 订阅套餐，由管理员定义，通过 Stripe 订阅按月扣费
+// RolloverPercent 表示周期结束时未用完的套餐额度可结转到下个周期的比例（0-100），
+// MaxRolloverQuota 为结转额度上限，0 表示不设上限
+type SubscriptionPlan struct {
+	Id               int            `json:"id"`
+	Name             string         `json:"name" gorm:"type:varchar(64);not null"`
+	Description      string         `json:"description" gorm:"type:varchar(255)"`
+	StripePriceId    string         `json:"stripe_price_id" gorm:"type:varchar(128)"`
+	Price            float64        `json:"price"` // 仅用于展示，实际扣费以 Stripe Price 为准
+	Currency         string         `json:"currency" gorm:"type:varchar(8);default:'USD'"`
+	MonthlyQuota     int            `json:"monthly_quota" gorm:"default:0"`
+	UpgradeGroup     string         `json:"upgrade_group" gorm:"type:varchar(64);default:''"`
+	RolloverPercent  int            `json:"rollover_percent" gorm:"default:0"`
+	MaxRolloverQuota int            `json:"max_rollover_quota" gorm:"default:0"`
+	Enabled          bool           `json:"enabled" gorm:"default:true"`
+	SortOrder        int            `json:"sort_order" gorm:"default:0"`
+	CreatedTime      int64          `json:"created_time" gorm:"bigint"`
+	UpdatedTime      int64          `json:"updated_time" gorm:"bigint"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// UserSubscription 用户的订阅记录，一个 Stripe 订阅对应一条记录
+type UserSubscription struct {
+	Id                   int               `json:"id"`
+	UserId               int               `json:"user_id" gorm:"index"`
+	PlanId               int               `json:"plan_id" gorm:"index"`
+	StripeSubscriptionId string            `json:"stripe_subscription_id" gorm:"type:varchar(128);uniqueIndex"`
+	Status               string            `json:"status" gorm:"type:varchar(32);index"`
+	CancelAtPeriodEnd    bool              `json:"cancel_at_period_end"`
+	CurrentPeriodStart   int64             `json:"current_period_start" gorm:"bigint"`
+	CurrentPeriodEnd     int64             `json:"current_period_end" gorm:"bigint"`
+	PeriodQuota          int               `json:"period_quota" gorm:"default:0"` // 本周期发放的套餐额度（含结转）
+	PeriodStartUsedQuota int               `json:"-" gorm:"default:0"`            // 本周期开始时用户的已用额度，用于计算套餐额度的剩余
+	OriginalGroup        string            `json:"original_group" gorm:"type:varchar(64);default:''"`
+	LastInvoiceId        string            `json:"-" gorm:"type:varchar(128);default:''"` // 最近一次发放额度的 Stripe Invoice，用于幂等
+	CreatedTime          int64             `json:"created_time" gorm:"bigint"`
+	UpdatedTime          int64             `json:"updated_time" gorm:"bigint"`
+	Plan                 *SubscriptionPlan `json:"plan,omitempty" gorm:"-:all"`
+}
+
+const (
+	SubscriptionStatusActive   = "active"
+	SubscriptionStatusPastDue  = "past_due"
+	SubscriptionStatusCanceled = "canceled"
+)
+
+func (plan *SubscriptionPlan) Insert() error {
+	now := common.GetTimestamp()
+	plan.CreatedTime = now
+	plan.UpdatedTime = now
+	return DB.Create(plan).Error
+}
+
+func (plan *SubscriptionPlan) Update() error {
+	plan.UpdatedTime = common.GetTimestamp()
+	return DB.Save(plan).Error
+}
+
+func DeleteSubscriptionPlanById(id int) error {
+	return DB.Delete(&SubscriptionPlan{}, id).Error
+}
+
+func GetSubscriptionPlanById(id int) (*SubscriptionPlan, error) {
+	var plan SubscriptionPlan
+	err := DB.Where("id = ?", id).First(&plan).Error
+	return &plan, err
+}
+
+// GetSubscriptionPlans 获取套餐列表，onlyEnabled 为 true 时只返回上架的套餐
+func GetSubscriptionPlans(onlyEnabled bool) (plans []*SubscriptionPlan, err error) {
+	tx := DB.Model(&SubscriptionPlan{})
+	if onlyEnabled {
+		tx = tx.Where("enabled = ?", true)
+	}
+	err = tx.Order("sort_order desc, id asc").Find(&plans).Error
+	return plans, err
+}
+
+func GetUserSubscriptionByStripeId(stripeSubscriptionId string) (*UserSubscription, error) {
+	var sub UserSubscription
+	err := DB.Where("stripe_subscription_id = ?", stripeSubscriptionId).First(&sub).Error
+	return &sub, err
+}
+
+// GetUserActiveSubscription 获取用户当前未取消的订阅
+func GetUserActiveSubscription(userId int) (*UserSubscription, error) {
+	var sub UserSubscription
+	err := DB.Where("user_id = ? AND status <> ?", userId, SubscriptionStatusCanceled).Order("id desc").First(&sub).Error
+	if err != nil {
+		return nil, err
+	}
+	sub.Plan, _ = GetSubscriptionPlanById(sub.PlanId)
+	return &sub, nil
+}
+
+func GetAllUserSubscriptions(userId int, status string, startIdx int, num int) (subs []*UserSubscription, total int64, err error) {
+	tx := DB.Model(&UserSubscription{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&subs).Error
+	return subs, total, err
+}
+
+func (sub *UserSubscription) Update() error {
+	sub.UpdatedTime = common.GetTimestamp()
+	return DB.Save(sub).Error
+}
+
+// CreateUserSubscription 创建订阅记录并将用户切换到套餐对应的分组，重复的 Stripe 订阅直接返回已有记录
+func CreateUserSubscription(userId int, planId int, stripeSubscriptionId string) (*UserSubscription, error) {
+	if stripeSubscriptionId == "" {
+		return nil, errors.New("未提供 Stripe 订阅 ID")
+	}
+	plan, err := GetSubscriptionPlanById(planId)
+	if err != nil {
+		return nil, fmt.Errorf("订阅套餐不存在: %d", planId)
+	}
+	sub := &UserSubscription{}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("stripe_subscription_id = ?", stripeSubscriptionId).First(sub).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var user User
+		if err = tx.Select("id", commonGroupCol).Where("id = ?", userId).First(&user).Error; err != nil {
+			return err
+		}
+		now := common.GetTimestamp()
+		sub = &UserSubscription{
+			UserId:               userId,
+			PlanId:               planId,
+			StripeSubscriptionId: stripeSubscriptionId,
+			Status:               SubscriptionStatusActive,
+			OriginalGroup:        user.Group,
+			CreatedTime:          now,
+			UpdatedTime:          now,
+		}
+		if err = tx.Create(sub).Error; err != nil {
+			return err
+		}
+		if plan.UpgradeGroup != "" {
+			return tx.Model(&User{}).Where("id = ?", userId).Update("group", plan.UpgradeGroup).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	_ = invalidateUserCache(userId)
+	return sub, nil
+}
+
+// RenewUserSubscription 新周期开始时发放套餐额度，并按结转规则处理上一周期未用完的套餐额度
+// invoiceId 用于幂等，同一张 Stripe Invoice 只会发放一次，Webhook 并发重复投递时也只有一次生效
+func RenewUserSubscription(sub *UserSubscription, invoiceId string, periodStart int64, periodEnd int64) error {
+	if invoiceId != "" && sub.LastInvoiceId == invoiceId {
+		return nil
+	}
+	var plan SubscriptionPlan
+	var expired, rollover int
+	renewed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if invoiceId != "" {
+			// 条件更新抢占该 Invoice，未更新到记录说明已被其他请求处理
+			result := tx.Model(&UserSubscription{}).Where("id = ? AND last_invoice_id <> ?", sub.Id, invoiceId).Update("last_invoice_id", invoiceId)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+		}
+		// 持有行锁后重新读取，结转计算使用最新的周期数据
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", sub.Id).First(sub).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", sub.PlanId).First(&plan).Error; err != nil {
+			return err
+		}
+		var user User
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").Select("id", "quota", "used_quota").Where("id = ?", sub.UserId).First(&user).Error; err != nil {
+			return err
+		}
+		if sub.PeriodQuota > 0 {
+			used := user.UsedQuota - sub.PeriodStartUsedQuota
+			unused := sub.PeriodQuota - used
+			if unused > 0 {
+				rollover = unused * plan.RolloverPercent / 100
+				if plan.MaxRolloverQuota > 0 && rollover > plan.MaxRolloverQuota {
+					rollover = plan.MaxRolloverQuota
+				}
+				expired = unused - rollover
+				// 只收回套餐额度，不会扣到用户自行充值的额度
+				if expired > user.Quota {
+					expired = user.Quota
+				}
+				if expired < 0 {
+					expired = 0
+				}
+			}
+		}
+		delta := plan.MonthlyQuota - expired
+		if err := tx.Model(&User{}).Where("id = ?", sub.UserId).Update("quota", gorm.Expr("quota + ?", delta)).Error; err != nil {
+			return err
+		}
+		sub.PeriodQuota = plan.MonthlyQuota + rollover
+		sub.PeriodStartUsedQuota = user.UsedQuota
+		sub.CurrentPeriodStart = periodStart
+		sub.CurrentPeriodEnd = periodEnd
+		sub.Status = SubscriptionStatusActive
+		sub.LastInvoiceId = invoiceId
+		sub.UpdatedTime = common.GetTimestamp()
+		renewed = true
+		return tx.Save(sub).Error
+	})
+	if err != nil || !renewed {
+		return err
+	}
+	_ = invalidateUserCache(sub.UserId)
+	content := fmt.Sprintf("订阅套餐 %s 续期，发放额度: %s", plan.Name, logger.LogQuota(plan.MonthlyQuota))
+	if rollover > 0 {
+		content += fmt.Sprintf("，结转额度: %s", logger.LogQuota(rollover))
+	}
+	if expired > 0 {
+		content += fmt.Sprintf("，过期额度: %s", logger.LogQuota(expired))
+	}
+	RecordLog(sub.UserId, LogTypeTopup, content)
+	return nil
+}
+
+// SwitchUserSubscriptionPlan 切换订阅套餐，分组立即生效，额度在下一周期按新套餐发放
+func SwitchUserSubscriptionPlan(sub *UserSubscription, plan *SubscriptionPlan) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		sub.PlanId = plan.Id
+		sub.UpdatedTime = common.GetTimestamp()
+		if err := tx.Save(sub).Error; err != nil {
+			return err
+		}
+		group := plan.UpgradeGroup
+		if group == "" {
+			group = sub.OriginalGroup
+		}
+		if group == "" {
+			return nil
+		}
+		return tx.Model(&User{}).Where("id = ?", sub.UserId).Update("group", group).Error
+	})
+	if err != nil {
+		return err
+	}
+	return invalidateUserCache(sub.UserId)
+}
+
+// CancelUserSubscription 订阅结束后恢复用户原来的分组
+func CancelUserSubscription(sub *UserSubscription) error {
+	if sub.Status == SubscriptionStatusCanceled {
+		return nil
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		sub.Status = SubscriptionStatusCanceled
+		sub.UpdatedTime = common.GetTimestamp()
+		if err := tx.Save(sub).Error; err != nil {
+			return err
+		}
+		if sub.OriginalGroup == "" {
+			return nil
+		}
+		return tx.Model(&User{}).Where("id = ?", sub.UserId).Update("group", sub.OriginalGroup).Error
+	})
+	if err != nil {
+		return err
+	}
+	RecordLog(sub.UserId, LogTypeSystem, fmt.Sprintf("订阅 %s 已结束", sub.StripeSubscriptionId))
+	return invalidateUserCache(sub.UserId)
+}
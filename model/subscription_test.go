@@ -0,0 +1,47 @@
+package model
+
+import (
+	"one-api/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewUserSubscriptionIdempotent(t *testing.T) {
+	setupSQLiteTestDB(t, &User{}, &SubscriptionPlan{}, &UserSubscription{}, &Log{})
+	originLogDB, redisEnabled := LOG_DB, common.RedisEnabled
+	LOG_DB, common.RedisEnabled = DB, false
+	t.Cleanup(func() {
+		LOG_DB, common.RedisEnabled = originLogDB, redisEnabled
+	})
+
+	require.NoError(t, DB.Create(&User{Id: 1, Username: "sub_user", Quota: 100}).Error)
+	plan := &SubscriptionPlan{Name: "pro", MonthlyQuota: 1000}
+	require.NoError(t, plan.Insert())
+	sub := &UserSubscription{UserId: 1, PlanId: plan.Id, StripeSubscriptionId: "sub_1", Status: SubscriptionStatusActive}
+	require.NoError(t, DB.Create(sub).Error)
+
+	quota := func() int {
+		var user User
+		require.NoError(t, DB.Select("quota").Where("id = ?", 1).First(&user).Error)
+		return user.Quota
+	}
+
+	// 两次投递读到的都是发放前的订阅记录，同一张 Invoice 只发放一次
+	stale := *sub
+	require.NoError(t, RenewUserSubscription(sub, "in_1", 100, 200))
+	assert.Equal(t, 1100, quota())
+	require.NoError(t, RenewUserSubscription(&stale, "in_1", 100, 200))
+	assert.Equal(t, 1100, quota())
+	var count int64
+	require.NoError(t, DB.Model(&Log{}).Where("type = ?", LogTypeTopup).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// 新的 Invoice 正常续期，结转计算使用数据库中最新的周期数据：上一周期未用完的额度过期，用户充值的额度保留
+	require.NoError(t, RenewUserSubscription(&stale, "in_2", 200, 300))
+	assert.Equal(t, 1100, quota())
+	assert.Equal(t, "in_2", stale.LastInvoiceId)
+	assert.Equal(t, 1000, stale.PeriodQuota)
+	assert.Equal(t, int64(300), stale.CurrentPeriodEnd)
+}
@@ -6,6 +6,8 @@ import (
 	"one-api/constant"
 	commonRelay "one-api/relay/common"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type TaskStatus string
@@ -21,22 +23,28 @@ const (
 )
 
 type Task struct {
-	ID         int64                 `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
-	CreatedAt  int64                 `json:"created_at" gorm:"index"`
-	UpdatedAt  int64                 `json:"updated_at"`
-	TaskID     string                `json:"task_id" gorm:"type:varchar(191);index"` // 第三方id，不一定有/ song id\ Task id
-	Platform   constant.TaskPlatform `json:"platform" gorm:"type:varchar(30);index"` // 平台
-	UserId     int                   `json:"user_id" gorm:"index"`
-	ChannelId  int                   `json:"channel_id" gorm:"index"`
-	Quota      int                   `json:"quota"`
-	Action     string                `json:"action" gorm:"type:varchar(40);index"` // 任务类型, song, lyrics, description-mode
-	Status     TaskStatus            `json:"status" gorm:"type:varchar(20);index"` // 任务状态
-	FailReason string                `json:"fail_reason"`
-	SubmitTime int64                 `json:"submit_time" gorm:"index"`
-	StartTime  int64                 `json:"start_time" gorm:"index"`
-	FinishTime int64                 `json:"finish_time" gorm:"index"`
-	Progress   string                `json:"progress" gorm:"type:varchar(20);index"`
-	Properties Properties            `json:"properties" gorm:"type:json"`
+	ID          int64                 `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	CreatedAt   int64                 `json:"created_at" gorm:"index"`
+	UpdatedAt   int64                 `json:"updated_at"`
+	TaskID      string                `json:"task_id" gorm:"type:varchar(191);index"` // 第三方id，不一定有/ song id\ Task id
+	Platform    constant.TaskPlatform `json:"platform" gorm:"type:varchar(30);index"` // 平台
+	UserId      int                   `json:"user_id" gorm:"index"`
+	ChannelId   int                   `json:"channel_id" gorm:"index"`
+	Quota       int                   `json:"quota"`
+	Action      string                `json:"action" gorm:"type:varchar(40);index"` // 任务类型, song, lyrics, description-mode
+	Status      TaskStatus            `json:"status" gorm:"type:varchar(20);index"` // 任务状态
+	FailReason  string                `json:"fail_reason"`
+	SubmitTime  int64                 `json:"submit_time" gorm:"index"`
+	StartTime   int64                 `json:"start_time" gorm:"index"`
+	FinishTime  int64                 `json:"finish_time" gorm:"index"`
+	Progress    string                `json:"progress" gorm:"type:varchar(20);index"`
+	Properties  Properties            `json:"properties" gorm:"type:json"`
+	CallbackUrl string                `json:"callback_url,omitempty" gorm:"type:varchar(1024)"` // 任务进入终态时的回调地址
+	NextPollAt  int64                 `json:"next_poll_at" gorm:"index"`                        // 下次轮询上游的时间
+	PollCount   int                   `json:"poll_count"`                                       // 进度未变化的连续轮询次数，用于退避
+	RefundQuota int                   `json:"refund_quota"`                                     // 结算时已退还的额度
+	QueueId     string                `json:"queue_id,omitempty" gorm:"type:varchar(64);index"` // 在网关排队时返回给用户的任务 ID，提交上游后仍可用于查询
+	DeletedAt   gorm.DeletedAt        `json:"-" gorm:"index"`
 
 	Data json.RawMessage `json:"data" gorm:"type:json"`
 }
@@ -51,8 +59,21 @@ func (t *Task) GetData(v any) error {
 	return err
 }
 
+// IsGatewayQueued 是否为网关排队中、尚未提交上游的占位任务
+func (t *Task) IsGatewayQueued() bool {
+	return t.QueueId != "" && t.TaskID == t.QueueId
+}
+
 type Properties struct {
-	Input string `json:"input"`
+	Input          string  `json:"input"`
+	Model          string  `json:"model,omitempty"`
+	Size           string  `json:"size,omitempty"`
+	RemixFrom      string  `json:"remix_from,omitempty"`       // remix 所基于的任务 ID
+	Duration       float64 `json:"duration,omitempty"`         // 提交时请求的时长（秒），即预扣额度对应的时长
+	UsageDuration  float64 `json:"usage_duration,omitempty"`   // 上游报告的实际生成时长（秒）
+	MediaKey       string  `json:"media_key,omitempty"`        // 结果转存到网关存储后的媒体标识
+	SongCount      int     `json:"song_count,omitempty"`       // 音乐任务按首计费时预扣额度对应的歌曲数
+	UsageSongCount int     `json:"usage_song_count,omitempty"` // 实际生成的歌曲数
 }
 
 func (m *Properties) Scan(val interface{}) error {
@@ -174,13 +195,120 @@ func GetAllUnFinishSyncTasks(limit int) []*Task {
 	var tasks []*Task
 	var err error
 	// get all tasks progress is not 100%
-	err = DB.Where("progress != ?", "100%").Limit(limit).Order("id").Find(&tasks).Error
+	err = DB.Where("progress != ?", "100%").Where(submittedTaskCondition).Limit(limit).Order("id").Find(&tasks).Error
+	if err != nil {
+		return nil
+	}
+	return tasks
+}
+
+// GetDueUnFinishSyncTasks 获取已到轮询时间的未完成任务
+func GetDueUnFinishSyncTasks(now int64, limit int) []*Task {
+	var tasks []*Task
+	err := DB.Where("progress != ? AND next_poll_at <= ?", "100%", now).Where(submittedTaskCondition).Limit(limit).Order("next_poll_at, id").Find(&tasks).Error
+	if err != nil {
+		return nil
+	}
+	return tasks
+}
+
+// GetTimeoutUnFinishSyncTasks 获取创建时间早于 before 且仍未完成的任务
+func GetTimeoutUnFinishSyncTasks(before int64, limit int) []*Task {
+	var tasks []*Task
+	err := DB.Where("progress != ? AND created_at < ?", "100%", before).Where(submittedTaskCondition).Limit(limit).Order("id").Find(&tasks).Error
 	if err != nil {
 		return nil
 	}
 	return tasks
 }
 
+type TaskQueueDepth struct {
+	Platform constant.TaskPlatform `json:"platform"`
+	Due      int                   `json:"due"`
+	Waiting  int                   `json:"waiting"`
+}
+
+// GetUnFinishSyncTaskDepth 按平台统计未完成任务中已到轮询时间与等待中的数量
+func GetUnFinishSyncTaskDepth(now int64) (depths []TaskQueueDepth, err error) {
+	err = DB.Model(&Task{}).
+		Select("platform, SUM(CASE WHEN next_poll_at <= ? THEN 1 ELSE 0 END) AS due, SUM(CASE WHEN next_poll_at > ? THEN 1 ELSE 0 END) AS waiting", now, now).
+		Where("progress != ?", "100%").Where(submittedTaskCondition).Group("platform").Scan(&depths).Error
+	return depths, err
+}
+
+// TaskUpdatePollSchedule 批量更新任务的下次轮询时间与退避计数
+func TaskUpdatePollSchedule(ids []int64, nextPollAt int64, pollCount int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return DB.Model(&Task{}).Where("id in (?)", ids).Updates(map[string]any{
+		"next_poll_at": nextPollAt,
+		"poll_count":   pollCount,
+	}).Error
+}
+
+// TaskFailIfUnfinished 将仍未完成的任务标记为失败，返回是否由本次调用完成了状态变更
+// 以条件更新保证并发场景下同一任务只会被判定失败（以及退还额度）一次
+func TaskFailIfUnfinished(task *Task, reason string, now int64) (bool, error) {
+	result := DB.Model(&Task{}).Where("id = ? AND progress != ?", task.ID, "100%").Updates(map[string]any{
+		"status":      TaskStatusFailure,
+		"progress":    "100%",
+		"fail_reason": reason,
+		"finish_time": now,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	task.Status = TaskStatusFailure
+	task.Progress = "100%"
+	task.FailReason = reason
+	task.FinishTime = now
+	return true, nil
+}
+
+// TaskUpdateRefundQuota 将已退还额度从 from 更新为 to，返回是否更新成功
+// 以 from 作为条件，避免并发结算时重复退还
+func TaskUpdateRefundQuota(id int64, from int, to int) (bool, error) {
+	result := DB.Model(&Task{}).Where("id = ? AND refund_quota = ?", id, from).Update("refund_quota", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TaskGetUserVideos 按游标分页获取用户的视频任务，afterId 为 0 时从头开始
+func TaskGetUserVideos(userId int, afterId int64, limit int, asc bool) ([]*Task, error) {
+	var tasks []*Task
+	query := DB.Where("user_id = ? AND platform NOT IN ?", userId, constant.MusicTaskPlatforms)
+	order := "id desc"
+	if asc {
+		order = "id asc"
+		if afterId > 0 {
+			query = query.Where("id > ?", afterId)
+		}
+	} else if afterId > 0 {
+		query = query.Where("id < ?", afterId)
+	}
+	err := query.Omit("channel_id").Order(order).Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+func (Task *Task) Delete() error {
+	return DB.Delete(Task).Error
+}
+
+func GetTaskById(id int64) (*Task, error) {
+	var task Task
+	err := DB.First(&task, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
 func GetByOnlyTaskId(taskId string) (*Task, bool, error) {
 	if taskId == "" {
 		return nil, false, nil
@@ -201,7 +329,7 @@ func GetByTaskId(userId int, taskId string) (*Task, bool, error) {
 	}
 	var task *Task
 	var err error
-	err = DB.Where("user_id = ? and task_id = ?", userId, taskId).
+	err = DB.Where("user_id = ? and (task_id = ? or queue_id = ?)", userId, taskId, taskId).
 		First(&task).Error
 	exist, err := RecordExist(err)
 	if err != nil {
@@ -216,7 +344,7 @@ func GetByTaskIds(userId int, taskIds []any) ([]*Task, error) {
 	}
 	var task []*Task
 	var err error
-	err = DB.Where("user_id = ? and task_id in (?)", userId, taskIds).
+	err = DB.Where("user_id = ? and (task_id in (?) or queue_id in (?))", userId, taskIds, taskIds).
 		Find(&task).Error
 	if err != nil {
 		return nil, err
@@ -224,6 +352,11 @@ func GetByTaskIds(userId int, taskIds []any) ([]*Task, error) {
 	return task, nil
 }
 
+// TaskUpdateProperties 仅更新任务属性，避免覆盖轮询协程同时写入的状态
+func TaskUpdateProperties(id int64, properties Properties) error {
+	return DB.Model(&Task{}).Where("id = ?", id).Update("properties", properties).Error
+}
+
 func TaskUpdateProgress(id int64, progress string) error {
 	return DB.Model(&Task{}).Where("id = ?", id).Update("progress", progress).Error
 }
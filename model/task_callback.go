@@ -0,0 +1,66 @@
+package model
+
+import (
+	"one-api/common"
+)
+
+// TaskCallback 异步任务回调的一次投递记录，每次重试都会新增一条记录
+type TaskCallback struct {
+	Id          int    `json:"id"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	TaskType    string `json:"task_type" gorm:"type:varchar(20);index:idx_task_callback_task,priority:1"`
+	TaskId      string `json:"task_id" gorm:"type:varchar(191);index:idx_task_callback_task,priority:2"`
+	Event       string `json:"event" gorm:"type:varchar(20)"` // 触发回调的任务终态
+	Url         string `json:"url" gorm:"type:varchar(1024)"`
+	Payload     string `json:"payload" gorm:"type:text"`
+	Attempt     int    `json:"attempt"`
+	StatusCode  int    `json:"status_code"`
+	Error       string `json:"error" gorm:"type:text"`
+	Status      string `json:"status" gorm:"type:varchar(20);index"`
+	NextRetryAt int64  `json:"next_retry_at" gorm:"index"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (callback *TaskCallback) Insert() error {
+	callback.CreatedAt = common.GetTimestamp()
+	return DB.Create(callback).Error
+}
+
+// GetDueTaskCallbacks 获取已到重试时间的投递记录
+func GetDueTaskCallbacks(now int64, limit int) (callbacks []*TaskCallback, err error) {
+	err = DB.Where("status = ? AND next_retry_at <= ?", common.TaskCallbackStatusRetrying, now).
+		Order("next_retry_at asc").Limit(limit).Find(&callbacks).Error
+	return callbacks, err
+}
+
+// ClaimTaskCallbackRetry 将待重试记录标记为失败，返回是否由当前节点取得重试权
+func ClaimTaskCallbackRetry(id int) (bool, error) {
+	result := DB.Model(&TaskCallback{}).Where("id = ? AND status = ?", id, common.TaskCallbackStatusRetrying).
+		Update("status", common.TaskCallbackStatusFailed)
+	return result.RowsAffected > 0, result.Error
+}
+
+func GetTaskCallbackById(id int) (*TaskCallback, error) {
+	var callback TaskCallback
+	err := DB.First(&callback, "id = ?", id).Error
+	return &callback, err
+}
+
+// GetTaskCallbacks 查询投递记录，userId 为 0 时查询所有用户
+func GetTaskCallbacks(userId int, taskId string, status string, startIdx int, num int) (callbacks []*TaskCallback, total int64, err error) {
+	tx := DB.Model(&TaskCallback{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if taskId != "" {
+		tx = tx.Where("task_id = ?", taskId)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&callbacks).Error
+	return callbacks, total, err
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"one-api/common"
+)
+
+const (
+	TaskQueueKindTask       = "task"
+	TaskQueueKindMidjourney = "mj"
+)
+
+// 排队中的占位任务以队列 ID 作为任务 ID，提交上游后任务 ID 被替换为上游 ID，据此区分占位任务与已提交的任务
+const (
+	submittedTaskCondition = "(queue_id IS NULL OR queue_id = '' OR task_id != queue_id)"
+	submittedMjCondition   = "(queue_id IS NULL OR queue_id = '' OR mj_id != queue_id)"
+)
+
+// TaskQueueItem 网关任务队列中等待提交上游的请求，请求上下文保存在入队节点的内存中
+type TaskQueueItem struct {
+	Id          int    `json:"id"`
+	Kind        string `json:"kind" gorm:"type:varchar(20);index:idx_task_queue_record,priority:1"`
+	RecordId    int64  `json:"record_id" gorm:"index:idx_task_queue_record,priority:2"` // 占位任务的主键
+	UserId      int    `json:"user_id" gorm:"index"`
+	ChannelId   int    `json:"channel_id" gorm:"index"`
+	Priority    int    `json:"priority"`
+	NodeId      string `json:"node_id" gorm:"type:varchar(128);index"`
+	HeartbeatAt int64  `json:"heartbeat_at" gorm:"bigint"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (item *TaskQueueItem) Insert() error {
+	item.CreatedAt = common.GetTimestamp()
+	item.HeartbeatAt = item.CreatedAt
+	return DB.Create(item).Error
+}
+
+// DeleteTaskQueueItem 将请求移出队列，返回是否由本次调用移出，保证同一请求只会被调度或判定失败一次
+func DeleteTaskQueueItem(id int) (bool, error) {
+	result := DB.Where("id = ?", id).Delete(&TaskQueueItem{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteTaskQueueItemByRecord 按占位任务将请求移出队列
+func DeleteTaskQueueItemByRecord(kind string, recordId int64) (bool, error) {
+	result := DB.Where("kind = ? AND record_id = ?", kind, recordId).Delete(&TaskQueueItem{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetTaskQueueItems 按调度顺序获取所有排队中的请求：优先级高的在前，同优先级先进先出
+func GetTaskQueueItems() (items []*TaskQueueItem, err error) {
+	err = DB.Order("priority desc, id asc").Find(&items).Error
+	return items, err
+}
+
+// GetStaleTaskQueueItems 获取入队节点已失效（心跳早于 heartbeatBefore）或排队超时（入队早于 createdBefore）的请求
+func GetStaleTaskQueueItems(heartbeatBefore int64, createdBefore int64) (items []*TaskQueueItem, err error) {
+	tx := DB.Where("heartbeat_at < ?", heartbeatBefore)
+	if createdBefore > 0 {
+		tx = tx.Or("created_at < ?", createdBefore)
+	}
+	err = tx.Find(&items).Error
+	return items, err
+}
+
+// TouchTaskQueueItems 更新节点所持有请求的心跳时间
+func TouchTaskQueueItems(nodeId string, now int64) error {
+	return DB.Model(&TaskQueueItem{}).Where("node_id = ?", nodeId).Update("heartbeat_at", now).Error
+}
+
+// CountUserTaskQueueItems 统计用户排队中的请求数
+func CountUserTaskQueueItems(userId int) (count int64, err error) {
+	err = DB.Model(&TaskQueueItem{}).Where("user_id = ?", userId).Count(&count).Error
+	return count, err
+}
+
+// HasTaskQueueItems 用户或渠道是否已有排队中的请求，有则新请求也需要排队，保证先进先出
+// userId 或 channelId 为 0 时不检查对应维度，未设置并发上限的维度不应让新请求排在其后
+func HasTaskQueueItems(userId int, channelId int) (bool, error) {
+	query := DB.Model(&TaskQueueItem{})
+	switch {
+	case userId != 0 && channelId != 0:
+		query = query.Where("user_id = ? OR channel_id = ?", userId, channelId)
+	case userId != 0:
+		query = query.Where("user_id = ?", userId)
+	case channelId != 0:
+		query = query.Where("channel_id = ?", channelId)
+	default:
+		return false, nil
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// GetTaskQueuePosition 获取占位任务的排队位置（从 1 开始），只计算同一用户或同一渠道中排在前面的请求，未排队时返回 0
+func GetTaskQueuePosition(kind string, recordId int64) int {
+	var item TaskQueueItem
+	if err := DB.Where("kind = ? AND record_id = ?", kind, recordId).First(&item).Error; err != nil {
+		return 0
+	}
+	var ahead int64
+	err := DB.Model(&TaskQueueItem{}).
+		Where("user_id = ? OR channel_id = ?", item.UserId, item.ChannelId).
+		Where("priority > ? OR (priority = ? AND id < ?)", item.Priority, item.Priority, item.Id).
+		Count(&ahead).Error
+	if err != nil {
+		return 0
+	}
+	return int(ahead) + 1
+}
+
+// CountUserRunningTasks 统计用户已提交上游且尚未完成的异步任务数（含 Midjourney 任务）
+func CountUserRunningTasks(userId int) (int64, error) {
+	return countRunningTasks("user_id", userId)
+}
+
+// CountChannelRunningTasks 统计渠道已提交上游且尚未完成的异步任务数（含 Midjourney 任务）
+func CountChannelRunningTasks(channelId int) (int64, error) {
+	return countRunningTasks("channel_id", channelId)
+}
+
+func countRunningTasks(column string, value int) (int64, error) {
+	var taskCount, mjCount int64
+	err := DB.Model(&Task{}).Where(column+" = ? AND progress != ?", value, "100%").
+		Where(submittedTaskCondition).Count(&taskCount).Error
+	if err != nil {
+		return 0, err
+	}
+	err = DB.Model(&Midjourney{}).Where(column+" = ? AND progress != ?", value, "100%").
+		Where(submittedMjCondition).Count(&mjCount).Error
+	if err != nil {
+		return 0, err
+	}
+	return taskCount + mjCount, nil
+}
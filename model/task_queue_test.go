@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasTaskQueueItems(t *testing.T) {
+	setupSQLiteTestDB(t, &TaskQueueItem{})
+	require.NoError(t, (&TaskQueueItem{Kind: TaskQueueKindTask, UserId: 1, ChannelId: 10}).Insert())
+
+	has := func(userId int, channelId int) bool {
+		queued, err := HasTaskQueueItems(userId, channelId)
+		require.NoError(t, err)
+		return queued
+	}
+	assert.True(t, has(1, 20))
+	assert.True(t, has(2, 10))
+	assert.False(t, has(2, 20))
+	// 未设置上限的维度传 0，不会因为该维度已有排队请求而排队
+	assert.False(t, has(2, 0))
+	assert.False(t, has(0, 20))
+	assert.True(t, has(0, 10))
+	assert.False(t, has(0, 0))
+}
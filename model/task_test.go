@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// setupSQLiteTestDB 使用内存 SQLite 替换全局 DB，测试结束后恢复
+func setupSQLiteTestDB(t *testing.T, models ...any) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	originDB, originLogDB, usingSQLite := DB, LOG_DB, common.UsingSQLite
+	DB, LOG_DB, common.UsingSQLite = db, db, true
+	t.Cleanup(func() {
+		DB, LOG_DB, common.UsingSQLite = originDB, originLogDB, usingSQLite
+		sqlDB.Close()
+	})
+}
+
+func TestTaskGetUserVideos(t *testing.T) {
+	setupSQLiteTestDB(t, &Task{})
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, DB.Create(&Task{ID: int64(i), UserId: 1, TaskID: fmt.Sprintf("video_%d", i), Platform: "kling"}).Error)
+	}
+	require.NoError(t, DB.Create(&Task{ID: 6, UserId: 1, TaskID: "song_6", Platform: constant.TaskPlatformSuno}).Error)
+	require.NoError(t, DB.Create(&Task{ID: 7, UserId: 2, TaskID: "video_7", Platform: "kling"}).Error)
+	require.NoError(t, DB.Create(&Task{ID: 8, UserId: 1, TaskID: "song_8", Platform: constant.MusicTaskPlatforms[1]}).Error)
+
+	taskIds := func(tasks []*Task) []int64 {
+		ids := make([]int64, 0, len(tasks))
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	// 默认按创建倒序，不包含音乐任务与其他用户的任务
+	tasks, err := TaskGetUserVideos(1, 0, 3, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{5, 4, 3}, taskIds(tasks))
+
+	tasks, err = TaskGetUserVideos(1, 3, 3, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, taskIds(tasks))
+
+	tasks, err = TaskGetUserVideos(1, 2, 2, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4}, taskIds(tasks))
+
+	// 已删除的任务不再出现在列表中
+	require.NoError(t, (&Task{ID: 4}).Delete())
+	tasks, err = TaskGetUserVideos(1, 2, 2, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 5}, taskIds(tasks))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"one-api/common"
+	"one-api/common/metrics"
 	"strings"
 
 	"github.com/bytedance/gopkg/util/gopool"
@@ -155,6 +156,7 @@ func GetTokenByKey(key string, fromDB bool) (token *Token, err error) {
 	if !fromDB && common.RedisEnabled {
 		// Try Redis first
 		token, err := cacheGetTokenByKey(key)
+		metrics.RecordCacheOperation("get_token", "redis", err == nil)
 		if err == nil {
 			return token, nil
 		}
@@ -10,14 +10,18 @@ import (
 )
 
 type TopUp struct {
-	Id           int     `json:"id"`
-	UserId       int     `json:"user_id" gorm:"index"`
-	Amount       int64   `json:"amount"`
-	Money        float64 `json:"money"`
-	TradeNo      string  `json:"trade_no" gorm:"unique;type:varchar(255);index"`
-	CreateTime   int64   `json:"create_time"`
-	CompleteTime int64   `json:"complete_time"`
-	Status       string  `json:"status"`
+	Id              int     `json:"id"`
+	UserId          int     `json:"user_id" gorm:"index"`
+	Amount          int64   `json:"amount"`
+	Money           float64 `json:"money"`
+	Currency        string  `json:"currency" gorm:"type:varchar(8);default:''"` // 实付金额的货币，空值为旧订单
+	Quota           int     `json:"quota" gorm:"default:0"`                     // 支付成功后应入账的额度，0 表示旧订单，按支付渠道的旧规则计算
+	TradeNo         string  `json:"trade_no" gorm:"unique;type:varchar(255);index"`
+	PaymentProvider string  `json:"payment_provider" gorm:"type:varchar(32);default:''"`
+	ExternalId      string  `json:"external_id" gorm:"type:varchar(128);default:''"` // 支付渠道侧的订单号
+	CreateTime      int64   `json:"create_time"`
+	CompleteTime    int64   `json:"complete_time"`
+	Status          string  `json:"status"`
 }
 
 func (topUp *TopUp) Insert() error {
@@ -52,50 +56,73 @@ func GetTopUpByTradeNo(tradeNo string) *TopUp {
 	return topUp
 }
 
-func Recharge(referenceId string, customerId string) (err error) {
-	if referenceId == "" {
-		return errors.New("未提供支付单号")
+// CompleteTopUp 支付成功后将订单标记为成功并为用户入账，订单已完成时直接返回，保证重复回调只入账一次
+// 返回的 bool 表示本次调用是否实际入账
+func CompleteTopUp(tradeNo string, externalId string, stripeCustomer string) (*TopUp, bool, error) {
+	if tradeNo == "" {
+		return nil, false, errors.New("未提供支付单号")
 	}
 
-	var quota float64
 	topUp := &TopUp{}
+	credited := false
+	quota := 0
 
 	refCol := "`trade_no`"
 	if common.UsingPostgreSQL {
 		refCol = `"trade_no"`
 	}
 
-	err = DB.Transaction(func(tx *gorm.DB) error {
-		err := tx.Set("gorm:query_option", "FOR UPDATE").Where(refCol+" = ?", referenceId).First(topUp).Error
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where(refCol+" = ?", tradeNo).First(topUp).Error
 		if err != nil {
 			return errors.New("充值订单不存在")
 		}
-
 		if topUp.Status != common.TopUpStatusPending {
-			return errors.New("充值订单状态错误")
+			return nil
 		}
 
 		topUp.CompleteTime = common.GetTimestamp()
 		topUp.Status = common.TopUpStatusSuccess
-		err = tx.Save(topUp).Error
-		if err != nil {
+		if externalId != "" {
+			topUp.ExternalId = externalId
+		}
+		if err = tx.Save(topUp).Error; err != nil {
 			return err
 		}
 
-		quota = topUp.Money * common.QuotaPerUnit
-		err = tx.Model(&User{}).Where("id = ?", topUp.UserId).Updates(map[string]interface{}{"stripe_customer": customerId, "quota": gorm.Expr("quota + ?", quota)}).Error
-		if err != nil {
+		quota = topUp.CreditedQuota()
+		updates := map[string]interface{}{"quota": gorm.Expr("quota + ?", quota)}
+		if stripeCustomer != "" {
+			updates["stripe_customer"] = stripeCustomer
+		}
+		if err = tx.Model(&User{}).Where("id = ?", topUp.UserId).Updates(updates).Error; err != nil {
 			return err
 		}
-
+		credited = true
 		return nil
 	})
 
 	if err != nil {
-		return errors.New("充值失败，" + err.Error())
+		return nil, false, errors.New("充值失败，" + err.Error())
+	}
+	if !credited {
+		return topUp, false, nil
 	}
 
-	RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("使用在线充值成功，充值金额: %v，支付金额：%d", logger.FormatQuota(int(quota)), topUp.Amount))
+	_ = invalidateUserCache(topUp.UserId)
+	RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("使用在线充值成功，充值金额: %v，支付金额：%.2f", logger.LogQuota(quota), topUp.Money))
+	return topUp, true, nil
+}
 
+// ExpireTopUp 将未支付的订单标记为已过期
+func ExpireTopUp(tradeNo string) error {
+	result := DB.Model(&TopUp{}).Where("trade_no = ? AND status = ?", tradeNo, common.TopUpStatusPending).
+		Update("status", common.TopUpStatusExpired)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("充值订单不存在或状态错误")
+	}
 	return nil
 }
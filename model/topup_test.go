@@ -0,0 +1,88 @@
+package model
+
+import (
+	"one-api/common"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTopUpTest(t *testing.T) {
+	setupSQLiteTestDB(t, &User{}, &TopUp{}, &Log{})
+	originLogDB, redisEnabled := LOG_DB, common.RedisEnabled
+	LOG_DB, common.RedisEnabled = DB, false
+	t.Cleanup(func() {
+		LOG_DB, common.RedisEnabled = originLogDB, redisEnabled
+	})
+	require.NoError(t, DB.Create(&User{Id: 1, Username: "topup_user", Quota: 100}).Error)
+}
+
+func topUpTestState(t *testing.T) (quota int, logs int64) {
+	var user User
+	require.NoError(t, DB.Select("quota").Where("id = ?", 1).First(&user).Error)
+	require.NoError(t, DB.Model(&Log{}).Where("type = ?", LogTypeTopup).Count(&logs).Error)
+	return user.Quota, logs
+}
+
+func TestCompleteTopUpIdempotent(t *testing.T) {
+	setupTopUpTest(t)
+	require.NoError(t, DB.Create(&TopUp{UserId: 1, TradeNo: "trade_1", Quota: 1000, Status: common.TopUpStatusPending}).Error)
+
+	topUp, credited, err := CompleteTopUp("trade_1", "ext_1", "cus_1")
+	require.NoError(t, err)
+	assert.True(t, credited)
+	assert.Equal(t, common.TopUpStatusSuccess, topUp.Status)
+	assert.Equal(t, "ext_1", topUp.ExternalId)
+
+	// 支付渠道重复回调同一订单时不再入账
+	topUp, credited, err = CompleteTopUp("trade_1", "ext_1", "cus_1")
+	require.NoError(t, err)
+	assert.False(t, credited)
+	assert.Equal(t, common.TopUpStatusSuccess, topUp.Status)
+
+	quota, logs := topUpTestState(t)
+	assert.Equal(t, 1100, quota)
+	assert.Equal(t, int64(1), logs)
+	var user User
+	require.NoError(t, DB.Where("id = ?", 1).First(&user).Error)
+	assert.Equal(t, "cus_1", user.StripeCustomer)
+
+	// 已过期或不存在的订单不入账
+	require.NoError(t, DB.Create(&TopUp{UserId: 1, TradeNo: "trade_2", Quota: 1000, Status: common.TopUpStatusExpired}).Error)
+	_, credited, err = CompleteTopUp("trade_2", "", "")
+	require.NoError(t, err)
+	assert.False(t, credited)
+	_, _, err = CompleteTopUp("trade_missing", "", "")
+	assert.Error(t, err)
+	quota, _ = topUpTestState(t)
+	assert.Equal(t, 1100, quota)
+}
+
+func TestCompleteTopUpConcurrent(t *testing.T) {
+	setupTopUpTest(t)
+	require.NoError(t, DB.Create(&TopUp{UserId: 1, TradeNo: "trade_1", Quota: 1000, Status: common.TopUpStatusPending}).Error)
+
+	// 回调与主动查询同时完成同一订单时只入账一次
+	var wg sync.WaitGroup
+	var creditedCount atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, credited, err := CompleteTopUp("trade_1", "", "")
+			assert.NoError(t, err)
+			if credited {
+				creditedCount.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), creditedCount.Load())
+	quota, logs := topUpTestState(t)
+	assert.Equal(t, 1100, quota)
+	assert.Equal(t, int64(1), logs)
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+const usageExportBatchSize = 1000
+
+// UsageExportFilter 用量导出的筛选条件，时间范围左闭右开，零值表示不限制
+// QuotaData 只按小时记录用户与模型维度，令牌、渠道与分组条件对其无效
+type UsageExportFilter struct {
+	StartTimestamp int64
+	EndTimestamp   int64
+	LogType        int
+	Username       string
+	TokenName      string
+	ModelName      string
+	Channel        int
+	Group          string
+}
+
+func (filter *UsageExportFilter) applyTimeRange(tx *gorm.DB) *gorm.DB {
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("created_at < ?", filter.EndTimestamp)
+	}
+	return tx
+}
+
+// ExportLogs 按 id 顺序分批读取日志，避免一次性加载全部数据
+func ExportLogs(filter UsageExportFilter, fn func(logs []*Log) error) error {
+	tx := filter.applyTimeRange(LOG_DB.Model(&Log{}))
+	if filter.LogType != LogTypeUnknown {
+		tx = tx.Where("type = ?", filter.LogType)
+	}
+	if filter.Username != "" {
+		tx = tx.Where("username = ?", filter.Username)
+	}
+	if filter.TokenName != "" {
+		tx = tx.Where("token_name = ?", filter.TokenName)
+	}
+	if filter.ModelName != "" {
+		tx = tx.Where("model_name = ?", filter.ModelName)
+	}
+	if filter.Channel != 0 {
+		tx = tx.Where("channel_id = ?", filter.Channel)
+	}
+	if filter.Group != "" {
+		tx = tx.Where(logGroupCol+" = ?", filter.Group)
+	}
+	var logs []*Log
+	return tx.FindInBatches(&logs, usageExportBatchSize, func(_ *gorm.DB, _ int) error {
+		return fn(logs)
+	}).Error
+}
+
+func ExportQuotaData(filter UsageExportFilter, fn func(quotaData []*QuotaData) error) error {
+	tx := filter.applyTimeRange(DB.Model(&QuotaData{}))
+	if filter.Username != "" {
+		tx = tx.Where("username = ?", filter.Username)
+	}
+	if filter.ModelName != "" {
+		tx = tx.Where("model_name = ?", filter.ModelName)
+	}
+	var quotaData []*QuotaData
+	return tx.FindInBatches(&quotaData, usageExportBatchSize, func(_ *gorm.DB, _ int) error {
+		return fn(quotaData)
+	}).Error
+}
+
+// UsageReport 定时生成的用量报表，同一日期、数据集与格式只生成一次
+type UsageReport struct {
+	Id          int    `json:"id"`
+	Period      string `json:"period" gorm:"type:varchar(10);uniqueIndex:uk_usage_report,priority:1"`
+	Dataset     string `json:"dataset" gorm:"type:varchar(32);uniqueIndex:uk_usage_report,priority:2"`
+	Format      string `json:"format" gorm:"type:varchar(16);uniqueIndex:uk_usage_report,priority:3"`
+	Destination string `json:"destination" gorm:"type:varchar(16)"`
+	Location    string `json:"location"`
+	Rows        int    `json:"rows" gorm:"default:0"`
+	Size        int64  `json:"size" gorm:"default:0"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+func (report *UsageReport) Insert() error {
+	report.CreatedTime = common.GetTimestamp()
+	return DB.Create(report).Error
+}
+
+func HasUsageReport(period string, dataset string, format string) (bool, error) {
+	var count int64
+	err := DB.Model(&UsageReport{}).Where("period = ? AND dataset = ? AND format = ?", period, dataset, format).Count(&count).Error
+	return count > 0, err
+}
+
+func GetUsageReports(startIdx int, num int) (reports []*UsageReport, total int64, err error) {
+	tx := DB.Model(&UsageReport{})
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&reports).Error
+	return reports, total, err
+}
@@ -45,6 +45,9 @@ type User struct {
 	Setting          string         `json:"setting" gorm:"type:text;column:setting"`
 	Remark           string         `json:"remark,omitempty" gorm:"type:varchar(255)" validate:"max=255"`
 	StripeCustomer   string         `json:"stripe_customer" gorm:"type:varchar(64);column:stripe_customer;index"`
+	BillingMode      string         `json:"billing_mode" gorm:"type:varchar(16);default:'prepaid'"` // prepaid, postpaid
+	CreditLimit      int            `json:"credit_limit" gorm:"type:int;default:0"`                 // 后付费用户允许透支的额度
+	BillingLocked    bool           `json:"billing_locked" gorm:"default:false"`                    // 存在逾期账单时锁定
 }
 
 func (user *User) ToBaseUser() *UserBase {
@@ -56,6 +59,10 @@ func (user *User) ToBaseUser() *UserBase {
 		Username: user.Username,
 		Setting:  user.Setting,
 		Email:    user.Email,
+
+		BillingMode:   user.BillingMode,
+		CreditLimit:   user.CreditLimit,
+		BillingLocked: user.BillingLocked,
 	}
 	return cache
 }
@@ -915,3 +922,7 @@ func RootUserExists() bool {
 	}
 	return true
 }
+
+func UpdateUserStripeCustomer(id int, customerId string) error {
+	return DB.Model(&User{}).Where("id = ?", id).Update("stripe_customer", customerId).Error
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"fmt"
 	"one-api/common"
+	"one-api/common/metrics"
 	"one-api/constant"
 	"one-api/dto"
 	"time"
@@ -21,6 +22,10 @@ type UserBase struct {
 	Status   int    `json:"status"`
 	Username string `json:"username"`
 	Setting  string `json:"setting"`
+
+	BillingMode   string `json:"billing_mode"`
+	CreditLimit   int    `json:"credit_limit"`
+	BillingLocked bool   `json:"billing_locked"`
 }
 
 func (user *UserBase) WriteContext(c *gin.Context) {
@@ -30,6 +35,15 @@ func (user *UserBase) WriteContext(c *gin.Context) {
 	common.SetContextKey(c, constant.ContextKeyUserEmail, user.Email)
 	common.SetContextKey(c, constant.ContextKeyUserName, user.Username)
 	common.SetContextKey(c, constant.ContextKeyUserSetting, user.GetSetting())
+	common.SetContextKey(c, constant.ContextKeyUserCreditLimit, user.GetCreditLimit())
+}
+
+// GetCreditLimit 返回用户可透支的额度，预付费用户为 0
+func (user *UserBase) GetCreditLimit() int {
+	if user.BillingMode != common.UserBillingModePostpaid {
+		return 0
+	}
+	return user.CreditLimit
 }
 
 func (user *UserBase) GetSetting() dto.UserSetting {
@@ -86,6 +100,9 @@ func GetUserCache(userId int) (userCache *UserBase, err error) {
 
 	// Try getting from Redis first
 	userCache, err = cacheGetUserBase(userId)
+	if common.RedisEnabled {
+		metrics.RecordCacheOperation("get_user", "redis", err == nil)
+	}
 	if err == nil {
 		return userCache, nil
 	}
@@ -98,15 +115,7 @@ func GetUserCache(userId int) (userCache *UserBase, err error) {
 	}
 
 	// Create cache object from user data
-	userCache = &UserBase{
-		Id:       user.Id,
-		Group:    user.Group,
-		Quota:    user.Quota,
-		Status:   user.Status,
-		Username: user.Username,
-		Setting:  user.Setting,
-		Email:    user.Email,
-	}
+	userCache = user.ToBaseUser()
 
 	return userCache, nil
 }
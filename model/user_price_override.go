@@ -0,0 +1,157 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserPriceOverride 用户协议价，按模型粒度覆盖默认定价
+// ModelName 支持以 * 结尾的前缀匹配，精确匹配优先，其次取最长前缀
+// Type 为 multiplier 时 Value 替代分组倍率；为 ratio 或 price 时 Value 直接作为模型倍率或按次价格，且不再叠加分组倍率与分时定价
+type UserPriceOverride struct {
+	Id          int     `json:"id"`
+	UserId      int     `json:"user_id" gorm:"uniqueIndex:idx_user_price_override"`
+	ModelName   string  `json:"model_name" gorm:"type:varchar(128);uniqueIndex:idx_user_price_override"`
+	Type        string  `json:"type" gorm:"type:varchar(16)"`
+	Value       float64 `json:"value"`
+	Remark      string  `json:"remark" gorm:"type:varchar(255)"`
+	CreatedTime int64   `json:"created_time" gorm:"bigint"`
+	UpdatedTime int64   `json:"updated_time" gorm:"bigint"`
+}
+
+const (
+	PriceOverrideTypeMultiplier = "multiplier"
+	PriceOverrideTypeRatio      = "ratio"
+	PriceOverrideTypePrice      = "price"
+)
+
+var userPriceOverrides map[int][]*UserPriceOverride
+var userPriceOverridesLock sync.RWMutex
+
+func (o *UserPriceOverride) Validate() error {
+	if o.UserId == 0 {
+		return errors.New("缺少用户 ID")
+	}
+	o.ModelName = strings.TrimSpace(o.ModelName)
+	if o.ModelName == "" {
+		return errors.New("模型名称不能为空")
+	}
+	if strings.Contains(strings.TrimSuffix(o.ModelName, "*"), "*") {
+		return errors.New("通配符 * 只能出现在模型名称末尾")
+	}
+	switch o.Type {
+	case PriceOverrideTypeMultiplier, PriceOverrideTypeRatio, PriceOverrideTypePrice:
+	default:
+		return fmt.Errorf("未知的协议价类型: %s", o.Type)
+	}
+	if o.Value < 0 {
+		return errors.New("协议价不能为负数")
+	}
+	return nil
+}
+
+func (o *UserPriceOverride) matches(modelName string) (bool, int) {
+	if prefix, ok := strings.CutSuffix(o.ModelName, "*"); ok {
+		return strings.HasPrefix(modelName, prefix), len(prefix)
+	}
+	return o.ModelName == modelName, len(o.ModelName) + 1
+}
+
+func (o *UserPriceOverride) Insert() error {
+	now := common.GetTimestamp()
+	o.CreatedTime = now
+	o.UpdatedTime = now
+	if err := DB.Create(o).Error; err != nil {
+		return err
+	}
+	InitUserPriceOverrideCache()
+	return nil
+}
+
+func (o *UserPriceOverride) Update() error {
+	o.UpdatedTime = common.GetTimestamp()
+	if err := DB.Save(o).Error; err != nil {
+		return err
+	}
+	InitUserPriceOverrideCache()
+	return nil
+}
+
+func GetUserPriceOverrideById(id int) (*UserPriceOverride, error) {
+	var override UserPriceOverride
+	err := DB.Where("id = ?", id).First(&override).Error
+	return &override, err
+}
+
+func DeleteUserPriceOverrideById(id int) error {
+	if err := DB.Delete(&UserPriceOverride{}, id).Error; err != nil {
+		return err
+	}
+	InitUserPriceOverrideCache()
+	return nil
+}
+
+func GetUserPriceOverrides(userId int, startIdx int, num int) (overrides []*UserPriceOverride, total int64, err error) {
+	tx := DB.Model(&UserPriceOverride{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("user_id asc, model_name asc").Limit(num).Offset(startIdx).Find(&overrides).Error
+	return overrides, total, err
+}
+
+// InitUserPriceOverrideCache 将全部协议价加载到内存，计费时不再查询数据库
+func InitUserPriceOverrideCache() {
+	var overrides []*UserPriceOverride
+	if err := DB.Find(&overrides).Error; err != nil {
+		common.SysError("failed to load user price overrides: " + err.Error())
+		return
+	}
+	cache := make(map[int][]*UserPriceOverride)
+	for _, override := range overrides {
+		cache[override.UserId] = append(cache[override.UserId], override)
+	}
+	userPriceOverridesLock.Lock()
+	userPriceOverrides = cache
+	userPriceOverridesLock.Unlock()
+}
+
+func SyncUserPriceOverrideCache(frequency int) {
+	for {
+		time.Sleep(time.Duration(frequency) * time.Second)
+		InitUserPriceOverrideCache()
+	}
+}
+
+// GetUserPriceOverride 返回用户在该模型上生效的协议价，没有时返回 nil
+func GetUserPriceOverride(userId int, modelName string) *UserPriceOverride {
+	userPriceOverridesLock.RLock()
+	defer userPriceOverridesLock.RUnlock()
+	var matched *UserPriceOverride
+	bestLen := -1
+	for _, override := range userPriceOverrides[userId] {
+		if ok, length := override.matches(modelName); ok && length > bestLen {
+			matched = override
+			bestLen = length
+		}
+	}
+	return matched
+}
+
+// GetUserPriceOverridesCopy 返回用户的全部协议价，用于价格页展示
+func GetUserPriceOverridesCopy(userId int) []UserPriceOverride {
+	userPriceOverridesLock.RLock()
+	defer userPriceOverridesLock.RUnlock()
+	overrides := make([]UserPriceOverride, 0, len(userPriceOverrides[userId]))
+	for _, override := range userPriceOverrides[userId] {
+		overrides = append(overrides, *override)
+	}
+	return overrides
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserPriceOverride(t *testing.T) {
+	userPriceOverridesLock.Lock()
+	userPriceOverrides = map[int][]*UserPriceOverride{
+		1: {
+			{Id: 1, UserId: 1, ModelName: "*", Type: PriceOverrideTypeMultiplier, Value: 0.9},
+			{Id: 2, UserId: 1, ModelName: "gpt-4o*", Type: PriceOverrideTypeMultiplier, Value: 0.8},
+			{Id: 3, UserId: 1, ModelName: "gpt-4o-mini", Type: PriceOverrideTypeRatio, Value: 0.05},
+		},
+	}
+	userPriceOverridesLock.Unlock()
+	defer func() {
+		userPriceOverridesLock.Lock()
+		userPriceOverrides = nil
+		userPriceOverridesLock.Unlock()
+	}()
+
+	override := GetUserPriceOverride(1, "gpt-4o-mini")
+	require.NotNil(t, override)
+	assert.Equal(t, 3, override.Id)
+
+	override = GetUserPriceOverride(1, "gpt-4o-2024-08-06")
+	require.NotNil(t, override)
+	assert.Equal(t, 2, override.Id)
+
+	override = GetUserPriceOverride(1, "claude-3-5-haiku")
+	require.NotNil(t, override)
+	assert.Equal(t, 1, override.Id)
+
+	assert.Nil(t, GetUserPriceOverride(2, "gpt-4o-mini"))
+}
+
+func TestUserPriceOverrideValidate(t *testing.T) {
+	assert.NoError(t, (&UserPriceOverride{UserId: 1, ModelName: "gpt-4o*", Type: PriceOverrideTypePrice, Value: 0.01}).Validate())
+	assert.Error(t, (&UserPriceOverride{UserId: 1, ModelName: "gpt-*-mini", Type: PriceOverrideTypeRatio, Value: 1}).Validate())
+	assert.Error(t, (&UserPriceOverride{UserId: 1, ModelName: "gpt-4o", Type: "discount", Value: 1}).Validate())
+	assert.Error(t, (&UserPriceOverride{UserId: 1, ModelName: "gpt-4o", Type: PriceOverrideTypeMultiplier, Value: -1}).Validate())
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"one-api/dto"
+	"one-api/model"
 	relaycommon "one-api/relay/common"
 	"one-api/types"
 
@@ -49,3 +50,17 @@ type TaskAdaptor interface {
 
 	ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error)
 }
+
+// TaskRemixAdaptor 由支持基于已生成视频再创作（remix）的任务适配器实现
+type TaskRemixAdaptor interface {
+	// ValidateRemixRequestAndSetAction 校验 remix 请求，设置 Action 并保存上游请求所需的信息
+	ValidateRemixRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo, originTask *model.Task) *dto.TaskError
+}
+
+// MusicTaskAdaptor 由支持统一音乐生成接口 /v1/music/generations 的任务适配器实现
+type MusicTaskAdaptor interface {
+	// SongCount 一次提交生成的歌曲数，按首计费时预扣额度为单价乘以歌曲数
+	SongCount(info *relaycommon.RelayInfo) int
+	// ParseMusicSongs 从任务保存的上游数据中解析已生成的歌曲
+	ParseMusicSongs(taskData []byte) ([]dto.MusicSong, error)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	common2 "one-api/common"
+	"one-api/common/tracing"
 	"one-api/logger"
 	"one-api/relay/common"
 	"one-api/relay/constant"
@@ -20,6 +21,7 @@ import (
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
 )
 
 func SetupApiRequestHeader(info *common.RelayInfo, c *gin.Context, req *http.Header) {
@@ -263,14 +265,30 @@ func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http
 		}
 	}
 
+	// 上游请求的 span 只覆盖到收到响应头为止，响应体的处理由 stream/handler 的 span 记录
+	_, span := tracing.StartClient(c.Request.Context(), "relay.upstream_request", req.Header,
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddress(req.URL.Hostname()),
+	)
+	defer span.End()
+	if info.ChannelMeta != nil {
+		span.SetAttributes(
+			tracing.AttrChannelId.Int(info.ChannelId),
+			tracing.AttrChannelType.Int(info.ChannelType),
+			tracing.AttrModel.String(info.UpstreamModelName),
+		)
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
+		tracing.RecordError(span, err)
 		logger.LogError(c, "do request failed: "+err.Error())
 		return nil, types.NewError(err, types.ErrorCodeDoRequestFailed, types.ErrOptionWithHideErrMsg("upstream error: do request failed"))
 	}
 	if resp == nil {
 		return nil, errors.New("resp is nil")
 	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 
 	_ = req.Body.Close()
 	_ = c.Request.Body.Close()
@@ -0,0 +1,140 @@
+package bfl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/dto"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// aspectRatios ultra 与 kontext 模型只支持按宽高比指定尺寸
+var aspectRatios = []string{"21:9", "16:9", "4:3", "3:2", "1:1", "2:3", "3:4", "9:16", "9:21"}
+
+type Adaptor struct {
+}
+
+func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/v1/%s", info.ChannelBaseUrl, info.UpstreamModelName), nil
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, header *http.Header, info *relaycommon.RelayInfo) error {
+	header.Set("Content-Type", "application/json")
+	header.Set("Accept", "application/json")
+	header.Set("x-key", info.ApiKey)
+	return nil
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	if request.N > 1 {
+		return nil, errors.New("flux models only support n=1")
+	}
+	c.Set("response_format", request.ResponseFormat)
+	payload := imageRequestPayload{
+		Prompt: request.Prompt,
+	}
+	modelName := info.UpstreamModelName
+	if strings.Contains(modelName, "ultra") || strings.Contains(modelName, "kontext") {
+		payload.AspectRatio = relaycommon.ClosestAspectRatio(request.Size, aspectRatios)
+	} else if width, height, ok := relaycommon.ParseImageSize(request.Size); ok {
+		// 宽高需为 32 的倍数
+		payload.Width = width - width%32
+		payload.Height = height - height%32
+	}
+	if len(request.OutputFormat) > 0 {
+		_ = json.Unmarshal(request.OutputFormat, &payload.OutputFormat)
+	}
+
+	if info.RelayMode == relayconstant.RelayModeImagesEdits {
+		images, mask, err := relaycommon.GetImageEditFiles(c)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(modelName, "fill") {
+			payload.Image = base64.StdEncoding.EncodeToString(images[0].Data)
+			if mask != nil {
+				payload.Mask = base64.StdEncoding.EncodeToString(mask.Data)
+			}
+		} else {
+			payload.InputImage = base64.StdEncoding.EncodeToString(images[0].Data)
+		}
+	}
+
+	if len(request.ExtraFields) > 0 {
+		extra, err := relaycommon.ImageExtraFields(request.ExtraFields, "width", "height", "aspect_ratio")
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal extra fields: %w", err)
+		}
+		extraBytes, err := json.Marshal(extra)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal extra fields: %w", err)
+		}
+		if err = json.Unmarshal(extraBytes, &payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal extra fields: %w", err)
+		}
+	}
+	return payload, nil
+}
+
+func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	// 编辑请求的原图已转为 base64 放入 JSON 请求体，与生成请求一致
+	return channel.DoApiRequest(a, c, info, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
+	switch info.RelayMode {
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+		usage, err = bflImageHandler(c, resp, info)
+	default:
+		err = types.NewError(errors.New("unsupported relay mode"), types.ErrorCodeInvalidRequest)
+	}
+	return
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
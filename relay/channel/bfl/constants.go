@@ -0,0 +1,14 @@
+package bfl
+
+const (
+	ChannelName = "bfl"
+)
+
+var ModelList = []string{
+	"flux-dev",
+	"flux-pro-1.1",
+	"flux-pro-1.1-ultra",
+	"flux-kontext-pro",
+	"flux-kontext-max",
+	"flux-pro-1.0-fill",
+}
@@ -0,0 +1,160 @@
+package bfl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/logger"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	pollInterval = time.Second
+	pollTimeout  = 5 * time.Minute
+)
+
+type imageRequestPayload struct {
+	Prompt       string `json:"prompt,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	AspectRatio  string `json:"aspect_ratio,omitempty"`
+	OutputFormat string `json:"output_format,omitempty"`
+	InputImage   string `json:"input_image,omitempty"` // kontext 模型的参考图，base64 编码
+	Image        string `json:"image,omitempty"`       // fill 模型的原图，base64 编码
+	Mask         string `json:"mask,omitempty"`        // fill 模型的遮罩，base64 编码
+}
+
+type submitResponse struct {
+	Id         string `json:"id"`
+	PollingUrl string `json:"polling_url"`
+	Detail     any    `json:"detail"`
+}
+
+type resultResponse struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	Result struct {
+		Sample string  `json:"sample"`
+		Prompt string  `json:"prompt"`
+		Seed   float64 `json:"seed"`
+	} `json:"result"`
+}
+
+// waitResult 轮询生成结果，直到任务完成、失败、超时或客户端断开
+func waitResult(c *gin.Context, info *relaycommon.RelayInfo, pollingUrl string) (*resultResponse, error) {
+	deadline := time.Now().Add(pollTimeout)
+	for {
+		result, err := fetchResult(c, info, pollingUrl)
+		if err != nil {
+			logger.LogWarn(c, "bfl fetch result failed: "+err.Error())
+		} else {
+			switch result.Status {
+			case "Ready":
+				return result, nil
+			case "Pending":
+			default:
+				// Error、Failed、Request Moderated、Content Moderated、Task not found
+				return nil, fmt.Errorf("image generation failed: %s", result.Status)
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("bfl image generation timeout")
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func fetchResult(c *gin.Context, info *relaycommon.RelayInfo, pollingUrl string) (*resultResponse, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, pollingUrl, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-key", info.ApiKey)
+	resp, err := channel.DoRequest(c, req, info)
+	if err != nil {
+		return nil, err
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, string(body))
+	}
+	var result resultResponse
+	if err = common.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// bflImageHandler 提交后轮询结果，并转换为 OpenAI 图片响应
+func bflImageHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+
+	var submit submitResponse
+	if err = common.Unmarshal(responseBody, &submit); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	if submit.Id == "" {
+		return nil, types.NewOpenAIError(fmt.Errorf("submit failed: %s", string(responseBody)), types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
+	pollingUrl := submit.PollingUrl
+	if pollingUrl == "" {
+		pollingUrl = fmt.Sprintf("%s/v1/get_result?id=%s", info.ChannelBaseUrl, submit.Id)
+	}
+
+	result, err := waitResult(c, info, pollingUrl)
+	if err != nil {
+		return nil, types.WithOpenAIError(types.OpenAIError{
+			Message: err.Error(),
+			Type:    "bfl_error",
+			Code:    "image_generation_failed",
+		}, http.StatusInternalServerError)
+	}
+
+	imageData := dto.ImageData{
+		Url:           result.Result.Sample,
+		RevisedPrompt: result.Result.Prompt,
+	}
+	if c.GetString("response_format") == "b64_json" {
+		// 结果地址只在短时间内有效，按需直接返回图片内容
+		_, b64, err := service.GetImageFromUrl(result.Result.Sample)
+		if err != nil {
+			return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+		}
+		imageData.Url = ""
+		imageData.B64Json = b64
+	}
+	imageResponse := dto.ImageResponse{
+		Created: info.StartTime.Unix(),
+		Data:    []dto.ImageData{imageData},
+	}
+	jsonResponse, err := common.Marshal(imageResponse)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(http.StatusOK)
+	_, _ = c.Writer.Write(jsonResponse)
+	return &dto.Usage{}, nil
+}
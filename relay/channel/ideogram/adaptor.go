@@ -0,0 +1,198 @@
+package ideogram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"one-api/dto"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/types"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Adaptor struct {
+	// hasMask 编辑请求是否上传了遮罩，有遮罩时使用 edit 接口，否则使用 remix 接口按原图重绘
+	hasMask bool
+}
+
+func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	action := "generate"
+	if info.RelayMode == relayconstant.RelayModeImagesEdits {
+		action = "remix"
+		if a.hasMask {
+			action = "edit"
+		}
+	}
+	return fmt.Sprintf("%s/v1/ideogram-v3/%s", info.ChannelBaseUrl, action), nil
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, header *http.Header, info *relaycommon.RelayInfo) error {
+	channel.SetupApiRequestHeader(info, c, header)
+	header.Set("Accept", "application/json")
+	header.Set("Api-Key", info.ApiKey)
+	return nil
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+// renderingSpeed 将 OpenAI 的 quality 映射为 Ideogram 的渲染速度
+func renderingSpeed(quality string) string {
+	switch strings.ToLower(quality) {
+	case "low":
+		return "TURBO"
+	case "hd", "high":
+		return "QUALITY"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// styleType 将 OpenAI 的 style 映射为 Ideogram 的风格类型，其余取值原样透传
+func styleType(style string) string {
+	switch strings.ToLower(style) {
+	case "":
+		return ""
+	case "natural":
+		return "REALISTIC"
+	case "vivid":
+		return "GENERAL"
+	default:
+		return strings.ToUpper(style)
+	}
+}
+
+// ConvertImageRequest Ideogram v3 接口只接受 multipart 表单
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	c.Set("response_format", request.ResponseFormat)
+	var requestBody bytes.Buffer
+	writer := multipart.NewWriter(&requestBody)
+	fields := map[string]string{
+		"prompt":          request.Prompt,
+		"rendering_speed": renderingSpeed(request.Quality),
+	}
+	if request.N > 0 {
+		fields["num_images"] = strconv.Itoa(int(request.N))
+	}
+	if strings.Contains(request.Size, ":") {
+		fields["aspect_ratio"] = strings.ReplaceAll(request.Size, ":", "x")
+	} else if _, _, ok := relaycommon.ParseImageSize(request.Size); ok {
+		fields["resolution"] = strings.ToLower(request.Size)
+	}
+	if len(request.Style) > 0 {
+		var style string
+		if err := json.Unmarshal(request.Style, &style); err == nil {
+			fields["style_type"] = styleType(style)
+		}
+	}
+	// extra_fields 中的 negative_prompt、seed、magic_prompt 等参数原样透传，尺寸、数量与渲染速度以计费时的 size、n、quality 为准
+	if len(request.ExtraFields) > 0 {
+		extra, err := relaycommon.ImageExtraFields(request.ExtraFields, "resolution", "aspect_ratio", "num_images", "rendering_speed")
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal extra fields: %w", err)
+		}
+		for key, value := range extra {
+			fields[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	if info.RelayMode == relayconstant.RelayModeImagesEdits {
+		images, mask, err := relaycommon.GetImageEditFiles(c)
+		if err != nil {
+			return nil, err
+		}
+		if err = writeFile(writer, "image", images[0]); err != nil {
+			return nil, err
+		}
+		if mask != nil {
+			a.hasMask = true
+			// edit 接口不支持指定尺寸，输出与原图一致
+			delete(fields, "resolution")
+			delete(fields, "aspect_ratio")
+			if err = writeFile(writer, "mask", *mask); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for key, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	// 关闭 multipart 编写器以设置分界线
+	writer.Close()
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return &requestBody, nil
+}
+
+func writeFile(writer *multipart.Writer, fieldName string, file relaycommon.ImageEditFile) error {
+	part, err := writer.CreateFormFile(fieldName, file.Filename)
+	if err != nil {
+		return fmt.Errorf("create form file failed for %s: %w", fieldName, err)
+	}
+	_, err = part.Write(file.Data)
+	return err
+}
+
+func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	return channel.DoApiRequest(a, c, info, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
+	switch info.RelayMode {
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+		usage, err = ideogramImageHandler(c, resp, info)
+	default:
+		err = types.NewError(errors.New("unsupported relay mode"), types.ErrorCodeInvalidRequest)
+	}
+	return
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
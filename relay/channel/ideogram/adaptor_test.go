@@ -0,0 +1,38 @@
+package ideogram
+
+import (
+	"bytes"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertImageRequestRenderingSpeed(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/generations", nil)
+	request := dto.ImageRequest{
+		Prompt:      "a cat",
+		Quality:     "low",
+		N:           1,
+		ExtraFields: []byte(`{"rendering_speed":"QUALITY","seed":7}`),
+	}
+	body, err := (&Adaptor{}).ConvertImageRequest(c, &relaycommon.RelayInfo{}, request)
+	require.NoError(t, err)
+
+	_, params, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	form, err := multipart.NewReader(bytes.NewReader(body.(*bytes.Buffer).Bytes()), params["boundary"]).ReadForm(1 << 20)
+	require.NoError(t, err)
+	// 渲染速度影响计费，只能由 quality 决定，extra_fields 不能覆盖
+	assert.Equal(t, []string{"TURBO"}, form.Value["rendering_speed"])
+	assert.Equal(t, []string{"7"}, form.Value["seed"])
+	assert.Equal(t, []string{"1"}, form.Value["num_images"])
+}
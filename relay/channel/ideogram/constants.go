@@ -0,0 +1,9 @@
+package ideogram
+
+const (
+	ChannelName = "ideogram"
+)
+
+var ModelList = []string{
+	"ideogram-v3",
+}
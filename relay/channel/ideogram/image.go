@@ -0,0 +1,82 @@
+package ideogram
+
+import (
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/logger"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImageResponse struct {
+	Created string `json:"created"`
+	Data    []struct {
+		Url         string `json:"url"`
+		Prompt      string `json:"prompt"`
+		Resolution  string `json:"resolution"`
+		IsImageSafe bool   `json:"is_image_safe"`
+		Seed        int64  `json:"seed"`
+	} `json:"data"`
+}
+
+// responseIdeogram2OpenAIImage 转换为 OpenAI 图片响应，未通过安全检查的图片不返回地址
+func responseIdeogram2OpenAIImage(c *gin.Context, response *ImageResponse, info *relaycommon.RelayInfo) *dto.ImageResponse {
+	imageResponse := dto.ImageResponse{
+		Created: info.StartTime.Unix(),
+	}
+	responseFormat := c.GetString("response_format")
+	for _, data := range response.Data {
+		if !data.IsImageSafe || data.Url == "" {
+			continue
+		}
+		imageData := dto.ImageData{
+			Url:           data.Url,
+			RevisedPrompt: data.Prompt,
+		}
+		if responseFormat == "b64_json" {
+			_, b64, err := service.GetImageFromUrl(data.Url)
+			if err != nil {
+				logger.LogError(c, "get_image_data_failed: "+err.Error())
+				continue
+			}
+			imageData.Url = ""
+			imageData.B64Json = b64
+		}
+		imageResponse.Data = append(imageResponse.Data, imageData)
+	}
+	return &imageResponse
+}
+
+func ideogramImageHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+
+	var ideogramResponse ImageResponse
+	if err = common.Unmarshal(responseBody, &ideogramResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	fullTextResponse := responseIdeogram2OpenAIImage(c, &ideogramResponse, info)
+	if len(fullTextResponse.Data) == 0 {
+		return nil, types.WithOpenAIError(types.OpenAIError{
+			Message: "no safe image generated",
+			Type:    "ideogram_error",
+			Code:    "image_generation_failed",
+		}, http.StatusBadRequest)
+	}
+	jsonResponse, err := common.Marshal(fullTextResponse)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = c.Writer.Write(jsonResponse)
+	return &dto.Usage{}, nil
+}
@@ -0,0 +1,185 @@
+package stability
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"one-api/dto"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var aspectRatios = []string{"21:9", "16:9", "3:2", "5:4", "1:1", "4:5", "2:3", "9:16", "9:21"}
+
+type Adaptor struct {
+}
+
+func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+// GetRequestURL 按模型选择生成、局部重绘或放大接口，sd3 系列模型共用同一接口并通过 model 字段区分
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	modelName := info.UpstreamModelName
+	var path string
+	switch {
+	case strings.HasPrefix(modelName, "sd3"):
+		path = "generate/sd3"
+	case modelName == "stable-image-ultra":
+		path = "generate/ultra"
+	case modelName == "stable-image-core":
+		path = "generate/core"
+	case modelName == "stable-image-inpaint":
+		path = "edit/inpaint"
+	case strings.HasPrefix(modelName, "stable-image-upscale-"):
+		path = "upscale/" + strings.TrimPrefix(modelName, "stable-image-upscale-")
+	default:
+		return "", fmt.Errorf("unsupported model: %s", modelName)
+	}
+	return fmt.Sprintf("%s/v2beta/stable-image/%s", info.ChannelBaseUrl, path), nil
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, header *http.Header, info *relaycommon.RelayInfo) error {
+	channel.SetupApiRequestHeader(info, c, header)
+	header.Set("Accept", "application/json")
+	header.Set("Authorization", "Bearer "+info.ApiKey)
+	return nil
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+// ConvertImageRequest Stability 接口只接受 multipart 表单
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	if request.N > 1 {
+		return nil, errors.New("stability models only support n=1")
+	}
+	modelName := info.UpstreamModelName
+	needImage := modelName == "stable-image-inpaint" || strings.HasPrefix(modelName, "stable-image-upscale-")
+
+	var requestBody bytes.Buffer
+	writer := multipart.NewWriter(&requestBody)
+	fields := map[string]string{
+		"prompt":        request.Prompt,
+		"output_format": "png",
+	}
+	if strings.HasPrefix(modelName, "sd3") {
+		fields["model"] = modelName
+	}
+	if modelName == "stable-image-upscale-fast" {
+		delete(fields, "prompt")
+	}
+	if !needImage {
+		fields["aspect_ratio"] = relaycommon.ClosestAspectRatio(request.Size, aspectRatios)
+	}
+	if len(request.Style) > 0 {
+		var style string
+		if err := json.Unmarshal(request.Style, &style); err == nil {
+			fields["style_preset"] = style
+		}
+	}
+	// extra_fields 中的 negative_prompt、seed 等参数原样透传，模型与尺寸以计费时的 model、size 为准
+	if len(request.ExtraFields) > 0 {
+		extra, err := relaycommon.ImageExtraFields(request.ExtraFields, "model", "aspect_ratio")
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal extra fields: %w", err)
+		}
+		for key, value := range extra {
+			fields[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	for key, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.RelayMode == relayconstant.RelayModeImagesEdits {
+		images, mask, err := relaycommon.GetImageEditFiles(c)
+		if err != nil {
+			return nil, err
+		}
+		if err = writeFile(writer, "image", images[0]); err != nil {
+			return nil, err
+		}
+		if mask != nil {
+			if err = writeFile(writer, "mask", *mask); err != nil {
+				return nil, err
+			}
+		}
+	} else if needImage {
+		return nil, fmt.Errorf("model %s requires an image, please use the image edits endpoint", modelName)
+	}
+
+	// 关闭 multipart 编写器以设置分界线
+	writer.Close()
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return &requestBody, nil
+}
+
+func writeFile(writer *multipart.Writer, fieldName string, file relaycommon.ImageEditFile) error {
+	part, err := writer.CreateFormFile(fieldName, file.Filename)
+	if err != nil {
+		return fmt.Errorf("create form file failed for %s: %w", fieldName, err)
+	}
+	_, err = part.Write(file.Data)
+	return err
+}
+
+func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	return channel.DoApiRequest(a, c, info, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
+	switch info.RelayMode {
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+		usage, err = stabilityImageHandler(c, resp, info)
+	default:
+		err = types.NewError(errors.New("unsupported relay mode"), types.ErrorCodeInvalidRequest)
+	}
+	return
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
@@ -0,0 +1,17 @@
+package stability
+
+const (
+	ChannelName = "stability"
+)
+
+var ModelList = []string{
+	"stable-image-ultra",
+	"stable-image-core",
+	"sd3.5-large",
+	"sd3.5-large-turbo",
+	"sd3.5-medium",
+	"stable-image-inpaint",
+	"stable-image-upscale-fast",
+	"stable-image-upscale-conservative",
+	"stable-image-upscale-creative",
+}
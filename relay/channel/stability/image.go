@@ -0,0 +1,128 @@
+package stability
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/logger"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	pollTimeout  = 5 * time.Minute
+)
+
+// imageResponse 同步接口直接返回 base64 图片，creative 放大等异步接口只返回 id
+type imageResponse struct {
+	Id           string `json:"id"`
+	Image        string `json:"image"`
+	FinishReason string `json:"finish_reason"`
+	Seed         int64  `json:"seed"`
+}
+
+// waitResult 轮询异步任务结果，接口返回 202 表示仍在生成中
+func waitResult(c *gin.Context, info *relaycommon.RelayInfo, id string) (*imageResponse, error) {
+	deadline := time.Now().Add(pollTimeout)
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return nil, c.Request.Context().Err()
+		case <-time.After(pollInterval):
+		}
+		result, done, err := fetchResult(c, info, id)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("stability image generation timeout")
+		}
+		logger.LogDebug(c, fmt.Sprintf("stability result %s is still in progress", id))
+	}
+}
+
+func fetchResult(c *gin.Context, info *relaycommon.RelayInfo, id string) (*imageResponse, bool, error) {
+	requestUrl := fmt.Sprintf("%s/v2beta/results/%s", info.ChannelBaseUrl, id)
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, requestUrl, http.NoBody)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+	resp, err := channel.DoRequest(c, req, info)
+	if err != nil {
+		return nil, false, err
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return nil, false, nil
+	case http.StatusOK:
+		var result imageResponse
+		if err = common.Unmarshal(body, &result); err != nil {
+			return nil, false, err
+		}
+		return &result, true, nil
+	default:
+		return nil, false, fmt.Errorf("status code %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// stabilityImageHandler 转换为 OpenAI 图片响应，Stability 只返回 base64 图片
+func stabilityImageHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+
+	result := &imageResponse{}
+	if err = common.Unmarshal(responseBody, result); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	if result.Image == "" && result.Id != "" {
+		if result, err = waitResult(c, info, result.Id); err != nil {
+			return nil, types.WithOpenAIError(types.OpenAIError{
+				Message: err.Error(),
+				Type:    "stability_error",
+				Code:    "image_generation_failed",
+			}, http.StatusInternalServerError)
+		}
+	}
+	if result.FinishReason == "CONTENT_FILTERED" || result.Image == "" {
+		return nil, types.WithOpenAIError(types.OpenAIError{
+			Message: "image generation failed: " + result.FinishReason,
+			Type:    "stability_error",
+			Code:    "image_generation_failed",
+		}, http.StatusBadRequest)
+	}
+
+	imageResponse := dto.ImageResponse{
+		Created: info.StartTime.Unix(),
+		Data:    []dto.ImageData{{B64Json: result.Image}},
+	}
+	jsonResponse, err := common.Marshal(imageResponse)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(http.StatusOK)
+	_, _ = c.Writer.Write(jsonResponse)
+	return &dto.Usage{}, nil
+}
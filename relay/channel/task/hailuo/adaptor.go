@@ -0,0 +1,278 @@
+package hailuo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+)
+
+// ============================
+// Request / Response structures
+// ============================
+
+type requestPayload struct {
+	Model           string `json:"model"`
+	Prompt          string `json:"prompt,omitempty"`
+	FirstFrameImage string `json:"first_frame_image,omitempty"`
+	LastFrameImage  string `json:"last_frame_image,omitempty"`
+	Duration        int    `json:"duration,omitempty"`
+	Resolution      string `json:"resolution,omitempty"`
+	PromptOptimizer *bool  `json:"prompt_optimizer,omitempty"`
+}
+
+type baseResp struct {
+	StatusCode int    `json:"status_code"`
+	StatusMsg  string `json:"status_msg"`
+}
+
+type submitResponse struct {
+	TaskId   string   `json:"task_id"`
+	BaseResp baseResp `json:"base_resp"`
+}
+
+// queryResponse 任务查询结果，download_url 由 FetchTask 根据 file_id 补充
+type queryResponse struct {
+	TaskId      string   `json:"task_id"`
+	Status      string   `json:"status"`
+	FileId      string   `json:"file_id"`
+	DownloadUrl string   `json:"download_url"`
+	BaseResp    baseResp `json:"base_resp"`
+}
+
+type fileResponse struct {
+	File struct {
+		DownloadUrl string `json:"download_url"`
+	} `json:"file"`
+	BaseResp baseResp `json:"base_resp"`
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	if taskErr := relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionTextGenerate); taskErr != nil {
+		return taskErr
+	}
+	req := c.MustGet("task_request").(relaycommon.TaskSubmitReq)
+	if len(req.Images) > 2 {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("at most 2 images are supported"), "invalid_request", http.StatusBadRequest)
+	}
+	// 补全默认值，预扣费按实际提交的时长与分辨率计算
+	req.Duration = defaultInt(req.Duration, 6)
+	req.Size = defaultString(req.Size, "768P")
+	c.Set("task_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, _ *relaycommon.RelayInfo) (io.Reader, error) {
+	v, exists := c.Get("task_request")
+	if !exists {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(relaycommon.TaskSubmitReq)
+
+	body, err := a.convertToRequestPayload(&req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(_ *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/v1/video_generation", a.baseURL), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+
+	var sResp submitResponse
+	if err = json.Unmarshal(responseBody, &sResp); err != nil {
+		taskErr = service.TaskErrorWrapper(errors.Wrap(err, string(responseBody)), "unmarshal_response_failed", http.StatusInternalServerError)
+		return
+	}
+	if sResp.BaseResp.StatusCode != 0 || sResp.TaskId == "" {
+		taskErr = service.TaskErrorWrapperLocal(fmt.Errorf("task failed: %s", sResp.BaseResp.StatusMsg), "task_failed", http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": sResp.TaskId})
+	return sResp.TaskId, responseBody, nil
+}
+
+// FetchTask 查询任务状态，成功时再根据 file_id 获取下载地址并合并到查询结果中
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http.Response, error) {
+	taskID, ok := body["task_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+
+	resp, err := doGet(fmt.Sprintf("%s/v1/query/video_generation?task_id=%s", baseUrl, url.QueryEscape(taskID)), key)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var qResp queryResponse
+	if err = json.Unmarshal(responseBody, &qResp); err == nil && qResp.Status == "Success" && qResp.FileId != "" {
+		downloadUrl, err := a.retrieveDownloadUrl(baseUrl, key, qResp.FileId)
+		if err != nil {
+			return nil, err
+		}
+		if responseBody, err = sjson.SetBytes(responseBody, "download_url", downloadUrl); err != nil {
+			return nil, err
+		}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+	resp.ContentLength = int64(len(responseBody))
+	return resp, nil
+}
+
+func (a *TaskAdaptor) retrieveDownloadUrl(baseUrl, key string, fileId string) (string, error) {
+	resp, err := doGet(fmt.Sprintf("%s/v1/files/retrieve?file_id=%s", baseUrl, url.QueryEscape(fileId)), key)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var fResp fileResponse
+	if err = json.NewDecoder(resp.Body).Decode(&fResp); err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal file response")
+	}
+	if fResp.BaseResp.StatusCode != 0 || fResp.File.DownloadUrl == "" {
+		return "", fmt.Errorf("retrieve file %s failed: %s", fileId, fResp.BaseResp.StatusMsg)
+	}
+	return fResp.File.DownloadUrl, nil
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{"MiniMax-Hailuo-02", "T2V-01", "I2V-01"}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "hailuo"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var qResp queryResponse
+	if err := json.Unmarshal(respBody, &qResp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+	if qResp.Status == "" && qResp.BaseResp.StatusCode != 0 {
+		return nil, fmt.Errorf("query task failed: %s", qResp.BaseResp.StatusMsg)
+	}
+
+	taskInfo := &relaycommon.TaskInfo{TaskID: qResp.TaskId}
+	switch qResp.Status {
+	case "Preparing", "Queueing":
+		taskInfo.Status = model.TaskStatusQueued
+	case "Processing":
+		taskInfo.Status = model.TaskStatusInProgress
+	case "Success":
+		taskInfo.Status = model.TaskStatusSuccess
+		taskInfo.Url = qResp.DownloadUrl
+	case "Fail":
+		taskInfo.Status = model.TaskStatusFailure
+		taskInfo.Reason = defaultString(qResp.BaseResp.StatusMsg, "video generation failed")
+	default:
+		return nil, fmt.Errorf("unknown task status: %s", qResp.Status)
+	}
+	return taskInfo, nil
+}
+
+// ============================
+// helpers
+// ============================
+
+func doGet(requestUrl string, key string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	return service.GetHttpClient().Do(req)
+}
+
+func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*requestPayload, error) {
+	r := requestPayload{
+		Model:      defaultString(req.Model, "MiniMax-Hailuo-02"),
+		Prompt:     req.Prompt,
+		Duration:   req.Duration,
+		Resolution: req.Size,
+	}
+	if len(req.Images) > 0 {
+		r.FirstFrameImage = req.Images[0]
+	}
+	if len(req.Images) > 1 {
+		r.LastFrameImage = req.Images[1]
+	}
+	metaBytes, err := json.Marshal(req.PayloadMetadata())
+	if err != nil {
+		return nil, errors.Wrap(err, "metadata marshal metadata failed")
+	}
+	if err = json.Unmarshal(metaBytes, &r); err != nil {
+		return nil, errors.Wrap(err, "unmarshal metadata failed")
+	}
+	return &r, nil
+}
+
+func defaultString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func defaultInt(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -6,15 +6,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"one-api/common"
 	"one-api/model"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/samber/lo"
-
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
 
 	"one-api/constant"
 	"one-api/dto"
@@ -67,6 +68,7 @@ type requestPayload struct {
 	CameraControl  *CameraControl `json:"camera_control,omitempty"`
 	CallbackUrl    string         `json:"callback_url,omitempty"`
 	ExternalTaskId string         `json:"external_task_id,omitempty"`
+	VideoId        string         `json:"video_id,omitempty"` // 视频续写时基于的视频 ID
 }
 
 type responsePayload struct {
@@ -114,9 +116,32 @@ func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycom
 	return relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionGenerate)
 }
 
+// ValidateRemixRequestAndSetAction maps remix onto Kling video extension of the origin video.
+func (a *TaskAdaptor) ValidateRemixRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo, originTask *model.Task) *dto.TaskError {
+	var req relaycommon.TaskSubmitReq
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("prompt is required"), "invalid_request", http.StatusBadRequest)
+	}
+	videoId := gjson.GetBytes(originTask.Data, "data.task_result.videos.0.id").String()
+	if videoId == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("video of task %s is not available for remix", originTask.TaskID), "invalid_request", http.StatusBadRequest)
+	}
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]interface{})
+	}
+	req.Metadata["video_id"] = videoId
+	info.Action = constant.TaskActionRemix
+	info.OriginTaskID = originTask.TaskID
+	c.Set("task_request", req)
+	return nil
+}
+
 // BuildRequestURL constructs the upstream URL.
 func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
-	path := lo.Ternary(info.Action == constant.TaskActionGenerate, "/v1/videos/image2video", "/v1/videos/text2video")
+	path := actionPath(info.Action)
 
 	if isNewAPIRelay(info.ApiKey) {
 		return fmt.Sprintf("%s/kling%s", a.baseURL, path), nil
@@ -147,6 +172,13 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 	}
 	req := v.(relaycommon.TaskSubmitReq)
 
+	if info.Action == constant.TaskActionRemix {
+		data, err := json.Marshal(a.convertToExtendPayload(&req))
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
 	body, err := a.convertToRequestPayload(&req)
 	if err != nil {
 		return nil, err
@@ -202,7 +234,7 @@ func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http
 	if !ok {
 		return nil, fmt.Errorf("invalid action")
 	}
-	path := lo.Ternary(action == constant.TaskActionGenerate, "/v1/videos/image2video", "/v1/videos/text2video")
+	path := actionPath(action)
 	url := fmt.Sprintf("%s%s/%s", baseUrl, path, taskID)
 	if isNewAPIRelay(key) {
 		url = fmt.Sprintf("%s/kling%s/%s", baseUrl, path, taskID)
@@ -268,6 +300,32 @@ func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*
 	return &r, nil
 }
 
+// convertToExtendPayload builds the video extension request, which only accepts a subset of fields.
+func (a *TaskAdaptor) convertToExtendPayload(req *relaycommon.TaskSubmitReq) *requestPayload {
+	r := &requestPayload{
+		Prompt:   req.Prompt,
+		CfgScale: 0.5,
+	}
+	r.VideoId, _ = req.Metadata["video_id"].(string)
+	r.NegativePrompt, _ = req.Metadata["negative_prompt"].(string)
+	if cfgScale, ok := req.Metadata["cfg_scale"].(float64); ok {
+		r.CfgScale = cfgScale
+	}
+	return r
+}
+
+// actionPath returns the Kling API path of an action, shared by submit and fetch.
+func actionPath(action string) string {
+	switch action {
+	case constant.TaskActionGenerate:
+		return "/v1/videos/image2video"
+	case constant.TaskActionRemix:
+		return "/v1/videos/video-extend"
+	default:
+		return "/v1/videos/text2video"
+	}
+}
+
 func (a *TaskAdaptor) getAspectRatio(size string) string {
 	switch size {
 	case "1024x1024", "512x512":
@@ -362,6 +420,9 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 	if videos := resPayload.Data.TaskResult.Videos; len(videos) > 0 {
 		video := videos[0]
 		taskInfo.Url = video.Url
+		if duration, err := strconv.ParseFloat(video.Duration, 64); err == nil {
+			taskInfo.Duration = duration
+		}
 	}
 	return taskInfo, nil
 }
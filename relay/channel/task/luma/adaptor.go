@@ -0,0 +1,222 @@
+package luma
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+)
+
+// ============================
+// Request / Response structures
+// ============================
+
+type keyframe struct {
+	Type string `json:"type"`
+	Url  string `json:"url,omitempty"`
+	Id   string `json:"id,omitempty"`
+}
+
+type requestPayload struct {
+	Prompt      string              `json:"prompt"`
+	Model       string              `json:"model"`
+	AspectRatio string              `json:"aspect_ratio,omitempty"`
+	Resolution  string              `json:"resolution,omitempty"`
+	Duration    string              `json:"duration,omitempty"`
+	Loop        bool                `json:"loop,omitempty"`
+	Keyframes   map[string]keyframe `json:"keyframes,omitempty"`
+}
+
+type generationResponse struct {
+	Id            string `json:"id"`
+	State         string `json:"state"`
+	FailureReason string `json:"failure_reason"`
+	Detail        string `json:"detail"`
+	Assets        struct {
+		Video string `json:"video"`
+	} `json:"assets"`
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	if taskErr := relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionTextGenerate); taskErr != nil {
+		return taskErr
+	}
+	req := c.MustGet("task_request").(relaycommon.TaskSubmitReq)
+	if len(req.Images) > 2 {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("at most 2 images are supported"), "invalid_request", http.StatusBadRequest)
+	}
+	// 补全默认值，预扣费按实际提交的时长与分辨率计算
+	req.Duration = defaultInt(req.Duration, 5)
+	req.Size = defaultString(req.Size, "720p")
+	c.Set("task_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, _ *relaycommon.RelayInfo) (io.Reader, error) {
+	v, exists := c.Get("task_request")
+	if !exists {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(relaycommon.TaskSubmitReq)
+
+	body, err := a.convertToRequestPayload(&req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(_ *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/dream-machine/v1/generations/video", a.baseURL), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+
+	var gResp generationResponse
+	if err = json.Unmarshal(responseBody, &gResp); err != nil {
+		taskErr = service.TaskErrorWrapper(errors.Wrap(err, string(responseBody)), "unmarshal_response_failed", http.StatusInternalServerError)
+		return
+	}
+	if gResp.Id == "" || gResp.State == "failed" {
+		taskErr = service.TaskErrorWrapperLocal(fmt.Errorf("task failed: %s", defaultString(gResp.FailureReason, gResp.Detail)), "task_failed", http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": gResp.Id})
+	return gResp.Id, responseBody, nil
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http.Response, error) {
+	taskID, ok := body["task_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/dream-machine/v1/generations/%s", baseUrl, taskID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	return service.GetHttpClient().Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{"ray-2", "ray-flash-2"}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "luma"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var gResp generationResponse
+	if err := json.Unmarshal(respBody, &gResp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+
+	taskInfo := &relaycommon.TaskInfo{TaskID: gResp.Id}
+	switch gResp.State {
+	case "queued":
+		taskInfo.Status = model.TaskStatusQueued
+	case "dreaming":
+		taskInfo.Status = model.TaskStatusInProgress
+	case "completed":
+		taskInfo.Status = model.TaskStatusSuccess
+		taskInfo.Url = gResp.Assets.Video
+	case "failed":
+		taskInfo.Status = model.TaskStatusFailure
+		taskInfo.Reason = gResp.FailureReason
+	default:
+		return nil, fmt.Errorf("unknown task state: %s", gResp.State)
+	}
+	return taskInfo, nil
+}
+
+// ============================
+// helpers
+// ============================
+
+func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*requestPayload, error) {
+	r := requestPayload{
+		Prompt:     req.Prompt,
+		Model:      defaultString(req.Model, "ray-2"),
+		Resolution: req.Size,
+		Duration:   strconv.Itoa(req.Duration) + "s",
+	}
+	// 第一张图片为首帧，第二张为尾帧
+	for i, image := range req.Images {
+		if r.Keyframes == nil {
+			r.Keyframes = make(map[string]keyframe)
+		}
+		r.Keyframes["frame"+strconv.Itoa(i)] = keyframe{Type: "image", Url: image}
+	}
+	metaBytes, err := json.Marshal(req.PayloadMetadata())
+	if err != nil {
+		return nil, errors.Wrap(err, "metadata marshal metadata failed")
+	}
+	if err = json.Unmarshal(metaBytes, &r); err != nil {
+		return nil, errors.Wrap(err, "unmarshal metadata failed")
+	}
+	return &r, nil
+}
+
+func defaultString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func defaultInt(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
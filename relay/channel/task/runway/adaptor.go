@@ -0,0 +1,254 @@
+package runway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+)
+
+const (
+	apiVersion   = "2024-11-06"
+	defaultModel = "gen4_turbo"
+)
+
+// imageOnlyModels 只支持图生视频的模型，纯文本请求会被上游拒绝
+var imageOnlyModels = map[string]bool{
+	"gen4_turbo":  true,
+	"gen3a_turbo": true,
+}
+
+// ============================
+// Request / Response structures
+// ============================
+
+type promptImage struct {
+	Uri      string `json:"uri"`
+	Position string `json:"position"`
+}
+
+type requestPayload struct {
+	Model       string `json:"model"`
+	PromptText  string `json:"promptText,omitempty"`
+	PromptImage any    `json:"promptImage,omitempty"` // 单张图片为字符串，首尾帧为 []promptImage
+	Ratio       string `json:"ratio,omitempty"`
+	Duration    int    `json:"duration,omitempty"`
+	Seed        *int   `json:"seed,omitempty"`
+}
+
+type submitResponse struct {
+	Id    string `json:"id"`
+	Error string `json:"error"`
+}
+
+type taskResponse struct {
+	Id          string   `json:"id"`
+	Status      string   `json:"status"`
+	Progress    float64  `json:"progress"`
+	Output      []string `json:"output"`
+	Failure     string   `json:"failure"`
+	FailureCode string   `json:"failureCode"`
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	if taskErr := relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionTextGenerate); taskErr != nil {
+		return taskErr
+	}
+	req := c.MustGet("task_request").(relaycommon.TaskSubmitReq)
+	if len(req.Images) > 2 {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("at most 2 images are supported"), "invalid_request", http.StatusBadRequest)
+	}
+	// 在预扣费之前拒绝，避免提交到上游后才失败
+	if modelName := defaultString(req.Model, defaultModel); len(req.Images) == 0 && imageOnlyModels[modelName] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("model %s requires an image", modelName), "invalid_request", http.StatusBadRequest)
+	}
+	// 补全默认值，预扣费按实际提交的时长计算
+	req.Duration = defaultInt(req.Duration, 5)
+	req.Size = defaultString(strings.ReplaceAll(req.Size, "x", ":"), "1280:720")
+	c.Set("task_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, _ *relaycommon.RelayInfo) (io.Reader, error) {
+	v, exists := c.Get("task_request")
+	if !exists {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(relaycommon.TaskSubmitReq)
+
+	body, err := a.convertToRequestPayload(&req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	if info.Action == constant.TaskActionTextGenerate {
+		return fmt.Sprintf("%s/v1/text_to_video", a.baseURL), nil
+	}
+	return fmt.Sprintf("%s/v1/image_to_video", a.baseURL), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	setHeaders(req, info.ApiKey)
+	req.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+
+	var sResp submitResponse
+	if err = json.Unmarshal(responseBody, &sResp); err != nil {
+		taskErr = service.TaskErrorWrapper(errors.Wrap(err, string(responseBody)), "unmarshal_response_failed", http.StatusInternalServerError)
+		return
+	}
+	if sResp.Id == "" {
+		taskErr = service.TaskErrorWrapperLocal(fmt.Errorf("task failed: %s", sResp.Error), "task_failed", http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": sResp.Id})
+	return sResp.Id, responseBody, nil
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http.Response, error) {
+	taskID, ok := body["task_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/tasks/%s", baseUrl, taskID), nil)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, key)
+	return service.GetHttpClient().Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{"gen4_turbo", "gen3a_turbo"}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "runway"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var taskResp taskResponse
+	if err := json.Unmarshal(respBody, &taskResp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+
+	taskInfo := &relaycommon.TaskInfo{TaskID: taskResp.Id}
+	switch taskResp.Status {
+	case "PENDING", "THROTTLED":
+		taskInfo.Status = model.TaskStatusQueued
+	case "RUNNING":
+		taskInfo.Status = model.TaskStatusInProgress
+		if taskResp.Progress > 0 {
+			taskInfo.Progress = fmt.Sprintf("%d%%", int(taskResp.Progress*100))
+		}
+	case "SUCCEEDED":
+		taskInfo.Status = model.TaskStatusSuccess
+		if len(taskResp.Output) > 0 {
+			taskInfo.Url = taskResp.Output[0]
+		}
+	case "FAILED", "CANCELLED":
+		taskInfo.Status = model.TaskStatusFailure
+		taskInfo.Reason = defaultString(taskResp.Failure, taskResp.FailureCode)
+	default:
+		return nil, fmt.Errorf("unknown task status: %s", taskResp.Status)
+	}
+	return taskInfo, nil
+}
+
+// ============================
+// helpers
+// ============================
+
+func setHeaders(req *http.Request, key string) {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("X-Runway-Version", apiVersion)
+}
+
+func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*requestPayload, error) {
+	r := requestPayload{
+		Model:      defaultString(req.Model, defaultModel),
+		PromptText: req.Prompt,
+		Ratio:      req.Size,
+		Duration:   req.Duration,
+	}
+	switch len(req.Images) {
+	case 0:
+	case 1:
+		r.PromptImage = req.Images[0]
+	default:
+		r.PromptImage = []promptImage{
+			{Uri: req.Images[0], Position: "first"},
+			{Uri: req.Images[1], Position: "last"},
+		}
+	}
+	metaBytes, err := json.Marshal(req.PayloadMetadata())
+	if err != nil {
+		return nil, errors.Wrap(err, "metadata marshal metadata failed")
+	}
+	if err = json.Unmarshal(metaBytes, &r); err != nil {
+		return nil, errors.Wrap(err, "unmarshal metadata failed")
+	}
+	return &r, nil
+}
+
+func defaultString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func defaultInt(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
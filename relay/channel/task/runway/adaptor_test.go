@@ -0,0 +1,44 @@
+package runway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/constant"
+	relaycommon "one-api/relay/common"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRequestAndSetAction(t *testing.T) {
+	validate := func(body string) (*relaycommon.RelayInfo, int) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/video/generations", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		info := &relaycommon.RelayInfo{
+			ChannelMeta:   &relaycommon.ChannelMeta{ChannelType: constant.ChannelTypeRunway},
+			TaskRelayInfo: &relaycommon.TaskRelayInfo{},
+		}
+		if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(c, info); taskErr != nil {
+			return info, taskErr.StatusCode
+		}
+		return info, http.StatusOK
+	}
+
+	// 默认模型只支持图生视频，纯文本请求在预扣费前拒绝
+	_, status := validate(`{"prompt":"a cat"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	_, status = validate(`{"model":"gen3a_turbo","prompt":"a cat"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	info, status := validate(`{"prompt":"a cat","image":"https://example.com/cat.png"}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, constant.TaskActionGenerate, info.Action)
+
+	info, status = validate(`{"prompt":"a cat","images":["https://example.com/first.png","https://example.com/last.png"]}`)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, constant.TaskActionFirstTailGenerate, info.Action)
+}
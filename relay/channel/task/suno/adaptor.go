@@ -12,6 +12,7 @@ import (
 	"one-api/dto"
 	"one-api/relay/channel"
 	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
 	"one-api/service"
 	"strings"
 	"time"
@@ -32,6 +33,9 @@ func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
 }
 
 func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.TaskError) {
+	if info.RelayMode == relayconstant.RelayModeMusicSubmit {
+		return a.validateMusicRequest(c, info)
+	}
 	action := strings.ToUpper(c.Param("action"))
 
 	var sunoRequest *dto.SunoSubmitReq
@@ -159,6 +163,71 @@ func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http
 	return resp, nil
 }
 
+// validateMusicRequest 将统一音乐生成请求转换为 Suno 的歌曲生成请求
+func (a *TaskAdaptor) validateMusicRequest(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	var musicRequest dto.MusicRequest
+	if err := common.UnmarshalBodyReusable(c, &musicRequest); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	if strings.TrimSpace(musicRequest.Prompt) == "" && strings.TrimSpace(musicRequest.Lyrics) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("prompt or lyrics is required"), "invalid_request", http.StatusBadRequest)
+	}
+	sunoRequest := &dto.SunoSubmitReq{
+		Title:            musicRequest.Title,
+		Tags:             musicRequest.Style,
+		MakeInstrumental: musicRequest.Instrumental,
+	}
+	// 提供歌词时使用自定义模式，否则由 Suno 根据描述生成歌词
+	if musicRequest.Lyrics != "" {
+		sunoRequest.Prompt = musicRequest.Lyrics
+	} else {
+		sunoRequest.GptDescriptionPrompt = musicRequest.Prompt
+	}
+	if mv, ok := musicRequest.Metadata["mv"].(string); ok {
+		sunoRequest.Mv = mv
+	}
+	if err := actionValidate(c, sunoRequest, constant.SunoActionMusic); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	info.Action = constant.SunoActionMusic
+	c.Set("task_request", sunoRequest)
+	return nil
+}
+
+// SongCount Suno 每次生成两首歌曲
+func (a *TaskAdaptor) SongCount(*relaycommon.RelayInfo) int {
+	return 2
+}
+
+func (a *TaskAdaptor) ParseMusicSongs(taskData []byte) ([]dto.MusicSong, error) {
+	if len(taskData) == 0 {
+		return nil, nil
+	}
+	var sunoSongs []dto.SunoSong
+	if err := json.Unmarshal(taskData, &sunoSongs); err != nil {
+		return nil, err
+	}
+	songs := make([]dto.MusicSong, 0, len(sunoSongs))
+	for _, song := range sunoSongs {
+		if song.AudioURL == "" {
+			continue
+		}
+		musicSong := dto.MusicSong{
+			Id:       song.ID,
+			Title:    song.Title,
+			AudioUrl: song.AudioURL,
+			ImageUrl: song.ImageURL,
+			Lyrics:   song.Metadata.Prompt,
+			Style:    song.Metadata.Tags,
+		}
+		if duration, ok := song.Metadata.Duration.(float64); ok {
+			musicSong.Duration = duration
+		}
+		songs = append(songs, musicSong)
+	}
+	return songs, nil
+}
+
 func actionValidate(c *gin.Context, sunoRequest *dto.SunoSubmitReq, action string) (err error) {
 	switch action {
 	case constant.SunoActionMusic:
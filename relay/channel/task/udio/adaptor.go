@@ -0,0 +1,273 @@
+package udio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+)
+
+// ============================
+// Request / Response structures
+// ============================
+
+// 兼容 PiAPI 等 Udio 代理的统一任务接口
+type requestPayload struct {
+	Model    string       `json:"model"`
+	TaskType string       `json:"task_type"`
+	Input    requestInput `json:"input"`
+}
+
+type requestInput struct {
+	GptDescriptionPrompt string `json:"gpt_description_prompt,omitempty"`
+	Lyrics               string `json:"lyrics,omitempty"`
+	LyricsType           string `json:"lyrics_type"` // generate, user, instrumental
+	Tags                 string `json:"tags,omitempty"`
+	Title                string `json:"title,omitempty"`
+	Duration             int    `json:"duration,omitempty"`
+	NegativeTags         string `json:"negative_tags,omitempty"`
+	Seed                 int    `json:"seed,omitempty"`
+}
+
+type song struct {
+	Id        string   `json:"id"`
+	Title     string   `json:"title"`
+	SongPath  string   `json:"song_path"`
+	ImagePath string   `json:"image_path"`
+	Lyrics    string   `json:"lyrics"`
+	Duration  float64  `json:"duration"`
+	Tags      []string `json:"tags"`
+}
+
+type taskResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		TaskId string `json:"task_id"`
+		Status string `json:"status"`
+		Output struct {
+			Songs []song `json:"songs"`
+		} `json:"output"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"data"`
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	var req dto.MusicRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	if strings.TrimSpace(req.Prompt) == "" && strings.TrimSpace(req.Lyrics) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("prompt or lyrics is required"), "invalid_request", http.StatusBadRequest)
+	}
+	info.Action = constant.TaskActionMusicGenerate
+	c.Set("task_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, exists := c.Get("task_request")
+	if !exists {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(dto.MusicRequest)
+
+	body, err := a.convertToRequestPayload(&req, info)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(_ *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/api/v1/task", a.baseURL), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-api-key", info.ApiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+
+	var tResp taskResponse
+	if err = json.Unmarshal(responseBody, &tResp); err != nil {
+		taskErr = service.TaskErrorWrapper(errors.Wrap(err, string(responseBody)), "unmarshal_response_failed", http.StatusInternalServerError)
+		return
+	}
+	if tResp.Data.TaskId == "" || tResp.Data.Status == "failed" {
+		message := tResp.Data.Error.Message
+		if message == "" {
+			message = tResp.Message
+		}
+		taskErr = service.TaskErrorWrapperLocal(fmt.Errorf("task failed: %s", message), "task_failed", http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": tResp.Data.TaskId})
+	return tResp.Data.TaskId, nil, nil
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http.Response, error) {
+	taskID, ok := body["task_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/task/%s", baseUrl, taskID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-api-key", key)
+	return service.GetHttpClient().Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{"music-u"}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "udio"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var tResp taskResponse
+	if err := json.Unmarshal(respBody, &tResp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+
+	taskInfo := &relaycommon.TaskInfo{TaskID: tResp.Data.TaskId}
+	switch tResp.Data.Status {
+	case "pending", "staged":
+		taskInfo.Status = model.TaskStatusQueued
+	case "processing":
+		taskInfo.Status = model.TaskStatusInProgress
+	case "completed":
+		taskInfo.Status = model.TaskStatusSuccess
+		// 结果保存在任务数据中，这里记录第一首歌曲的地址
+		if len(tResp.Data.Output.Songs) > 0 {
+			taskInfo.Url = tResp.Data.Output.Songs[0].SongPath
+		}
+	case "failed":
+		taskInfo.Status = model.TaskStatusFailure
+		taskInfo.Reason = tResp.Data.Error.Message
+	default:
+		return nil, fmt.Errorf("unknown task status: %s", tResp.Data.Status)
+	}
+	return taskInfo, nil
+}
+
+// SongCount Udio 每次生成两首歌曲
+func (a *TaskAdaptor) SongCount(*relaycommon.RelayInfo) int {
+	return 2
+}
+
+func (a *TaskAdaptor) ParseMusicSongs(taskData []byte) ([]dto.MusicSong, error) {
+	if len(taskData) == 0 {
+		return nil, nil
+	}
+	var tResp taskResponse
+	if err := json.Unmarshal(taskData, &tResp); err != nil {
+		return nil, err
+	}
+	songs := make([]dto.MusicSong, 0, len(tResp.Data.Output.Songs))
+	for _, s := range tResp.Data.Output.Songs {
+		if s.SongPath == "" {
+			continue
+		}
+		songs = append(songs, dto.MusicSong{
+			Id:       s.Id,
+			Title:    s.Title,
+			AudioUrl: s.SongPath,
+			ImageUrl: s.ImagePath,
+			Lyrics:   s.Lyrics,
+			Style:    strings.Join(s.Tags, ", "),
+			Duration: s.Duration,
+		})
+	}
+	return songs, nil
+}
+
+// ============================
+// helpers
+// ============================
+
+func (a *TaskAdaptor) convertToRequestPayload(req *dto.MusicRequest, info *relaycommon.RelayInfo) (*requestPayload, error) {
+	r := requestPayload{
+		Model:    defaultString(info.UpstreamModelName, "music-u"),
+		TaskType: "generate_music",
+		Input: requestInput{
+			GptDescriptionPrompt: req.Prompt,
+			LyricsType:           "generate",
+			Tags:                 req.Style,
+			Title:                req.Title,
+			Duration:             req.Duration,
+		},
+	}
+	if req.Instrumental {
+		r.Input.LyricsType = "instrumental"
+	} else if req.Lyrics != "" {
+		r.Input.LyricsType = "user"
+		r.Input.Lyrics = req.Lyrics
+	}
+	metaBytes, err := json.Marshal(req.Metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "metadata marshal metadata failed")
+	}
+	if err = json.Unmarshal(metaBytes, &r.Input); err != nil {
+		return nil, errors.Wrap(err, "unmarshal metadata failed")
+	}
+	return &r, nil
+}
+
+func defaultString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,129 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImageEditFile 图片编辑请求中上传的文件
+type ImageEditFile struct {
+	Filename string
+	Data     []byte
+}
+
+// GetImageEditFiles 读取图片编辑请求中的原图与遮罩，原图兼容 image、image[] 字段，未上传遮罩时 mask 为 nil
+func GetImageEditFiles(c *gin.Context) (images []ImageEditFile, mask *ImageEditFile, err error) {
+	mf := c.Request.MultipartForm
+	if mf == nil {
+		if _, err = c.MultipartForm(); err != nil {
+			return nil, nil, errors.New("failed to parse multipart form")
+		}
+		mf = c.Request.MultipartForm
+	}
+	var imageFiles []*multipart.FileHeader
+	for fieldName, files := range mf.File {
+		if fieldName == "image" || strings.HasPrefix(fieldName, "image[") {
+			imageFiles = append(imageFiles, files...)
+		}
+	}
+	if len(imageFiles) == 0 {
+		return nil, nil, errors.New("image is required")
+	}
+	for _, fileHeader := range imageFiles {
+		file, err := readFormFile(fileHeader)
+		if err != nil {
+			return nil, nil, err
+		}
+		images = append(images, *file)
+	}
+	if maskFiles := mf.File["mask"]; len(maskFiles) > 0 {
+		if mask, err = readFormFile(maskFiles[0]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return images, mask, nil
+}
+
+func readFormFile(fileHeader *multipart.FileHeader) (*ImageEditFile, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", fileHeader.Filename, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", fileHeader.Filename, err)
+	}
+	return &ImageEditFile{Filename: fileHeader.Filename, Data: data}, nil
+}
+
+// ParseImageSize 解析 1024x1024 形式的图片尺寸
+func ParseImageSize(size string) (width int, height int, ok bool) {
+	w, h, found := strings.Cut(strings.ToLower(size), "x")
+	if !found {
+		return 0, 0, false
+	}
+	width, err := strconv.Atoi(strings.TrimSpace(w))
+	if err != nil || width <= 0 {
+		return 0, 0, false
+	}
+	height, err = strconv.Atoi(strings.TrimSpace(h))
+	if err != nil || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// ClosestAspectRatio 从候选宽高比（如 16:9）中选出与图片尺寸最接近的一个，size 本身为宽高比时原样返回
+func ClosestAspectRatio(size string, candidates []string) string {
+	if strings.Contains(size, ":") {
+		return size
+	}
+	width, height, ok := ParseImageSize(size)
+	if !ok || len(candidates) == 0 {
+		return ""
+	}
+	target := float64(width) / float64(height)
+	best, bestDiff := "", 0.0
+	for _, candidate := range candidates {
+		w, h, found := strings.Cut(candidate, ":")
+		if !found {
+			continue
+		}
+		cw, err1 := strconv.ParseFloat(w, 64)
+		ch, err2 := strconv.ParseFloat(h, 64)
+		if err1 != nil || err2 != nil || ch == 0 {
+			continue
+		}
+		diff := cw/ch - target
+		if diff < 0 {
+			diff = -diff
+		}
+		if best == "" || diff < bestDiff {
+			best, bestDiff = candidate, diff
+		}
+	}
+	return best
+}
+
+// ImageExtraFields 解析 extra_fields 并去掉影响计费的字段，计费依据请求中的 model、size 与 n，不能被 extra_fields 覆盖
+func ImageExtraFields(extraFields json.RawMessage, pricingKeys ...string) (map[string]any, error) {
+	extra := make(map[string]any)
+	if len(extraFields) == 0 {
+		return extra, nil
+	}
+	if err := json.Unmarshal(extraFields, &extra); err != nil {
+		return nil, err
+	}
+	for _, key := range pricingKeys {
+		delete(extra, key)
+	}
+	return extra, nil
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClosestAspectRatio(t *testing.T) {
+	candidates := []string{"1:1", "16:9", "9:16", "3:2"}
+	assert.Equal(t, "1:1", ClosestAspectRatio("1024x1024", candidates))
+	assert.Equal(t, "16:9", ClosestAspectRatio("1792x1024", candidates))
+	assert.Equal(t, "9:16", ClosestAspectRatio("1024x1792", candidates))
+	assert.Equal(t, "21:9", ClosestAspectRatio("21:9", candidates))
+	assert.Equal(t, "", ClosestAspectRatio("auto", candidates))
+}
+
+func TestImageExtraFields(t *testing.T) {
+	extra, err := ImageExtraFields([]byte(`{"width":1440,"height":1440,"seed":1}`), "width", "height")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"seed": float64(1)}, extra)
+
+	extra, err = ImageExtraFields(nil, "width")
+	require.NoError(t, err)
+	assert.Empty(t, extra)
+
+	_, err = ImageExtraFields([]byte(`[1]`))
+	assert.Error(t, err)
+}
@@ -509,6 +509,21 @@ func (t TaskSubmitReq) HasImage() bool {
 	return len(t.Images) > 0
 }
 
+// taskPricingMetadataKeys 按秒计费依据请求中的 model、duration 与 size，metadata 中的同类字段不能覆盖
+var taskPricingMetadataKeys = []string{"model", "duration", "resolution", "ratio"}
+
+// PayloadMetadata 返回可以合并到上游请求体的 metadata，去掉影响计费的字段
+func (t TaskSubmitReq) PayloadMetadata() map[string]interface{} {
+	metadata := make(map[string]interface{}, len(t.Metadata))
+	for k, v := range t.Metadata {
+		metadata[k] = v
+	}
+	for _, key := range taskPricingMetadataKeys {
+		delete(metadata, key)
+	}
+	return metadata
+}
+
 type TaskInfo struct {
 	Code     int    `json:"code"`
 	TaskID   string `json:"task_id"`
@@ -516,4 +531,6 @@ type TaskInfo struct {
 	Reason   string `json:"reason,omitempty"`
 	Url      string `json:"url,omitempty"`
 	Progress string `json:"progress,omitempty"`
+	// Duration 上游报告的实际生成时长（秒），未知时为 0
+	Duration float64 `json:"duration,omitempty"`
 }
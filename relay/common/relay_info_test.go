@@ -285,4 +285,20 @@ func TestStreamSupportedChannels(t *testing.T) {
 	// Test a channel that shouldn't exist
 	assert.False(t, streamSupportedChannels[99999],
 		"Non-existent channel type should not support stream options")
-}
\ No newline at end of file
+}
+
+func TestTaskSubmitReq_PayloadMetadata(t *testing.T) {
+	req := TaskSubmitReq{
+		Duration: 1,
+		Metadata: map[string]interface{}{
+			"duration":   10,
+			"resolution": "1080P",
+			"ratio":      "1280:720",
+			"model":      "gen4",
+			"seed":       42,
+		},
+	}
+	assert.Equal(t, map[string]interface{}{"seed": 42}, req.PayloadMetadata())
+	// 不修改原始请求
+	assert.Len(t, req.Metadata, 5)
+}
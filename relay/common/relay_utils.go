@@ -81,13 +81,19 @@ func ValidateBasicTaskRequest(c *gin.Context, info *RelayInfo, action string) *d
 
 	if req.HasImage() {
 		action = constant.TaskActionGenerate
-		if info.ChannelType == constant.ChannelTypeVidu {
+		switch info.ChannelType {
+		case constant.ChannelTypeVidu:
 			// vidu 增加 首尾帧生视频和参考图生视频
 			if len(req.Images) == 2 {
 				action = constant.TaskActionFirstTailGenerate
 			} else if len(req.Images) > 2 {
 				action = constant.TaskActionReferenceGenerate
 			}
+		case constant.ChannelTypeRunway, constant.ChannelTypeLuma, constant.ChannelTypeMiniMax:
+			// 两张图片依次作为首帧和尾帧
+			if len(req.Images) == 2 {
+				action = constant.TaskActionFirstTailGenerate
+			}
 		}
 	}
 
@@ -24,6 +24,7 @@ const (
 	RelayModeMidjourneyTaskFetch
 	RelayModeMidjourneyTaskImageSeed
 	RelayModeMidjourneyTaskFetchByCondition
+	RelayModeMidjourneyTaskCancel
 	RelayModeMidjourneyAction
 	RelayModeMidjourneyModal
 	RelayModeMidjourneyShorten
@@ -43,6 +44,8 @@ const (
 	RelayModeVideoFetchByID
 	RelayModeVideoSubmit
 
+	RelayModeMusicSubmit
+
 	RelayModeRerank
 
 	RelayModeResponses
@@ -129,6 +132,8 @@ func Path2RelayModeMidjourney(path string) int {
 		relayMode = RelayModeMidjourneyTaskImageSeed
 	} else if strings.HasSuffix(path, "/list-by-condition") {
 		relayMode = RelayModeMidjourneyTaskFetchByCondition
+	} else if strings.HasSuffix(path, "/cancel") {
+		relayMode = RelayModeMidjourneyTaskCancel
 	}
 	return relayMode
 }
@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"bytes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BufferedResponseWriter 暂存适配器写出的响应，便于在返回客户端前统一改写
+type BufferedResponseWriter struct {
+	gin.ResponseWriter
+	Body       *bytes.Buffer
+	StatusCode int
+}
+
+func NewBufferedResponseWriter(writer gin.ResponseWriter) *BufferedResponseWriter {
+	return &BufferedResponseWriter{ResponseWriter: writer, Body: &bytes.Buffer{}}
+}
+
+func (w *BufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.Body.Write(data)
+}
+
+func (w *BufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.Body.WriteString(s)
+}
+
+func (w *BufferedResponseWriter) WriteHeader(statusCode int) {
+	w.StatusCode = statusCode
+}
+
+func (w *BufferedResponseWriter) WriteHeaderNow() {
+}
@@ -3,9 +3,13 @@ package helper
 import (
 	"fmt"
 	"one-api/common"
+	"one-api/common/tracing"
+	"one-api/dto"
+	"one-api/model"
 	relaycommon "one-api/relay/common"
 	"one-api/setting/ratio_setting"
 	"one-api/types"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,6 +30,22 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.
 		relayInfo.UsingGroup = autoGroup.(string)
 	}
 
+	// 用户协议价的优先级最高：multiplier 替代分组倍率，ratio、price 在 ModelPriceHelper 中替代模型定价
+	if override := model.GetUserPriceOverride(relayInfo.UserId, relayInfo.OriginModelName); override != nil {
+		groupRatioInfo.PriceOverride = &types.PriceOverride{
+			Id:        override.Id,
+			ModelName: override.ModelName,
+			Type:      override.Type,
+			Value:     override.Value,
+		}
+		if override.Type == model.PriceOverrideTypeMultiplier {
+			groupRatioInfo.GroupRatio = override.Value
+		} else {
+			groupRatioInfo.GroupRatio = 1
+		}
+		return groupRatioInfo
+	}
+
 	// check user group special ratio
 	userGroupRatio, ok := ratio_setting.GetGroupGroupRatio(relayInfo.UserGroup, relayInfo.UsingGroup)
 	if ok {
@@ -41,10 +61,48 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.
 	return groupRatioInfo
 }
 
+// applyPricingSchedule 按请求开始时间匹配分时定价规则，将倍率计入分组倍率
+func applyPricingSchedule(relayInfo *relaycommon.RelayInfo, groupRatioInfo *types.GroupRatioInfo) {
+	groupRatioInfo.TimeMultiplier = 1
+	if isAbsolutePriceOverride(groupRatioInfo.PriceOverride) {
+		return
+	}
+	now := relayInfo.StartTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	multiplier, schedule := ratio_setting.GetPricingScheduleMultiplier(relayInfo.OriginModelName, relayInfo.UsingGroup, now)
+	if schedule == nil {
+		return
+	}
+	groupRatioInfo.TimeMultiplier = multiplier
+	groupRatioInfo.PricingSchedule = schedule.Name
+	groupRatioInfo.GroupRatio *= multiplier
+	if groupRatioInfo.HasSpecialRatio {
+		groupRatioInfo.GroupSpecialRatio *= multiplier
+	}
+}
+
+// isAbsolutePriceOverride 协议价直接给出模型倍率或按次价格时，不再叠加分组倍率与分时定价
+func isAbsolutePriceOverride(override *types.PriceOverride) bool {
+	return override != nil && override.Type != model.PriceOverrideTypeMultiplier
+}
+
 func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens int, meta *types.TokenCountMeta) (types.PriceData, error) {
+	_, span := tracing.Start(c.Request.Context(), "relay.price")
+	defer span.End()
 	modelPrice, usePrice := ratio_setting.GetModelPrice(info.OriginModelName, false)
 
 	groupRatioInfo := HandleGroupRatio(c, info)
+	applyPricingSchedule(info, &groupRatioInfo)
+	if override := groupRatioInfo.PriceOverride; override != nil {
+		switch override.Type {
+		case model.PriceOverrideTypePrice:
+			modelPrice, usePrice = override.Value, true
+		case model.PriceOverrideTypeRatio:
+			usePrice = false
+		}
+	}
 
 	var preConsumedQuota int
 	var modelRatio float64
@@ -54,6 +112,8 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 	var cacheCreationRatio float64
 	var audioRatio float64
 	var audioCompletionRatio float64
+	var pricingTier int
+	var pricingTierMaxTokens int
 	if !usePrice {
 		preConsumedTokens := common.Max(promptTokens, common.PreConsumedQuota)
 		if meta.MaxTokens != 0 {
@@ -62,7 +122,7 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		var success bool
 		var matchName string
 		modelRatio, success, matchName = ratio_setting.GetModelRatio(info.OriginModelName)
-		if !success {
+		if !success && !ratio_setting.HasTieredRatio(info.OriginModelName) && !isAbsolutePriceOverride(groupRatioInfo.PriceOverride) {
 			acceptUnsetRatio := false
 			if info.UserSetting.AcceptUnsetRatioModel {
 				acceptUnsetRatio = true
@@ -77,12 +137,33 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		imageRatio, _ = ratio_setting.GetImageRatio(info.OriginModelName)
 		audioRatio = ratio_setting.GetAudioRatio(info.OriginModelName)
 		audioCompletionRatio = ratio_setting.GetAudioCompletionRatio(info.OriginModelName)
+		if tiered, ok := ratio_setting.GetTieredRatios(info.OriginModelName, promptTokens); ok {
+			modelRatio = tiered.ModelRatio
+			completionRatio = tiered.CompletionRatio
+			cacheRatio = tiered.CacheRatio
+			cacheCreationRatio = tiered.CacheCreationRatio
+			pricingTier = tiered.Tier
+			pricingTierMaxTokens = tiered.MaxTokens
+		}
+		if override := groupRatioInfo.PriceOverride; override != nil && override.Type == model.PriceOverrideTypeRatio {
+			modelRatio = override.Value
+			pricingTier, pricingTierMaxTokens = 0, 0
+		}
 		ratio := modelRatio * groupRatioInfo.GroupRatio
 		preConsumedQuota = int(float64(preConsumedTokens) * ratio)
 	} else {
 		if meta.ImagePriceRatio != 0 {
 			modelPrice = modelPrice * meta.ImagePriceRatio
 		}
+		// 按次计费的图片模型按请求尺寸调整单价
+		if imageRequest, ok := info.Request.(*dto.ImageRequest); ok {
+			if sizeRatio, ok := ratio_setting.GetImageSizeRatio(info.OriginModelName, imageRequest.Size); ok {
+				modelPrice = modelPrice * sizeRatio
+			}
+			if qualityRatio, ok := ratio_setting.GetImageQualityRatio(info.OriginModelName, imageRequest.Quality); ok {
+				modelPrice = modelPrice * qualityRatio
+			}
+		}
 		preConsumedQuota = int(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio)
 	}
 
@@ -92,6 +173,8 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		CompletionRatio:        completionRatio,
 		GroupRatioInfo:         groupRatioInfo,
 		UsePrice:               usePrice,
+		PricingTier:            pricingTier,
+		PricingTierMaxTokens:   pricingTierMaxTokens,
 		CacheRatio:             cacheRatio,
 		ImageRatio:             imageRatio,
 		AudioRatio:             audioRatio,
@@ -110,6 +193,7 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 // ModelPriceHelperPerCall 按次计费的 PriceHelper (MJ、Task)
 func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) types.PerCallPriceData {
 	groupRatioInfo := HandleGroupRatio(c, info)
+	applyPricingSchedule(info, &groupRatioInfo)
 
 	modelPrice, success := ratio_setting.GetModelPrice(info.OriginModelName, true)
 	// 如果没有配置价格，则使用默认价格
@@ -121,6 +205,9 @@ func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) types.
 			modelPrice = defaultPrice
 		}
 	}
+	if override := groupRatioInfo.PriceOverride; override != nil && override.Type == model.PriceOverrideTypePrice {
+		modelPrice = override.Value
+	}
 	quota := int(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio)
 	priceData := types.PerCallPriceData{
 		ModelPrice:     modelPrice,
@@ -130,6 +217,13 @@ func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) types.
 	return priceData
 }
 
+// HandleTaskGroupRatio 异步任务按提交时的定价计费，与按次计费一样叠加用户协议价与分时定价
+func HandleTaskGroupRatio(c *gin.Context, info *relaycommon.RelayInfo) types.GroupRatioInfo {
+	groupRatioInfo := HandleGroupRatio(c, info)
+	applyPricingSchedule(info, &groupRatioInfo)
+	return groupRatioInfo
+}
+
 func ContainPriceOrRatio(modelName string) bool {
 	_, ok := ratio_setting.GetModelPrice(modelName, false)
 	if ok {
@@ -139,5 +233,5 @@ func ContainPriceOrRatio(modelName string) bool {
 	if ok {
 		return true
 	}
-	return false
+	return ratio_setting.HasTieredRatio(modelName)
 }
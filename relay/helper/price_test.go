@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/ratio_setting"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// setupPriceOverrides 使用内存 SQLite 加载协议价缓存，测试结束后清空缓存并恢复全局 DB
+func setupPriceOverrides(t *testing.T, overrides ...*model.UserPriceOverride) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.UserPriceOverride{}))
+	originDB, usingSQLite := model.DB, common.UsingSQLite
+	model.DB, common.UsingSQLite = db, true
+	t.Cleanup(func() {
+		require.NoError(t, db.Where("1 = 1").Delete(&model.UserPriceOverride{}).Error)
+		model.InitUserPriceOverrideCache()
+		model.DB, common.UsingSQLite = originDB, usingSQLite
+		sqlDB.Close()
+	})
+	for _, override := range overrides {
+		require.NoError(t, db.Create(override).Error)
+	}
+	model.InitUserPriceOverrideCache()
+}
+
+func TestHandleTaskGroupRatio(t *testing.T) {
+	setupPriceOverrides(t,
+		&model.UserPriceOverride{UserId: 1, ModelName: "kling-*", Type: model.PriceOverrideTypeMultiplier, Value: 0.8},
+		&model.UserPriceOverride{UserId: 1, ModelName: "suno_music", Type: model.PriceOverrideTypePrice, Value: 0.05},
+	)
+	require.NoError(t, ratio_setting.UpdatePricingScheduleByJSONString(`[
+		{"name":"video-off-peak","timezone":"UTC","start":"00:00","end":"08:00","models":["kling-*","vidu-*"],"multiplier":0.5}
+	]`))
+	t.Cleanup(func() {
+		ratio_setting.UpdatePricingScheduleByJSONString("[]")
+	})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	newInfo := func(userId int, modelName string) *relaycommon.RelayInfo {
+		return &relaycommon.RelayInfo{
+			UserId:          userId,
+			UserGroup:       "default",
+			UsingGroup:      "default",
+			OriginModelName: modelName,
+			StartTime:       time.Date(2025, 3, 3, 1, 0, 0, 0, time.UTC),
+		}
+	}
+
+	// 协议价的倍率与分时定价叠加，并记录到分组倍率信息中
+	groupRatioInfo := HandleTaskGroupRatio(c, newInfo(1, "kling-v1"))
+	require.NotNil(t, groupRatioInfo.PriceOverride)
+	assert.InDelta(t, 0.4, groupRatioInfo.GroupRatio, 1e-9)
+	assert.Equal(t, "video-off-peak", groupRatioInfo.PricingSchedule)
+	assert.Equal(t, 0.5, groupRatioInfo.TimeMultiplier)
+
+	// 按次协议价不叠加分组倍率与分时定价
+	groupRatioInfo = HandleTaskGroupRatio(c, newInfo(1, "suno_music"))
+	require.NotNil(t, groupRatioInfo.PriceOverride)
+	assert.Equal(t, model.PriceOverrideTypePrice, groupRatioInfo.PriceOverride.Type)
+	assert.Equal(t, 1.0, groupRatioInfo.GroupRatio)
+	assert.Empty(t, groupRatioInfo.PricingSchedule)
+
+	// 没有协议价的用户只按分组倍率与分时定价计费
+	groupRatioInfo = HandleTaskGroupRatio(c, newInfo(2, "vidu-q1"))
+	assert.Nil(t, groupRatioInfo.PriceOverride)
+	assert.InDelta(t, ratio_setting.GetGroupRatio("default")*0.5, groupRatioInfo.GroupRatio, 1e-9)
+}
@@ -3,10 +3,13 @@ package helper
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/logger"
 	relaycommon "one-api/relay/common"
@@ -241,6 +244,9 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		return
 	}
 
+	_, span := tracing.Start(c.Request.Context(), "relay.stream")
+	defer span.End()
+
 	// 确保Worker Manager已启动
 	globalStreamManager.ensureStarted()
 
@@ -437,6 +443,8 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		if err := scanner.Err(); err != nil {
 			if err != io.EOF {
 				logger.LogError(c, "scanner error: "+err.Error())
+				metrics.RecordStreamDisconnect(info.ChannelType, "upstream_error")
+				tracing.RecordError(span, err)
 			}
 		}
 	})
@@ -446,11 +454,15 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	case <-ticker.C:
 		// 超时处理逻辑
 		logger.LogError(c, "streaming timeout")
+		metrics.RecordStreamDisconnect(info.ChannelType, "timeout")
+		tracing.RecordError(span, errors.New("streaming timeout"))
 	case <-stopChan:
 		// 正常结束
 		logger.LogInfo(c, "streaming finished")
 	case <-c.Request.Context().Done():
 		// 客户端断开连接
 		logger.LogInfo(c, "client disconnected")
+		metrics.RecordStreamDisconnect(info.ChannelType, "client_disconnected")
+		span.AddEvent("client_disconnected")
 	}
 }
\ No newline at end of file
@@ -12,10 +12,14 @@ import (
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting/model_setting"
+	"one-api/setting/operation_setting"
 	"one-api/types"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types.NewAPIError) {
@@ -98,7 +102,27 @@ func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 		}
 	}
 
+	var imageWriter *helper.BufferedResponseWriter
+	if !info.IsStream && service.MediaStorageEnabled() && operation_setting.GetMediaStorageSetting().StoreImageResults {
+		imageWriter = helper.NewBufferedResponseWriter(c.Writer)
+		c.Writer = imageWriter
+	}
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	if imageWriter != nil {
+		c.Writer = imageWriter.ResponseWriter
+		body := imageWriter.Body.Bytes()
+		if newAPIError == nil {
+			body = storeImageResponseMedia(c, info, body)
+		}
+		if len(body) > 0 || imageWriter.StatusCode != 0 {
+			if imageWriter.StatusCode == 0 {
+				imageWriter.StatusCode = http.StatusOK
+			}
+			c.Writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			c.Writer.WriteHeader(imageWriter.StatusCode)
+			_, _ = c.Writer.Write(body)
+		}
+	}
 	if newAPIError != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
@@ -126,3 +150,24 @@ func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 	postConsumeQuota(c, info, usage.(*dto.Usage), logContent)
 	return nil
 }
+
+// storeImageResponseMedia 转存响应中 data[].url 指向的图片，并替换为网关签名地址，转存失败时保留原地址
+func storeImageResponseMedia(c *gin.Context, info *relaycommon.RelayInfo, body []byte) []byte {
+	requestId := c.GetString(common.RequestIdKey)
+	gjson.GetBytes(body, "data").ForEach(func(index, item gjson.Result) bool {
+		imageUrl := item.Get("url").String()
+		if !strings.HasPrefix(imageUrl, "http") {
+			return true
+		}
+		media, err := service.StoreMediaFromUrl(info.UserId, service.MediaSourceImage, requestId, imageUrl)
+		if err != nil {
+			logger.LogError(c, fmt.Sprintf("store image media failed: %s", err.Error()))
+			return true
+		}
+		if newBody, err := sjson.SetBytes(body, fmt.Sprintf("data.%d.url", index.Int()), service.SignMediaUrl(media.Key)); err == nil {
+			body = newBody
+		}
+		return true
+	})
+	return body
+}
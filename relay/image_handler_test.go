@@ -0,0 +1,77 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay/channel/bfl"
+	"one-api/relay/channel/ideogram"
+	"one-api/relay/channel/stability"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"one-api/setting/ratio_setting"
+	"one-api/setting/system_setting"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestStoreImageResponseMedia(t *testing.T) {
+	setupSQLiteTestDB(t, &model.Media{})
+	mediaSetting, fetchSetting := operation_setting.GetMediaStorageSetting(), system_setting.GetFetchSetting()
+	originMedia, originFetch, isMasterNode := *mediaSetting, *fetchSetting, common.IsMasterNode
+	t.Cleanup(func() {
+		*mediaSetting, *fetchSetting, common.IsMasterNode = originMedia, originFetch, isMasterNode
+	})
+	mediaSetting.Enabled = true
+	mediaSetting.Backend = operation_setting.MediaStorageBackendLocal
+	mediaSetting.LocalDir = t.TempDir()
+	mediaSetting.UserStorageMB = 0
+	common.IsMasterNode = true
+	// 测试服务监听在本机地址上
+	fetchSetting.EnableSSRFProtection = false
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/image.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\nimage"))
+	}))
+	defer upstream.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(common.RequestIdKey, "req_1")
+	info := &relaycommon.RelayInfo{UserId: 1}
+	body := []byte(fmt.Sprintf(`{"created":1,"data":[{"url":"%s/image.png"},{"url":"%s/missing.png"},{"b64_json":"aW1hZ2U="}]}`, upstream.URL, upstream.URL))
+	body = storeImageResponseMedia(c, info, body)
+
+	media := model.GetMediaBySource(service.MediaSourceImage, "req_1")
+	require.NotNil(t, media)
+	assert.Equal(t, 1, media.UserId)
+	storedUrl := gjson.GetBytes(body, "data.0.url").String()
+	assert.True(t, strings.HasPrefix(storedUrl, system_setting.ServerAddress+"/media/"+media.Key+"?"), storedUrl)
+	// 转存失败时保留上游地址，其余字段不变
+	assert.Equal(t, upstream.URL+"/missing.png", gjson.GetBytes(body, "data.1.url").String())
+	assert.Equal(t, "aW1hZ2U=", gjson.GetBytes(body, "data.2.b64_json").String())
+	assert.Equal(t, int64(1), gjson.GetBytes(body, "created").Int())
+}
+
+func TestImageModelsHaveDefaultPrice(t *testing.T) {
+	ratio_setting.InitRatioSettings()
+	// 按次计费的图片渠道未配置价格时会被拒绝，内置模型都需要默认价格
+	for _, models := range [][]string{bfl.ModelList, stability.ModelList, ideogram.ModelList} {
+		for _, modelName := range models {
+			_, ok := ratio_setting.GetModelPrice(modelName, false)
+			assert.True(t, ok, modelName)
+		}
+	}
+}
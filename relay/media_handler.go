@@ -0,0 +1,25 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/logger"
+	"one-api/model"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeMedia 将转存的生成结果写入响应
+func ServeMedia(c *gin.Context, media *model.Media) {
+	reader, err := service.OpenMedia(media)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("open media %s failed: %s", media.Key, err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "media_not_found",
+		})
+		return
+	}
+	defer reader.Close()
+	c.DataFromReader(http.StatusOK, media.Size, media.ContentType, reader, nil)
+}
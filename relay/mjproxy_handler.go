@@ -10,6 +10,7 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/dto"
+	"one-api/logger"
 	"one-api/model"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
@@ -21,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 )
 
@@ -33,6 +35,12 @@ func RelayMidjourneyImage(c *gin.Context) {
 		})
 		return
 	}
+	if service.MediaStorageEnabled() {
+		if media := model.GetMediaBySource(service.MediaSourceMidjourney, midjourneyTask.MjId); media != nil {
+			ServeMedia(c, media)
+			return
+		}
+	}
 	var httpClient *http.Client
 	if channel, err := model.CacheGetChannel(midjourneyTask.ChannelId); err == nil {
 		proxy := channel.GetSetting().Proxy
@@ -109,6 +117,7 @@ func RelayMidjourneyNotify(c *gin.Context) *dto.MidjourneyResponse {
 	midjourneyTask.VideoUrl = midjRequest.VideoUrl
 	videoUrlsStr, _ := json.Marshal(midjRequest.VideoUrls)
 	midjourneyTask.VideoUrls = string(videoUrlsStr)
+	previousStatus := midjourneyTask.Status
 	midjourneyTask.Status = midjRequest.Status
 	midjourneyTask.FailReason = midjRequest.FailReason
 	err = midjourneyTask.Update()
@@ -118,10 +127,31 @@ func RelayMidjourneyNotify(c *gin.Context) *dto.MidjourneyResponse {
 			Description: "update_midjourney_task_failed",
 		}
 	}
+	if previousStatus != midjourneyTask.Status {
+		NotifyMidjourneyFinished(midjourneyTask)
+	}
 
 	return nil
 }
 
+// NotifyMidjourneyFinished Midjourney 任务进入终态（成功或失败）时向提交时指定的 callback_url 推送结果
+func NotifyMidjourneyFinished(task *model.Midjourney) {
+	if task.Status != "SUCCESS" && task.Status != "FAILURE" {
+		return
+	}
+	if service.MediaStorageEnabled() && task.Status == "SUCCESS" && task.ImageUrl != "" {
+		// 转存后 /mj/image 直接由网关存储提供，上游地址过期后仍可访问
+		gopool.Go(func() {
+			if _, err := service.StoreMediaFromUrl(task.UserId, service.MediaSourceMidjourney, task.MjId, task.ImageUrl); err != nil {
+				common.SysError(fmt.Sprintf("failed to store media for midjourney task %s: %s", task.MjId, err.Error()))
+			}
+			service.NotifyTaskCallback(task.UserId, common.TaskCallbackTypeMidjourney, task.MjId, task.Status, task.CallbackUrl, coverMidjourneyTaskDto(nil, task))
+		})
+		return
+	}
+	service.NotifyTaskCallback(task.UserId, common.TaskCallbackTypeMidjourney, task.MjId, task.Status, task.CallbackUrl, coverMidjourneyTaskDto(nil, task))
+}
+
 func coverMidjourneyTaskDto(c *gin.Context, originTask *model.Midjourney) (midjourneyTask dto.MidjourneyDto) {
 	midjourneyTask.MjId = originTask.MjId
 	midjourneyTask.Progress = originTask.Progress
@@ -168,6 +198,9 @@ func coverMidjourneyTaskDto(c *gin.Context, originTask *model.Midjourney) (midjo
 			midjourneyTask.Properties = &properties
 		}
 	}
+	if originTask.Status == model.TaskStatusQueued && originTask.IsGatewayQueued() {
+		midjourneyTask.QueuePosition = model.GetTaskQueuePosition(model.TaskQueueKindMidjourney, int64(originTask.Id))
+	}
 	return
 }
 
@@ -177,6 +210,10 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 	if err != nil {
 		return service.MidjourneyErrorWrapper(constant.MjRequestError, "bind_request_body_failed")
 	}
+	callbackUrl, err := service.GetTaskCallbackUrl(c)
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "invalid_callback_url")
+	}
 
 	info.InitChannelMeta(c)
 
@@ -195,7 +232,8 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 		}
 	}
 
-	if userQuota-priceData.Quota < 0 {
+	creditLimit := common.GetContextKeyInt(c, constant.ContextKeyUserCreditLimit)
+	if userQuota+creditLimit-priceData.Quota < 0 {
 		return &dto.MidjourneyResponse{
 			Code:        4,
 			Description: "quota_not_enough",
@@ -251,6 +289,8 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 		FailReason:  "",
 		ChannelId:   c.GetInt("channel_id"),
 		Quota:       priceData.Quota,
+		CallbackUrl: callbackUrl,
+		TokenId:     info.TokenId,
 	}
 	err = midjourneyTask.Insert()
 	if err != nil {
@@ -268,6 +308,21 @@ func RelaySwapFace(c *gin.Context, info *relaycommon.RelayInfo) *dto.MidjourneyR
 	return nil
 }
 
+// useMjTaskChannel 将请求转发到任务所属的渠道，查询类请求不经过渠道分发，需要在这里设置渠道密钥
+func useMjTaskChannel(c *gin.Context, task *model.Midjourney) (*model.Channel, *dto.MidjourneyResponse) {
+	channel, err := model.GetChannelById(task.ChannelId, true)
+	if err != nil {
+		return nil, service.MidjourneyErrorWrapper(constant.MjRequestError, "get_channel_info_failed")
+	}
+	if channel.Status != common.ChannelStatusEnabled {
+		return nil, service.MidjourneyErrorWrapper(constant.MjRequestError, "该任务所属渠道已被禁用")
+	}
+	c.Set("channel_id", task.ChannelId)
+	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+	common.SetContextKey(c, constant.ContextKeyChannelKey, channel.Key)
+	return channel, nil
+}
+
 func RelayMidjourneyTaskImageSeed(c *gin.Context) *dto.MidjourneyResponse {
 	taskId := c.Param("id")
 	userId := c.GetInt("id")
@@ -275,23 +330,37 @@ func RelayMidjourneyTaskImageSeed(c *gin.Context) *dto.MidjourneyResponse {
 	if originTask == nil {
 		return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_no_found")
 	}
-	channel, err := model.GetChannelById(originTask.ChannelId, true)
-	if err != nil {
-		return service.MidjourneyErrorWrapper(constant.MjRequestError, "get_channel_info_failed")
+	if originTask.Status != "SUCCESS" {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_status_not_success")
 	}
-	if channel.Status != common.ChannelStatusEnabled {
-		return service.MidjourneyErrorWrapper(constant.MjRequestError, "该任务所属渠道已被禁用")
+	// 种子在任务完成后不会变化，获取过一次后直接返回
+	if originTask.Seed != "" {
+		c.JSON(http.StatusOK, dto.MidjourneyResponse{
+			Code:        1,
+			Description: "成功",
+			Result:      originTask.Seed,
+		})
+		return nil
+	}
+	channel, mjErr := useMjTaskChannel(c, originTask)
+	if mjErr != nil {
+		return mjErr
 	}
-	c.Set("channel_id", originTask.ChannelId)
-	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
 
-	requestURL := getMjRequestPath(c.Request.URL.String())
+	// 使用排队时返回的 ID 查询时，转发上游需要换成上游任务 ID
+	requestURL := strings.Replace(getMjRequestPath(c.Request.URL.String()), taskId, originTask.MjId, 1)
 	fullRequestURL := fmt.Sprintf("%s%s", channel.GetBaseURL(), requestURL)
 	midjResponseWithStatus, _, err := service.DoMidjourneyHttpRequest(c, time.Second*30, fullRequestURL)
 	if err != nil {
 		return &midjResponseWithStatus.Response
 	}
 	midjResponse := &midjResponseWithStatus.Response
+	if midjResponse.Code == 1 && midjResponse.Result != "" {
+		originTask.Seed = midjResponse.Result
+		if err = originTask.Update(); err != nil {
+			common.SysError(fmt.Sprintf("failed to save seed of midjourney task %s: %s", originTask.MjId, err.Error()))
+		}
+	}
 	c.Writer.WriteHeader(midjResponseWithStatus.StatusCode)
 	respBody, err := json.Marshal(midjResponse)
 	if err != nil {
@@ -301,6 +370,88 @@ func RelayMidjourneyTaskImageSeed(c *gin.Context) *dto.MidjourneyResponse {
 	return nil
 }
 
+// RelayMidjourneyTaskCancel 取消排队中或执行中的任务，上游取消成功后退还额度
+func RelayMidjourneyTaskCancel(c *gin.Context) *dto.MidjourneyResponse {
+	taskId := c.Param("id")
+	userId := c.GetInt("id")
+	originTask := model.GetByMJId(userId, taskId)
+	if originTask == nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_no_found")
+	}
+	if originTask.Progress == "100%" || originTask.Status == "SUCCESS" || originTask.Status == "FAILURE" {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_already_finished")
+	}
+	if originTask.IsGatewayQueued() {
+		return cancelGatewayQueuedMjTask(c, originTask)
+	}
+	channel, mjErr := useMjTaskChannel(c, originTask)
+	if mjErr != nil {
+		return mjErr
+	}
+
+	// 取消接口不需要请求体，统一转发空 JSON 对象
+	c.Request.Body = io.NopCloser(strings.NewReader("{}"))
+	c.Request.Header.Set("Content-Type", "application/json")
+	// 使用排队时返回的 ID 查询时，转发上游需要换成上游任务 ID
+	requestURL := strings.Replace(getMjRequestPath(c.Request.URL.String()), taskId, originTask.MjId, 1)
+	fullRequestURL := fmt.Sprintf("%s%s", channel.GetBaseURL(), requestURL)
+	midjResponseWithStatus, _, err := service.DoMidjourneyHttpRequest(c, time.Second*30, fullRequestURL)
+	if err != nil {
+		return &midjResponseWithStatus.Response
+	}
+	midjResponse := &midjResponseWithStatus.Response
+	if midjResponseWithStatus.StatusCode != http.StatusOK || midjResponse.Code != 1 {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "cancel_task_failed: "+midjResponse.Description)
+	}
+
+	cancelled, err := model.CancelMjTask(originTask.Id, "任务已取消")
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "update_midjourney_task_failed")
+	}
+	// 轮询可能已先一步将任务更新为结束状态，此时由轮询负责退款
+	if cancelled {
+		if originTask.Quota != 0 {
+			if err = model.IncreaseUserQuota(originTask.UserId, originTask.Quota, false); err != nil {
+				common.SysError("fail to increase user quota: " + err.Error())
+			}
+			logContent := fmt.Sprintf("任务取消 %s，补偿 %s", originTask.MjId, logger.LogQuota(originTask.Quota))
+			model.RecordLog(originTask.UserId, model.LogTypeSystem, logContent)
+		}
+		if task := model.GetByMJId(userId, taskId); task != nil {
+			NotifyMidjourneyFinished(task)
+		}
+	}
+	c.JSON(http.StatusOK, midjResponse)
+	return nil
+}
+
+// cancelGatewayQueuedMjTask 网关排队中的任务尚未提交上游，也未扣费，直接移出队列
+func cancelGatewayQueuedMjTask(c *gin.Context, originTask *model.Midjourney) *dto.MidjourneyResponse {
+	removed, err := model.DeleteTaskQueueItemByRecord(model.TaskQueueKindMidjourney, int64(originTask.Id))
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "update_midjourney_task_failed")
+	}
+	if !removed {
+		// 已被调度提交上游，稍后可按上游任务取消
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_is_submitting")
+	}
+	cancelled, err := model.CancelMjTask(originTask.Id, "任务已取消")
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "update_midjourney_task_failed")
+	}
+	if cancelled {
+		if task := model.GetMjByuId(originTask.Id); task != nil {
+			NotifyMidjourneyFinished(task)
+		}
+	}
+	c.JSON(http.StatusOK, dto.MidjourneyResponse{
+		Code:        1,
+		Description: "成功",
+		Result:      originTask.MjId,
+	})
+	return nil
+}
+
 func RelayMidjourneyTask(c *gin.Context, relayMode int) *dto.MidjourneyResponse {
 	userId := c.GetInt("id")
 	var err error
@@ -325,7 +476,11 @@ func RelayMidjourneyTask(c *gin.Context, relayMode int) *dto.MidjourneyResponse
 		}
 	case relayconstant.RelayModeMidjourneyTaskFetchByCondition:
 		var condition = struct {
-			IDs []string `json:"ids"`
+			IDs    []string `json:"ids"`
+			Status string   `json:"status"`
+			Action string   `json:"action"`
+			Page   int      `json:"page"`
+			Size   int      `json:"size"`
 		}{}
 		err = c.BindJSON(&condition)
 		if err != nil {
@@ -335,8 +490,21 @@ func RelayMidjourneyTask(c *gin.Context, relayMode int) *dto.MidjourneyResponse
 			}
 		}
 		var tasks []dto.MidjourneyDto
-		if len(condition.IDs) != 0 {
-			originTasks := model.GetByMJIds(userId, condition.IDs)
+		// 兼容只按 ids 查询的原有用法，未指定任何条件时返回空列表
+		if len(condition.IDs) != 0 || condition.Status != "" || condition.Action != "" || condition.Page > 0 {
+			if condition.Page < 1 {
+				condition.Page = 1
+			}
+			if condition.Size < 1 || condition.Size > 100 {
+				condition.Size = 20
+			}
+			originTasks := model.GetUserMjTasksByCondition(userId, model.MjTaskCondition{
+				Ids:    condition.IDs,
+				Status: condition.Status,
+				Action: condition.Action,
+				Offset: (condition.Page - 1) * condition.Size,
+				Limit:  condition.Size,
+			})
 			for _, originTask := range originTasks {
 				midjourneyTask := coverMidjourneyTaskDto(c, originTask)
 				tasks = append(tasks, midjourneyTask)
@@ -373,6 +541,10 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 	if err != nil {
 		return service.MidjourneyErrorWrapper(constant.MjRequestError, "bind_request_body_failed")
 	}
+	callbackUrl, err := service.GetTaskCallbackUrl(c)
+	if err != nil {
+		return service.MidjourneyErrorWrapper(constant.MjRequestError, "invalid_callback_url")
+	}
 
 	relayInfo.InitChannelMeta(c)
 
@@ -443,7 +615,15 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 		originTask := model.GetByMJId(relayInfo.UserId, mjId)
 		if originTask == nil {
 			return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_not_found")
+		} else if originTask.IsGatewayQueued() {
+			return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_status_not_success")
 		} else { //原任务的Status=SUCCESS，则可以做放大UPSCALE、变换VARIATION等动作，此时必须使用原来的请求地址才能正确处理
+			if originTask.MjId != mjId {
+				// 使用排队时返回的 ID 引用任务，转发上游前替换为上游任务 ID
+				if err := replaceMjRequestTaskId(c, mjId, originTask.MjId); err != nil {
+					return service.MidjourneyErrorWrapper(constant.MjRequestError, "read_request_body_failed")
+				}
+			}
 			if setting.MjActionCheckSuccessEnabled {
 				if originTask.Status != "SUCCESS" && relayInfo.RelayMode != relayconstant.RelayModeMidjourneyModal {
 					return service.MidjourneyErrorWrapper(constant.MjRequestError, "task_status_not_success")
@@ -496,7 +676,8 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 		}
 	}
 
-	if consumeQuota && userQuota-priceData.Quota < 0 {
+	creditLimit := common.GetContextKeyInt(c, constant.ContextKeyUserCreditLimit)
+	if consumeQuota && userQuota+creditLimit-priceData.Quota < 0 {
 		return &dto.MidjourneyResponse{
 			Code:        4,
 			Description: "quota_not_enough",
@@ -558,6 +739,8 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 		FailReason:  "",
 		ChannelId:   c.GetInt("channel_id"),
 		Quota:       priceData.Quota,
+		CallbackUrl: callbackUrl,
+		TokenId:     relayInfo.TokenId,
 	}
 	if midjResponse.Code == 3 {
 		//无实例账号自动禁用渠道（No available account instance）
@@ -602,8 +785,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 		midjourneyTask.Progress = "100%"
 		midjourneyTask.Status = "SUCCESS"
 	}
-	err = midjourneyTask.Insert()
-	if err != nil {
+	if err = saveSubmittedMjTask(c, midjourneyTask); err != nil {
 		return &dto.MidjourneyResponse{
 			Code:        4,
 			Description: "insert_midjourney_task_failed",
@@ -640,6 +822,18 @@ func RelayMidjourneySubmit(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dt
 	return nil
 }
 
+// replaceMjRequestTaskId 将请求体中引用的任务 ID 替换为上游任务 ID
+func replaceMjRequestTaskId(c *gin.Context, from string, to string) error {
+	requestBody, err := common.GetRequestBody(c)
+	if err != nil {
+		return err
+	}
+	requestBody = bytes.ReplaceAll(requestBody, []byte(from), []byte(to))
+	c.Set(common.KeyRequestBody, requestBody)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+	return nil
+}
+
 type taskChangeParams struct {
 	ID     string
 	Action string
@@ -657,3 +851,17 @@ func getMjRequestPath(path string) string {
 	}
 	return requestURL
 }
+
+// saveSubmittedMjTask 保存已提交上游的绘图任务，从网关队列调度的请求复用排队时创建的占位任务，
+// 用户仍可使用排队时返回的 ID 查询
+func saveSubmittedMjTask(c *gin.Context, midjourneyTask *model.Midjourney) error {
+	queued, _ := common.GetContextKey(c, constant.ContextKeyQueuedTask)
+	placeholder, ok := queued.(*model.Midjourney)
+	if !ok {
+		return midjourneyTask.Insert()
+	}
+	midjourneyTask.Id = placeholder.Id
+	midjourneyTask.QueueId = placeholder.QueueId
+	midjourneyTask.SubmitTime = placeholder.SubmitTime
+	return midjourneyTask.Update()
+}
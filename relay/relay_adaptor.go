@@ -7,6 +7,7 @@ import (
 	"one-api/relay/channel/aws"
 	"one-api/relay/channel/baidu"
 	"one-api/relay/channel/baidu_v2"
+	"one-api/relay/channel/bfl"
 	"one-api/relay/channel/claude"
 	"one-api/relay/channel/cloudflare"
 	"one-api/relay/channel/cohere"
@@ -14,6 +15,7 @@ import (
 	"one-api/relay/channel/deepseek"
 	"one-api/relay/channel/dify"
 	"one-api/relay/channel/gemini"
+	"one-api/relay/channel/ideogram"
 	"one-api/relay/channel/jimeng"
 	"one-api/relay/channel/jina"
 	"one-api/relay/channel/mistral"
@@ -24,9 +26,14 @@ import (
 	"one-api/relay/channel/palm"
 	"one-api/relay/channel/perplexity"
 	"one-api/relay/channel/siliconflow"
+	"one-api/relay/channel/stability"
+	taskhailuo "one-api/relay/channel/task/hailuo"
 	taskjimeng "one-api/relay/channel/task/jimeng"
 	"one-api/relay/channel/task/kling"
+	taskluma "one-api/relay/channel/task/luma"
+	taskrunway "one-api/relay/channel/task/runway"
 	"one-api/relay/channel/task/suno"
+	taskudio "one-api/relay/channel/task/udio"
 	taskvertex "one-api/relay/channel/task/vertex"
 	taskVidu "one-api/relay/channel/task/vidu"
 	"one-api/relay/channel/tencent"
@@ -103,12 +110,22 @@ func GetAdaptor(apiType int) channel.Adaptor {
 		return &jimeng.Adaptor{}
 	case constant.APITypeMoonshot:
 		return &moonshot.Adaptor{} // Moonshot uses Claude API
+	case constant.APITypeBFL:
+		return &bfl.Adaptor{}
+	case constant.APITypeStability:
+		return &stability.Adaptor{}
+	case constant.APITypeIdeogram:
+		return &ideogram.Adaptor{}
 	}
 	return nil
 }
 
 func GetTaskPlatform(c *gin.Context) constant.TaskPlatform {
 	channelType := c.GetInt("channel_type")
+	if channelType == constant.ChannelTypeSunoAPI {
+		// 统一音乐接口分发到 Suno 渠道时沿用 suno 平台，由 Suno 专用的轮询处理
+		return constant.TaskPlatformSuno
+	}
 	if channelType > 0 {
 		return constant.TaskPlatform(strconv.Itoa(channelType))
 	}
@@ -132,6 +149,15 @@ func GetTaskAdaptor(platform constant.TaskPlatform) channel.TaskAdaptor {
 			return &taskvertex.TaskAdaptor{}
 		case constant.ChannelTypeVidu:
 			return &taskVidu.TaskAdaptor{}
+		case constant.ChannelTypeRunway:
+			return &taskrunway.TaskAdaptor{}
+		case constant.ChannelTypeLuma:
+			return &taskluma.TaskAdaptor{}
+		case constant.ChannelTypeMiniMax:
+			// MiniMax 渠道的异步任务为海螺视频生成
+			return &taskhailuo.TaskAdaptor{}
+		case constant.ChannelTypeUdio:
+			return &taskudio.TaskAdaptor{}
 		}
 	}
 	return nil
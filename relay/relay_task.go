@@ -11,14 +11,20 @@ import (
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/model"
+	"one-api/relay/channel"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting/ratio_setting"
+	"one-api/types"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 /*
@@ -41,18 +47,47 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 		return service.TaskErrorWrapperLocal(fmt.Errorf("invalid api platform: %s", platform), "invalid_api_platform", http.StatusBadRequest)
 	}
 	adaptor.Init(info)
+	// 统一音乐接口默认按次计费，配置了按首计费的单价时预扣额度为单价乘以一次生成的歌曲数
+	songCount := 0
+	if info.RelayMode == relayconstant.RelayModeMusicSubmit {
+		musicAdaptor, ok := adaptor.(channel.MusicTaskAdaptor)
+		if !ok {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("music generation is not supported by platform %s", platform), "music_not_supported", http.StatusBadRequest)
+		}
+		songCount = musicAdaptor.SongCount(info)
+	}
 	// get & validate taskRequest 获取并验证文本请求
-	taskErr = adaptor.ValidateRequestAndSetAction(c, info)
+	remixFrom := common.GetContextKeyString(c, constant.ContextKeyTaskRemixFrom)
+	if remixFrom != "" {
+		taskErr = validateRemixRequest(c, info, adaptor, platform, remixFrom)
+	} else {
+		taskErr = adaptor.ValidateRequestAndSetAction(c, info)
+	}
 	if taskErr != nil {
 		return
 	}
+	callbackUrl, err := service.GetTaskCallbackUrl(c)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_callback_url", http.StatusBadRequest)
+	}
 
 	modelName := info.OriginModelName
 	if modelName == "" {
 		modelName = service.CoverTaskActionToModelName(platform, info.Action)
+		// 协议价与分时定价按计费的模型名匹配
+		info.OriginModelName = modelName
+	}
+	// 配置了按秒计费的视频模型按提交的时长与分辨率计价，否则按次计费
+	videoSeconds, videoResolution := taskVideoSpec(c)
+	var pricePerSecond, pricePerSong float64
+	perSecondPricing := false
+	if videoSeconds > 0 {
+		pricePerSecond, perSecondPricing = ratio_setting.GetVideoPricePerSecond(modelName, videoResolution)
 	}
 	modelPrice, success := ratio_setting.GetModelPrice(modelName, true)
-	if !success {
+	if perSecondPricing {
+		modelPrice = pricePerSecond * float64(videoSeconds)
+	} else if !success {
 		defaultPrice, ok := ratio_setting.GetDefaultModelRatioMap()[modelName]
 		if !ok {
 			modelPrice = 0.1
@@ -60,23 +95,34 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 			modelPrice = defaultPrice
 		}
 	}
+	if songCount > 0 {
+		// 模型价格是按次配置的，只有单独配置了 "<模型>-per-song" 的单价时才按首计费
+		if price, ok := ratio_setting.GetMusicPricePerSong(modelName); ok {
+			pricePerSong = price
+			modelPrice = pricePerSong * float64(songCount)
+		} else {
+			songCount = 0
+		}
+	}
 
-	// 预扣
-	groupRatio := ratio_setting.GetGroupRatio(info.UsingGroup)
-	var ratio float64
-	userGroupRatio, hasUserGroupRatio := ratio_setting.GetGroupGroupRatio(info.UserGroup, info.UsingGroup)
-	if hasUserGroupRatio {
-		ratio = modelPrice * userGroupRatio
-	} else {
-		ratio = modelPrice * groupRatio
+	groupRatioInfo := helper.HandleTaskGroupRatio(c, info)
+	if override := groupRatioInfo.PriceOverride; override != nil && override.Type == model.PriceOverrideTypePrice {
+		// 协议价为按次价格，不再按时长或歌曲数计费
+		modelPrice = override.Value
+		perSecondPricing = false
+		songCount = 0
 	}
+
+	// 预扣
+	ratio := modelPrice * groupRatioInfo.GroupRatio
 	userQuota, err := model.GetUserQuota(info.UserId, false)
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 		return
 	}
 	quota := int(ratio * common.QuotaPerUnit)
-	if userQuota-quota < 0 {
+	creditLimit := common.GetContextKeyInt(c, constant.ContextKeyUserCreditLimit)
+	if userQuota+creditLimit-quota < 0 {
 		taskErr = service.TaskErrorWrapperLocal(errors.New("user quota is not enough"), "quota_not_enough", http.StatusForbidden)
 		return
 	}
@@ -122,7 +168,7 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 		return
 	}
 	// handle response
-	if resp != nil && resp.StatusCode != http.StatusOK {
+	if resp != nil && (resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices) {
 		responseBody, _ := io.ReadAll(resp.Body)
 		taskErr = service.TaskErrorWrapper(fmt.Errorf(string(responseBody)), "fail_to_fetch_task", resp.StatusCode)
 		return
@@ -138,16 +184,22 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 			}
 			if quota != 0 {
 				tokenName := c.GetString("token_name")
-				gRatio := groupRatio
-				if hasUserGroupRatio {
-					gRatio = userGroupRatio
-				}
+				gRatio := groupRatioInfo.GroupRatio
 				logContent := fmt.Sprintf("模型固定价格 %.2f，分组倍率 %.2f，操作 %s", modelPrice, gRatio, info.Action)
-				other := make(map[string]interface{})
-				other["model_price"] = modelPrice
-				other["group_ratio"] = groupRatio
-				if hasUserGroupRatio {
-					other["user_group_ratio"] = userGroupRatio
+				other := service.GenerateMjOtherInfo(types.PerCallPriceData{
+					ModelPrice:     modelPrice,
+					Quota:          quota,
+					GroupRatioInfo: groupRatioInfo,
+				})
+				if perSecondPricing {
+					logContent = fmt.Sprintf("按秒计费 %.4f/秒，时长 %d 秒，分辨率 %s，分组倍率 %.2f，操作 %s", pricePerSecond, videoSeconds, videoResolution, gRatio, info.Action)
+					other["video_price_per_second"] = pricePerSecond
+					other["video_seconds"] = videoSeconds
+					other["video_resolution"] = videoResolution
+				} else if songCount > 0 {
+					logContent = fmt.Sprintf("按首计费 %.4f/首，%d 首，分组倍率 %.2f，操作 %s", pricePerSong, songCount, gRatio, info.Action)
+					other["music_price_per_song"] = pricePerSong
+					other["song_count"] = songCount
 				}
 				model.RecordConsumeLog(c, info.UserId, model.RecordConsumeLogParams{
 					ChannelId: info.ChannelId,
@@ -176,14 +228,84 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 	task.Quota = quota
 	task.Data = taskData
 	task.Action = info.Action
-	err = task.Insert()
-	if err != nil {
+	task.CallbackUrl = callbackUrl
+	task.Properties = buildTaskProperties(c, modelName)
+	task.Properties.RemixFrom = remixFrom
+	// 请求未指定时记录适配器补全的默认值，结算时据此按实际时长退款
+	if task.Properties.Duration == 0 {
+		task.Properties.Duration = float64(videoSeconds)
+	}
+	if task.Properties.Size == "" {
+		task.Properties.Size = videoResolution
+	}
+	if songCount > 0 {
+		// 音乐任务按歌曲数结算，请求中的时长不参与退款计算
+		task.Properties.SongCount = songCount
+		task.Properties.Duration = 0
+	}
+	if err = saveSubmittedTask(c, task); err != nil {
 		taskErr = service.TaskErrorWrapper(err, "insert_task_failed", http.StatusInternalServerError)
 		return
 	}
+	common.SetContextKey(c, constant.ContextKeyTask, task)
 	return nil
 }
 
+// saveSubmittedTask 保存已提交上游的任务，从网关队列调度的请求复用排队时创建的占位任务，
+// 用户仍可使用排队时返回的 ID 查询；超时从提交上游时开始计算，不包含排队等待的时间
+func saveSubmittedTask(c *gin.Context, task *model.Task) error {
+	queued, _ := common.GetContextKey(c, constant.ContextKeyQueuedTask)
+	placeholder, ok := queued.(*model.Task)
+	if !ok {
+		return task.Insert()
+	}
+	task.ID = placeholder.ID
+	task.QueueId = placeholder.QueueId
+	task.SubmitTime = placeholder.SubmitTime
+	task.CreatedAt = time.Now().Unix()
+	return task.Update()
+}
+
+// validateRemixRequest 校验基于已有任务的 remix 请求，仅支持实现了 TaskRemixAdaptor 的平台
+func validateRemixRequest(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.TaskAdaptor, platform constant.TaskPlatform, remixFrom string) *dto.TaskError {
+	remixAdaptor, ok := adaptor.(channel.TaskRemixAdaptor)
+	if !ok {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("remix is not supported by platform %s", platform), "remix_not_supported", http.StatusBadRequest)
+	}
+	originTask, exist, err := model.GetByTaskId(info.UserId, remixFrom)
+	if err != nil {
+		return service.TaskErrorWrapper(err, "get_origin_task_failed", http.StatusInternalServerError)
+	}
+	if !exist {
+		return service.TaskErrorWrapperLocal(errors.New("task_origin_not_exist"), "task_not_exist", http.StatusBadRequest)
+	}
+	if originTask.Platform != platform || originTask.Status != model.TaskStatusSuccess {
+		return service.TaskErrorWrapperLocal(errors.New("only completed videos of the same platform can be remixed"), "invalid_request", http.StatusBadRequest)
+	}
+	return remixAdaptor.ValidateRemixRequestAndSetAction(c, info, originTask)
+}
+
+// taskVideoSpec 获取提交给上游的视频时长（秒）与分辨率，部分适配器会在校验请求时补全默认值
+func taskVideoSpec(c *gin.Context) (int, string) {
+	if v, ok := c.Get("task_request"); ok {
+		if req, ok := v.(relaycommon.TaskSubmitReq); ok {
+			return req.Duration, req.Size
+		}
+	}
+	return 0, ""
+}
+
+// buildTaskProperties 记录提交时的请求参数，用于结算与 OpenAI 兼容接口的展示
+func buildTaskProperties(c *gin.Context, modelName string) model.Properties {
+	requestBody, _ := common.GetRequestBody(c)
+	return model.Properties{
+		Input:    gjson.GetBytes(requestBody, "prompt").String(),
+		Model:    modelName,
+		Size:     gjson.GetBytes(requestBody, "size").String(),
+		Duration: gjson.GetBytes(requestBody, "duration").Float(),
+	}
+}
+
 var fetchRespBuilders = map[int]func(c *gin.Context) (respBody []byte, taskResp *dto.TaskError){
 	relayconstant.RelayModeSunoFetchByID:  sunoFetchByIDRespBodyBuilder,
 	relayconstant.RelayModeSunoFetch:      sunoFetchRespBodyBuilder,
@@ -353,7 +475,7 @@ func videoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *d
 				"metadata": nil,
 				"status":   status,
 				"task_id":  originTask.TaskID,
-				"url":      originTask.FailReason,
+				"url":      service.TaskResultUrl(originTask),
 			}
 			respBody, _ = json.Marshal(dto.TaskResponse[any]{
 				Code: "success",
@@ -373,14 +495,123 @@ func videoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *d
 
 func TaskModel2Dto(task *model.Task) *dto.TaskDto {
 	return &dto.TaskDto{
-		TaskID:     task.TaskID,
-		Action:     task.Action,
-		Status:     string(task.Status),
-		FailReason: task.FailReason,
-		SubmitTime: task.SubmitTime,
-		StartTime:  task.StartTime,
-		FinishTime: task.FinishTime,
-		Progress:   task.Progress,
-		Data:       task.Data,
+		TaskID:        task.TaskID,
+		Action:        task.Action,
+		Status:        string(task.Status),
+		FailReason:    service.TaskResultUrl(task),
+		SubmitTime:    task.SubmitTime,
+		StartTime:     task.StartTime,
+		FinishTime:    task.FinishTime,
+		Progress:      task.Progress,
+		Data:          task.Data,
+		QueuePosition: TaskQueuePosition(task),
+	}
+}
+
+// TaskQueuePosition 获取网关排队中任务的排队位置，已提交上游的任务返回 0
+func TaskQueuePosition(task *model.Task) int {
+	if task.Status != model.TaskStatusQueued || !task.IsGatewayQueued() {
+		return 0
+	}
+	return model.GetTaskQueuePosition(model.TaskQueueKindTask, task.ID)
+}
+
+// TaskModel2OpenAIVideo 将任务转换为 OpenAI /v1/videos 接口的视频对象
+func TaskModel2OpenAIVideo(task *model.Task) *dto.OpenAIVideo {
+	video := &dto.OpenAIVideo{
+		Id:        task.TaskID,
+		Object:    "video",
+		Model:     task.Properties.Model,
+		Status:    "queued",
+		CreatedAt: task.SubmitTime,
+		Size:      task.Properties.Size,
+	}
+	if task.Properties.Duration > 0 {
+		video.Seconds = strconv.FormatFloat(task.Properties.Duration, 'f', -1, 64)
+	}
+	if task.Properties.RemixFrom != "" {
+		video.RemixedFromVideoId = &task.Properties.RemixFrom
+	}
+	video.Progress, _ = strconv.Atoi(strings.TrimSuffix(task.Progress, "%"))
+	switch task.Status {
+	case model.TaskStatusInProgress:
+		video.Status = "in_progress"
+	case model.TaskStatusSuccess:
+		video.Status = "completed"
+		video.Progress = 100
+	case model.TaskStatusFailure:
+		video.Status = "failed"
+		video.Error = &dto.OpenAIVideoError{
+			Code:    "video_generation_failed",
+			Message: task.FailReason,
+		}
+	}
+	if task.FinishTime > 0 && (video.Status == "completed" || video.Status == "failed") {
+		video.CompletedAt = &task.FinishTime
+	}
+	video.QueuePosition = TaskQueuePosition(task)
+	return video
+}
+
+// NotifyTaskFinished 任务进入终态（成功或失败）时向提交时指定的 callback_url 推送结果
+func NotifyTaskFinished(task *model.Task) {
+	if task.Status != model.TaskStatusSuccess && task.Status != model.TaskStatusFailure {
+		return
+	}
+	if service.MediaStorageEnabled() && task.Status == model.TaskStatusSuccess && task.Properties.MediaKey == "" && strings.HasPrefix(task.FailReason, "http") {
+		// 转存完成后再推送，回调中即可使用网关地址
+		gopool.Go(func() {
+			if err := service.StoreTaskMedia(task, task.FailReason); err != nil {
+				common.SysError(fmt.Sprintf("failed to store media for task %s: %s", task.TaskID, err.Error()))
+			} else if err = model.TaskUpdateProperties(task.ID, task.Properties); err != nil {
+				common.SysError(fmt.Sprintf("failed to update task %s properties: %s", task.TaskID, err.Error()))
+			}
+			service.NotifyTaskCallback(task.UserId, common.TaskCallbackTypeTask, task.TaskID, string(task.Status), task.CallbackUrl, TaskModel2Dto(task))
+		})
+		return
+	}
+	service.NotifyTaskCallback(task.UserId, common.TaskCallbackTypeTask, task.TaskID, string(task.Status), task.CallbackUrl, TaskModel2Dto(task))
+}
+
+// MusicTaskSongs 解析音乐任务已生成的歌曲，平台不支持统一音乐接口时返回错误
+func MusicTaskSongs(task *model.Task) ([]dto.MusicSong, error) {
+	adaptor := GetTaskAdaptor(task.Platform)
+	musicAdaptor, ok := adaptor.(channel.MusicTaskAdaptor)
+	if !ok {
+		return nil, fmt.Errorf("music generation is not supported by platform %s", task.Platform)
+	}
+	return musicAdaptor.ParseMusicSongs(task.Data)
+}
+
+// TaskModel2Music 将任务转换为 /v1/music/generations 接口的音乐任务
+func TaskModel2Music(task *model.Task) *dto.MusicTask {
+	music := &dto.MusicTask{
+		TaskId:        task.TaskID,
+		Object:        "music.generation",
+		Model:         task.Properties.Model,
+		Status:        "queued",
+		Progress:      task.Progress,
+		CreatedAt:     task.SubmitTime,
+		FinishedAt:    task.FinishTime,
+		Songs:         make([]dto.MusicSong, 0),
+		QueuePosition: TaskQueuePosition(task),
+	}
+	switch task.Status {
+	case model.TaskStatusInProgress:
+		music.Status = "in_progress"
+	case model.TaskStatusSuccess:
+		music.Status = "completed"
+	case model.TaskStatusFailure:
+		music.Status = "failed"
+		music.Error = &dto.MusicError{
+			Code:    "music_generation_failed",
+			Message: task.FailReason,
+		}
+	}
+	if task.Status == model.TaskStatusSuccess {
+		if songs, err := MusicTaskSongs(task); err == nil && songs != nil {
+			music.Songs = songs
+		}
 	}
+	return music
 }
@@ -0,0 +1,57 @@
+package relay
+
+import (
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskModel2OpenAIVideo(t *testing.T) {
+	newTask := func(status model.TaskStatus, progress string) *model.Task {
+		return &model.Task{
+			TaskID:     "task_1",
+			Status:     status,
+			Progress:   progress,
+			SubmitTime: 100,
+			FinishTime: 200,
+			FailReason: "upstream error",
+			Properties: model.Properties{Model: "sora-2", Size: "720x1280", Duration: 4},
+		}
+	}
+
+	cases := []struct {
+		status   model.TaskStatus
+		progress string
+		want     string
+		percent  int
+	}{
+		{model.TaskStatusNotStart, "0%", "queued", 0},
+		{model.TaskStatusSubmitted, "10%", "queued", 10},
+		{model.TaskStatusQueued, "0%", "queued", 0},
+		{model.TaskStatusInProgress, "30%", "in_progress", 30},
+		{model.TaskStatusSuccess, "90%", "completed", 100},
+		{model.TaskStatusFailure, "100%", "failed", 100},
+	}
+	for _, tc := range cases {
+		video := TaskModel2OpenAIVideo(newTask(tc.status, tc.progress))
+		assert.Equal(t, tc.want, video.Status, tc.status)
+		assert.Equal(t, tc.percent, video.Progress, tc.status)
+	}
+
+	video := TaskModel2OpenAIVideo(newTask(model.TaskStatusSuccess, "100%"))
+	assert.Equal(t, "video", video.Object)
+	assert.Equal(t, "4", video.Seconds)
+	require.NotNil(t, video.CompletedAt)
+	assert.Equal(t, int64(200), *video.CompletedAt)
+	assert.Nil(t, video.Error)
+
+	// 只有失败的任务携带错误信息，未结束的任务没有完成时间
+	video = TaskModel2OpenAIVideo(newTask(model.TaskStatusFailure, "100%"))
+	require.NotNil(t, video.Error)
+	assert.Equal(t, "upstream error", video.Error.Message)
+	video = TaskModel2OpenAIVideo(newTask(model.TaskStatusInProgress, "50%"))
+	assert.Nil(t, video.CompletedAt)
+	assert.Nil(t, video.Error)
+}
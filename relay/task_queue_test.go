@@ -0,0 +1,99 @@
+package relay
+
+import (
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// setupSQLiteTestDB 使用内存 SQLite 替换全局 DB，测试结束后恢复
+func setupSQLiteTestDB(t *testing.T, models ...any) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	originDB, usingSQLite := model.DB, common.UsingSQLite
+	model.DB, common.UsingSQLite = db, true
+	t.Cleanup(func() {
+		model.DB, common.UsingSQLite = originDB, usingSQLite
+		sqlDB.Close()
+	})
+}
+
+func TestSaveSubmittedTask(t *testing.T) {
+	setupSQLiteTestDB(t, &model.Task{})
+	placeholder := &model.Task{
+		UserId:     1,
+		TaskID:     "queue_1",
+		QueueId:    "queue_1",
+		Status:     model.TaskStatusQueued,
+		SubmitTime: 100,
+	}
+	require.NoError(t, placeholder.Insert())
+	require.True(t, placeholder.IsGatewayQueued())
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	common.SetContextKey(c, constant.ContextKeyQueuedTask, placeholder)
+	task := &model.Task{UserId: 1, TaskID: "upstream_1", Status: model.TaskStatusSubmitted, SubmitTime: 200}
+	require.NoError(t, saveSubmittedTask(c, task))
+
+	// 复用占位任务，排队时返回的 ID 与上游 ID 都能查到同一条记录
+	var count int64
+	require.NoError(t, model.DB.Model(&model.Task{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	for _, taskId := range []string{"queue_1", "upstream_1"} {
+		saved, exist, err := model.GetByTaskId(1, taskId)
+		require.NoError(t, err)
+		require.True(t, exist, taskId)
+		assert.Equal(t, placeholder.ID, saved.ID)
+		assert.Equal(t, "upstream_1", saved.TaskID)
+		assert.Equal(t, int64(100), saved.SubmitTime)
+		assert.False(t, saved.IsGatewayQueued())
+	}
+
+	// 未经过队列的请求直接插入新任务
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	require.NoError(t, saveSubmittedTask(c, &model.Task{UserId: 1, TaskID: "upstream_2"}))
+	require.NoError(t, model.DB.Model(&model.Task{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestSaveSubmittedMjTask(t *testing.T) {
+	setupSQLiteTestDB(t, &model.Midjourney{})
+	placeholder := &model.Midjourney{
+		UserId:     1,
+		MjId:       "queue_1",
+		QueueId:    "queue_1",
+		Status:     model.TaskStatusQueued,
+		SubmitTime: 100,
+	}
+	require.NoError(t, placeholder.Insert())
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	common.SetContextKey(c, constant.ContextKeyQueuedTask, placeholder)
+	require.NoError(t, saveSubmittedMjTask(c, &model.Midjourney{UserId: 1, MjId: "upstream_1", Progress: "0%", SubmitTime: 200}))
+
+	var count int64
+	require.NoError(t, model.DB.Model(&model.Midjourney{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	for _, mjId := range []string{"queue_1", "upstream_1"} {
+		saved := model.GetByMJId(1, mjId)
+		require.NotNil(t, saved, mjId)
+		assert.Equal(t, placeholder.Id, saved.Id)
+		assert.Equal(t, "upstream_1", saved.MjId)
+		assert.Equal(t, int64(100), saved.SubmitTime)
+		assert.False(t, saved.IsGatewayQueued())
+	}
+}
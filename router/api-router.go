@@ -24,6 +24,8 @@ func SetApiRouter(router *gin.Engine) {
 		//apiRouter.GET("/midjourney", controller.GetMidjourney)
 		apiRouter.GET("/home_page_content", controller.GetHomePageContent)
 		apiRouter.GET("/pricing", middleware.TryUserAuth(), controller.GetPricing)
+		apiRouter.POST("/pricing/estimate", middleware.CriticalRateLimit(), middleware.UserAuth(), controller.EstimatePricing)
+		apiRouter.POST("/pricing/estimate/batch", middleware.CriticalRateLimit(), middleware.UserAuth(), controller.EstimatePricingBatch)
 		apiRouter.GET("/verification", middleware.EmailVerificationRateLimit(), middleware.TurnstileCheck(), controller.SendEmailVerification)
 		apiRouter.GET("/reset_password", middleware.CriticalRateLimit(), middleware.TurnstileCheck(), controller.SendPasswordResetEmail)
 		apiRouter.POST("/user/reset", middleware.CriticalRateLimit(), controller.ResetPassword)
@@ -39,6 +41,8 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/ratio_config", middleware.CriticalRateLimit(), controller.GetRatioConfig)
 
 		apiRouter.POST("/stripe/webhook", controller.StripeWebhook)
+		apiRouter.GET("/payment/:provider/notify", controller.PaymentNotify)
+		apiRouter.POST("/payment/:provider/notify", controller.PaymentNotify)
 
 		userRoute := apiRouter.Group("/user")
 		{
@@ -66,6 +70,16 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.POST("/amount", controller.RequestAmount)
 				selfRoute.POST("/stripe/pay", middleware.CriticalRateLimit(), controller.RequestStripePay)
 				selfRoute.POST("/stripe/amount", controller.RequestStripeAmount)
+				selfRoute.POST("/payment/pay", middleware.CriticalRateLimit(), controller.RequestPayment)
+				selfRoute.POST("/payment/amount", controller.RequestPaymentAmount)
+				selfRoute.GET("/payment/:trade_no", middleware.CriticalRateLimit(), controller.QueryPaymentOrder)
+				selfRoute.GET("/subscription", controller.GetSelfSubscription)
+				selfRoute.GET("/subscription/plans", controller.GetEnabledSubscriptionPlans)
+				selfRoute.POST("/subscription", middleware.CriticalRateLimit(), controller.RequestSubscription)
+				selfRoute.POST("/subscription/switch", middleware.CriticalRateLimit(), controller.SwitchSubscription)
+				selfRoute.POST("/subscription/cancel", controller.CancelSubscription)
+				selfRoute.GET("/postpaid/invoices", controller.GetUserPostpaidInvoices)
+				selfRoute.POST("/postpaid/pay", middleware.CriticalRateLimit(), controller.RequestPostpaidPay)
 				selfRoute.POST("/aff_transfer", controller.TransferAffQuota)
 				selfRoute.PUT("/setting", controller.UpdateUserSetting)
 
@@ -86,6 +100,7 @@ func SetApiRouter(router *gin.Engine) {
 				adminRoute.POST("/", controller.CreateUser)
 				adminRoute.POST("/manage", controller.ManageUser)
 				adminRoute.PUT("/", controller.UpdateUser)
+				adminRoute.PUT("/billing", controller.UpdateUserBilling)
 				adminRoute.DELETE("/:id", controller.DeleteUser)
 
 				// Admin 2FA routes
@@ -168,16 +183,68 @@ func SetApiRouter(router *gin.Engine) {
 			redemptionRoute.PUT("/", controller.UpdateRedemption)
 			redemptionRoute.DELETE("/invalid", controller.DeleteInvalidRedemption)
 			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
+			redemptionRoute.POST("/:id/reverse", controller.ReverseRedemption)
+		}
+		topUpRoute := apiRouter.Group("/topup")
+		topUpRoute.Use(middleware.AdminAuth())
+		{
+			topUpRoute.GET("/", controller.GetAllTopUps)
+			topUpRoute.GET("/reversals", controller.GetQuotaReversals)
+			topUpRoute.POST("/:id/refund", controller.RefundTopUp)
 		}
 		logRoute := apiRouter.Group("/log")
 		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
 		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
 		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
+		logRoute.GET("/tag_stat", middleware.AdminAuth(), controller.GetLogsTagStat)
+		logRoute.GET("/self/tag_stat", middleware.UserAuth(), controller.GetLogsSelfTagStat)
 		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
 
+		postpaidRoute := apiRouter.Group("/postpaid")
+		postpaidRoute.Use(middleware.AdminAuth())
+		{
+			postpaidRoute.GET("/invoices", controller.GetAllPostpaidInvoices)
+			postpaidRoute.POST("/run", controller.RunPostpaidBilling)
+		}
+
+		subscriptionRoute := apiRouter.Group("/subscription")
+		subscriptionRoute.Use(middleware.AdminAuth())
+		{
+			subscriptionRoute.GET("/", controller.GetAllUserSubscriptions)
+			subscriptionRoute.GET("/plans", controller.GetSubscriptionPlans)
+			subscriptionRoute.POST("/plans", controller.CreateSubscriptionPlan)
+			subscriptionRoute.PUT("/plans", controller.UpdateSubscriptionPlan)
+			subscriptionRoute.DELETE("/plans/:id", controller.DeleteSubscriptionPlan)
+		}
+
+		priceOverrideRoute := apiRouter.Group("/price_override")
+		priceOverrideRoute.Use(middleware.AdminAuth())
+		{
+			priceOverrideRoute.GET("/", controller.GetUserPriceOverrides)
+			priceOverrideRoute.POST("/", controller.CreateUserPriceOverride)
+			priceOverrideRoute.PUT("/", controller.UpdateUserPriceOverride)
+			priceOverrideRoute.DELETE("/:id", controller.DeleteUserPriceOverride)
+		}
+
+		statementRoute := apiRouter.Group("/statement")
+		statementRoute.GET("/self", middleware.UserAuth(), controller.GetUserStatements)
+		statementRoute.GET("/self/:id/download", middleware.UserAuth(), controller.DownloadUserStatement)
+		statementRoute.GET("/", middleware.AdminAuth(), controller.GetAllStatements)
+		statementRoute.POST("/", middleware.AdminAuth(), controller.GenerateStatement)
+		statementRoute.GET("/:id/download", middleware.AdminAuth(), controller.DownloadStatement)
+
+		exportRoute := apiRouter.Group("/export")
+		exportRoute.Use(middleware.AdminAuth())
+		{
+			exportRoute.GET("/logs", controller.ExportLogs)
+			exportRoute.GET("/quota_data", controller.ExportQuotaData)
+			exportRoute.GET("/reports", controller.GetUsageReports)
+			exportRoute.POST("/reports", controller.GenerateUsageReports)
+		}
+
 		dataRoute := apiRouter.Group("/data")
 		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.UserAuth(), controller.GetUserQuotaDates)
@@ -204,11 +271,16 @@ func SetApiRouter(router *gin.Engine) {
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
 		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
+		mjRoute.POST("/retry", middleware.AdminAuth(), controller.RetryMidjourneyTasks)
 
 		taskRoute := apiRouter.Group("/task")
 		{
 			taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserTask)
 			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+			taskRoute.GET("/callbacks/self", middleware.UserAuth(), controller.GetUserTaskCallbacks)
+			taskRoute.GET("/callbacks", middleware.AdminAuth(), controller.GetAllTaskCallbacks)
+			taskRoute.POST("/callbacks/:id/retry", middleware.AdminAuth(), controller.RetryTaskCallback)
+			taskRoute.POST("/:id/settle", middleware.AdminAuth(), controller.SettleTask)
 		}
 
 		vendorRoute := apiRouter.Group("/vendors")
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"one-api/common/metrics"
+	"one-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetMetricsRouter(router *gin.Engine) {
+	if !metrics.Enabled() {
+		return
+	}
+	router.GET("/metrics", middleware.MetricsAuth(), metrics.Handler())
+}
@@ -162,6 +162,9 @@ func SetRelayRouter(router *gin.Engine) {
 	registerMjRouterGroup(relayMjModeRouter)
 	//relayMjRouter.Use()
 
+	// 转存的生成结果，通过签名校验访问权限
+	router.GET("/media/:key", controller.GetMedia)
+
 	relaySunoRouter := router.Group("/suno")
 	relaySunoRouter.Use(middleware.TokenAuth(), middleware.Distribute())
 	{
@@ -170,6 +173,14 @@ func SetRelayRouter(router *gin.Engine) {
 		relaySunoRouter.GET("/fetch/:id", controller.RelayTask)
 	}
 
+	// 统一的音乐生成接口，按渠道类型转换为 Suno、Udio 等平台的请求
+	relayMusicRouter := router.Group("/v1/music")
+	relayMusicRouter.Use(middleware.TokenAuth())
+	{
+		relayMusicRouter.POST("/generations", middleware.Distribute(), controller.RelayMusic)
+		relayMusicRouter.GET("/generations/:task_id", controller.RetrieveMusic)
+	}
+
 	relayGeminiRouter := router.Group("/v1beta")
 	relayGeminiRouter.Use(middleware.TokenAuth())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
@@ -200,6 +211,7 @@ func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 		relayMjRouter.GET("/task/:id/fetch", controller.RelayMidjourney)
 		relayMjRouter.GET("/task/:id/image-seed", controller.RelayMidjourney)
 		relayMjRouter.POST("/task/list-by-condition", controller.RelayMidjourney)
+		relayMjRouter.POST("/task/:id/cancel", controller.RelayMidjourney)
 		relayMjRouter.POST("/insight-face/swap", controller.RelayMidjourney)
 		relayMjRouter.POST("/submit/upload-discord-images", controller.RelayMidjourney)
 	}
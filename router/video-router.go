@@ -15,6 +15,18 @@ func SetVideoRouter(router *gin.Engine) {
 		videoV1Router.GET("/video/generations/:task_id", controller.RelayTask)
 	}
 
+	// OpenAI compatible /v1/videos routes
+	openAIVideoRouter := router.Group("/v1/videos")
+	openAIVideoRouter.Use(middleware.TokenAuth())
+	{
+		openAIVideoRouter.POST("", middleware.OpenAIVideoRequestConvert(), middleware.Distribute(), controller.RelayOpenAIVideo)
+		openAIVideoRouter.POST("/:video_id/remix", middleware.OpenAIVideoRequestConvert(), middleware.Distribute(), controller.RelayOpenAIVideo)
+		openAIVideoRouter.GET("", controller.ListOpenAIVideos)
+		openAIVideoRouter.GET("/:video_id", controller.RetrieveOpenAIVideo)
+		openAIVideoRouter.DELETE("/:video_id", controller.DeleteOpenAIVideo)
+		openAIVideoRouter.GET("/:video_id/content", controller.GetOpenAIVideoContent)
+	}
+
 	klingV1Router := router.Group("/kling/v1")
 	klingV1Router.Use(middleware.KlingRequestConvert(), middleware.TokenAuth(), middleware.Distribute())
 	{
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const CostTagsHeader = "X-NewAPI-Tags"
+
+// ParseCostTags 解析请求的成本归属标签，请求头 X-NewAPI-Tags 的格式为 key1=value1,key2=value2，同名标签优先于请求体 metadata
+// 请求头专用于标签，未允许的键会返回错误；metadata 可能另有用途，其中未允许的键直接忽略，读取为标签的键在转发前移除
+func ParseCostTags(c *gin.Context) (map[string]string, error) {
+	if !operation_setting.IsCostTagEnabled() {
+		return nil, nil
+	}
+	tags := make(map[string]string)
+	if strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json") {
+		body, err := common.GetRequestBody(c)
+		if err != nil {
+			return nil, err
+		}
+		metadata := gjson.GetBytes(body, "metadata")
+		if metadata.IsObject() {
+			var consumed []string
+			metadata.ForEach(func(key, value gjson.Result) bool {
+				if !operation_setting.IsCostTagKeyAllowed(key.String()) {
+					return true
+				}
+				switch value.Type {
+				case gjson.String, gjson.Number, gjson.True, gjson.False:
+					tags[key.String()] = value.String()
+					consumed = append(consumed, key.String())
+				}
+				return true
+			})
+			if len(consumed) > 0 {
+				if err = stripCostTagMetadata(c, body, consumed); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if header := c.Request.Header.Get(CostTagsHeader); header != "" {
+		for _, pair := range strings.Split(header, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return nil, fmt.Errorf("标签格式错误: %s", pair)
+			}
+			if !operation_setting.IsCostTagKeyAllowed(key) {
+				return nil, fmt.Errorf("不允许的标签: %s", key)
+			}
+			tags[key] = value
+		}
+	}
+	return validateCostTags(tags)
+}
+
+// stripCostTagMetadata 从请求体 metadata 中移除已读取的标签，metadata 为空时整个移除，避免 metadata 格式严格的上游（如 Anthropic 只接受 user_id）拒绝请求
+func stripCostTagMetadata(c *gin.Context, body []byte, keys []string) error {
+	var err error
+	for _, key := range keys {
+		if body, err = sjson.DeleteBytes(body, "metadata."+gjson.Escape(key)); err != nil {
+			return err
+		}
+	}
+	if metadata := gjson.GetBytes(body, "metadata"); len(metadata.Map()) == 0 {
+		if body, err = sjson.DeleteBytes(body, "metadata"); err != nil {
+			return err
+		}
+	}
+	c.Set(common.KeyRequestBody, body)
+	return nil
+}
+
+func validateCostTags(tags map[string]string) (map[string]string, error) {
+	costTagSetting := operation_setting.GetCostTagSetting()
+	if costTagSetting.MaxTags > 0 && len(tags) > costTagSetting.MaxTags {
+		return nil, fmt.Errorf("标签数量不能超过 %d 个", costTagSetting.MaxTags)
+	}
+	for key, value := range tags {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			delete(tags, key)
+			continue
+		}
+		if costTagSetting.MaxValueLength > 0 && len(value) > costTagSetting.MaxValueLength {
+			return nil, fmt.Errorf("标签 %s 的值长度不能超过 %d", key, costTagSetting.MaxValueLength)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCostTagContext(body string, header string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if header != "" {
+		c.Request.Header.Set(CostTagsHeader, header)
+	}
+	return c
+}
+
+func TestParseCostTags(t *testing.T) {
+	costTagSetting := operation_setting.GetCostTagSetting()
+	costTagSetting.AllowedKeys = []string{"project", "team"}
+	defer func() {
+		costTagSetting.AllowedKeys = []string{}
+	}()
+
+	tags, err := ParseCostTags(newCostTagContext(`{"model":"gpt-4o","metadata":{"project":"alpha","user":"bob","team":{"a":1}}}`, "team=ml"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"project": "alpha", "team": "ml"}, tags)
+
+	tags, err = ParseCostTags(newCostTagContext(`{"metadata":{"project":"alpha"}}`, "project= beta "))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"project": "beta"}, tags)
+
+	_, err = ParseCostTags(newCostTagContext(`{}`, "cost_center=42"))
+	assert.Error(t, err)
+	_, err = ParseCostTags(newCostTagContext(`{}`, "project"))
+	assert.Error(t, err)
+	_, err = ParseCostTags(newCostTagContext(`{}`, "project="+strings.Repeat("x", 65)))
+	assert.Error(t, err)
+
+	costTagSetting.AllowedKeys = []string{}
+	tags, err = ParseCostTags(newCostTagContext(`{}`, "cost_center=42"))
+	require.NoError(t, err)
+	assert.Nil(t, tags)
+}
+
+func TestParseCostTagsStripsMetadata(t *testing.T) {
+	costTagSetting := operation_setting.GetCostTagSetting()
+	costTagSetting.AllowedKeys = []string{"project", "team", "cost.center"}
+	defer func() {
+		costTagSetting.AllowedKeys = []string{}
+	}()
+
+	requestBody := func(c *gin.Context) string {
+		body, err := common.GetRequestBody(c)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// 只移除读取为标签的键，保留上游需要的其他键
+	c := newCostTagContext(`{"model":"claude-3","metadata":{"user_id":"u1","project":"alpha","team":{"a":1},"cost.center":42}}`, "")
+	tags, err := ParseCostTags(c)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"project": "alpha", "cost.center": "42"}, tags)
+	assert.JSONEq(t, `{"model":"claude-3","metadata":{"user_id":"u1","team":{"a":1}}}`, requestBody(c))
+
+	// metadata 只含标签时整个移除
+	c = newCostTagContext(`{"model":"claude-3","metadata":{"project":"alpha"}}`, "")
+	_, err = ParseCostTags(c)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"claude-3"}`, requestBody(c))
+
+	// 没有读取标签时请求体保持原样
+	original := `{"model":"claude-3","metadata":{}}`
+	c = newCostTagContext(original, "project=beta")
+	_, err = ParseCostTags(c)
+	require.NoError(t, err)
+	assert.Equal(t, original, requestBody(c))
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"time"
+)
+
+// SyncExchangeRateFile 按设置的间隔从本地汇率文件刷新汇率，未配置汇率文件时仅使用手动配置的汇率
+func SyncExchangeRateFile() {
+	for {
+		if err := operation_setting.LoadExchangeRateFile(); err != nil {
+			common.SysError("failed to load exchange rate file: " + err.Error())
+		}
+		time.Sleep(time.Duration(operation_setting.GetRateFileRefreshMinutes()) * time.Minute)
+	}
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+type ColumnType int
+
+const (
+	ColumnTypeInt64 ColumnType = iota
+	ColumnTypeString
+	ColumnTypeBool
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Writer 按行写出导出数据，Close 只写出尾部数据，不会关闭底层的 io.Writer
+type Writer interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return true
+	}
+	return false
+}
+
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case FormatParquet:
+		return newParquetWriter(w, columns), nil
+	}
+	return nil, fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/octet-stream"
+}
+
+func FileExtension(format string) string {
+	return "." + format
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("期望整数，实际为 %T", value)
+}
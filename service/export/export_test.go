@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testColumns = []Column{
+	{Name: "id", Type: ColumnTypeInt64},
+	{Name: "model_name", Type: ColumnTypeString},
+	{Name: "is_stream", Type: ColumnTypeBool},
+}
+
+func writeRows(t *testing.T, format string, rows [][]any) []byte {
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf, testColumns)
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, writer.WriteRow(row))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestCSVAndNDJSONWriter(t *testing.T) {
+	rows := [][]any{{1, "gpt-4o", true}, {int64(2), `say "hi", bye`, false}}
+
+	data := writeRows(t, FormatCSV, rows)
+	assert.Equal(t, "id,model_name,is_stream\n1,gpt-4o,true\n2,\"say \"\"hi\"\", bye\",false\n", string(data))
+
+	data = writeRows(t, FormatNDJSON, rows)
+	assert.Equal(t, `{"id":1,"model_name":"gpt-4o","is_stream":true}`+"\n"+`{"id":2,"model_name":"say \"hi\", bye","is_stream":false}`+"\n", string(data))
+
+	_, err := NewWriter("xlsx", &bytes.Buffer{}, testColumns)
+	assert.Error(t, err)
+}
+
+// decodeThrift 独立实现的 Thrift compact protocol 解码，用于校验写入器生成的元数据
+func decodeThrift(t *testing.T, r *bufio.Reader) map[int16]any {
+	fields := make(map[int16]any)
+	var lastId int16
+	for {
+		b, err := r.ReadByte()
+		require.NoError(t, err)
+		if b == 0 {
+			return fields
+		}
+		id := lastId + int16(b>>4)
+		if b>>4 == 0 {
+			id = int16(readZigzag(t, r))
+		}
+		lastId = id
+		fields[id] = decodeThriftValue(t, r, b&0x0f)
+	}
+}
+
+func decodeThriftValue(t *testing.T, r *bufio.Reader, valueType byte) any {
+	switch valueType {
+	case thriftTypeI32, thriftTypeI64:
+		return readZigzag(t, r)
+	case thriftTypeBinary:
+		size, err := binary.ReadUvarint(r)
+		require.NoError(t, err)
+		data := make([]byte, size)
+		_, err = r.Read(data)
+		require.NoError(t, err)
+		return string(data)
+	case thriftTypeList:
+		b, err := r.ReadByte()
+		require.NoError(t, err)
+		size := uint64(b >> 4)
+		if size == 15 {
+			size, err = binary.ReadUvarint(r)
+			require.NoError(t, err)
+		}
+		values := make([]any, size)
+		for i := range values {
+			values[i] = decodeThriftValue(t, r, b&0x0f)
+		}
+		return values
+	case thriftTypeStruct:
+		return decodeThrift(t, r)
+	}
+	t.Fatalf("unexpected thrift type %d", valueType)
+	return nil
+}
+
+func readZigzag(t *testing.T, r *bufio.Reader) int64 {
+	v, err := binary.ReadUvarint(r)
+	require.NoError(t, err)
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func TestParquetWriter(t *testing.T) {
+	rows := make([][]any, parquetRowGroupSize+2)
+	for i := range rows {
+		rows[i] = []any{i, strings.Repeat("m", i%3), i%2 == 0}
+	}
+	data := writeRows(t, FormatParquet, rows)
+	require.Equal(t, parquetMagic, string(data[:4]))
+	require.Equal(t, parquetMagic, string(data[len(data)-4:]))
+
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := decodeThrift(t, bufio.NewReader(bytes.NewReader(data[len(data)-8-footerSize:])))
+	assert.EqualValues(t, len(rows), footer[3])
+	schema := footer[2].([]any)
+	require.Len(t, schema, 4)
+	assert.Equal(t, "model_name", schema[2].(map[int16]any)[4])
+	assert.EqualValues(t, parquetTypeByteArray, schema[2].(map[int16]any)[1])
+	rowGroups := footer[4].([]any)
+	require.Len(t, rowGroups, 2)
+	assert.EqualValues(t, 2, rowGroups[1].(map[int16]any)[3])
+
+	// 解析第二个行组的各列数据页
+	columns := rowGroups[1].(map[int16]any)[1].([]any)
+	readPage := func(column int) []byte {
+		meta := columns[column].(map[int16]any)[3].(map[int16]any)
+		reader := bufio.NewReader(bytes.NewReader(data[meta[9].(int64):]))
+		header := decodeThrift(t, reader)
+		assert.EqualValues(t, 2, header[5].(map[int16]any)[1])
+		page := make([]byte, header[3].(int64))
+		_, err := reader.Read(page)
+		require.NoError(t, err)
+		return page
+	}
+	ids := readPage(0)
+	assert.EqualValues(t, parquetRowGroupSize, binary.LittleEndian.Uint64(ids[:8]))
+	assert.EqualValues(t, parquetRowGroupSize+1, binary.LittleEndian.Uint64(ids[8:]))
+	names := readPage(1)
+	assert.Equal(t, []byte{1, 0, 0, 0, 'm', 2, 0, 0, 0, 'm', 'm'}, names)
+	assert.Equal(t, []byte{0b01}, readPage(2))
+}
+
+func TestParquetWriterEmpty(t *testing.T) {
+	data := writeRows(t, FormatParquet, nil)
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := decodeThrift(t, bufio.NewReader(bytes.NewReader(data[4:4+footerSize])))
+	assert.EqualValues(t, 0, footer[3])
+	assert.Len(t, footer[4], 0)
+}
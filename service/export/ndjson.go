@@ -0,0 +1,43 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"one-api/common"
+)
+
+// ndjsonWriter 每行一个 JSON 对象，字段顺序与列定义一致
+type ndjsonWriter struct {
+	writer *bufio.Writer
+	keys   [][]byte
+}
+
+func newNDJSONWriter(w io.Writer, columns []Column) *ndjsonWriter {
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		key, _ := common.Marshal(column.Name)
+		keys[i] = append(key, ':')
+	}
+	return &ndjsonWriter{writer: bufio.NewWriter(w), keys: keys}
+}
+
+func (w *ndjsonWriter) WriteRow(values []any) error {
+	w.writer.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			w.writer.WriteByte(',')
+		}
+		w.writer.Write(w.keys[i])
+		data, err := common.Marshal(value)
+		if err != nil {
+			return err
+		}
+		w.writer.Write(data)
+	}
+	w.writer.WriteByte('}')
+	return w.writer.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.writer.Flush()
+}
@@ -0,0 +1,330 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 以下常量取自 Parquet 格式定义（parquet.thrift）
+const (
+	parquetTypeBoolean   = 0
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+
+	parquetRepetitionRequired = 0
+	parquetConvertedTypeUTF8  = 0
+	parquetEncodingPlain      = 0
+	parquetEncodingRLE        = 3
+	parquetCodecUncompressed  = 0
+	parquetPageTypeData       = 0
+
+	parquetMagic        = "PAR1"
+	parquetRowGroupSize = 10000
+)
+
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type parquetRowGroup struct {
+	columns []parquetColumnChunk
+	size    int64
+	rows    int64
+}
+
+// parquetWriter 不依赖第三方库的 Parquet 写入器：所有列均为 REQUIRED，PLAIN 编码且不压缩
+// 每累计 parquetRowGroupSize 行写出一个行组，内存占用与导出总行数无关
+type parquetWriter struct {
+	writer    io.Writer
+	columns   []Column
+	buffers   []bytes.Buffer
+	bits      [][]bool
+	rows      int64
+	offset    int64
+	rowGroups []parquetRowGroup
+	err       error
+}
+
+func newParquetWriter(w io.Writer, columns []Column) *parquetWriter {
+	return &parquetWriter{
+		writer:  w,
+		columns: columns,
+		buffers: make([]bytes.Buffer, len(columns)),
+		bits:    make([][]bool, len(columns)),
+	}
+}
+
+func (w *parquetWriter) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.writer.Write(data)
+	w.offset += int64(n)
+	w.err = err
+}
+
+func (w *parquetWriter) WriteRow(values []any) error {
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("列数不匹配: %d != %d", len(values), len(w.columns))
+	}
+	if w.offset == 0 {
+		w.write([]byte(parquetMagic))
+	}
+	for i, column := range w.columns {
+		buf := &w.buffers[i]
+		switch column.Type {
+		case ColumnTypeInt64:
+			v, err := toInt64(values[i])
+			if err != nil {
+				return fmt.Errorf("列 %s: %w", column.Name, err)
+			}
+			_ = binary.Write(buf, binary.LittleEndian, v)
+		case ColumnTypeString:
+			v, ok := values[i].(string)
+			if !ok {
+				return fmt.Errorf("列 %s: 期望字符串，实际为 %T", column.Name, values[i])
+			}
+			_ = binary.Write(buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+		case ColumnTypeBool:
+			v, ok := values[i].(bool)
+			if !ok {
+				return fmt.Errorf("列 %s: 期望布尔值，实际为 %T", column.Name, values[i])
+			}
+			w.bits[i] = append(w.bits[i], v)
+		}
+	}
+	w.rows++
+	if w.rows >= parquetRowGroupSize {
+		w.flushRowGroup()
+	}
+	return w.err
+}
+
+func (w *parquetWriter) flushRowGroup() {
+	if w.rows == 0 {
+		return
+	}
+	rowGroup := parquetRowGroup{rows: w.rows}
+	for i, column := range w.columns {
+		data := w.buffers[i].Bytes()
+		if column.Type == ColumnTypeBool {
+			data = packBits(w.bits[i])
+		}
+		header := encodePageHeader(len(data), w.rows)
+		chunk := parquetColumnChunk{offset: w.offset, values: w.rows, size: int64(len(header) + len(data))}
+		w.write(header)
+		w.write(data)
+		rowGroup.columns = append(rowGroup.columns, chunk)
+		rowGroup.size += chunk.size
+		w.buffers[i].Reset()
+		w.bits[i] = w.bits[i][:0]
+	}
+	w.rowGroups = append(w.rowGroups, rowGroup)
+	w.rows = 0
+}
+
+func (w *parquetWriter) Close() error {
+	if w.offset == 0 {
+		w.write([]byte(parquetMagic))
+	}
+	w.flushRowGroup()
+	footer := w.encodeFileMetaData()
+	w.write(footer)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	w.write(length[:])
+	w.write([]byte(parquetMagic))
+	return w.err
+}
+
+// packBits 布尔值 PLAIN 编码：按位打包，低位在前
+func packBits(values []bool) []byte {
+	data := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			data[i/8] |= 1 << (i % 8)
+		}
+	}
+	return data
+}
+
+func encodePageHeader(size int, rows int64) []byte {
+	s := newThriftStruct()
+	s.i32(1, parquetPageTypeData)
+	s.i32(2, int32(size))
+	s.i32(3, int32(size))
+	s.structField(5, func(s *thriftStruct) {
+		s.i32(1, int32(rows))
+		s.i32(2, parquetEncodingPlain)
+		s.i32(3, parquetEncodingRLE)
+		s.i32(4, parquetEncodingRLE)
+	})
+	return s.bytes()
+}
+
+func (w *parquetWriter) encodeFileMetaData() []byte {
+	var totalRows int64
+	for _, rowGroup := range w.rowGroups {
+		totalRows += rowGroup.rows
+	}
+	s := newThriftStruct()
+	s.i32(1, 1)
+	s.structList(2, len(w.columns)+1, func(i int, s *thriftStruct) {
+		if i == 0 {
+			s.binary(4, "schema")
+			s.i32(5, int32(len(w.columns)))
+			return
+		}
+		column := w.columns[i-1]
+		s.i32(1, parquetPhysicalType(column.Type))
+		s.i32(3, parquetRepetitionRequired)
+		s.binary(4, column.Name)
+		if column.Type == ColumnTypeString {
+			s.i32(6, parquetConvertedTypeUTF8)
+		}
+	})
+	s.i64(3, totalRows)
+	s.structList(4, len(w.rowGroups), func(i int, s *thriftStruct) {
+		rowGroup := w.rowGroups[i]
+		s.structList(1, len(rowGroup.columns), func(j int, s *thriftStruct) {
+			chunk := rowGroup.columns[j]
+			column := w.columns[j]
+			s.i64(2, chunk.offset)
+			s.structField(3, func(s *thriftStruct) {
+				s.i32(1, parquetPhysicalType(column.Type))
+				s.i32List(2, []int32{parquetEncodingPlain})
+				s.stringList(3, []string{column.Name})
+				s.i32(4, parquetCodecUncompressed)
+				s.i64(5, chunk.values)
+				s.i64(6, chunk.size)
+				s.i64(7, chunk.size)
+				s.i64(9, chunk.offset)
+			})
+		})
+		s.i64(2, rowGroup.size)
+		s.i64(3, rowGroup.rows)
+	})
+	s.binary(6, "new-api")
+	return s.bytes()
+}
+
+func parquetPhysicalType(columnType ColumnType) int32 {
+	switch columnType {
+	case ColumnTypeInt64:
+		return parquetTypeInt64
+	case ColumnTypeBool:
+		return parquetTypeBoolean
+	}
+	return parquetTypeByteArray
+}
+
+// Thrift compact protocol 的字段类型
+const (
+	thriftTypeI32    = 5
+	thriftTypeI64    = 6
+	thriftTypeBinary = 8
+	thriftTypeList   = 9
+	thriftTypeStruct = 12
+)
+
+// thriftStruct 按 Thrift compact protocol 编码一个结构体，只实现 Parquet 元数据用到的类型
+type thriftStruct struct {
+	buf    *bytes.Buffer
+	lastId int16
+}
+
+func newThriftStruct() *thriftStruct {
+	return &thriftStruct{buf: &bytes.Buffer{}}
+}
+
+func (s *thriftStruct) bytes() []byte {
+	s.buf.WriteByte(0)
+	return s.buf.Bytes()
+}
+
+func (s *thriftStruct) fieldHeader(id int16, fieldType byte) {
+	delta := id - s.lastId
+	if delta > 0 && delta <= 15 {
+		s.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		s.buf.WriteByte(fieldType)
+		s.varint(zigzag(int64(id)))
+	}
+	s.lastId = id
+}
+
+func (s *thriftStruct) varint(v uint64) {
+	var data [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(data[:], v)
+	s.buf.Write(data[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (s *thriftStruct) listHeader(size int, elemType byte) {
+	if size < 15 {
+		s.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	s.buf.WriteByte(0xF0 | elemType)
+	s.varint(uint64(size))
+}
+
+func (s *thriftStruct) i32(id int16, v int32) {
+	s.fieldHeader(id, thriftTypeI32)
+	s.varint(zigzag(int64(v)))
+}
+
+func (s *thriftStruct) i64(id int16, v int64) {
+	s.fieldHeader(id, thriftTypeI64)
+	s.varint(zigzag(v))
+}
+
+func (s *thriftStruct) binary(id int16, v string) {
+	s.fieldHeader(id, thriftTypeBinary)
+	s.varint(uint64(len(v)))
+	s.buf.WriteString(v)
+}
+
+func (s *thriftStruct) structField(id int16, encode func(s *thriftStruct)) {
+	s.fieldHeader(id, thriftTypeStruct)
+	s.nested(encode)
+}
+
+func (s *thriftStruct) nested(encode func(s *thriftStruct)) {
+	child := &thriftStruct{buf: s.buf}
+	encode(child)
+	s.buf.WriteByte(0)
+}
+
+func (s *thriftStruct) structList(id int16, size int, encode func(i int, s *thriftStruct)) {
+	s.fieldHeader(id, thriftTypeList)
+	s.listHeader(size, thriftTypeStruct)
+	for i := 0; i < size; i++ {
+		s.nested(func(s *thriftStruct) { encode(i, s) })
+	}
+}
+
+func (s *thriftStruct) i32List(id int16, values []int32) {
+	s.fieldHeader(id, thriftTypeList)
+	s.listHeader(len(values), thriftTypeI32)
+	for _, v := range values {
+		s.varint(zigzag(int64(v)))
+	}
+}
+
+func (s *thriftStruct) stringList(id int16, values []string) {
+	s.fieldHeader(id, thriftTypeList)
+	s.listHeader(len(values), thriftTypeBinary)
+	for _, v := range values {
+		s.varint(uint64(len(v)))
+		s.buf.WriteString(v)
+	}
+}
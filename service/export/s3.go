@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// S3Client 上传文件到 S3 兼容的对象存储（AWS S3、MinIO、R2 等），使用 SigV4 签名的 PutObject 请求
+type S3Client struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyId     string
+	SecretAccessKey string
+	PathStyle       bool // MinIO 等自建存储通常需要路径风格的地址
+	HTTPClient      *http.Client
+}
+
+func (s *S3Client) objectUrl(key string) (string, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return "", err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return "", fmt.Errorf("无效的 S3 地址: %s", s.Endpoint)
+	}
+	segments := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	objectPath := strings.Join(segments, "/")
+	if s.PathStyle {
+		return fmt.Sprintf("%s://%s%s/%s/%s", endpoint.Scheme, endpoint.Host, endpoint.Path, s.Bucket, objectPath), nil
+	}
+	return fmt.Sprintf("%s://%s.%s%s/%s", endpoint.Scheme, s.Bucket, endpoint.Host, endpoint.Path, objectPath), nil
+}
+
+// PutFile 上传本地文件，返回对象地址
+func (s *S3Client) PutFile(ctx context.Context, key string, path string, contentType string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return s.putObject(ctx, key, io.NopCloser(file), size, hex.EncodeToString(hash.Sum(nil)), contentType)
+}
+
+// PutBytes 上传内存中的数据，返回对象地址
+func (s *S3Client) PutBytes(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	hash := sha256.Sum256(data)
+	return s.putObject(ctx, key, io.NopCloser(bytes.NewReader(data)), int64(len(data)), hex.EncodeToString(hash[:]), contentType)
+}
+
+func (s *S3Client) putObject(ctx context.Context, key string, body io.ReadCloser, size int64, payloadHash string, contentType string) (string, error) {
+	objectUrl, err := s.objectUrl(key)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectUrl, body)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.do(ctx, req, payloadHash)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("上传到 S3 失败，状态码 %d: %s", resp.StatusCode, string(body))
+	}
+	return objectUrl, nil
+}
+
+// GetObject 下载对象，调用方负责关闭返回的响应体
+func (s *S3Client) GetObject(ctx context.Context, key string) (*http.Response, error) {
+	objectUrl, err := s.objectUrl(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("从 S3 下载失败，状态码 %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// DeleteObject 删除对象，对象不存在时同样视为成功
+func (s *S3Client) DeleteObject(ctx context.Context, key string) error {
+	objectUrl, err := s.objectUrl(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, objectUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("从 S3 删除失败，状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// emptyPayloadHash 空请求体的 SHA256，用于 GET、DELETE 请求的签名
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// do 对请求进行 SigV4 签名并发送
+func (s *S3Client) do(ctx context.Context, req *http.Request, payloadHash string) (*http.Response, error) {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	region := s.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	credentials := aws.Credentials{AccessKeyID: s.AccessKeyId, SecretAccessKey: s.SecretAccessKey}
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, payloadHash, "s3", region, time.Now()); err != nil {
+		return nil, err
+	}
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
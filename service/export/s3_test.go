@@ -0,0 +1,98 @@
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 模拟 MinIO 的 PutObject 接口
+func TestS3ClientPutFile(t *testing.T) {
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		hash := sha256.Sum256(body)
+		if r.Method != http.MethodPut ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") ||
+			r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		objects[r.URL.Path] = body
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "logs.csv")
+	require.NoError(t, os.WriteFile(path, []byte("id\n1\n"), 0644))
+	client := &S3Client{
+		Endpoint:        server.URL,
+		Bucket:          "reports",
+		AccessKeyId:     "minio",
+		SecretAccessKey: "minio123",
+		PathStyle:       true,
+	}
+	location, err := client.PutFile(context.Background(), "usage/2026-10-17/logs.csv", path, ContentType(FormatCSV))
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/reports/usage/2026-10-17/logs.csv", location)
+	assert.Equal(t, []byte("id\n1\n"), objects["/reports/usage/2026-10-17/logs.csv"])
+
+	client.AccessKeyId = "other"
+	_, err = client.PutFile(context.Background(), "usage/logs.csv", path, ContentType(FormatCSV))
+	assert.Error(t, err)
+}
+
+func TestS3ClientObjectLifecycle(t *testing.T) {
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := &S3Client{
+		Endpoint:        server.URL,
+		Bucket:          "media",
+		AccessKeyId:     "minio",
+		SecretAccessKey: "minio123",
+		PathStyle:       true,
+	}
+	ctx := context.Background()
+	_, err := client.PutBytes(ctx, "ab/abc.png", []byte("png"), "image/png")
+	require.NoError(t, err)
+
+	resp, err := client.GetObject(ctx, "ab/abc.png")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, []byte("png"), data)
+
+	require.NoError(t, client.DeleteObject(ctx, "ab/abc.png"))
+	_, err = client.GetObject(ctx, "ab/abc.png")
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const leaderKeyPrefix = "leader:"
+
+// renewLeaderScript 仅当锁仍属于当前节点时续期
+var renewLeaderScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseLeaderScript 仅当锁仍属于当前节点时释放
+var releaseLeaderScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// LeaderElector 基于 Redis 锁的主节点选举，同一名称的选举在集群内同时只有一个节点成为 leader
+// 未启用 Redis 时退化为由 NODE_TYPE 决定，主节点始终为 leader
+type LeaderElector struct {
+	name     string
+	nodeId   string
+	ttl      time.Duration
+	isLeader atomic.Bool
+	once     sync.Once
+}
+
+var (
+	leaderElectors     = make(map[string]*LeaderElector)
+	leaderElectorsLock sync.Mutex
+)
+
+// GetLeaderElector 获取指定名称的选举器，首次获取时在后台开始参与选举
+func GetLeaderElector(name string) *LeaderElector {
+	leaderElectorsLock.Lock()
+	defer leaderElectorsLock.Unlock()
+	elector, ok := leaderElectors[name]
+	if !ok {
+		elector = NewLeaderElector(name, 30*time.Second)
+		leaderElectors[name] = elector
+	}
+	elector.once.Do(func() {
+		go elector.Run(context.Background())
+	})
+	return elector
+}
+
+func NewLeaderElector(name string, ttl time.Duration) *LeaderElector {
+	hostname, _ := os.Hostname()
+	return &LeaderElector{
+		name:   name,
+		nodeId: fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), common.GetRandomString(8)),
+		ttl:    ttl,
+	}
+}
+
+// IsLeader 当前节点是否为 leader
+func (e *LeaderElector) IsLeader() bool {
+	if !common.RedisEnabled {
+		return common.IsMasterNode
+	}
+	return e.isLeader.Load()
+}
+
+// Run 周期性地竞选或续期，直到 ctx 结束后主动释放
+func (e *LeaderElector) Run(ctx context.Context) {
+	if !common.RedisEnabled {
+		return
+	}
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		e.tick(ctx)
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaderElector) key() string {
+	return leaderKeyPrefix + e.name
+}
+
+// tick 执行一次竞选或续期
+func (e *LeaderElector) tick(ctx context.Context) {
+	wasLeader := e.isLeader.Load()
+	var leader bool
+	var err error
+	if wasLeader {
+		var renewed int64
+		renewed, err = renewLeaderScript.Run(ctx, common.RDB, []string{e.key()}, e.nodeId, e.ttl.Milliseconds()).Int64()
+		leader = renewed == 1
+	} else {
+		leader, err = common.RDB.SetNX(ctx, e.key(), e.nodeId, e.ttl).Result()
+	}
+	if err != nil {
+		// Redis 异常时无法确认锁归属，放弃 leader 身份，避免多个节点同时执行
+		common.SysError(fmt.Sprintf("leader election %s failed: %s", e.name, err.Error()))
+		leader = false
+	}
+	e.isLeader.Store(leader)
+	if leader != wasLeader {
+		if leader {
+			common.SysLog(fmt.Sprintf("node %s became leader of %s", e.nodeId, e.name))
+		} else {
+			common.SysLog(fmt.Sprintf("node %s lost leadership of %s", e.nodeId, e.name))
+		}
+	}
+}
+
+func (e *LeaderElector) release() {
+	if !e.isLeader.Load() {
+		return
+	}
+	e.isLeader.Store(false)
+	_ = releaseLeaderScript.Run(context.Background(), common.RDB, []string{e.key()}, e.nodeId).Err()
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderElection(t *testing.T) {
+	server := miniredis.RunT(t)
+	previousRDB, previousEnabled := common.RDB, common.RedisEnabled
+	common.RDB = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	common.RedisEnabled = true
+	defer func() {
+		common.RDB, common.RedisEnabled = previousRDB, previousEnabled
+	}()
+
+	ctx := context.Background()
+	first := NewLeaderElector("test", 3*time.Second)
+	second := NewLeaderElector("test", 3*time.Second)
+	first.tick(ctx)
+	second.tick(ctx)
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+
+	// 续期后其他节点仍无法抢占
+	first.tick(ctx)
+	second.tick(ctx)
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+
+	// leader 失联、锁过期后由其他节点接替
+	server.FastForward(4 * time.Second)
+	second.tick(ctx)
+	first.tick(ctx)
+	assert.True(t, second.IsLeader())
+	assert.False(t, first.IsLeader())
+
+	// 主动释放后其他节点可立即接替
+	second.release()
+	first.tick(ctx)
+	assert.True(t, first.IsLeader())
+}
+
+func TestTaskPollInterval(t *testing.T) {
+	pollSetting := operation_setting.GetTaskPollSetting()
+	previous := *pollSetting
+	defer func() { *pollSetting = previous }()
+	pollSetting.IntervalSeconds = 10
+	pollSetting.PlatformIntervalSeconds = map[string]int{"suno": 20}
+	pollSetting.MaxIntervalSeconds = 100
+
+	assert.Equal(t, 10, TaskPollInterval("kling", 0))
+	assert.Equal(t, 40, TaskPollInterval("kling", 2))
+	assert.Equal(t, 100, TaskPollInterval("kling", 4))
+	assert.Equal(t, 20, TaskPollInterval("suno", 0))
+	assert.Equal(t, 100, TaskPollInterval("suno", 1000))
+}
@@ -20,6 +20,12 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 	other["cache_ratio"] = cacheRatio
 	other["model_price"] = modelPrice
 	other["user_group_ratio"] = userGroupRatio
+	appendPricingScheduleInfo(other, relayInfo.PriceData.GroupRatioInfo)
+	appendPriceOverrideInfo(other, relayInfo.PriceData.GroupRatioInfo)
+	if relayInfo.PriceData.PricingTier > 0 {
+		other["pricing_tier"] = relayInfo.PriceData.PricingTier
+		other["pricing_tier_max_tokens"] = relayInfo.PriceData.PricingTierMaxTokens
+	}
 	other["frt"] = float64(relayInfo.FirstResponseTime.UnixMilli() - relayInfo.StartTime.UnixMilli())
 	if relayInfo.ReasoningEffort != "" {
 		other["reasoning_effort"] = relayInfo.ReasoningEffort
@@ -85,5 +91,28 @@ func GenerateMjOtherInfo(priceData types.PerCallPriceData) map[string]interface{
 	if priceData.GroupRatioInfo.HasSpecialRatio {
 		other["user_group_ratio"] = priceData.GroupRatioInfo.GroupSpecialRatio
 	}
+	appendPricingScheduleInfo(other, priceData.GroupRatioInfo)
+	appendPriceOverrideInfo(other, priceData.GroupRatioInfo)
 	return other
 }
+
+// appendPricingScheduleInfo 记录命中的分时定价规则，分组倍率中已包含该倍率
+func appendPricingScheduleInfo(other map[string]interface{}, groupRatioInfo types.GroupRatioInfo) {
+	if groupRatioInfo.PricingSchedule == "" {
+		return
+	}
+	other["time_multiplier"] = groupRatioInfo.TimeMultiplier
+	other["pricing_schedule"] = groupRatioInfo.PricingSchedule
+}
+
+// appendPriceOverrideInfo 记录命中的用户协议价
+func appendPriceOverrideInfo(other map[string]interface{}, groupRatioInfo types.GroupRatioInfo) {
+	override := groupRatioInfo.PriceOverride
+	if override == nil {
+		return
+	}
+	other["price_override_id"] = override.Id
+	other["price_override_model"] = override.ModelName
+	other["price_override_type"] = override.Type
+	other["price_override_value"] = override.Value
+}
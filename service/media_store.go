@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service/export"
+	"one-api/setting/operation_setting"
+	"one-api/setting/system_setting"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MediaSourceTask       = "task"
+	MediaSourceMidjourney = "midjourney"
+	MediaSourceImage      = "image"
+)
+
+var ErrMediaQuotaExceeded = errors.New("media storage quota exceeded")
+
+// ErrMediaLocalBackendMultiNode 本地存储的文件只能由写入的节点读取，多节点部署必须使用 S3
+var ErrMediaLocalBackendMultiNode = errors.New("local media storage is not supported on slave nodes, use s3 for multi-node deployments")
+
+// MediaStorageEnabled 是否转存生成结果，从节点上配置为本地存储时不转存，结果仍使用上游地址
+func MediaStorageEnabled() bool {
+	setting := operation_setting.GetMediaStorageSetting()
+	return setting.Enabled && checkMediaBackend(setting) == nil
+}
+
+// checkMediaBackend 从节点（NODE_TYPE=slave）说明是多节点部署，拒绝使用本地存储
+func checkMediaBackend(setting *operation_setting.MediaStorageSetting) error {
+	if setting.Backend == operation_setting.MediaStorageBackendLocal && !common.IsMasterNode {
+		return ErrMediaLocalBackendMultiNode
+	}
+	return nil
+}
+
+func newMediaS3Client(setting *operation_setting.MediaStorageSetting) *export.S3Client {
+	return &export.S3Client{
+		Endpoint:        setting.S3Endpoint,
+		Region:          setting.S3Region,
+		Bucket:          setting.S3Bucket,
+		AccessKeyId:     setting.S3AccessKeyId,
+		SecretAccessKey: setting.S3SecretAccessKey,
+		PathStyle:       setting.S3PathStyle,
+	}
+}
+
+// fetchMediaContent 读取 data URL 或下载远程地址的内容，超过大小上限时返回错误
+func fetchMediaContent(originUrl string, maxSize int64) ([]byte, string, error) {
+	if strings.HasPrefix(originUrl, "data:") {
+		header, encoded, found := strings.Cut(strings.TrimPrefix(originUrl, "data:"), ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return nil, "", errors.New("invalid data url")
+		}
+		if maxSize > 0 && int64(base64.StdEncoding.DecodedLen(len(encoded))) > maxSize+2 {
+			return nil, "", fmt.Errorf("media size exceeds %d bytes", maxSize)
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, strings.TrimSuffix(header, ";base64"), nil
+	}
+	resp, err := DoDownloadRequest(originUrl, "media storage")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download media failed, status code %d", resp.StatusCode)
+	}
+	reader := io.Reader(resp.Body)
+	if maxSize > 0 {
+		reader = io.LimitReader(resp.Body, maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, "", fmt.Errorf("media size exceeds %d bytes", maxSize)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// StoreMediaFromUrl 下载生成结果并转存，originUrl 可以是远程地址或 data URL
+func StoreMediaFromUrl(userId int, sourceType string, sourceId string, originUrl string) (*model.Media, error) {
+	maxSize := int64(operation_setting.GetMediaStorageSetting().MaxFileSizeMB) << 20
+	data, contentType, err := fetchMediaContent(originUrl, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return SaveMedia(userId, sourceType, sourceId, data, contentType)
+}
+
+// SaveMedia 将内容写入配置的存储后端并记录，超出用户存储上限时返回 ErrMediaQuotaExceeded
+func SaveMedia(userId int, sourceType string, sourceId string, data []byte, contentType string) (*model.Media, error) {
+	setting := operation_setting.GetMediaStorageSetting()
+	if err := checkMediaBackend(setting); err != nil {
+		return nil, err
+	}
+	if setting.MaxFileSizeMB > 0 && int64(len(data)) > int64(setting.MaxFileSizeMB)<<20 {
+		return nil, fmt.Errorf("media size exceeds %d MB", setting.MaxFileSizeMB)
+	}
+	if setting.UserStorageMB > 0 {
+		used, err := model.GetUserMediaSize(userId)
+		if err != nil {
+			return nil, err
+		}
+		if used+int64(len(data)) > int64(setting.UserStorageMB)<<20 {
+			return nil, ErrMediaQuotaExceeded
+		}
+	}
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(data)
+	}
+	media := &model.Media{
+		UserId:      userId,
+		Key:         common.GetUUID(),
+		SourceType:  sourceType,
+		SourceId:    sourceId,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Backend:     setting.Backend,
+	}
+	name := media.Key
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		name += exts[0]
+	}
+	switch setting.Backend {
+	case operation_setting.MediaStorageBackendLocal:
+		media.Path = filepath.Join(setting.LocalDir, media.Key[:2], name)
+		if err := os.MkdirAll(filepath.Dir(media.Path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(media.Path, data, 0644); err != nil {
+			return nil, err
+		}
+	case operation_setting.MediaStorageBackendS3:
+		media.Path = path.Join(setting.S3Prefix, media.Key[:2], name)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if _, err := newMediaS3Client(setting).PutBytes(ctx, media.Path, data, contentType); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的媒体存储方式: %s", setting.Backend)
+	}
+	if setting.RetentionDays > 0 {
+		media.ExpiresAt = time.Now().Unix() + int64(setting.RetentionDays)*24*3600
+	}
+	if err := media.Insert(); err != nil {
+		_ = removeMediaObject(media)
+		return nil, err
+	}
+	return media, nil
+}
+
+// OpenMedia 打开已转存的内容，调用方负责关闭
+func OpenMedia(media *model.Media) (io.ReadCloser, error) {
+	switch media.Backend {
+	case operation_setting.MediaStorageBackendLocal:
+		return os.Open(media.Path)
+	case operation_setting.MediaStorageBackendS3:
+		resp, err := newMediaS3Client(operation_setting.GetMediaStorageSetting()).GetObject(context.Background(), media.Path)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Body, nil
+	default:
+		return nil, fmt.Errorf("不支持的媒体存储方式: %s", media.Backend)
+	}
+}
+
+func removeMediaObject(media *model.Media) error {
+	switch media.Backend {
+	case operation_setting.MediaStorageBackendLocal:
+		if err := os.Remove(media.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	case operation_setting.MediaStorageBackendS3:
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		return newMediaS3Client(operation_setting.GetMediaStorageSetting()).DeleteObject(ctx, media.Path)
+	default:
+		return fmt.Errorf("不支持的媒体存储方式: %s", media.Backend)
+	}
+}
+
+// DeleteMedia 删除存储内容及记录
+func DeleteMedia(media *model.Media) error {
+	if err := removeMediaObject(media); err != nil {
+		return err
+	}
+	return media.Delete()
+}
+
+func signMedia(key string, expires int64) string {
+	return common.GenerateHMAC(key + ":" + strconv.FormatInt(expires, 10))
+}
+
+// SignMediaUrl 生成带过期时间与签名的网关访问地址
+func SignMediaUrl(key string) string {
+	expires := time.Now().Unix() + int64(operation_setting.GetMediaStorageSetting().UrlExpireSeconds)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signMedia(key, expires))
+	return fmt.Sprintf("%s/media/%s?%s", system_setting.ServerAddress, key, query.Encode())
+}
+
+// VerifyMediaSignature 校验访问地址的签名与过期时间
+func VerifyMediaSignature(key string, expires string, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || expiresAt < time.Now().Unix() {
+		return false
+	}
+	return hmac.Equal([]byte(signMedia(key, expiresAt)), []byte(signature))
+}
+
+// StoreTaskMedia 转存成功任务的结果并记录到任务属性中，由调用方负责保存任务
+func StoreTaskMedia(task *model.Task, resultUrl string) error {
+	if !MediaStorageEnabled() || task.Status != model.TaskStatusSuccess || task.Properties.MediaKey != "" || resultUrl == "" {
+		return nil
+	}
+	media, err := StoreMediaFromUrl(task.UserId, MediaSourceTask, task.TaskID, resultUrl)
+	if err != nil {
+		return err
+	}
+	task.Properties.MediaKey = media.Key
+	return nil
+}
+
+// TaskResultUrl 返回任务结果的对外地址，已转存时使用网关签名地址
+func TaskResultUrl(task *model.Task) string {
+	if task.Properties.MediaKey != "" {
+		return SignMediaUrl(task.Properties.MediaKey)
+	}
+	return task.FailReason
+}
+
+// StartMediaCleanupTask 定时删除超过保留期的转存内容
+func StartMediaCleanupTask() {
+	for {
+		time.Sleep(time.Hour)
+		if !MediaStorageEnabled() {
+			continue
+		}
+		for {
+			medias, err := model.GetExpiredMedia(time.Now().Unix(), 100)
+			if err != nil {
+				common.SysError("failed to get expired media: " + err.Error())
+				break
+			}
+			deleted := 0
+			for _, media := range medias {
+				if err := DeleteMedia(media); err != nil {
+					common.SysError(fmt.Sprintf("failed to delete media %s: %s", media.Key, err.Error()))
+					continue
+				}
+				deleted++
+			}
+			if deleted == 0 {
+				break
+			}
+		}
+	}
+}
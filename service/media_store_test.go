@@ -0,0 +1,117 @@
+package service
+
+import (
+	"io"
+	"net/url"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// setupSQLiteTestDB 使用内存 SQLite 替换全局 DB，测试结束后恢复
+func setupSQLiteTestDB(t *testing.T, models ...any) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	// 内存数据库每个连接相互独立，只使用一个连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	originDB, usingSQLite := model.DB, common.UsingSQLite
+	model.DB, common.UsingSQLite = db, true
+	t.Cleanup(func() {
+		model.DB, common.UsingSQLite = originDB, usingSQLite
+		sqlDB.Close()
+	})
+}
+
+// setupLocalMediaStorage 启用写入临时目录的本地存储，测试结束后恢复配置
+func setupLocalMediaStorage(t *testing.T) *operation_setting.MediaStorageSetting {
+	setupSQLiteTestDB(t, &model.Media{})
+	setting := operation_setting.GetMediaStorageSetting()
+	origin, isMasterNode := *setting, common.IsMasterNode
+	t.Cleanup(func() {
+		*setting, common.IsMasterNode = origin, isMasterNode
+	})
+	setting.Enabled = true
+	setting.Backend = operation_setting.MediaStorageBackendLocal
+	setting.LocalDir = t.TempDir()
+	setting.UserStorageMB = 0
+	common.IsMasterNode = true
+	return setting
+}
+
+func TestSaveMediaLocal(t *testing.T) {
+	setting := setupLocalMediaStorage(t)
+	media, err := SaveMedia(1, MediaSourceImage, "req_1", []byte("\x89PNG\r\n\x1a\nimage"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", media.ContentType)
+	assert.True(t, strings.HasPrefix(media.Path, setting.LocalDir))
+	assert.True(t, strings.HasSuffix(media.Path, ".png"))
+
+	saved := model.GetMediaBySource(MediaSourceImage, "req_1")
+	require.NotNil(t, saved)
+	assert.Equal(t, media.Key, saved.Key)
+	reader, err := OpenMedia(saved)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, reader.Close())
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG\r\n\x1a\nimage", string(data))
+
+	require.NoError(t, DeleteMedia(saved))
+	_, err = OpenMedia(saved)
+	assert.Error(t, err)
+	assert.Nil(t, model.GetMediaBySource(MediaSourceImage, "req_1"))
+}
+
+func TestSaveMediaUserStorageLimit(t *testing.T) {
+	setting := setupLocalMediaStorage(t)
+	setting.UserStorageMB = 1
+	_, err := SaveMedia(1, MediaSourceImage, "req_1", make([]byte, 600<<10), "image/png")
+	require.NoError(t, err)
+	_, err = SaveMedia(1, MediaSourceImage, "req_2", make([]byte, 600<<10), "image/png")
+	assert.ErrorIs(t, err, ErrMediaQuotaExceeded)
+	assert.Nil(t, model.GetMediaBySource(MediaSourceImage, "req_2"))
+
+	// 上限按用户统计，其他用户不受影响
+	_, err = SaveMedia(2, MediaSourceImage, "req_3", make([]byte, 600<<10), "image/png")
+	assert.NoError(t, err)
+}
+
+func TestSaveMediaLocalOnSlaveNode(t *testing.T) {
+	setupLocalMediaStorage(t)
+	common.IsMasterNode = false
+	assert.False(t, MediaStorageEnabled())
+	_, err := SaveMedia(1, MediaSourceImage, "req_1", []byte("image"), "image/png")
+	assert.ErrorIs(t, err, ErrMediaLocalBackendMultiNode)
+
+	operation_setting.GetMediaStorageSetting().Backend = operation_setting.MediaStorageBackendS3
+	assert.True(t, MediaStorageEnabled())
+}
+
+func TestSignMediaUrl(t *testing.T) {
+	common.CryptoSecret = "test-secret"
+	signed, err := url.Parse(SignMediaUrl("abc"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(signed.Path, "/media/abc"))
+
+	query := signed.Query()
+	assert.True(t, VerifyMediaSignature("abc", query.Get("expires"), query.Get("signature")))
+	assert.False(t, VerifyMediaSignature("abd", query.Get("expires"), query.Get("signature")))
+	assert.False(t, VerifyMediaSignature("abc", query.Get("expires")+"0", query.Get("signature")))
+
+	// 过期的地址即使签名正确也拒绝访问
+	expired := time.Now().Unix() - 1
+	assert.False(t, VerifyMediaSignature("abc", strconv.FormatInt(expired, 10), signMedia("abc", expired)))
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"one-api/common/metrics"
+	relaycommon "one-api/relay/common"
+	"time"
+)
+
+// RecordRelayUsageMetrics 在请求结算时记录 token 用量、首字延迟、生成速度与结算额度
+// 流式请求的生成速度从首个响应开始计算，非流式请求按整个请求耗时计算
+func RecordRelayUsageMetrics(relayInfo *relaycommon.RelayInfo, promptTokens int, completionTokens int, quota int) {
+	if !metrics.Enabled() {
+		return
+	}
+	var ttft time.Duration
+	generation := time.Since(relayInfo.StartTime)
+	if relayInfo.IsStream && relayInfo.HasSendResponse() {
+		ttft = relayInfo.FirstResponseTime.Sub(relayInfo.StartTime)
+		generation = time.Since(relayInfo.FirstResponseTime)
+	}
+	metrics.ObserveRelayUsage(relayInfo.ChannelType, relayInfo.OriginModelName, relayInfo.ChannelId, promptTokens, completionTokens, ttft, generation)
+	metrics.RecordQuotaOperation("settle", quota)
+}
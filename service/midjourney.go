@@ -1,14 +1,18 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"one-api/common"
 	"one-api/constant"
 	"one-api/dto"
+	"one-api/model"
 	relayconstant "one-api/relay/constant"
 	"one-api/setting"
 	"strconv"
@@ -256,3 +260,125 @@ func DoMidjourneyHttpRequest(c *gin.Context, timeout time.Duration, fullRequestU
 		Response:   midjResponse,
 	}, responseBody, nil
 }
+
+// RetryMidjourneyTask 使用原提示词向原渠道重新提交失败的绘图任务，提交成功后重新扣除额度并复用原任务记录
+func RetryMidjourneyTask(c *gin.Context, task *model.Midjourney) (err error) {
+	if task.Status != model.TaskStatusFailure {
+		return errors.New("只能重试失败的任务")
+	}
+	if task.Action != constant.MjActionImagine || task.Prompt == "" {
+		return errors.New("只支持重试有提示词的绘图任务")
+	}
+	channel, err := model.GetChannelById(task.ChannelId, true)
+	if err != nil {
+		return errors.New("获取渠道信息失败")
+	}
+	if channel.Status != common.ChannelStatusEnabled {
+		return errors.New("该任务所属渠道已被禁用")
+	}
+	// 管理员重试没有用户请求的上下文，从用户记录读取额度与后付费的信用额度
+	user, err := model.GetUserById(task.UserId, false)
+	if err != nil {
+		return err
+	}
+	if user.Quota+user.ToBaseUser().GetCreditLimit()-task.Quota < 0 {
+		return errors.New("用户额度不足")
+	}
+	// 先抢占任务再提交上游，并发重试同一任务时只有一次生效
+	claimed, err := model.ClaimMjTaskForRetry(task.Id)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errors.New("任务已在重试中或不是失败状态")
+	}
+	submitted := false
+	defer func() {
+		if !submitted {
+			if releaseErr := model.ReleaseMjTaskRetry(task.Id); releaseErr != nil {
+				common.SysError(fmt.Sprintf("failed to release midjourney retry %d: %s", task.Id, releaseErr.Error()))
+			}
+		}
+	}()
+
+	body, err := json.Marshal(map[string]string{"prompt": task.Prompt})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.GetBaseURL()+"/mj/submit/imagine", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("mj-api-secret", channel.Key)
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer CloseResponseBodyGracefully(resp)
+	var midjResponse dto.MidjourneyResponse
+	if err = json.NewDecoder(resp.Body).Decode(&midjResponse); err != nil {
+		return fmt.Errorf("解析上游响应失败: %w", err)
+	}
+	// 22 表示任务已进入排队
+	if (midjResponse.Code != 1 && midjResponse.Code != 22) || midjResponse.Result == "" {
+		return fmt.Errorf("上游提交失败: %s", midjResponse.Description)
+	}
+	submitted = true
+
+	if task.Quota != 0 {
+		if err = model.DecreaseUserQuota(task.UserId, task.Quota); err != nil {
+			return err
+		}
+	}
+	// 令牌可能已被删除，此时只扣除用户额度
+	tokenName := ""
+	if task.TokenId != 0 {
+		if token, err := model.GetTokenById(task.TokenId); err == nil {
+			tokenName = token.Name
+			if task.Quota != 0 {
+				if err = model.DecreaseTokenQuota(token.Id, token.Key, task.Quota); err != nil {
+					common.SysLog("error consuming token remain quota: " + err.Error())
+				}
+			}
+		}
+	}
+	oldMjId := task.MjId
+	task.MjId = midjResponse.Result
+	task.Code = midjResponse.Code
+	task.Description = midjResponse.Description
+	task.Status = ""
+	task.Progress = "0%"
+	task.FailReason = ""
+	task.SubmitTime = time.Now().UnixNano() / int64(time.Millisecond)
+	task.StartTime = 0
+	task.FinishTime = 0
+	task.ImageUrl = ""
+	task.Buttons = ""
+	task.Properties = ""
+	task.Seed = ""
+	if err = task.Update(); err != nil {
+		return err
+	}
+	// 与正常提交一样记录消费日志并累计用户、渠道的已用额度，日志归属任务所属用户而非操作的管理员
+	logCtx := c.Copy()
+	if username, err := model.GetUsernameById(task.UserId, false); err == nil {
+		logCtx.Set("username", username)
+	}
+	group, _ := model.GetUserGroup(task.UserId, false)
+	logContent := fmt.Sprintf("管理员重试绘图任务 %s，操作 %s，新任务 %s", oldMjId, task.Action, task.MjId)
+	model.RecordConsumeLog(logCtx, task.UserId, model.RecordConsumeLogParams{
+		ChannelId: task.ChannelId,
+		ModelName: CoverActionToModelName(task.Action),
+		TokenName: tokenName,
+		Quota:     task.Quota,
+		Content:   logContent,
+		TokenId:   task.TokenId,
+		Group:     group,
+	})
+	model.UpdateUserUsedQuotaAndRequestCount(task.UserId, task.Quota)
+	model.UpdateChannelUsedQuota(task.ChannelId, task.Quota)
+	return nil
+}
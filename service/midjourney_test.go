@@ -0,0 +1,131 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMjRetryTest 准备一个失败的绘图任务及其所属用户、令牌和指向 upstream 的渠道
+func setupMjRetryTest(t *testing.T, upstream http.HandlerFunc, user *model.User) *model.Midjourney {
+	setupSQLiteTestDB(t, &model.Midjourney{}, &model.User{}, &model.Token{}, &model.Channel{}, &model.Log{})
+	originLogDB, redisEnabled, batchUpdateEnabled, client := model.LOG_DB, common.RedisEnabled, common.BatchUpdateEnabled, httpClient
+	model.LOG_DB, common.RedisEnabled, common.BatchUpdateEnabled = model.DB, false, false
+	if httpClient == nil {
+		InitHttpClient()
+	}
+	t.Cleanup(func() {
+		model.LOG_DB, common.RedisEnabled, common.BatchUpdateEnabled, httpClient = originLogDB, redisEnabled, batchUpdateEnabled, client
+	})
+
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+	baseURL := server.URL
+	require.NoError(t, model.DB.Create(&model.Channel{Id: 1, Name: "mj", Key: "secret", Status: common.ChannelStatusEnabled, BaseURL: &baseURL}).Error)
+	user.Id, user.Username = 1, "mj_user"
+	require.NoError(t, model.DB.Create(user).Error)
+	require.NoError(t, model.DB.Create(&model.Token{Id: 1, UserId: 1, Key: "token_key", Name: "mj_token", RemainQuota: 1000}).Error)
+	task := &model.Midjourney{Id: 1, UserId: 1, TokenId: 1, ChannelId: 1, MjId: "old_id", Action: constant.MjActionImagine,
+		Prompt: "a cat", Status: model.TaskStatusFailure, Progress: "100%", FailReason: "upstream error", Quota: 100}
+	require.NoError(t, model.DB.Create(task).Error)
+	return task
+}
+
+func newMjRetryContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/mj/retry", nil)
+	return c
+}
+
+func TestRetryMidjourneyTaskBilling(t *testing.T) {
+	var submits atomic.Int32
+	setupMjRetryTest(t, func(w http.ResponseWriter, r *http.Request) {
+		submits.Add(1)
+		assert.Equal(t, "/mj/submit/imagine", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("mj-api-secret"))
+		w.Write([]byte(`{"code":1,"result":"new_id"}`))
+	}, &model.User{Quota: 1000})
+
+	// 并发重试同一任务时只有一次提交上游并扣费
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := RetryMidjourneyTask(newMjRetryContext(), model.GetMjByuId(1)); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), succeeded.Load())
+	assert.Equal(t, int32(1), submits.Load())
+
+	task := model.GetMjByuId(1)
+	assert.Equal(t, "new_id", task.MjId)
+	assert.Equal(t, "0%", task.Progress)
+	assert.Empty(t, task.FailReason)
+	user, err := model.GetUserById(1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 900, user.Quota)
+	token, err := model.GetTokenById(1)
+	require.NoError(t, err)
+	assert.Equal(t, 900, token.RemainQuota)
+	var logs []model.Log
+	require.NoError(t, model.DB.Where("type = ?", model.LogTypeConsume).Find(&logs).Error)
+	require.Len(t, logs, 1)
+	assert.Equal(t, 100, logs[0].Quota)
+	assert.Equal(t, "mj_user", logs[0].Username)
+
+	// 已重新提交的任务不再是失败状态，不能再次重试
+	assert.Error(t, RetryMidjourneyTask(newMjRetryContext(), model.GetMjByuId(1)))
+	assert.Equal(t, int32(1), submits.Load())
+}
+
+func TestRetryMidjourneyTaskUpstreamFailure(t *testing.T) {
+	setupMjRetryTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":4,"description":"banned prompt"}`))
+	}, &model.User{Quota: 1000})
+
+	assert.Error(t, RetryMidjourneyTask(newMjRetryContext(), model.GetMjByuId(1)))
+	// 提交失败时不扣费，并恢复为失败状态以便再次重试
+	task := model.GetMjByuId(1)
+	assert.Equal(t, model.TaskStatusFailure, task.Status)
+	assert.Equal(t, "old_id", task.MjId)
+	user, err := model.GetUserById(1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, user.Quota)
+}
+
+func TestRetryMidjourneyTaskCreditLimit(t *testing.T) {
+	// 预付费用户额度不足时拒绝重试
+	var submits atomic.Int32
+	setupMjRetryTest(t, func(w http.ResponseWriter, r *http.Request) {
+		submits.Add(1)
+		w.Write([]byte(`{"code":1,"result":"new_id"}`))
+	}, &model.User{Quota: 50})
+	assert.Error(t, RetryMidjourneyTask(newMjRetryContext(), model.GetMjByuId(1)))
+	assert.Equal(t, int32(0), submits.Load())
+	assert.Equal(t, model.TaskStatusFailure, model.GetMjByuId(1).Status)
+
+	// 后付费用户可以在信用额度内透支
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id = ?", 1).Updates(map[string]any{
+		"billing_mode": common.UserBillingModePostpaid,
+		"credit_limit": 100,
+	}).Error)
+	require.NoError(t, RetryMidjourneyTask(newMjRetryContext(), model.GetMjByuId(1)))
+	assert.Equal(t, int32(1), submits.Load())
+	user, err := model.GetUserById(1, false)
+	require.NoError(t, err)
+	assert.Equal(t, -50, user.Quota)
+}
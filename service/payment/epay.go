@@ -0,0 +1,168 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"one-api/setting/system_setting"
+	"path"
+	"strconv"
+
+	"github.com/Calcium-Ion/go-epay/epay"
+)
+
+type EpayProvider struct {
+}
+
+func (*EpayProvider) Name() string {
+	return ProviderEpay
+}
+
+func (*EpayProvider) Enabled() bool {
+	return operation_setting.PayAddress != "" && operation_setting.EpayId != "" && operation_setting.EpayKey != ""
+}
+
+func (*EpayProvider) UnitPrice() float64 {
+	return operation_setting.Price
+}
+
+// Currencies 易支付只支持人民币结算
+func (*EpayProvider) Currencies() []string {
+	return []string{"CNY"}
+}
+
+func (*EpayProvider) MinTopUp() int {
+	return operation_setting.MinTopUp
+}
+
+func GetEpayClient() *epay.Client {
+	if operation_setting.PayAddress == "" || operation_setting.EpayId == "" || operation_setting.EpayKey == "" {
+		return nil
+	}
+	withUrl, err := epay.NewClient(&epay.Config{
+		PartnerID: operation_setting.EpayId,
+		Key:       operation_setting.EpayKey,
+	}, operation_setting.PayAddress)
+	if err != nil {
+		return nil
+	}
+	return withUrl
+}
+
+func (*EpayProvider) CreateOrder(order *Order) (*PayResult, error) {
+	if !operation_setting.ContainsPayMethod(order.PaymentMethod) {
+		return nil, errors.New("支付方式不存在")
+	}
+	client := GetEpayClient()
+	if client == nil {
+		return nil, errors.New("当前管理员未配置支付信息")
+	}
+	returnUrl, _ := url.Parse(system_setting.ServerAddress + "/console/log")
+	notifyUrl, _ := url.Parse(service.GetCallbackAddress() + "/api/user/epay/notify")
+	uri, params, err := client.Purchase(&epay.PurchaseArgs{
+		Type:           order.PaymentMethod,
+		ServiceTradeNo: order.TradeNo,
+		Name:           order.Subject,
+		Money:          strconv.FormatFloat(order.Money, 'f', 2, 64),
+		Device:         epay.PC,
+		NotifyUrl:      notifyUrl,
+		ReturnUrl:      returnUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PayResult{PayLink: uri, Params: params}, nil
+}
+
+// VerifyWebhook 易支付以 GET 参数回调
+func (*EpayProvider) VerifyWebhook(r *http.Request, body []byte) (*Notification, error) {
+	client := GetEpayClient()
+	if client == nil {
+		return nil, errors.New("未找到易支付配置信息")
+	}
+	params := make(map[string]string)
+	for key := range r.URL.Query() {
+		params[key] = r.URL.Query().Get(key)
+	}
+	verifyInfo, err := client.Verify(params)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyInfo.VerifyStatus {
+		return nil, errors.New("易支付回调签名验证失败")
+	}
+	notification := &Notification{
+		TradeNo:    verifyInfo.ServiceTradeNo,
+		ExternalId: verifyInfo.TradeNo,
+		Status:     OrderStatusPending,
+		Event:      verifyInfo,
+	}
+	if verifyInfo.TradeStatus == epay.StatusTradeSuccess {
+		notification.Status = OrderStatusPaid
+	}
+	return notification, nil
+}
+
+func (*EpayProvider) WebhookResponse(success bool) (int, string) {
+	if success {
+		return http.StatusOK, "success"
+	}
+	return http.StatusOK, "fail"
+}
+
+type epayQueryResponse struct {
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+	TradeNo    string `json:"trade_no"`
+	OutTradeNo string `json:"out_trade_no"`
+	Status     int    `json:"status"`
+}
+
+// QueryOrder 通过易支付的 api.php?act=order 接口查单
+func (*EpayProvider) QueryOrder(topUp *model.TopUp) (*Notification, error) {
+	if operation_setting.PayAddress == "" || operation_setting.EpayId == "" || operation_setting.EpayKey == "" {
+		return nil, errors.New("当前管理员未配置支付信息")
+	}
+	u, err := url.Parse(operation_setting.PayAddress)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "/api.php")
+	u.RawQuery = url.Values{
+		"act":          {"order"},
+		"pid":          {operation_setting.EpayId},
+		"key":          {operation_setting.EpayKey},
+		"out_trade_no": {topUp.TradeNo},
+	}.Encode()
+	resp, err := service.GetHttpClient().Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result epayQueryResponse
+	if err = json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("易支付查单响应解析失败: %w", err)
+	}
+	if result.Code != 1 {
+		return nil, fmt.Errorf("易支付查单失败: %s", result.Msg)
+	}
+	notification := &Notification{
+		TradeNo:    topUp.TradeNo,
+		ExternalId: result.TradeNo,
+		Status:     OrderStatusPending,
+	}
+	if result.Status == 1 {
+		notification.Status = OrderStatusPaid
+	}
+	return notification, nil
+}
@@ -0,0 +1,326 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting"
+	"one-api/setting/system_setting"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	payPalApiBase        = "https://api-m.paypal.com"
+	payPalSandboxApiBase = "https://api-m.sandbox.paypal.com"
+)
+
+type PayPalProvider struct {
+}
+
+type payPalToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+var (
+	payPalTokenLock     sync.Mutex
+	payPalAccessToken   string
+	payPalTokenExpireAt int64
+	payPalTokenClientId string
+)
+
+type payPalLink struct {
+	Href string `json:"href"`
+	Rel  string `json:"rel"`
+}
+
+type payPalPurchaseUnit struct {
+	CustomId string `json:"custom_id"`
+}
+
+type payPalOrder struct {
+	Id            string               `json:"id"`
+	Status        string               `json:"status"`
+	PurchaseUnits []payPalPurchaseUnit `json:"purchase_units"`
+	Links         []payPalLink         `json:"links"`
+}
+
+type payPalCapture struct {
+	Id                string `json:"id"`
+	Status            string `json:"status"`
+	CustomId          string `json:"custom_id"`
+	SupplementaryData struct {
+		RelatedIds struct {
+			OrderId string `json:"order_id"`
+		} `json:"related_ids"`
+	} `json:"supplementary_data"`
+}
+
+type payPalWebhookEvent struct {
+	Id         string          `json:"id"`
+	EventType  string          `json:"event_type"`
+	Resource   json.RawMessage `json:"resource"`
+	CreateTime string          `json:"create_time"`
+}
+
+func (*PayPalProvider) Name() string {
+	return ProviderPayPal
+}
+
+func (*PayPalProvider) Enabled() bool {
+	return setting.PayPalClientId != "" && setting.PayPalClientSecret != "" && setting.PayPalWebhookId != ""
+}
+
+func (*PayPalProvider) UnitPrice() float64 {
+	return setting.PayPalUnitPrice
+}
+
+func (*PayPalProvider) Currencies() []string {
+	return parseCurrencies(setting.PayPalCurrency)
+}
+
+func (*PayPalProvider) MinTopUp() int {
+	return setting.PayPalMinTopUp
+}
+
+func payPalBaseUrl() string {
+	if setting.PayPalSandbox {
+		return payPalSandboxApiBase
+	}
+	return payPalApiBase
+}
+
+// getPayPalAccessToken 获取并缓存 OAuth 访问令牌，提前一分钟刷新
+func getPayPalAccessToken() (string, error) {
+	payPalTokenLock.Lock()
+	defer payPalTokenLock.Unlock()
+	now := time.Now().Unix()
+	if payPalAccessToken != "" && payPalTokenClientId == setting.PayPalClientId && now < payPalTokenExpireAt-60 {
+		return payPalAccessToken, nil
+	}
+	req, err := http.NewRequest(http.MethodPost, payPalBaseUrl()+"/v1/oauth2/token", strings.NewReader("grant_type=client_credentials"))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(setting.PayPalClientId, setting.PayPalClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("获取 PayPal 访问令牌失败，状态码: %d", resp.StatusCode)
+	}
+	var token payPalToken
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	payPalAccessToken = token.AccessToken
+	payPalTokenExpireAt = now + token.ExpiresIn
+	payPalTokenClientId = setting.PayPalClientId
+	return payPalAccessToken, nil
+}
+
+func doPayPalRequest(method string, path string, payload any, result any) error {
+	token, err := getPayPalAccessToken()
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, payPalBaseUrl()+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("PayPal 请求失败，状态码: %d，响应: %s", resp.StatusCode, string(respBody))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+// payPalZeroDecimalCurrencies PayPal 不接受小数金额的货币
+var payPalZeroDecimalCurrencies = map[string]bool{"JPY": true, "HUF": true, "TWD": true}
+
+func (p *PayPalProvider) CreateOrder(order *Order) (*PayResult, error) {
+	currency := orderCurrency(p, order)
+	decimals := 2
+	if payPalZeroDecimalCurrencies[currency] {
+		decimals = 0
+	}
+	payload := map[string]any{
+		"intent": "CAPTURE",
+		"purchase_units": []map[string]any{
+			{
+				"custom_id":   order.TradeNo,
+				"invoice_id":  order.TradeNo,
+				"description": order.Subject,
+				"amount": map[string]string{
+					"currency_code": currency,
+					"value":         strconv.FormatFloat(order.Money, 'f', decimals, 64),
+				},
+			},
+		},
+		"payment_source": map[string]any{
+			"paypal": map[string]any{
+				"experience_context": map[string]string{
+					"return_url":  system_setting.ServerAddress + "/console/log",
+					"cancel_url":  system_setting.ServerAddress + "/topup",
+					"user_action": "PAY_NOW",
+				},
+			},
+		},
+	}
+	var result payPalOrder
+	if err := doPayPalRequest(http.MethodPost, "/v2/checkout/orders", payload, &result); err != nil {
+		return nil, err
+	}
+	for _, link := range result.Links {
+		if link.Rel == "payer-action" || link.Rel == "approve" {
+			return &PayResult{PayLink: link.Href, ExternalId: result.Id}, nil
+		}
+	}
+	return nil, errors.New("PayPal 未返回支付链接")
+}
+
+// capturePayPalOrder 买家授权后扣款，订单已扣款时 PayPal 返回 422，此时以查单结果为准
+func capturePayPalOrder(orderId string) (*payPalOrder, error) {
+	var result payPalOrder
+	err := doPayPalRequest(http.MethodPost, "/v2/checkout/orders/"+url.PathEscape(orderId)+"/capture", map[string]any{}, &result)
+	if err != nil {
+		if getErr := doPayPalRequest(http.MethodGet, "/v2/checkout/orders/"+url.PathEscape(orderId), nil, &result); getErr != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+func payPalOrderNotification(order *payPalOrder, tradeNo string) *Notification {
+	notification := &Notification{
+		TradeNo:    tradeNo,
+		ExternalId: order.Id,
+		Status:     OrderStatusPending,
+	}
+	if notification.TradeNo == "" && len(order.PurchaseUnits) > 0 {
+		notification.TradeNo = order.PurchaseUnits[0].CustomId
+	}
+	switch order.Status {
+	case "COMPLETED":
+		notification.Status = OrderStatusPaid
+	case "VOIDED":
+		notification.Status = OrderStatusExpired
+	}
+	return notification
+}
+
+// VerifyWebhook 通过 PayPal 的验签接口校验回调，买家授权的订单在此处完成扣款
+func (*PayPalProvider) VerifyWebhook(r *http.Request, body []byte) (*Notification, error) {
+	var event payPalWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	verifyPayload := map[string]any{
+		"auth_algo":         r.Header.Get("PAYPAL-AUTH-ALGO"),
+		"cert_url":          r.Header.Get("PAYPAL-CERT-URL"),
+		"transmission_id":   r.Header.Get("PAYPAL-TRANSMISSION-ID"),
+		"transmission_sig":  r.Header.Get("PAYPAL-TRANSMISSION-SIG"),
+		"transmission_time": r.Header.Get("PAYPAL-TRANSMISSION-TIME"),
+		"webhook_id":        setting.PayPalWebhookId,
+		"webhook_event":     json.RawMessage(body),
+	}
+	var verifyResult struct {
+		VerificationStatus string `json:"verification_status"`
+	}
+	if err := doPayPalRequest(http.MethodPost, "/v1/notifications/verify-webhook-signature", verifyPayload, &verifyResult); err != nil {
+		return nil, err
+	}
+	if verifyResult.VerificationStatus != "SUCCESS" {
+		return nil, errors.New("PayPal 回调签名验证失败")
+	}
+
+	switch event.EventType {
+	case "CHECKOUT.ORDER.APPROVED":
+		var order payPalOrder
+		if err := json.Unmarshal(event.Resource, &order); err != nil {
+			return nil, err
+		}
+		captured, err := capturePayPalOrder(order.Id)
+		if err != nil {
+			return nil, err
+		}
+		tradeNo := ""
+		if len(order.PurchaseUnits) > 0 {
+			tradeNo = order.PurchaseUnits[0].CustomId
+		}
+		notification := payPalOrderNotification(captured, tradeNo)
+		notification.Event = event
+		return notification, nil
+	case "PAYMENT.CAPTURE.COMPLETED":
+		var capture payPalCapture
+		if err := json.Unmarshal(event.Resource, &capture); err != nil {
+			return nil, err
+		}
+		return &Notification{
+			TradeNo:    capture.CustomId,
+			ExternalId: capture.SupplementaryData.RelatedIds.OrderId,
+			Status:     OrderStatusPaid,
+			Event:      event,
+		}, nil
+	}
+	return &Notification{Event: event}, nil
+}
+
+func (*PayPalProvider) WebhookResponse(success bool) (int, string) {
+	if success {
+		return http.StatusOK, ""
+	}
+	return http.StatusBadRequest, ""
+}
+
+// QueryOrder 查询 PayPal 订单，买家已授权但未扣款时主动扣款
+func (*PayPalProvider) QueryOrder(topUp *model.TopUp) (*Notification, error) {
+	if topUp.ExternalId == "" {
+		return nil, errors.New("订单未记录 PayPal 订单号")
+	}
+	var order payPalOrder
+	if err := doPayPalRequest(http.MethodGet, "/v2/checkout/orders/"+url.PathEscape(topUp.ExternalId), nil, &order); err != nil {
+		return nil, err
+	}
+	if order.Status == "APPROVED" {
+		captured, err := capturePayPalOrder(order.Id)
+		if err != nil {
+			return nil, err
+		}
+		order = *captured
+	}
+	return payPalOrderNotification(&order, topUp.TradeNo), nil
+}
@@ -0,0 +1,224 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ProviderEpay    = "epay"
+	ProviderStripe  = "stripe"
+	ProviderPayPal  = "paypal"
+	ProviderWebhook = "webhook"
+)
+
+// 支付渠道通知或查单得到的订单状态
+const (
+	OrderStatusPending = "pending"
+	OrderStatusPaid    = "paid"
+	OrderStatusExpired = "expired"
+)
+
+var ErrProviderNotFound = errors.New("支付渠道不存在")
+
+// Order 发往支付渠道的下单参数
+type Order struct {
+	TradeNo       string
+	UserId        int
+	Email         string
+	CustomerId    string  // 支付渠道侧的客户 ID，目前仅 Stripe 使用
+	PaymentMethod string  // 支付渠道内的支付方式，如易支付的 alipay、wxpay
+	Amount        int64   // 充值数量，即用户在充值页面输入的数量
+	Money         float64 // 实付金额
+	Currency      string  // 结算货币，为空时使用渠道的默认货币
+	Subject       string
+}
+
+// PayResult 下单结果，前端跳转 PayLink 或以表单提交 Params 完成支付
+type PayResult struct {
+	PayLink    string            `json:"pay_link"`
+	Params     map[string]string `json:"params,omitempty"`
+	ExternalId string            `json:"-"`
+}
+
+// Notification 支付渠道回调或查单的结果
+// Status 为空表示与充值订单无关的事件，例如 Stripe 的订阅事件，由调用方根据 Event 自行处理
+type Notification struct {
+	TradeNo    string
+	ExternalId string
+	Status     string
+	CustomerId string
+	Event      any
+}
+
+// Provider 支付渠道，下单、回调验签与查单由各渠道实现，订单锁与入账由调用方统一处理
+type Provider interface {
+	Name() string
+	Enabled() bool
+	// UnitPrice 每单位充值数量对应的支付金额，以默认货币计
+	UnitPrice() float64
+	// Currencies 渠道可用的结算货币，第一个为默认货币
+	Currencies() []string
+	// MinTopUp 最低充值数量，以展示单位计
+	MinTopUp() int
+	CreateOrder(order *Order) (*PayResult, error)
+	// VerifyWebhook 校验回调签名并解析通知，body 为已读取的请求体
+	VerifyWebhook(r *http.Request, body []byte) (*Notification, error)
+	// WebhookResponse 回调处理完成后返回给支付渠道的状态码与响应内容
+	WebhookResponse(success bool) (int, string)
+	QueryOrder(topUp *model.TopUp) (*Notification, error)
+}
+
+var providers = []Provider{
+	&EpayProvider{},
+	&StripeProvider{},
+	&PayPalProvider{},
+	&WebhookProvider{},
+}
+
+func GetProvider(name string) (Provider, error) {
+	for _, provider := range providers {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrProviderNotFound
+}
+
+// GetEnabledProviders 返回管理员已完成配置的支付渠道
+func GetEnabledProviders() []Provider {
+	enabled := make([]Provider, 0, len(providers))
+	for _, provider := range providers {
+		if provider.Enabled() {
+			enabled = append(enabled, provider)
+		}
+	}
+	return enabled
+}
+
+// NormalizeAmount 将充值数量换算为展示单位，未开启以货币展示时充值数量以额度计
+func NormalizeAmount(amount int64) int64 {
+	if common.DisplayInCurrencyEnabled {
+		return amount
+	}
+	dAmount := decimal.NewFromInt(amount)
+	dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
+	return dAmount.Div(dQuotaPerUnit).IntPart()
+}
+
+// GetQuota 充值数量对应的入账额度
+func GetQuota(amount int64) int {
+	dAmount := decimal.NewFromInt(NormalizeAmount(amount))
+	dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
+	return int(dAmount.Mul(dQuotaPerUnit).IntPart())
+}
+
+// parseCurrencies 解析逗号分隔的货币列表，为空时返回美元
+func parseCurrencies(value string) []string {
+	currencies := make([]string, 0)
+	for _, currency := range strings.Split(value, ",") {
+		if currency = strings.ToUpper(strings.TrimSpace(currency)); currency != "" {
+			currencies = append(currencies, currency)
+		}
+	}
+	if len(currencies) == 0 {
+		currencies = append(currencies, operation_setting.BaseCurrency)
+	}
+	return currencies
+}
+
+// ResolveCurrency 校验渠道是否支持所选货币，未选择时返回渠道的默认货币
+func ResolveCurrency(provider Provider, currency string) (string, error) {
+	currencies := provider.Currencies()
+	if currency == "" {
+		return currencies[0], nil
+	}
+	currency = strings.ToUpper(currency)
+	for _, c := range currencies {
+		if c == currency {
+			return currency, nil
+		}
+	}
+	return "", fmt.Errorf("支付渠道 %s 不支持货币 %s", provider.Name(), currency)
+}
+
+// orderCurrency 订单的结算货币，未指定时为渠道的默认货币
+func orderCurrency(provider Provider, order *Order) string {
+	if order.Currency != "" {
+		return order.Currency
+	}
+	return provider.Currencies()[0]
+}
+
+// getUnitPrice 每单位充值数量在指定货币下的价格
+// 优先使用管理员为该货币配置的充值售价，其次为渠道默认货币的单价，其余货币按汇率由默认货币单价换算
+func getUnitPrice(provider Provider, currency string) (float64, error) {
+	if price, ok := operation_setting.GetTopUpPrice(currency); ok {
+		return price, nil
+	}
+	defaultCurrency := provider.Currencies()[0]
+	if currency == defaultCurrency {
+		return provider.UnitPrice(), nil
+	}
+	fromRate, ok := operation_setting.GetExchangeRate(defaultCurrency)
+	if !ok {
+		return 0, fmt.Errorf("未配置货币 %s 的汇率", defaultCurrency)
+	}
+	toRate, ok := operation_setting.GetExchangeRate(currency)
+	if !ok {
+		return 0, fmt.Errorf("未配置货币 %s 的汇率", currency)
+	}
+	return provider.UnitPrice() / fromRate * toRate, nil
+}
+
+// GetPayMoney 计算实付金额：充值数量 × 渠道在该货币下的单价 × 充值分组倍率 × 充值折扣
+func GetPayMoney(provider Provider, amount int64, group string, currency string) (float64, error) {
+	dAmount := decimal.NewFromInt(amount)
+
+	if !common.DisplayInCurrencyEnabled {
+		dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
+		dAmount = dAmount.Div(dQuotaPerUnit)
+	}
+
+	topupGroupRatio := common.GetTopupGroupRatio(group)
+	if topupGroupRatio == 0 {
+		topupGroupRatio = 1
+	}
+
+	dTopupGroupRatio := decimal.NewFromFloat(topupGroupRatio)
+	unitPrice, err := getUnitPrice(provider, currency)
+	if err != nil {
+		return 0, err
+	}
+	dPrice := decimal.NewFromFloat(unitPrice)
+	// apply optional preset discount by the original request amount (if configured), default 1.0
+	discount := 1.0
+	if ds, ok := operation_setting.GetPaymentSetting().AmountDiscount[int(amount)]; ok {
+		if ds > 0 {
+			discount = ds
+		}
+	}
+	dDiscount := decimal.NewFromFloat(discount)
+
+	payMoney := dAmount.Mul(dPrice).Mul(dTopupGroupRatio).Mul(dDiscount)
+
+	return payMoney.InexactFloat64(), nil
+}
+
+// GetMinTopUp 最低充值数量，未开启以货币展示时换算为额度
+func GetMinTopUp(provider Provider) int64 {
+	minTopup := provider.MinTopUp()
+	if !common.DisplayInCurrencyEnabled {
+		dMinTopup := decimal.NewFromInt(int64(minTopup))
+		dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
+		minTopup = int(dMinTopup.Mul(dQuotaPerUnit).IntPart())
+	}
+	return int64(minTopup)
+}
@@ -0,0 +1,48 @@
+package payment
+
+import (
+	"one-api/setting"
+	"one-api/setting/operation_setting"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUnitPrice(t *testing.T) {
+	currencySetting := operation_setting.GetCurrencySetting()
+	eurRate, hasEurRate := currencySetting.Rates["EUR"]
+	payPalCurrency, payPalUnitPrice := setting.PayPalCurrency, setting.PayPalUnitPrice
+	currencySetting.Rates["EUR"] = 0.9
+	currencySetting.TopUpPrices["GBP"] = 0.85
+	setting.PayPalCurrency = "USD,EUR,GBP"
+	setting.PayPalUnitPrice = 1.1
+	t.Cleanup(func() {
+		if hasEurRate {
+			currencySetting.Rates["EUR"] = eurRate
+		} else {
+			delete(currencySetting.Rates, "EUR")
+		}
+		delete(currencySetting.TopUpPrices, "GBP")
+		setting.PayPalCurrency, setting.PayPalUnitPrice = payPalCurrency, payPalUnitPrice
+	})
+	provider := &PayPalProvider{}
+
+	price, err := getUnitPrice(provider, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 1.1, price)
+
+	price, err = getUnitPrice(provider, "EUR")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.99, price, 1e-9)
+
+	price, err = getUnitPrice(provider, "GBP")
+	require.NoError(t, err)
+	assert.Equal(t, 0.85, price)
+
+	currency, err := ResolveCurrency(provider, "eur")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", currency)
+	_, err = ResolveCurrency(provider, "JPY")
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package payment
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"one-api/model"
+	"one-api/setting"
+	"one-api/setting/system_setting"
+	"strings"
+
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/checkout/session"
+	"github.com/stripe/stripe-go/v81/price"
+	"github.com/stripe/stripe-go/v81/webhook"
+)
+
+// stripeMaxQuantity 单次充值数量上限
+const stripeMaxQuantity = 10000
+
+// stripeZeroDecimalCurrencies Stripe 以整数金额计价的货币
+var stripeZeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true, "KMF": true, "KRW": true, "MGA": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// stripeThreeDecimalCurrencies Stripe 以千分之一为最小单位的货币，最后一位必须为 0
+var stripeThreeDecimalCurrencies = map[string]bool{"BHD": true, "JOD": true, "KWD": true, "OMR": true, "TND": true}
+
+// stripeUnitAmount 将实付金额换算为 Stripe 使用的最小货币单位
+func stripeUnitAmount(currency string, money float64) int64 {
+	switch {
+	case stripeZeroDecimalCurrencies[currency]:
+		return int64(math.Round(money))
+	case stripeThreeDecimalCurrencies[currency]:
+		return int64(math.Round(money*100)) * 10
+	default:
+		return int64(math.Round(money * 100))
+	}
+}
+
+// stripeLineItem 按网关计算的实付金额生成商品，商品沿用管理员配置的 Stripe Price 所属的 Product
+func stripeLineItem(productId string, currency string, money float64) *stripe.CheckoutSessionLineItemParams {
+	return &stripe.CheckoutSessionLineItemParams{
+		PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+			Currency:   stripe.String(strings.ToLower(currency)),
+			Product:    stripe.String(productId),
+			UnitAmount: stripe.Int64(stripeUnitAmount(currency, money)),
+		},
+		Quantity: stripe.Int64(1),
+	}
+}
+
+type StripeProvider struct {
+}
+
+func (*StripeProvider) Name() string {
+	return ProviderStripe
+}
+
+func (*StripeProvider) Enabled() bool {
+	return setting.StripeApiSecret != "" && setting.StripeWebhookSecret != "" && setting.StripePriceId != ""
+}
+
+func (*StripeProvider) UnitPrice() float64 {
+	return setting.StripeUnitPrice
+}
+
+func (*StripeProvider) Currencies() []string {
+	return parseCurrencies(setting.StripeCurrencies)
+}
+
+func (*StripeProvider) MinTopUp() int {
+	return setting.StripeMinTopUp
+}
+
+func setStripeKey() error {
+	if !strings.HasPrefix(setting.StripeApiSecret, "sk_") && !strings.HasPrefix(setting.StripeApiSecret, "rk_") {
+		return errors.New("无效的Stripe API密钥")
+	}
+	stripe.Key = setting.StripeApiSecret
+	return nil
+}
+
+// CreateOrder 创建 Stripe Checkout 会话，按网关计算的实付金额与结算货币扣费，与充值页面展示的金额一致
+func (p *StripeProvider) CreateOrder(order *Order) (*PayResult, error) {
+	if order.Amount > stripeMaxQuantity {
+		return nil, errors.New("充值数量不能大于 10000")
+	}
+	if err := setStripeKey(); err != nil {
+		return nil, err
+	}
+	stripePrice, err := price.Get(setting.StripePriceId, nil)
+	if err != nil {
+		return nil, err
+	}
+	if stripePrice.Product == nil {
+		return nil, errors.New("Stripe Price 未关联 Product")
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(order.TradeNo),
+		SuccessURL:        stripe.String(system_setting.ServerAddress + "/console/log"),
+		CancelURL:         stripe.String(system_setting.ServerAddress + "/topup"),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			stripeLineItem(stripePrice.Product.ID, orderCurrency(p, order), order.Money),
+		},
+		Mode:                stripe.String(string(stripe.CheckoutSessionModePayment)),
+		AllowPromotionCodes: stripe.Bool(setting.StripePromotionCodesEnabled),
+	}
+
+	if "" == order.CustomerId {
+		if "" != order.Email {
+			params.CustomerEmail = stripe.String(order.Email)
+		}
+
+		params.CustomerCreation = stripe.String(string(stripe.CheckoutSessionCustomerCreationAlways))
+	} else {
+		params.Customer = stripe.String(order.CustomerId)
+	}
+
+	result, err := session.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return &PayResult{PayLink: result.URL, ExternalId: result.ID}, nil
+}
+
+// VerifyWebhook 只将一次性支付的 Checkout 事件解析为充值通知，其余事件仅校验签名并通过 Event 返回
+func (*StripeProvider) VerifyWebhook(r *http.Request, body []byte) (*Notification, error) {
+	event, err := webhook.ConstructEventWithOptions(body, r.Header.Get("Stripe-Signature"), setting.StripeWebhookSecret, webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	notification := &Notification{Event: event}
+	if event.GetObjectValue("mode") != string(stripe.CheckoutSessionModePayment) {
+		return notification, nil
+	}
+	switch event.Type {
+	case stripe.EventTypeCheckoutSessionCompleted:
+		if event.GetObjectValue("status") != "complete" {
+			return nil, errors.New("错误的Stripe Checkout完成状态: " + event.GetObjectValue("status"))
+		}
+		notification.Status = OrderStatusPaid
+	case stripe.EventTypeCheckoutSessionExpired:
+		if event.GetObjectValue("status") != "expired" {
+			return nil, errors.New("错误的Stripe Checkout过期状态: " + event.GetObjectValue("status"))
+		}
+		notification.Status = OrderStatusExpired
+	default:
+		return notification, nil
+	}
+	notification.TradeNo = event.GetObjectValue("client_reference_id")
+	notification.ExternalId = event.GetObjectValue("id")
+	notification.CustomerId = event.GetObjectValue("customer")
+	return notification, nil
+}
+
+func (*StripeProvider) WebhookResponse(success bool) (int, string) {
+	if success {
+		return http.StatusOK, ""
+	}
+	return http.StatusBadRequest, ""
+}
+
+// QueryOrder 根据下单时记录的 Checkout 会话 ID 查询支付状态
+func (*StripeProvider) QueryOrder(topUp *model.TopUp) (*Notification, error) {
+	if topUp.ExternalId == "" {
+		return nil, errors.New("订单未记录 Stripe 会话")
+	}
+	if err := setStripeKey(); err != nil {
+		return nil, err
+	}
+	s, err := session.Get(topUp.ExternalId, nil)
+	if err != nil {
+		return nil, err
+	}
+	notification := &Notification{
+		TradeNo:    topUp.TradeNo,
+		ExternalId: s.ID,
+		Status:     OrderStatusPending,
+	}
+	if s.Customer != nil {
+		notification.CustomerId = s.Customer.ID
+	}
+	switch {
+	case s.Status == stripe.CheckoutSessionStatusComplete && s.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid:
+		notification.Status = OrderStatusPaid
+	case s.Status == stripe.CheckoutSessionStatusExpired:
+		notification.Status = OrderStatusExpired
+	}
+	return notification, nil
+}
@@ -0,0 +1,20 @@
+package payment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripeLineItem(t *testing.T) {
+	item := stripeLineItem("prod_1", "EUR", 19.99)
+	assert.Equal(t, "eur", *item.PriceData.Currency)
+	assert.Equal(t, "prod_1", *item.PriceData.Product)
+	assert.Equal(t, int64(1999), *item.PriceData.UnitAmount)
+	assert.Equal(t, int64(1), *item.Quantity)
+	assert.Nil(t, item.Price)
+
+	// 零位小数与三位小数的货币按各自的最小单位换算
+	assert.Equal(t, int64(1500), stripeUnitAmount("JPY", 1499.6))
+	assert.Equal(t, int64(12340), stripeUnitAmount("KWD", 12.34))
+}
@@ -0,0 +1,192 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting"
+	"one-api/setting/system_setting"
+	"time"
+)
+
+// webhookTimestampTolerance 回调时间戳允许的最大偏差，超过视为重放
+const webhookTimestampTolerance = 5 * 60
+
+const webhookSignatureHeader = "X-Webhook-Signature"
+
+// WebhookProvider 通用支付网关，适配自建或第三方聚合支付
+// 下单与查单请求体、回调请求体都以 HMAC-SHA256(PaymentWebhookSecret, body) 的十六进制放在 X-Webhook-Signature 请求头中
+type WebhookProvider struct {
+}
+
+type webhookCreateRequest struct {
+	TradeNo   string  `json:"trade_no"`
+	UserId    int     `json:"user_id"`
+	Amount    int64   `json:"amount"`
+	Money     float64 `json:"money"`
+	Currency  string  `json:"currency"`
+	Subject   string  `json:"subject"`
+	NotifyUrl string  `json:"notify_url"`
+	ReturnUrl string  `json:"return_url"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+type webhookCreateResponse struct {
+	PayUrl  string `json:"pay_url"`
+	OrderId string `json:"order_id"`
+}
+
+// webhookOrderStatus 回调与查单响应的结构，status 取值 pending、paid、expired
+type webhookOrderStatus struct {
+	TradeNo   string `json:"trade_no"`
+	OrderId   string `json:"order_id"`
+	Status    string `json:"status"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (*WebhookProvider) Name() string {
+	return ProviderWebhook
+}
+
+func (*WebhookProvider) Enabled() bool {
+	return setting.PaymentWebhookCreateUrl != "" && setting.PaymentWebhookSecret != ""
+}
+
+func (*WebhookProvider) UnitPrice() float64 {
+	return setting.PaymentWebhookUnitPrice
+}
+
+func (*WebhookProvider) Currencies() []string {
+	return parseCurrencies(setting.PaymentWebhookCurrencies)
+}
+
+func (*WebhookProvider) MinTopUp() int {
+	return setting.PaymentWebhookMinTopUp
+}
+
+func signWebhookPayload(payload []byte) []byte {
+	h := hmac.New(sha256.New, []byte(setting.PaymentWebhookSecret))
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+func postSignedWebhook(url string, payload any, result any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	fetchSetting := system_setting.GetFetchSetting()
+	if err = common.ValidateURLWithFetchSetting(url, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+		return fmt.Errorf("request reject: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader, hex.EncodeToString(signWebhookPayload(data)))
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("支付网关请求失败，状态码: %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, result)
+}
+
+func (p *WebhookProvider) CreateOrder(order *Order) (*PayResult, error) {
+	payload := webhookCreateRequest{
+		TradeNo:   order.TradeNo,
+		UserId:    order.UserId,
+		Amount:    order.Amount,
+		Money:     order.Money,
+		Currency:  orderCurrency(p, order),
+		Subject:   order.Subject,
+		NotifyUrl: service.GetCallbackAddress() + "/api/payment/" + ProviderWebhook + "/notify",
+		ReturnUrl: system_setting.ServerAddress + "/console/log",
+		Timestamp: time.Now().Unix(),
+	}
+	var result webhookCreateResponse
+	if err := postSignedWebhook(setting.PaymentWebhookCreateUrl, payload, &result); err != nil {
+		return nil, err
+	}
+	if result.PayUrl == "" {
+		return nil, errors.New("支付网关未返回支付链接")
+	}
+	return &PayResult{PayLink: result.PayUrl, ExternalId: result.OrderId}, nil
+}
+
+func webhookNotification(status *webhookOrderStatus) (*Notification, error) {
+	notification := &Notification{
+		TradeNo:    status.TradeNo,
+		ExternalId: status.OrderId,
+		Event:      status,
+	}
+	switch status.Status {
+	case OrderStatusPaid, OrderStatusExpired, OrderStatusPending:
+		notification.Status = status.Status
+	default:
+		return nil, fmt.Errorf("未知的订单状态: %s", status.Status)
+	}
+	return notification, nil
+}
+
+func (*WebhookProvider) VerifyWebhook(r *http.Request, body []byte) (*Notification, error) {
+	if setting.PaymentWebhookSecret == "" {
+		return nil, errors.New("未配置支付网关密钥")
+	}
+	signature, err := hex.DecodeString(r.Header.Get(webhookSignatureHeader))
+	if err != nil || !hmac.Equal(signature, signWebhookPayload(body)) {
+		return nil, errors.New("支付网关回调签名验证失败")
+	}
+	var status webhookOrderStatus
+	if err = json.Unmarshal(body, &status); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	if status.Timestamp < now-webhookTimestampTolerance || status.Timestamp > now+webhookTimestampTolerance {
+		return nil, errors.New("支付网关回调已过期")
+	}
+	return webhookNotification(&status)
+}
+
+func (*WebhookProvider) WebhookResponse(success bool) (int, string) {
+	if success {
+		return http.StatusOK, "success"
+	}
+	return http.StatusBadRequest, "fail"
+}
+
+func (*WebhookProvider) QueryOrder(topUp *model.TopUp) (*Notification, error) {
+	if setting.PaymentWebhookQueryUrl == "" {
+		return nil, errors.New("支付网关未配置查单地址")
+	}
+	payload := map[string]any{
+		"trade_no":  topUp.TradeNo,
+		"order_id":  topUp.ExternalId,
+		"timestamp": time.Now().Unix(),
+	}
+	var status webhookOrderStatus
+	if err := postSignedWebhook(setting.PaymentWebhookQueryUrl, payload, &status); err != nil {
+		return nil, err
+	}
+	if status.TradeNo != topUp.TradeNo {
+		return nil, errors.New("支付网关返回的订单号不匹配")
+	}
+	return webhookNotification(&status)
+}
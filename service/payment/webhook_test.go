@@ -0,0 +1,38 @@
+package payment
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http/httptest"
+	"one-api/setting"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookProviderVerifyWebhook(t *testing.T) {
+	setting.PaymentWebhookSecret = "test-secret"
+	defer func() { setting.PaymentWebhookSecret = "" }()
+	provider := &WebhookProvider{}
+
+	body := []byte(fmt.Sprintf(`{"trade_no":"USR1NOabc","order_id":"gw_1","status":"paid","timestamp":%d}`, time.Now().Unix()))
+	req := httptest.NewRequest("POST", "/api/payment/webhook/notify", strings.NewReader(string(body)))
+	req.Header.Set(webhookSignatureHeader, hex.EncodeToString(signWebhookPayload(body)))
+	notification, err := provider.VerifyWebhook(req, body)
+	require.NoError(t, err)
+	assert.Equal(t, "USR1NOabc", notification.TradeNo)
+	assert.Equal(t, "gw_1", notification.ExternalId)
+	assert.Equal(t, OrderStatusPaid, notification.Status)
+
+	req.Header.Set(webhookSignatureHeader, hex.EncodeToString([]byte("forged")))
+	_, err = provider.VerifyWebhook(req, body)
+	assert.Error(t, err)
+
+	stale := []byte(`{"trade_no":"USR1NOabc","status":"paid","timestamp":1}`)
+	req.Header.Set(webhookSignatureHeader, hex.EncodeToString(signWebhookPayload(stale)))
+	_, err = provider.VerifyWebhook(req, stale)
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"one-api/common"
+	"one-api/logger"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"time"
+)
+
+// StartPostpaidBillingTask 定时为后付费用户出账，并处理逾期账单
+func StartPostpaidBillingTask() {
+	for {
+		RunPostpaidBilling(time.Now())
+		time.Sleep(time.Hour)
+	}
+}
+
+// RunPostpaidBilling 执行一次出账与逾期检查，出账只在每月的出账日进行
+func RunPostpaidBilling(now time.Time) {
+	if now.Day() == operation_setting.GetPostpaidSetting().BillingDay {
+		if err := IssuePostpaidInvoices(now); err != nil {
+			common.SysError("failed to issue postpaid invoices: " + err.Error())
+		}
+	}
+	if err := CheckPostpaidInvoices(now); err != nil {
+		common.SysError("failed to check postpaid invoices: " + err.Error())
+	}
+}
+
+// IssuePostpaidInvoices 为余额为负且没有未结清账单的后付费用户生成账单
+func IssuePostpaidInvoices(now time.Time) error {
+	debtors, err := model.GetPostpaidDebtors()
+	if err != nil {
+		return err
+	}
+	termDays := operation_setting.GetPostpaidSetting().PaymentTermDays
+	for _, user := range debtors {
+		hasOpen, err := model.HasOpenPostpaidInvoice(user.Id)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to check postpaid invoice of user %d: %s", user.Id, err.Error()))
+			continue
+		}
+		if hasOpen {
+			continue
+		}
+		debt := -user.Quota
+		invoice := &model.PostpaidInvoice{
+			UserId:  user.Id,
+			Quota:   debt,
+			Amount:  int64(math.Ceil(float64(debt) / common.QuotaPerUnit)),
+			Status:  common.PostpaidInvoiceStatusPending,
+			DueTime: now.AddDate(0, 0, termDays).Unix(),
+		}
+		if err = invoice.Insert(); err != nil {
+			common.SysError(fmt.Sprintf("failed to create postpaid invoice for user %d: %s", user.Id, err.Error()))
+			continue
+		}
+		model.RecordLog(user.Id, model.LogTypeSystem, fmt.Sprintf("生成后付费账单 #%d，欠款额度: %s，请在 %s 前完成支付",
+			invoice.Id, logger.LogQuota(debt), time.Unix(invoice.DueTime, 0).Format(time.DateOnly)))
+	}
+	return nil
+}
+
+// CheckPostpaidInvoices 余额已补足的账单视为结清，超过付款期限的账单标记逾期并锁定账户
+func CheckPostpaidInvoices(now time.Time) error {
+	invoices, err := model.GetOpenPostpaidInvoices()
+	if err != nil {
+		return err
+	}
+	for _, invoice := range invoices {
+		quota, err := model.GetUserQuota(invoice.UserId, true)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to get quota of user %d: %s", invoice.UserId, err.Error()))
+			continue
+		}
+		if quota >= 0 {
+			if err = model.MarkPostpaidInvoicePaid(invoice); err != nil {
+				common.SysError(fmt.Sprintf("failed to settle postpaid invoice %d: %s", invoice.Id, err.Error()))
+			}
+			continue
+		}
+		if invoice.Status == common.PostpaidInvoiceStatusPending && now.Unix() > invoice.DueTime {
+			invoice.Status = common.PostpaidInvoiceStatusOverdue
+			if err = invoice.Update(); err != nil {
+				common.SysError(fmt.Sprintf("failed to update postpaid invoice %d: %s", invoice.Id, err.Error()))
+				continue
+			}
+			if err = model.SetUserBillingLocked(invoice.UserId, true); err != nil {
+				common.SysError(fmt.Sprintf("failed to lock user %d: %s", invoice.UserId, err.Error()))
+				continue
+			}
+			model.RecordLog(invoice.UserId, model.LogTypeSystem, fmt.Sprintf("后付费账单 #%d 已逾期，账户已暂停使用", invoice.Id))
+		}
+	}
+	return nil
+}
@@ -3,9 +3,9 @@ package service
 import (
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"one-api/common"
+	"one-api/common/metrics"
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/logger"
@@ -46,6 +46,27 @@ func hasCustomModelRatio(modelName string, currentRatio float64) bool {
 	return currentRatio != defaultRatio
 }
 
+// ApplyTieredPricing 按实际输入 token 数重新选择分段倍率，预扣费时的估算值可能与实际所在分段不同
+func ApplyTieredPricing(relayInfo *relaycommon.RelayInfo, promptTokens int) {
+	if relayInfo.PriceData.UsePrice {
+		return
+	}
+	// 协议价直接指定了模型倍率，不再按分段调整
+	if override := relayInfo.PriceData.GroupRatioInfo.PriceOverride; override != nil && override.Type == model.PriceOverrideTypeRatio {
+		return
+	}
+	tiered, ok := ratio_setting.GetTieredRatios(relayInfo.OriginModelName, promptTokens)
+	if !ok {
+		return
+	}
+	relayInfo.PriceData.ModelRatio = tiered.ModelRatio
+	relayInfo.PriceData.CompletionRatio = tiered.CompletionRatio
+	relayInfo.PriceData.CacheRatio = tiered.CacheRatio
+	relayInfo.PriceData.CacheCreationRatio = tiered.CacheCreationRatio
+	relayInfo.PriceData.PricingTier = tiered.Tier
+	relayInfo.PriceData.PricingTierMaxTokens = tiered.MaxTokens
+}
+
 func calculateAudioQuota(info QuotaInfo) int {
 	if info.UsePrice {
 		modelPrice := decimal.NewFromFloat(info.ModelPrice)
@@ -104,21 +125,9 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 	textOutTokens := usage.OutputTokenDetails.TextTokens
 	audioInputTokens := usage.InputTokenDetails.AudioTokens
 	audioOutTokens := usage.OutputTokenDetails.AudioTokens
-	groupRatio := ratio_setting.GetGroupRatio(relayInfo.UsingGroup)
-	modelRatio, _, _ := ratio_setting.GetModelRatio(modelName)
-
-	autoGroup, exists := ctx.Get("auto_group")
-	if exists {
-		groupRatio = ratio_setting.GetGroupRatio(autoGroup.(string))
-		log.Printf("final group ratio: %f", groupRatio)
-		relayInfo.UsingGroup = autoGroup.(string)
-	}
-
-	actualGroupRatio := groupRatio
-	userGroupRatio, ok := ratio_setting.GetGroupGroupRatio(relayInfo.UserGroup, relayInfo.UsingGroup)
-	if ok {
-		actualGroupRatio = userGroupRatio
-	}
+	// 与结算时一致，使用请求开始时计算的定价，已包含自动分组、用户协议价与分时定价
+	modelRatio := relayInfo.PriceData.ModelRatio
+	actualGroupRatio := relayInfo.PriceData.GroupRatioInfo.GroupRatio
 
 	quotaInfo := QuotaInfo{
 		InputDetails: TokenDetails{
@@ -137,7 +146,8 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 
 	quota := calculateAudioQuota(quotaInfo)
 
-	if userQuota < quota {
+	creditLimit := common.GetContextKeyInt(ctx, constant.ContextKeyUserCreditLimit)
+	if userQuota+creditLimit-quota < 0 {
 		return fmt.Errorf("user quota is not enough, user quota: %s, need quota: %s", logger.FormatQuota(userQuota), logger.FormatQuota(quota))
 	}
 
@@ -218,6 +228,7 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 	}
 	other := GenerateWssOtherInfo(ctx, relayInfo, usage, modelRatio, groupRatio,
 		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), modelPrice, relayInfo.PriceData.GroupRatioInfo.GroupSpecialRatio)
+	RecordRelayUsageMetrics(relayInfo, usage.InputTokens, usage.OutputTokens, quota)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     usage.InputTokens,
@@ -235,6 +246,12 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 }
 
 func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.Usage) {
+	// Claude 原生接口的 input_tokens 不含缓存，分段按完整上下文长度判断
+	contextTokens := usage.PromptTokens
+	if relayInfo.ChannelType != constant.ChannelTypeOpenRouter {
+		contextTokens += usage.PromptTokensDetails.CachedTokens + usage.PromptTokensDetails.CachedCreationTokens
+	}
+	ApplyTieredPricing(relayInfo, contextTokens)
 
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
@@ -359,6 +376,7 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 
 	other := GenerateClaudeOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio,
 		cacheTokens, cacheRatio, cacheCreationTokens, cacheCreationRatio, modelPrice, relayInfo.PriceData.GroupRatioInfo.GroupSpecialRatio)
+	RecordRelayUsageMetrics(relayInfo, promptTokens, completionTokens, quota)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     promptTokens,
@@ -491,6 +509,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 	}
 	other := GenerateAudioOtherInfo(ctx, relayInfo, usage, modelRatio, groupRatio,
 		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), modelPrice, relayInfo.PriceData.GroupRatioInfo.GroupSpecialRatio)
+	RecordRelayUsageMetrics(relayInfo, usage.PromptTokens, usage.CompletionTokens, quota)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     usage.PromptTokens,
@@ -620,6 +639,8 @@ func recordPromptCacheMetrics(relayInfo *relaycommon.RelayInfo, usage *dto.Usage
 	// Total = cache read + cache creation + uncached
 	originalPromptTokens := cacheReadTokens + cacheCreationTokens + uncachedTokens
 
+	metrics.RecordPromptCacheTokens(relayInfo.ChannelType, cacheReadTokens, cacheCreationTokens, uncachedTokens)
+
 	// Calculate cache hit rate
 	// Hit rate = cache read tokens / (cache read tokens + uncached tokens)
 	// Note: cache creation tokens are excluded as they represent first-time caching
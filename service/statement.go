@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"time"
+)
+
+const StatementPeriodLayout = "2006-01"
+
+// ParseStatementPeriod 解析账单周期（YYYY-MM），返回该自然月的起止时间戳（左闭右开）
+func ParseStatementPeriod(period string) (int64, int64, error) {
+	start, err := time.ParseInLocation(StatementPeriodLayout, period, time.Local)
+	if err != nil {
+		return 0, 0, fmt.Errorf("账单周期格式错误，应为 YYYY-MM: %s", period)
+	}
+	end := start.AddDate(0, 1, 0)
+	return start.Unix(), end.Unix(), nil
+}
+
+// GenerateStatement 为用户（tokenId 为 0）或单个令牌生成指定月份的账单
+// 只能为已经结束的月份生成，生成后的账单不可修改
+func GenerateStatement(userId int, tokenId int, period string) (*model.Statement, error) {
+	startTime, endTime, err := ParseStatementPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	if endTime > common.GetTimestamp() {
+		return nil, errors.New("只能为已结束的月份生成账单")
+	}
+
+	statement := &model.Statement{
+		UserId:       userId,
+		TokenId:      tokenId,
+		Period:       period,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Currency:     "USD",
+		QuotaPerUnit: common.QuotaPerUnit,
+	}
+	if tokenId != 0 {
+		token, err := model.GetTokenById(tokenId)
+		if err != nil {
+			return nil, err
+		}
+		if token.UserId != userId {
+			return nil, errors.New("令牌不属于该用户")
+		}
+		statement.TokenName = token.Name
+	}
+	statement.Username, _ = model.GetUsernameById(userId, false)
+
+	items, err := model.AggregateConsumeLogs(userId, tokenId, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		statement.RequestCount += item.RequestCount
+		statement.PromptTokens += item.PromptTokens
+		statement.CompletionTokens += item.CompletionTokens
+		statement.CacheTokens += item.CacheTokens
+		statement.CacheCreationTokens += item.CacheCreationTokens
+		statement.Quota += item.Quota
+	}
+	statement.Amount = common.QuotaToUSD(float64(statement.Quota))
+	itemsJson, err := common.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	statement.Items = string(itemsJson)
+
+	if err = statement.Insert(); err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+// GenerateStatementsForPeriod 为该月份内所有产生过消费的用户生成账单，已存在的账单会被跳过
+func GenerateStatementsForPeriod(period string) (int, error) {
+	startTime, endTime, err := ParseStatementPeriod(period)
+	if err != nil {
+		return 0, err
+	}
+	userIds, err := model.GetConsumeUserIds(startTime, endTime)
+	if err != nil {
+		return 0, err
+	}
+	created := 0
+	for _, userId := range userIds {
+		_, err = GenerateStatement(userId, 0, period)
+		if err != nil {
+			if errors.Is(err, model.ErrStatementExists) {
+				continue
+			}
+			common.SysError(fmt.Sprintf("failed to generate statement for user %d: %s", userId, err.Error()))
+			continue
+		}
+		created++
+	}
+	return created, nil
+}
+
+func formatStatementAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 6, 64)
+}
+
+// RenderStatementCSV 将账单渲染为 CSV，每个模型一行，最后一行为合计
+func RenderStatementCSV(statement *model.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	header := []string{"period", "user_id", "username", "token_id", "token_name", "model_name", "request_count",
+		"prompt_tokens", "completion_tokens", "cache_tokens", "cache_creation_tokens", "quota", "amount", "currency"}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	row := func(modelName string, item model.StatementItem) []string {
+		return []string{
+			statement.Period,
+			strconv.Itoa(statement.UserId),
+			statement.Username,
+			strconv.Itoa(statement.TokenId),
+			statement.TokenName,
+			modelName,
+			strconv.Itoa(item.RequestCount),
+			strconv.Itoa(item.PromptTokens),
+			strconv.Itoa(item.CompletionTokens),
+			strconv.Itoa(item.CacheTokens),
+			strconv.Itoa(item.CacheCreationTokens),
+			strconv.Itoa(item.Quota),
+			formatStatementAmount(item.Amount),
+			statement.Currency,
+		}
+	}
+	for _, item := range statement.GetItems() {
+		if err := writer.Write(row(item.ModelName, item)); err != nil {
+			return nil, err
+		}
+	}
+	total := model.StatementItem{
+		RequestCount:        statement.RequestCount,
+		PromptTokens:        statement.PromptTokens,
+		CompletionTokens:    statement.CompletionTokens,
+		CacheTokens:         statement.CacheTokens,
+		CacheCreationTokens: statement.CacheCreationTokens,
+		Quota:               statement.Quota,
+		Amount:              statement.Amount,
+	}
+	if err := writer.Write(row("TOTAL", total)); err != nil {
+		return nil, err
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// RenderStatementPDF 将账单渲染为简单的表格式 PDF
+func RenderStatementPDF(statement *model.Statement) ([]byte, error) {
+	doc := newPdfDocument()
+	doc.addLine(16, "Usage Statement "+statement.Period)
+	doc.addLine(10, fmt.Sprintf("User: %s (#%d)", statement.Username, statement.UserId))
+	if statement.TokenId != 0 {
+		doc.addLine(10, fmt.Sprintf("Token: %s (#%d)", statement.TokenName, statement.TokenId))
+	}
+	doc.addLine(10, fmt.Sprintf("Period: %s - %s",
+		time.Unix(statement.StartTime, 0).Format(time.DateOnly),
+		time.Unix(statement.EndTime-1, 0).Format(time.DateOnly)))
+	doc.addLine(10, fmt.Sprintf("Quota per unit: %.0f", statement.QuotaPerUnit))
+	doc.addLine(10, "")
+
+	lineFormat := "%-28s %8s %12s %12s %10s %12s %14s"
+	doc.addLine(8, fmt.Sprintf(lineFormat, "Model", "Requests", "Prompt", "Completion", "Cache", "Quota", "Amount("+statement.Currency+")"))
+	for _, item := range statement.GetItems() {
+		modelName := item.ModelName
+		if len(modelName) > 28 {
+			modelName = modelName[:25] + "..."
+		}
+		doc.addLine(8, fmt.Sprintf(lineFormat, modelName,
+			strconv.Itoa(item.RequestCount),
+			strconv.Itoa(item.PromptTokens),
+			strconv.Itoa(item.CompletionTokens),
+			strconv.Itoa(item.CacheTokens+item.CacheCreationTokens),
+			strconv.Itoa(item.Quota),
+			formatStatementAmount(item.Amount)))
+	}
+	doc.addLine(8, fmt.Sprintf(lineFormat, "TOTAL",
+		strconv.Itoa(statement.RequestCount),
+		strconv.Itoa(statement.PromptTokens),
+		strconv.Itoa(statement.CompletionTokens),
+		strconv.Itoa(statement.CacheTokens+statement.CacheCreationTokens),
+		strconv.Itoa(statement.Quota),
+		formatStatementAmount(statement.Amount)))
+	doc.addLine(10, "")
+	doc.addLine(8, "Generated at "+time.Unix(statement.CreatedTime, 0).Format(time.DateTime))
+	return doc.render(), nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// pdfDocument 极简的 PDF 生成器，仅支持等宽字体的纯文本行，满足账单导出的需要
+// 内置 Courier 字体只覆盖 ASCII，其余字符会被替换为 '?'
+type pdfDocument struct {
+	pages [][]pdfLine
+	y     float64
+}
+
+type pdfLine struct {
+	size float64
+	y    float64
+	text string
+}
+
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 40.0
+)
+
+func newPdfDocument() *pdfDocument {
+	return &pdfDocument{}
+}
+
+func (d *pdfDocument) addLine(size float64, text string) {
+	lineHeight := size * 1.5
+	if len(d.pages) == 0 || d.y-lineHeight < pdfMargin {
+		d.pages = append(d.pages, nil)
+		d.y = pdfPageHeight - pdfMargin
+	}
+	d.y -= lineHeight
+	page := len(d.pages) - 1
+	d.pages[page] = append(d.pages[page], pdfLine{size: size, y: d.y, text: text})
+}
+
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (d *pdfDocument) render() []byte {
+	if len(d.pages) == 0 {
+		d.addLine(10, "")
+	}
+	var objects []string
+	// 1: catalog, 2: pages, 3: font, 之后每页占用 page 与 content 两个对象
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, 0, len(d.pages))
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 4+i*2))
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, lines := range d.pages {
+		var content bytes.Buffer
+		for _, line := range lines {
+			if line.text == "" {
+				continue
+			}
+			content.WriteString(fmt.Sprintf("BT /F1 %.1f Tf %.1f %.1f Td (%s) Tj ET\n", line.size, pdfMargin, line.y, pdfEscape(line.text)))
+		}
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+i*2))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		out.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, obj))
+	}
+	xrefOffset := out.Len()
+	out.WriteString(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(objects)+1))
+	for _, offset := range offsets {
+		out.WriteString(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	out.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset))
+	return out.Bytes()
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"one-api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatementPeriod(t *testing.T) {
+	start, end, err := ParseStatementPeriod("2025-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local).Unix(), start)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local).Unix(), end)
+
+	_, _, err = ParseStatementPeriod("2025/02")
+	assert.Error(t, err)
+}
+
+func TestRenderStatement(t *testing.T) {
+	statement := &model.Statement{
+		UserId:           1,
+		Username:         "alice",
+		Period:           "2025-01",
+		StartTime:        time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local).Unix(),
+		EndTime:          time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local).Unix(),
+		RequestCount:     3,
+		PromptTokens:     300,
+		CompletionTokens: 30,
+		CacheTokens:      100,
+		Quota:            1500,
+		Amount:           0.003,
+		Currency:         "USD",
+		Items:            `[{"model_name":"gpt-4o","request_count":2,"prompt_tokens":200,"completion_tokens":20,"cache_tokens":100,"quota":1000,"amount":0.002},{"model_name":"claude-3(haiku)","request_count":1,"prompt_tokens":100,"completion_tokens":10,"quota":500,"amount":0.001}]`,
+	}
+
+	data, err := RenderStatementCSV(statement)
+	require.NoError(t, err)
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, "gpt-4o", records[1][5])
+	assert.Equal(t, "100", records[1][9])
+	assert.Equal(t, "TOTAL", records[3][5])
+	assert.Equal(t, "1500", records[3][11])
+
+	pdf, err := RenderStatementPDF(statement)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), `claude-3\(haiku\)`)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"one-api/setting/system_setting"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// TaskCallbackPayload 异步任务进入终态时推送给客户端的负载
+type TaskCallbackPayload struct {
+	Event     string `json:"event"`
+	TaskType  string `json:"task_type"`
+	TaskId    string `json:"task_id"`
+	Status    string `json:"status"`
+	Data      any    `json:"data"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+const taskCallbackEvent = "task.finished"
+
+// GetTaskCallbackUrl 从请求体中读取并校验 callback_url，未指定时返回空字符串
+func GetTaskCallbackUrl(c *gin.Context) (string, error) {
+	requestBody, err := common.GetRequestBody(c)
+	if err != nil {
+		return "", err
+	}
+	callbackUrl := gjson.GetBytes(requestBody, "callback_url").String()
+	if callbackUrl == "" {
+		return "", nil
+	}
+	if !operation_setting.GetTaskCallbackSetting().Enabled {
+		return "", errors.New("task callback is disabled")
+	}
+	if len(callbackUrl) > 1024 {
+		return "", errors.New("callback_url is too long")
+	}
+	fetchSetting := system_setting.GetFetchSetting()
+	if err = common.ValidateURLWithFetchSetting(callbackUrl, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+		return "", fmt.Errorf("invalid callback_url: %v", err)
+	}
+	return callbackUrl, nil
+}
+
+// NotifyTaskCallback 任务进入终态时异步投递回调，callbackUrl 为空时不做任何事
+func NotifyTaskCallback(userId int, taskType string, taskId string, status string, callbackUrl string, data any) {
+	if callbackUrl == "" || !operation_setting.GetTaskCallbackSetting().Enabled {
+		return
+	}
+	payload, err := common.Marshal(TaskCallbackPayload{
+		Event:     taskCallbackEvent,
+		TaskType:  taskType,
+		TaskId:    taskId,
+		Status:    status,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to marshal callback payload of task %s: %s", taskId, err.Error()))
+		return
+	}
+	callback := &model.TaskCallback{
+		UserId:   userId,
+		TaskType: taskType,
+		TaskId:   taskId,
+		Event:    status,
+		Url:      callbackUrl,
+		Payload:  string(payload),
+		Attempt:  1,
+	}
+	gopool.Go(func() {
+		DeliverTaskCallback(callback)
+	})
+}
+
+// DeliverTaskCallback 投递一次回调并保存投递记录，失败且未达到最大次数时按指数退避安排重试
+func DeliverTaskCallback(callback *model.TaskCallback) {
+	callbackSetting := operation_setting.GetTaskCallbackSetting()
+	secret := ""
+	if userSetting, err := model.GetUserSetting(callback.UserId, false); err == nil {
+		secret = userSetting.WebhookSecret
+	}
+	client := &http.Client{
+		Transport: GetHttpClient().Transport,
+		Timeout:   time.Duration(callbackSetting.TimeoutSeconds) * time.Second,
+	}
+	statusCode, err := postWebhook(client, callback.Url, secret, []byte(callback.Payload))
+	callback.StatusCode = statusCode
+	if err == nil {
+		callback.Status = common.TaskCallbackStatusSuccess
+	} else {
+		callback.Error = err.Error()
+		callback.Status = common.TaskCallbackStatusFailed
+		if callback.Attempt < callbackSetting.MaxAttempts {
+			callback.Status = common.TaskCallbackStatusRetrying
+			callback.NextRetryAt = time.Now().Add(taskCallbackBackoff(callbackSetting.BackoffBaseSeconds, callback.Attempt)).Unix()
+		}
+	}
+	if err := callback.Insert(); err != nil {
+		common.SysError(fmt.Sprintf("failed to save callback of task %s: %s", callback.TaskId, err.Error()))
+	}
+}
+
+// taskCallbackBackoff 第 attempt 次投递失败后的等待时间
+func taskCallbackBackoff(baseSeconds int, attempt int) time.Duration {
+	if baseSeconds <= 0 {
+		baseSeconds = 1
+	}
+	if attempt > 16 {
+		attempt = 16
+	}
+	return time.Duration(baseSeconds) * time.Second << (attempt - 1)
+}
+
+// RetryTaskCallback 基于一条投递记录发起下一次投递
+func RetryTaskCallback(previous *model.TaskCallback) {
+	DeliverTaskCallback(&model.TaskCallback{
+		UserId:   previous.UserId,
+		TaskType: previous.TaskType,
+		TaskId:   previous.TaskId,
+		Event:    previous.Event,
+		Url:      previous.Url,
+		Payload:  previous.Payload,
+		Attempt:  previous.Attempt + 1,
+	})
+}
+
+// StartTaskCallbackRetryTask 定时重试到期的回调，领取后再投递以免与手动重试重复
+func StartTaskCallbackRetryTask() {
+	for {
+		time.Sleep(10 * time.Second)
+		callbacks, err := model.GetDueTaskCallbacks(time.Now().Unix(), 100)
+		if err != nil {
+			common.SysError("failed to get due task callbacks: " + err.Error())
+			continue
+		}
+		for _, callback := range callbacks {
+			claimed, err := model.ClaimTaskCallbackRetry(callback.Id)
+			if err != nil || !claimed {
+				continue
+			}
+			gopool.Go(func() {
+				RetryTaskCallback(callback)
+			})
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"one-api/setting/system_setting"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTaskCallbackUrl(t *testing.T) {
+	newContext := func(body string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/video/generations", strings.NewReader(body))
+		return c
+	}
+
+	callbackUrl, err := GetTaskCallbackUrl(newContext(`{"model":"kling-v1"}`))
+	require.NoError(t, err)
+	assert.Empty(t, callbackUrl)
+
+	callbackUrl, err = GetTaskCallbackUrl(newContext(`{"callback_url":"https://93.184.216.34/hook"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "https://93.184.216.34/hook", callbackUrl)
+
+	// 默认开启 SSRF 防护，内网地址与非常用端口都会被拒绝
+	_, err = GetTaskCallbackUrl(newContext(`{"callback_url":"http://127.0.0.1/hook"}`))
+	assert.Error(t, err)
+	_, err = GetTaskCallbackUrl(newContext(`{"callback_url":"https://93.184.216.34:6379/hook"}`))
+	assert.Error(t, err)
+}
+
+func TestPostWebhookSignature(t *testing.T) {
+	fetchSetting := system_setting.GetFetchSetting()
+	fetchSetting.EnableSSRFProtection = false
+	defer func() {
+		fetchSetting.EnableSSRFProtection = true
+	}()
+
+	payload := []byte(`{"event":"task.finished","task_id":"task_1"}`)
+	var received []byte
+	var signature string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Webhook-Signature")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	statusCode, err := postWebhook(server.Client(), server.URL, "secret", payload)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, payload, received)
+	assert.Equal(t, generateSignature("secret", payload), signature)
+
+	status = http.StatusBadGateway
+	statusCode, err = postWebhook(server.Client(), server.URL, "", payload)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadGateway, statusCode)
+	assert.Empty(t, signature)
+}
+
+func TestPostWebhookNoRedirect(t *testing.T) {
+	fetchSetting := system_setting.GetFetchSetting()
+	fetchSetting.EnableSSRFProtection = false
+	defer func() {
+		fetchSetting.EnableSSRFProtection = true
+	}()
+
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// 重定向目标未经过 SSRF 校验，不能跟随
+	statusCode, err := postWebhook(server.Client(), server.URL, "", []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, statusCode)
+	assert.False(t, redirected)
+}
+
+func TestTaskCallbackBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, taskCallbackBackoff(10, 1))
+	assert.Equal(t, 40*time.Second, taskCallbackBackoff(10, 3))
+	assert.Equal(t, taskCallbackBackoff(10, 16), taskCallbackBackoff(10, 30))
+}
@@ -0,0 +1,23 @@
+package service
+
+import "one-api/setting/operation_setting"
+
+// maxTaskPollBackoff 退避倍数的指数上限
+const maxTaskPollBackoff = 5
+
+// TaskPollInterval 计算任务下次轮询的间隔（秒）
+// pollCount 为进度连续未变化的轮询次数，每多一次间隔翻倍，直到达到配置的上限
+func TaskPollInterval(platform string, pollCount int) int {
+	pollSetting := operation_setting.GetTaskPollSetting()
+	interval := pollSetting.GetTaskPollInterval(platform)
+	if pollCount > maxTaskPollBackoff {
+		pollCount = maxTaskPollBackoff
+	}
+	if pollCount > 0 {
+		interval <<= pollCount
+	}
+	if pollSetting.MaxIntervalSeconds > 0 && interval > pollSetting.MaxIntervalSeconds {
+		interval = pollSetting.MaxIntervalSeconds
+	}
+	return interval
+}
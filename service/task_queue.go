@@ -0,0 +1,167 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"os"
+	"sync"
+)
+
+// TaskQueueNodeId 当前节点在网关任务队列中的标识，排队请求的上下文只保存在入队节点的内存中
+var TaskQueueNodeId = func() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), common.GetRandomString(8))
+}()
+
+// 已放行但尚未写入任务记录的提交数，避免并发提交在任务入库前同时通过检查而超出上限
+var (
+	taskQueueInflightUser    = make(map[int]int)
+	taskQueueInflightChannel = make(map[int]int)
+	taskQueueInflightLock    sync.Mutex
+)
+
+// TaskQueueUsage 用户与渠道当前占用的并发数
+type TaskQueueUsage struct {
+	User    map[int]int
+	Channel map[int]int
+}
+
+// LoadTaskQueueUsage 统计指定用户与渠道正在执行的任务数，包括本节点已放行但尚未入库的提交
+func LoadTaskQueueUsage(userIds []int, channelIds []int) (*TaskQueueUsage, error) {
+	usage, err := loadRunningTaskUsage(userIds, channelIds)
+	if err != nil {
+		return nil, err
+	}
+	taskQueueInflightLock.Lock()
+	defer taskQueueInflightLock.Unlock()
+	usage.addInflight()
+	return usage, nil
+}
+
+func loadRunningTaskUsage(userIds []int, channelIds []int) (*TaskQueueUsage, error) {
+	usage := &TaskQueueUsage{
+		User:    make(map[int]int, len(userIds)),
+		Channel: make(map[int]int, len(channelIds)),
+	}
+	for _, userId := range userIds {
+		if _, ok := usage.User[userId]; ok {
+			continue
+		}
+		count, err := model.CountUserRunningTasks(userId)
+		if err != nil {
+			return nil, err
+		}
+		usage.User[userId] = int(count)
+	}
+	for _, channelId := range channelIds {
+		if _, ok := usage.Channel[channelId]; ok {
+			continue
+		}
+		count, err := model.CountChannelRunningTasks(channelId)
+		if err != nil {
+			return nil, err
+		}
+		usage.Channel[channelId] = int(count)
+	}
+	return usage, nil
+}
+
+// addInflight 累加本节点已放行的提交数，调用方需持有 taskQueueInflightLock
+func (u *TaskQueueUsage) addInflight() {
+	for userId := range u.User {
+		u.User[userId] += taskQueueInflightUser[userId]
+	}
+	for channelId := range u.Channel {
+		u.Channel[channelId] += taskQueueInflightChannel[channelId]
+	}
+}
+
+// Available 用户与渠道是否都还有空闲的并发名额
+func (u *TaskQueueUsage) Available(setting *operation_setting.TaskQueueSetting, userId int, channelId int) bool {
+	if !setting.Limited() {
+		return true
+	}
+	if setting.UserConcurrency > 0 && u.User[userId] >= setting.UserConcurrency {
+		return false
+	}
+	if setting.ChannelConcurrency > 0 && u.Channel[channelId] >= setting.ChannelConcurrency {
+		return false
+	}
+	return true
+}
+
+// PlanTaskQueueDispatch 按调度顺序为排队的请求分配并发名额，返回可以提交上游的请求
+// 名额已满的用户或渠道后续的请求继续等待，因此同一用户或渠道内保持先进先出
+func PlanTaskQueueDispatch(items []*model.TaskQueueItem, usage *TaskQueueUsage, setting *operation_setting.TaskQueueSetting) []*model.TaskQueueItem {
+	ready := make([]*model.TaskQueueItem, 0)
+	for _, item := range items {
+		if !usage.Available(setting, item.UserId, item.ChannelId) {
+			continue
+		}
+		usage.User[item.UserId]++
+		usage.Channel[item.ChannelId]++
+		ready = append(ready, item)
+	}
+	return ready
+}
+
+// TaskQueueReserve 为直接提交的请求占用并发名额，返回 false 表示名额已满或已有请求在排队，需要进入队列
+// 返回 true 时调用方在任务入库（或提交失败）后需要调用 TaskQueueRelease 释放
+func TaskQueueReserve(userId int, channelId int) (bool, error) {
+	setting := operation_setting.GetTaskQueueSetting()
+	if !setting.Limited() {
+		TaskQueueAcquire(userId, channelId)
+		return true, nil
+	}
+	// 只有设置了上限的维度才需要排在已有请求之后
+	checkUserId, checkChannelId := 0, 0
+	if setting.UserConcurrency > 0 {
+		checkUserId = userId
+	}
+	if setting.ChannelConcurrency > 0 {
+		checkChannelId = channelId
+	}
+	queued, err := model.HasTaskQueueItems(checkUserId, checkChannelId)
+	if err != nil {
+		return false, err
+	}
+	if queued {
+		return false, nil
+	}
+	usage, err := loadRunningTaskUsage([]int{userId}, []int{channelId})
+	if err != nil {
+		return false, err
+	}
+	// 判断与占用名额在同一把锁内完成，本节点的并发提交不会同时通过检查
+	taskQueueInflightLock.Lock()
+	defer taskQueueInflightLock.Unlock()
+	usage.addInflight()
+	if !usage.Available(setting, userId, channelId) {
+		return false, nil
+	}
+	taskQueueInflightUser[userId]++
+	taskQueueInflightChannel[channelId]++
+	return true, nil
+}
+
+// TaskQueueAcquire 为从队列调度的请求占用并发名额，名额已在调度时分配，这里只记录放行数
+func TaskQueueAcquire(userId int, channelId int) {
+	taskQueueInflightLock.Lock()
+	defer taskQueueInflightLock.Unlock()
+	taskQueueInflightUser[userId]++
+	taskQueueInflightChannel[channelId]++
+}
+
+// TaskQueueRelease 释放 TaskQueueReserve 或 TaskQueueAcquire 占用的名额
+func TaskQueueRelease(userId int, channelId int) {
+	taskQueueInflightLock.Lock()
+	defer taskQueueInflightLock.Unlock()
+	if taskQueueInflightUser[userId]--; taskQueueInflightUser[userId] <= 0 {
+		delete(taskQueueInflightUser, userId)
+	}
+	if taskQueueInflightChannel[channelId]--; taskQueueInflightChannel[channelId] <= 0 {
+		delete(taskQueueInflightChannel, channelId)
+	}
+}
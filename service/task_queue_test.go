@@ -0,0 +1,41 @@
+package service
+
+import (
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanTaskQueueDispatch(t *testing.T) {
+	setting := &operation_setting.TaskQueueSetting{
+		Enabled:            true,
+		UserConcurrency:    2,
+		ChannelConcurrency: 3,
+	}
+	items := []*model.TaskQueueItem{
+		{Id: 1, UserId: 1, ChannelId: 1},
+		{Id: 2, UserId: 1, ChannelId: 1},
+		{Id: 3, UserId: 1, ChannelId: 2},
+		{Id: 4, UserId: 2, ChannelId: 1},
+		{Id: 5, UserId: 3, ChannelId: 1},
+	}
+	usage := &TaskQueueUsage{
+		User:    map[int]int{1: 1, 2: 0, 3: 0},
+		Channel: map[int]int{1: 1, 2: 0},
+	}
+	ready := PlanTaskQueueDispatch(items, usage, setting)
+	ids := make([]int, 0, len(ready))
+	for _, item := range ready {
+		ids = append(ids, item.Id)
+	}
+	// 用户 1 只剩一个名额，渠道 1 只剩两个名额
+	assert.Equal(t, []int{1, 4}, ids)
+	assert.Equal(t, 2, usage.User[1])
+	assert.Equal(t, 3, usage.Channel[1])
+
+	// 未启用时全部放行
+	setting.Enabled = false
+	assert.Len(t, PlanTaskQueueDispatch(items, usage, setting), len(items))
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/logger"
+	"one-api/model"
+)
+
+// TaskRefundQuota 计算任务按当前状态应退还的总额度
+// 失败退还全部预扣额度；成功且上游报告的实际时长少于请求时长时，按比例退还差额，不追加扣费
+// 按首计费的音乐任务实际生成的歌曲数少于预扣歌曲数时，同样按比例退还
+// 上游返回了失败原因并已结束、但状态不是失败的任务（如 Suno）同样退还全部预扣额度
+func TaskRefundQuota(task *model.Task) int {
+	if task.Quota <= 0 {
+		return 0
+	}
+	switch task.Status {
+	case model.TaskStatusFailure:
+		return task.Quota
+	case model.TaskStatusSuccess:
+		if songs, used := task.Properties.SongCount, task.Properties.UsageSongCount; songs > 0 && used > 0 && used < songs {
+			return task.Quota - task.Quota*used/songs
+		}
+		requested := task.Properties.Duration
+		used := task.Properties.UsageDuration
+		if requested > 0 && used > 0 && used < requested {
+			return task.Quota - int(float64(task.Quota)*used/requested)
+		}
+	default:
+		if task.Progress == "100%" && task.FailReason != "" {
+			return task.Quota
+		}
+	}
+	return 0
+}
+
+// SettleTask 结算进入终态的任务，返回本次退还的额度
+// 已退还的额度记录在 RefundQuota 中，重复结算只退还差额，因此可以安全地多次调用
+func SettleTask(task *model.Task) (int, error) {
+	refundQuota := TaskRefundQuota(task)
+	delta := refundQuota - task.RefundQuota
+	if delta <= 0 {
+		return 0, nil
+	}
+	// 先记录退还额度再增加用户额度，避免并发结算重复退还；增加额度失败时回滚记录，之后可以重新结算
+	previousRefundQuota := task.RefundQuota
+	updated, err := model.TaskUpdateRefundQuota(task.ID, previousRefundQuota, refundQuota)
+	if err != nil {
+		return 0, err
+	}
+	if !updated {
+		return 0, errors.New("task refund quota has been changed, please reload the task and retry")
+	}
+	if err = model.IncreaseUserQuota(task.UserId, delta, false); err != nil {
+		common.SysError(fmt.Sprintf("failed to refund %d quota of task %s to user %d: %s", delta, task.TaskID, task.UserId, err.Error()))
+		if _, rollbackErr := model.TaskUpdateRefundQuota(task.ID, refundQuota, previousRefundQuota); rollbackErr != nil {
+			common.SysError(fmt.Sprintf("failed to roll back refund quota of task %s: %s", task.TaskID, rollbackErr.Error()))
+		}
+		return 0, err
+	}
+	task.RefundQuota = refundQuota
+	var logContent string
+	if task.Status != model.TaskStatusSuccess {
+		logContent = fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, logger.LogQuota(delta))
+	} else if task.Properties.SongCount > 0 {
+		logContent = fmt.Sprintf("音乐任务实际生成 %d 首，少于预扣的 %d 首 %s，补偿 %s",
+			task.Properties.UsageSongCount, task.Properties.SongCount, task.TaskID, logger.LogQuota(delta))
+	} else {
+		logContent = fmt.Sprintf("异步任务实际时长 %.1f 秒，少于请求时长 %.1f 秒 %s，补偿 %s",
+			task.Properties.UsageDuration, task.Properties.Duration, task.TaskID, logger.LogQuota(delta))
+	}
+	model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+	return delta, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskRefundQuota(t *testing.T) {
+	task := &model.Task{Quota: 1000, Status: model.TaskStatusFailure}
+	assert.Equal(t, 1000, TaskRefundQuota(task))
+
+	task.Status = model.TaskStatusInProgress
+	assert.Equal(t, 0, TaskRefundQuota(task))
+
+	// 成功但未报告实际时长，不做调整
+	task.Status = model.TaskStatusSuccess
+	task.Properties.Duration = 10
+	assert.Equal(t, 0, TaskRefundQuota(task))
+
+	task.Properties.UsageDuration = 5
+	assert.Equal(t, 500, TaskRefundQuota(task))
+
+	// 实际时长超过请求时长时不追加扣费
+	task.Properties.UsageDuration = 12
+	assert.Equal(t, 0, TaskRefundQuota(task))
+
+	// 按首计费的音乐任务只生成了部分歌曲
+	task = &model.Task{Quota: 1000, Status: model.TaskStatusSuccess}
+	task.Properties.SongCount = 2
+	assert.Equal(t, 0, TaskRefundQuota(task))
+	task.Properties.UsageSongCount = 1
+	assert.Equal(t, 500, TaskRefundQuota(task))
+	task.Properties.UsageSongCount = 2
+	assert.Equal(t, 0, TaskRefundQuota(task))
+
+	// 上游返回失败原因但状态未置为失败，结束后全额退还
+	task = &model.Task{Quota: 1000, Status: model.TaskStatusInProgress, FailReason: "content moderated"}
+	assert.Equal(t, 0, TaskRefundQuota(task))
+	task.Progress = "100%"
+	assert.Equal(t, 1000, TaskRefundQuota(task))
+}
+
+func setupSettleTaskTest(t *testing.T, models ...any) {
+	setupSQLiteTestDB(t, models...)
+	originLogDB, redisEnabled, batchUpdateEnabled := model.LOG_DB, common.RedisEnabled, common.BatchUpdateEnabled
+	model.LOG_DB, common.RedisEnabled, common.BatchUpdateEnabled = model.DB, false, false
+	t.Cleanup(func() {
+		model.LOG_DB, common.RedisEnabled, common.BatchUpdateEnabled = originLogDB, redisEnabled, batchUpdateEnabled
+	})
+}
+
+func TestSettleTask(t *testing.T) {
+	setupSettleTaskTest(t, &model.Task{}, &model.User{}, &model.Log{})
+	require.NoError(t, model.DB.Create(&model.User{Id: 1, Username: "settle_user", Quota: 0}).Error)
+	task := &model.Task{ID: 1, UserId: 1, TaskID: "song_1", Quota: 1000, Status: model.TaskStatusSuccess}
+	task.Properties.SongCount = 2
+	task.Properties.UsageSongCount = 1
+	require.NoError(t, model.DB.Create(task).Error)
+
+	refund, err := SettleTask(task)
+	require.NoError(t, err)
+	assert.Equal(t, 500, refund)
+	assert.Equal(t, 500, task.RefundQuota)
+
+	// 重复结算不再退还
+	refund, err = SettleTask(task)
+	require.NoError(t, err)
+	assert.Equal(t, 0, refund)
+
+	// 状态变化后只退还差额
+	task.Status = model.TaskStatusFailure
+	refund, err = SettleTask(task)
+	require.NoError(t, err)
+	assert.Equal(t, 500, refund)
+	user, err := model.GetUserById(1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, user.Quota)
+}
+
+func TestSettleTaskRollsBackRefundQuota(t *testing.T) {
+	// 缺少用户表使增加用户额度失败
+	setupSettleTaskTest(t, &model.Task{}, &model.Log{})
+	task := &model.Task{ID: 1, UserId: 1, TaskID: "video_1", Quota: 1000, Status: model.TaskStatusFailure}
+	require.NoError(t, model.DB.Create(task).Error)
+
+	_, err := SettleTask(task)
+	assert.Error(t, err)
+	assert.Equal(t, 0, task.RefundQuota)
+	stored, err := model.GetTaskById(1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.RefundQuota)
+
+	// 恢复后可以重新结算
+	require.NoError(t, model.DB.AutoMigrate(&model.User{}))
+	require.NoError(t, model.DB.Create(&model.User{Id: 1, Username: "settle_user"}).Error)
+	refund, err := SettleTask(stored)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, refund)
+}
@@ -11,6 +11,7 @@ import (
 	"log"
 	"math"
 	"one-api/common"
+	"one-api/common/tracing"
 	"one-api/constant"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
@@ -21,10 +22,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/tiktoken-go/tokenizer"
-	"github.com/tiktoken-go/tokenizer/codec"
 )
 
-// tokenEncoderMap won't grow after initialization
+// defaultTokenEncoder is used for models that match no registered tokenizer
 var defaultTokenEncoder tokenizer.Codec
 
 // tokenEncoderMap is used to store token encoders for different models
@@ -35,7 +35,9 @@ var tokenEncoderMutex sync.RWMutex
 
 func InitTokenEncoders() {
 	common.SysLog("initializing token encoders")
-	defaultTokenEncoder = codec.NewCl100kBase()
+	defaultTokenEncoder = newCl100kBase()
+	tokenizerVocabDir = common.GetEnvOrDefaultString("TOKENIZER_VOCAB_DIR", "")
+	registerDefaultTokenizers()
 	common.SysLog("token encoders initialized")
 }
 
@@ -57,7 +59,13 @@ func getTokenEncoder(model string) tokenizer.Codec {
 		return encoder
 	}
 
-	// Create new encoder
+	// Resolve from the tokenizer registry, vocabularies are loaded lazily on first use
+	if encoder := resolveTokenizer(model); encoder != nil {
+		tokenEncoderMap[model] = encoder
+		return encoder
+	}
+
+	// Fall back to the legacy OpenAI model table
 	modelCodec, err := tokenizer.ForModel(tokenizer.Model(model))
 	if err != nil {
 		// Cache the default encoder for this model to avoid repeated failures
@@ -78,7 +86,7 @@ func getTokenNum(tokenEncoder tokenizer.Codec, text string) int {
 	return tkm
 }
 
-func getImageToken(fileMeta *types.FileMeta, model string, stream bool) (int, error) {
+func getImageToken(fileMeta *types.FileMeta, model string, stream bool, fetchRemote bool) (int, error) {
 	if fileMeta == nil {
 		return 0, fmt.Errorf("image_url_is_nil")
 	}
@@ -162,6 +170,10 @@ func getImageToken(fileMeta *types.FileMeta, model string, stream bool) (int, er
 		config, format, b64str, err = DecodeBase64ImageData(fileMeta.ParsedData.Base64Data)
 	} else {
 		if strings.HasPrefix(fileMeta.OriginData, "http") {
+			// 不下载远程图片时无法得知尺寸，按未统计图片 token 时的方式估算
+			if !fetchRemote {
+				return 3 * baseTokens, nil
+			}
 			config, format, err = DecodeUrlImageData(fileMeta.OriginData)
 		} else {
 			common.SysLog(fmt.Sprintf("decoding image"))
@@ -253,6 +265,8 @@ func getImageToken(fileMeta *types.FileMeta, model string, stream bool) (int, er
 }
 
 func CountRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *relaycommon.RelayInfo) (int, error) {
+	_, span := tracing.Start(c.Request.Context(), "relay.count_tokens")
+	defer span.End()
 	if !constant.GetMediaToken {
 		return 0, nil
 	}
@@ -283,6 +297,8 @@ func CountRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *relayco
 	}
 
 	shouldFetchFiles := true
+	// 费用估算等场景不下载远程文件，统一按图片估算
+	skipRemoteMedia := common.GetContextKeyBool(c, constant.ContextKeySkipRemoteMedia)
 
 	if info.RelayFormat == types.RelayFormatGemini {
 		shouldFetchFiles = false
@@ -290,7 +306,9 @@ func CountRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *relayco
 
 	if shouldFetchFiles {
 		for _, file := range meta.Files {
-			if strings.HasPrefix(file.OriginData, "http") {
+			if strings.HasPrefix(file.OriginData, "http") && skipRemoteMedia {
+				file.FileType = types.FileTypeImage
+			} else if strings.HasPrefix(file.OriginData, "http") {
 				mineType, err := GetFileTypeFromUrl(c, file.OriginData, "token_counter")
 				if err != nil {
 					return 0, fmt.Errorf("error getting file base64 from url: %v", err)
@@ -339,7 +357,7 @@ func CountRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *relayco
 			if info.RelayFormat == types.RelayFormatGemini {
 				tkm += 256
 			} else {
-				token, err := getImageToken(file, model, info.IsStream)
+				token, err := getImageToken(file, model, info.IsStream, !skipRemoteMedia)
 				if err != nil {
 					return 0, fmt.Errorf("error counting image token, media index[%d], original data[%s], err: %v", i, file.OriginData, err)
 				}
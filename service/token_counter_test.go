@@ -0,0 +1,46 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	relaycommon "one-api/relay/common"
+	"one-api/types"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountRequestTokenSkipRemoteMedia(t *testing.T) {
+	InitTokenEncoders()
+	getMediaToken, getMediaTokenNotStream := constant.GetMediaToken, constant.GetMediaTokenNotStream
+	constant.GetMediaToken, constant.GetMediaTokenNotStream = true, true
+	t.Cleanup(func() {
+		constant.GetMediaToken, constant.GetMediaTokenNotStream = getMediaToken, getMediaTokenNotStream
+	})
+
+	fetched := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+	}))
+	defer server.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	common.SetContextKey(c, constant.ContextKeyOriginalModel, "gpt-4o")
+	common.SetContextKey(c, constant.ContextKeySkipRemoteMedia, true)
+	meta := &types.TokenCountMeta{
+		CombineText: "hello",
+		Files:       []*types.FileMeta{{OriginData: server.URL + "/cat.png"}},
+	}
+	info := &relaycommon.RelayInfo{RelayFormat: types.RelayFormatOpenAI}
+
+	tokens, err := CountRequestToken(c, meta, info)
+	require.NoError(t, err)
+	assert.False(t, fetched)
+	// 远程图片按 3 倍基础 token 估算
+	assert.GreaterOrEqual(t, tokens, 3*85)
+}
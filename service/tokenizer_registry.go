@@ -0,0 +1,321 @@
+package service
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"one-api/common"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/regexp2"
+	"github.com/tiktoken-go/tokenizer"
+	"github.com/tiktoken-go/tokenizer/codec"
+)
+
+// TokenizerLoader 构造分词器，仅在第一次命中对应模型时调用
+type TokenizerLoader func() (tokenizer.Codec, error)
+
+type tokenizerEntry struct {
+	name     string
+	patterns []string
+	loader   TokenizerLoader
+
+	once  sync.Once
+	codec tokenizer.Codec
+}
+
+func (e *tokenizerEntry) get() tokenizer.Codec {
+	e.once.Do(func() {
+		c, err := e.loader()
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to load tokenizer %s: %s", e.name, err.Error()))
+			return
+		}
+		common.SysLog(fmt.Sprintf("tokenizer %s loaded (%s)", e.name, c.GetName()))
+		e.codec = c
+	})
+	return e.codec
+}
+
+var tokenizerRegistry []*tokenizerEntry
+var tokenizerRegistryMutex sync.RWMutex
+
+// tokenizerVocabDir 存放 tiktoken 格式词表文件的目录（TOKENIZER_VOCAB_DIR）
+// 程序只内置 OpenAI 的 o200k_base 与 cl100k_base 词表，Llama 3、Qwen、GLM 的词表需自行放入该目录，
+// 未配置或文件不存在时这些模型直接使用相近的 OpenAI 词表计数，名称中带有 "≈"
+var tokenizerVocabDir string
+
+// RegisterTokenizer 注册分词器，patterns 为小写模型名，支持 * 前后缀通配
+// 按注册顺序匹配，越具体的规则应越先注册；同名注册会覆盖原有规则
+func RegisterTokenizer(name string, patterns []string, loader TokenizerLoader) {
+	tokenizerRegistryMutex.Lock()
+	defer tokenizerRegistryMutex.Unlock()
+	entry := &tokenizerEntry{name: name, patterns: patterns, loader: loader}
+	for i, e := range tokenizerRegistry {
+		if e.name == name {
+			tokenizerRegistry[i] = entry
+			return
+		}
+	}
+	tokenizerRegistry = append(tokenizerRegistry, entry)
+}
+
+func matchTokenizerPattern(pattern string, model string) bool {
+	prefix := strings.HasPrefix(pattern, "*")
+	suffix := strings.HasSuffix(pattern, "*")
+	core := strings.Trim(pattern, "*")
+	switch {
+	case prefix && suffix:
+		return strings.Contains(model, core)
+	case suffix:
+		return strings.HasPrefix(model, core)
+	case prefix:
+		return strings.HasSuffix(model, core)
+	default:
+		return model == core
+	}
+}
+
+// resolveTokenizer 返回模型匹配到的分词器，未匹配或加载失败时返回 nil
+func resolveTokenizer(model string) tokenizer.Codec {
+	lowerModel := strings.ToLower(model)
+	tokenizerRegistryMutex.RLock()
+	defer tokenizerRegistryMutex.RUnlock()
+	for _, entry := range tokenizerRegistry {
+		for _, pattern := range entry.patterns {
+			if matchTokenizerPattern(pattern, lowerModel) {
+				return entry.get()
+			}
+		}
+	}
+	return nil
+}
+
+// approxCodec 没有官方词表时直接使用相近的词表计数，只修改名称以便在日志中区分近似结果
+// 不按系数折算：折算系数需要用官方分词器在真实语料上测得，见 TestApproximateTokenizers
+type approxCodec struct {
+	tokenizer.Codec
+	name string
+}
+
+func (c *approxCodec) GetName() string {
+	return c.name
+}
+
+func approxLoader(name string, base func() tokenizer.Codec) TokenizerLoader {
+	return func() (tokenizer.Codec, error) {
+		return &approxCodec{Codec: base(), name: name}, nil
+	}
+}
+
+// bpeCodec 基于 tiktoken 格式词表（每行 "base64 token rank"）的字节级 BPE 分词器
+type bpeCodec struct {
+	name        string
+	ranks       map[string]uint
+	reverse     map[uint]string
+	reverseOnce sync.Once
+	splitRegexp *regexp2.Regexp
+}
+
+func loadTiktokenFile(name string, path string, pattern string) (*bpeCodec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	ranks := make(map[string]uint)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid vocab line: %s", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid vocab token %s: %w", fields[0], err)
+		}
+		rank, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vocab rank %s: %w", fields[1], err)
+		}
+		ranks[string(token)] = uint(rank)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("empty vocab: %s", path)
+	}
+	// 字节级 BPE 词表包含全部单字节 token，未登录的片段可以退回按字节切分
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("vocab %s is missing byte token 0x%02x", path, b)
+		}
+	}
+	splitRegexp, err := regexp2.Compile(pattern, regexp2.None)
+	if err != nil {
+		return nil, err
+	}
+	return &bpeCodec{name: name, ranks: ranks, splitRegexp: splitRegexp}, nil
+}
+
+func (c *bpeCodec) GetName() string {
+	return c.name
+}
+
+func (c *bpeCodec) tokenize(input string, yield func(uint, string)) error {
+	match, err := c.splitRegexp.FindStringMatch(input)
+	if err != nil {
+		return err
+	}
+	for match != nil {
+		piece := match.String()
+		if id, ok := c.ranks[piece]; ok {
+			yield(id, piece)
+		} else {
+			bounds := c.merge(piece)
+			for i := 0; i < len(bounds)-1; i++ {
+				token := piece[bounds[i]:bounds[i+1]]
+				if id, ok := c.ranks[token]; ok {
+					yield(id, token)
+					continue
+				}
+				for j := 0; j < len(token); j++ {
+					yield(c.ranks[token[j:j+1]], token[j:j+1])
+				}
+			}
+		}
+		if match, err = c.splitRegexp.FindNextMatch(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge 反复合并相邻且 rank 最小的片段，返回最终各 token 在 piece 中的边界
+func (c *bpeCodec) merge(piece string) []int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		minRank := uint(math.MaxUint)
+		minIndex := -1
+		for i := 0; i < len(bounds)-2; i++ {
+			if rank, ok := c.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < minRank {
+				minRank = rank
+				minIndex = i
+			}
+		}
+		if minIndex < 0 {
+			break
+		}
+		bounds = append(bounds[:minIndex+1], bounds[minIndex+2:]...)
+	}
+	return bounds
+}
+
+func (c *bpeCodec) Count(input string) (int, error) {
+	count := 0
+	err := c.tokenize(input, func(uint, string) { count++ })
+	return count, err
+}
+
+func (c *bpeCodec) Encode(input string) ([]uint, []string, error) {
+	var ids []uint
+	var tokens []string
+	err := c.tokenize(input, func(id uint, token string) {
+		ids = append(ids, id)
+		tokens = append(tokens, token)
+	})
+	return ids, tokens, err
+}
+
+func (c *bpeCodec) Decode(ids []uint) (string, error) {
+	c.reverseOnce.Do(func() {
+		c.reverse = make(map[uint]string, len(c.ranks))
+		for token, id := range c.ranks {
+			c.reverse[id] = token
+		}
+	})
+	var sb strings.Builder
+	for _, id := range ids {
+		token, ok := c.reverse[id]
+		if !ok {
+			return "", fmt.Errorf("invalid token: %d", id)
+		}
+		sb.WriteString(token)
+	}
+	return sb.String(), nil
+}
+
+// vocabLoader 优先从 tokenizerVocabDir 加载词表，文件不存在时使用 fallback
+func vocabLoader(name string, fileName string, pattern string, fallback TokenizerLoader) TokenizerLoader {
+	return func() (tokenizer.Codec, error) {
+		if tokenizerVocabDir != "" {
+			path := filepath.Join(tokenizerVocabDir, fileName)
+			if _, err := os.Stat(path); err == nil {
+				return loadTiktokenFile(name, path, pattern)
+			}
+		}
+		return fallback()
+	}
+}
+
+const (
+	// llama3SplitPattern 同样适用于 GLM-4 的 tiktoken 词表
+	llama3SplitPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+	qwenSplitPattern   = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+)
+
+func newO200kBase() tokenizer.Codec {
+	return codec.NewO200kBase()
+}
+
+func newCl100kBase() tokenizer.Codec {
+	return codec.NewCl100kBase()
+}
+
+func codecLoader(base func() tokenizer.Codec) TokenizerLoader {
+	return func() (tokenizer.Codec, error) {
+		return base(), nil
+	}
+}
+
+// registerDefaultTokenizers 注册内置分词器
+// 精确计数只覆盖 OpenAI 系列以及在 tokenizerVocabDir 中提供了词表的 Llama 3、Qwen、GLM；
+// Claude 未公开词表，Gemini、Gemma 为 SentencePiece 模型，程序不内置这些词表，
+// 它们与未提供词表的 Llama 3、Qwen、GLM 一样直接使用相近的 OpenAI 词表计数，只作为预扣与估算的参考，
+// 实际计费以上游返回的用量为准
+func registerDefaultTokenizers() {
+	RegisterTokenizer("o200k_base", []string{
+		"gpt-4o*", "chatgpt-4o*", "gpt-4.1*", "gpt-4.5*", "gpt-5*", "gpt-oss*", "o1*", "o3*", "o4*", "codex-*",
+	}, codecLoader(newO200kBase))
+	RegisterTokenizer("cl100k_base", []string{
+		"gpt-4*", "gpt-3.5*", "text-embedding-*",
+	}, codecLoader(newCl100kBase))
+	RegisterTokenizer("llama3", []string{
+		"llama-3*", "llama3*", "*/llama-3*", "*/meta-llama-3*", "meta-llama-3*",
+	}, vocabLoader("llama3", "llama3.tiktoken", llama3SplitPattern, approxLoader("llama3≈cl100k_base", newCl100kBase)))
+	RegisterTokenizer("qwen", []string{
+		"qwen*", "qwq*", "qvq*", "*/qwen*",
+	}, vocabLoader("qwen", "qwen.tiktoken", qwenSplitPattern, approxLoader("qwen≈o200k_base", newO200kBase)))
+	RegisterTokenizer("glm", []string{
+		"glm-*", "chatglm*", "*/glm-*",
+	}, vocabLoader("glm", "glm4.tiktoken", llama3SplitPattern, approxLoader("glm≈o200k_base", newO200kBase)))
+	RegisterTokenizer("claude", []string{
+		"claude*", "*/claude*", "anthropic.claude*",
+	}, approxLoader("claude≈cl100k_base", newCl100kBase))
+	RegisterTokenizer("gemini", []string{
+		"gemini*", "gemma*", "*/gemini*", "*/gemma*",
+	}, approxLoader("gemini≈o200k_base", newO200kBase))
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+func TestResolveTokenizer(t *testing.T) {
+	InitTokenEncoders()
+
+	assert.Equal(t, "o200k_base", getTokenEncoder("gpt-4o-mini").GetName())
+	assert.Equal(t, "cl100k_base", getTokenEncoder("gpt-4-turbo").GetName())
+	assert.Equal(t, "claude≈cl100k_base", getTokenEncoder("claude-3-5-sonnet-20241022").GetName())
+	assert.Equal(t, "qwen≈o200k_base", getTokenEncoder("Qwen/Qwen2.5-72B-Instruct").GetName())
+	assert.Equal(t, "llama3≈cl100k_base", getTokenEncoder("llama-3.1-70b-instruct").GetName())
+	assert.Equal(t, "cl100k_base", getTokenEncoder("unknown-model").GetName())
+
+	// 近似分词器不做折算，计数与所用的 OpenAI 词表一致
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+	assert.Equal(t, getTokenNum(defaultTokenEncoder, text), getTokenNum(getTokenEncoder("claude-sonnet-4-20250514"), text))
+}
+
+// TestApproximateTokenizers 对比近似分词器与 TOKENIZER_VOCAB_DIR 中官方词表的计数差异，未提供词表时跳过
+func TestApproximateTokenizers(t *testing.T) {
+	dir := os.Getenv("TOKENIZER_VOCAB_DIR")
+	if dir == "" {
+		t.Skip("TOKENIZER_VOCAB_DIR is not set")
+	}
+	corpus := []string{
+		strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20),
+		strings.Repeat("分词器会把中文按字或词切分，不同词表的差异主要来自这里。", 20),
+		strings.Repeat("func main() {\n\tfmt.Println(\"hello, world\", 42)\n}\n", 20),
+	}
+	for _, family := range []struct {
+		name     string
+		fileName string
+		pattern  string
+		approx   func() tokenizer.Codec
+	}{
+		{"llama3", "llama3.tiktoken", llama3SplitPattern, newCl100kBase},
+		{"qwen", "qwen.tiktoken", qwenSplitPattern, newO200kBase},
+		{"glm", "glm4.tiktoken", llama3SplitPattern, newO200kBase},
+	} {
+		path := filepath.Join(dir, family.fileName)
+		if _, err := os.Stat(path); err != nil {
+			t.Logf("%s: %s not found, skipped", family.name, family.fileName)
+			continue
+		}
+		exact, err := loadTiktokenFile(family.name, path, family.pattern)
+		require.NoError(t, err)
+		approx := family.approx()
+		for i, text := range corpus {
+			want := getTokenNum(exact, text)
+			got := getTokenNum(approx, text)
+			t.Logf("%s corpus %d: exact %d, %s %d (%.2fx)", family.name, i, want, approx.GetName(), got, float64(want)/float64(got))
+		}
+	}
+}
+
+func TestLoadTiktokenFile(t *testing.T) {
+	var lines []string
+	for i := 0; i < 256; i++ {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i))
+	}
+	for i, token := range []string{"ab", "abc"} {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(token)), 256+i))
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test.tiktoken"), []byte(strings.Join(lines, "\n")), 0644))
+
+	c, err := loadTiktokenFile("test", filepath.Join(dir, "test.tiktoken"), `\S+|\s+`)
+	require.NoError(t, err)
+	ids, tokens, err := c.Encode("abc cab")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abc", " ", "c", "ab"}, tokens)
+	decoded, err := c.Decode(ids)
+	require.NoError(t, err)
+	assert.Equal(t, "abc cab", decoded)
+
+	// 未登录的多字节字符按字节切分，而不是全部计为 rank 0
+	ids, tokens, err = c.Encode("分")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{0xe5, 0x88, 0x86}, ids)
+	assert.Len(t, tokens, 3)
+
+	// 缺少单字节 token 的词表无法保证所有输入都能切分
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.tiktoken"), []byte(strings.Join(lines[1:], "\n")), 0644))
+	_, err = loadTiktokenFile("broken", filepath.Join(dir, "broken.tiktoken"), `\S+|\s+`)
+	assert.Error(t, err)
+}
+
+func BenchmarkTokenizers(b *testing.B) {
+	InitTokenEncoders()
+	text := strings.Repeat("Tokenizer benchmark 分词器基准测试 func main() { fmt.Println(42) }\n", 50)
+	for _, model := range []string{"gpt-3.5-turbo", "gpt-4o", "claude-sonnet-4-20250514", "gemini-2.5-pro", "qwen-max"} {
+		encoder := getTokenEncoder(model)
+		b.Run(model, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				getTokenNum(encoder, text)
+			}
+		})
+	}
+}
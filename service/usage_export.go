@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service/export"
+	"one-api/setting/operation_setting"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	UsageDatasetLogs      = "logs"
+	UsageDatasetQuotaData = "quota_data"
+)
+
+var logExportColumns = []export.Column{
+	{Name: "id", Type: export.ColumnTypeInt64},
+	{Name: "created_at", Type: export.ColumnTypeInt64},
+	{Name: "type", Type: export.ColumnTypeInt64},
+	{Name: "user_id", Type: export.ColumnTypeInt64},
+	{Name: "username", Type: export.ColumnTypeString},
+	{Name: "token_id", Type: export.ColumnTypeInt64},
+	{Name: "token_name", Type: export.ColumnTypeString},
+	{Name: "model_name", Type: export.ColumnTypeString},
+	{Name: "channel_id", Type: export.ColumnTypeInt64},
+	{Name: "group", Type: export.ColumnTypeString},
+	{Name: "quota", Type: export.ColumnTypeInt64},
+	{Name: "prompt_tokens", Type: export.ColumnTypeInt64},
+	{Name: "completion_tokens", Type: export.ColumnTypeInt64},
+	{Name: "use_time", Type: export.ColumnTypeInt64},
+	{Name: "is_stream", Type: export.ColumnTypeBool},
+	{Name: "ip", Type: export.ColumnTypeString},
+	{Name: "content", Type: export.ColumnTypeString},
+	{Name: "other", Type: export.ColumnTypeString},
+}
+
+var quotaDataExportColumns = []export.Column{
+	{Name: "id", Type: export.ColumnTypeInt64},
+	{Name: "created_at", Type: export.ColumnTypeInt64},
+	{Name: "user_id", Type: export.ColumnTypeInt64},
+	{Name: "username", Type: export.ColumnTypeString},
+	{Name: "model_name", Type: export.ColumnTypeString},
+	{Name: "count", Type: export.ColumnTypeInt64},
+	{Name: "quota", Type: export.ColumnTypeInt64},
+	{Name: "token_used", Type: export.ColumnTypeInt64},
+}
+
+func IsValidUsageDataset(dataset string) bool {
+	return dataset == UsageDatasetLogs || dataset == UsageDatasetQuotaData
+}
+
+// ExportUsage 将日志或数据看板数据以指定格式写出，返回写出的行数
+func ExportUsage(w io.Writer, dataset string, format string, filter model.UsageExportFilter) (int, error) {
+	if !IsValidUsageDataset(dataset) {
+		return 0, fmt.Errorf("不支持的数据集: %s", dataset)
+	}
+	columns := logExportColumns
+	if dataset == UsageDatasetQuotaData {
+		columns = quotaDataExportColumns
+	}
+	writer, err := export.NewWriter(format, w, columns)
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	if dataset == UsageDatasetLogs {
+		err = model.ExportLogs(filter, func(logs []*model.Log) error {
+			for _, log := range logs {
+				if err := writer.WriteRow([]any{log.Id, log.CreatedAt, log.Type, log.UserId, log.Username, log.TokenId, log.TokenName,
+					log.ModelName, log.ChannelId, log.Group, log.Quota, log.PromptTokens, log.CompletionTokens, log.UseTime,
+					log.IsStream, log.Ip, log.Content, log.Other}); err != nil {
+					return err
+				}
+				rows++
+			}
+			return nil
+		})
+	} else {
+		err = model.ExportQuotaData(filter, func(quotaData []*model.QuotaData) error {
+			for _, data := range quotaData {
+				if err := writer.WriteRow([]any{data.Id, data.CreatedAt, data.UserID, data.Username, data.ModelName,
+					data.Count, data.Quota, data.TokenUsed}); err != nil {
+					return err
+				}
+				rows++
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+// StartUsageReportTask 定时生成前一天的用量报表
+func StartUsageReportTask() {
+	for {
+		if operation_setting.GetUsageReportSetting().Enabled {
+			period := time.Now().AddDate(0, 0, -1).Format(time.DateOnly)
+			if _, err := GenerateUsageReports(period); err != nil {
+				common.SysError("failed to generate usage reports: " + err.Error())
+			}
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// GenerateUsageReports 为指定日期（YYYY-MM-DD）生成配置中全部数据集的报表，已生成的报表会被跳过
+func GenerateUsageReports(period string) ([]*model.UsageReport, error) {
+	start, err := time.ParseInLocation(time.DateOnly, period, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("报表日期格式错误，应为 YYYY-MM-DD: %s", period)
+	}
+	end := start.AddDate(0, 0, 1)
+	if end.After(time.Now()) {
+		return nil, errors.New("只能为已结束的日期生成报表")
+	}
+	setting := operation_setting.GetUsageReportSetting()
+	if !export.IsValidFormat(setting.Format) {
+		return nil, fmt.Errorf("不支持的导出格式: %s", setting.Format)
+	}
+	reports := make([]*model.UsageReport, 0, len(setting.Datasets))
+	var errs []error
+	for _, dataset := range setting.Datasets {
+		exists, err := model.HasUsageReport(period, dataset, setting.Format)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if exists {
+			continue
+		}
+		filter := model.UsageExportFilter{StartTimestamp: start.Unix(), EndTimestamp: end.Unix()}
+		report, err := generateUsageReport(setting, period, dataset, filter)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dataset, err))
+			continue
+		}
+		reports = append(reports, report)
+		common.SysLog(fmt.Sprintf("用量报表已生成: %s", report.Location))
+	}
+	return reports, errors.Join(errs...)
+}
+
+func generateUsageReport(setting *operation_setting.UsageReportSetting, period string, dataset string, filter model.UsageExportFilter) (*model.UsageReport, error) {
+	if !IsValidUsageDataset(dataset) {
+		return nil, fmt.Errorf("不支持的数据集: %s", dataset)
+	}
+	// 本地目录直接在目标目录生成临时文件，完成后重命名，避免出现写了一半的报表
+	tempDir := ""
+	if setting.Destination == operation_setting.UsageReportDestinationLocal {
+		if err := os.MkdirAll(setting.LocalDir, 0755); err != nil {
+			return nil, err
+		}
+		tempDir = setting.LocalDir
+	}
+	file, err := os.CreateTemp(tempDir, ".usage-report-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	rows, err := ExportUsage(file, dataset, setting.Format, filter)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(file.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s-%s%s", dataset, period, export.FileExtension(setting.Format))
+	report := &model.UsageReport{
+		Period:      period,
+		Dataset:     dataset,
+		Format:      setting.Format,
+		Destination: setting.Destination,
+		Rows:        rows,
+		Size:        info.Size(),
+	}
+	switch setting.Destination {
+	case operation_setting.UsageReportDestinationLocal:
+		report.Location = filepath.Join(setting.LocalDir, name)
+		if err = os.Rename(file.Name(), report.Location); err != nil {
+			return nil, err
+		}
+	case operation_setting.UsageReportDestinationS3:
+		client := &export.S3Client{
+			Endpoint:        setting.S3Endpoint,
+			Region:          setting.S3Region,
+			Bucket:          setting.S3Bucket,
+			AccessKeyId:     setting.S3AccessKeyId,
+			SecretAccessKey: setting.S3SecretAccessKey,
+			PathStyle:       setting.S3PathStyle,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		report.Location, err = client.PutFile(ctx, path.Join(setting.S3Prefix, name), file.Name(), export.ContentType(setting.Format))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的报表存储位置: %s", setting.Destination)
+	}
+	if err = report.Insert(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
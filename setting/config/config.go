@@ -17,6 +17,15 @@ type ConfigManager struct {
 
 var GlobalConfig = NewConfigManager()
 
+// Locker 配置对象实现该接口时，从数据库或后台更新配置期间持有写锁、导出配置期间持有读锁，
+// 读取 map 等非原子字段的地方应使用同一把锁
+type Locker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
 func NewConfigManager() *ConfigManager {
 	return &ConfigManager{
 		configs: make(map[string]interface{}),
@@ -91,6 +100,10 @@ func (cm *ConfigManager) SaveToDB(updateFunc func(key, value string) error) erro
 // 辅助函数：将配置对象转换为map
 func configToMap(config interface{}) (map[string]string, error) {
 	result := make(map[string]string)
+	if locker, ok := config.(Locker); ok {
+		locker.RLock()
+		defer locker.RUnlock()
+	}
 
 	val := reflect.ValueOf(config)
 	if val.Kind() == reflect.Ptr {
@@ -150,6 +163,10 @@ func configToMap(config interface{}) (map[string]string, error) {
 
 // 辅助函数：从map更新配置对象
 func updateConfigFromMap(config interface{}, configMap map[string]string) error {
+	if locker, ok := config.(Locker); ok {
+		locker.Lock()
+		defer locker.Unlock()
+	}
 	val := reflect.ValueOf(config)
 	if val.Kind() != reflect.Ptr {
 		return nil
@@ -0,0 +1,37 @@
+package operation_setting
+
+import (
+	"one-api/setting/config"
+	"slices"
+)
+
+// CostTagSetting 请求成本归属标签设置，只有 AllowedKeys 中的标签会被记录，为空时不记录任何标签
+type CostTagSetting struct {
+	AllowedKeys    []string `json:"allowed_keys"`
+	MaxTags        int      `json:"max_tags"`
+	MaxValueLength int      `json:"max_value_length"`
+}
+
+// 默认配置
+var costTagSetting = CostTagSetting{
+	AllowedKeys:    []string{},
+	MaxTags:        10,
+	MaxValueLength: 64,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("cost_tag_setting", &costTagSetting)
+}
+
+func GetCostTagSetting() *CostTagSetting {
+	return &costTagSetting
+}
+
+func IsCostTagEnabled() bool {
+	return len(costTagSetting.AllowedKeys) > 0
+}
+
+func IsCostTagKeyAllowed(key string) bool {
+	return slices.Contains(costTagSetting.AllowedKeys, key)
+}
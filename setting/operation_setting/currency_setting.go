@@ -0,0 +1,209 @@
+package operation_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/setting/config"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BaseCurrency 额度的计价货币，QuotaPerUnit 即 1 美元对应的额度
+const BaseCurrency = "USD"
+
+// CurrencySetting 多币种设置
+// Rates 为 1 美元可兑换的各货币数量；配置了 RateFile 时定期从该 JSON 文件读取汇率，文件中的汇率优先
+// TopUpPrices 为 1 美元额度在各货币下的充值售价，未配置的货币按支付渠道单价与汇率换算
+type CurrencySetting struct {
+	DisplayCurrency        string             `json:"display_currency"`
+	Rates                  map[string]float64 `json:"rates"`
+	RateFile               string             `json:"rate_file"`
+	RateFileRefreshMinutes int                `json:"rate_file_refresh_minutes"`
+	TopUpPrices            map[string]float64 `json:"top_up_prices"`
+
+	// 后台更新设置时会原地修改 Rates 等 map，读取时需要加锁
+	mutex sync.RWMutex
+}
+
+func (s *CurrencySetting) Lock()    { s.mutex.Lock() }
+func (s *CurrencySetting) Unlock()  { s.mutex.Unlock() }
+func (s *CurrencySetting) RLock()   { s.mutex.RLock() }
+func (s *CurrencySetting) RUnlock() { s.mutex.RUnlock() }
+
+var currencySetting = CurrencySetting{
+	DisplayCurrency: BaseCurrency,
+	Rates: map[string]float64{
+		"CNY": 7.2,
+		"EUR": 0.92,
+		"GBP": 0.79,
+		"JPY": 150,
+		"HKD": 7.8,
+	},
+	RateFileRefreshMinutes: 60,
+	TopUpPrices:            map[string]float64{},
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"CNY": "¥",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "JP¥",
+	"HKD": "HK$",
+}
+
+var fileRates map[string]float64
+var fileRatesMutex sync.RWMutex
+
+func init() {
+	config.GlobalConfig.Register("currency_setting", &currencySetting)
+}
+
+func GetCurrencySetting() *CurrencySetting {
+	return &currencySetting
+}
+
+// GetExchangeRate 返回 1 美元可兑换的目标货币数量
+func GetExchangeRate(currency string) (float64, bool) {
+	currency = strings.ToUpper(currency)
+	if currency == BaseCurrency {
+		return 1, true
+	}
+	fileRatesMutex.RLock()
+	rate, ok := fileRates[currency]
+	fileRatesMutex.RUnlock()
+	if ok && rate > 0 {
+		return rate, true
+	}
+	currencySetting.RLock()
+	rate, ok = currencySetting.Rates[currency]
+	currencySetting.RUnlock()
+	return rate, ok && rate > 0
+}
+
+// GetExchangeRates 返回全部可用货币的汇率
+func GetExchangeRates() map[string]float64 {
+	rates := map[string]float64{BaseCurrency: 1}
+	currencySetting.RLock()
+	for currency, rate := range currencySetting.Rates {
+		if rate > 0 {
+			rates[strings.ToUpper(currency)] = rate
+		}
+	}
+	currencySetting.RUnlock()
+	fileRatesMutex.RLock()
+	for currency, rate := range fileRates {
+		rates[currency] = rate
+	}
+	fileRatesMutex.RUnlock()
+	return rates
+}
+
+// GetSupportedCurrencies 返回按代码排序的可用货币
+func GetSupportedCurrencies() []string {
+	rates := GetExchangeRates()
+	currencies := make([]string, 0, len(rates))
+	for currency := range rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// ResolveDisplayCurrency 用户未选择或选择的货币不可用时，依次回退到系统默认展示货币与美元
+func ResolveDisplayCurrency(currency string) string {
+	currencySetting.RLock()
+	displayCurrency := currencySetting.DisplayCurrency
+	currencySetting.RUnlock()
+	for _, c := range []string{currency, displayCurrency} {
+		if c == "" {
+			continue
+		}
+		if _, ok := GetExchangeRate(c); ok {
+			return strings.ToUpper(c)
+		}
+	}
+	return BaseCurrency
+}
+
+// QuotaToCurrency 将额度换算为指定货币金额，货币不可用时按美元计算
+func QuotaToCurrency(quota float64, currency string) float64 {
+	if common.QuotaPerUnit == 0 {
+		return 0
+	}
+	rate, ok := GetExchangeRate(currency)
+	if !ok {
+		rate = 1
+	}
+	return quota / common.QuotaPerUnit * rate
+}
+
+func GetCurrencySymbol(currency string) string {
+	currency = strings.ToUpper(currency)
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol
+	}
+	return currency + " "
+}
+
+// FormatQuotaInCurrency 以货币符号加金额的形式展示额度
+func FormatQuotaInCurrency(quota int, currency string) string {
+	return fmt.Sprintf("%s%.6f", GetCurrencySymbol(currency), QuotaToCurrency(float64(quota), currency))
+}
+
+// GetRateFileRefreshMinutes 汇率文件的刷新间隔，未配置时为 60 分钟
+func GetRateFileRefreshMinutes() int {
+	currencySetting.RLock()
+	defer currencySetting.RUnlock()
+	if currencySetting.RateFileRefreshMinutes <= 0 {
+		return 60
+	}
+	return currencySetting.RateFileRefreshMinutes
+}
+
+// GetTopUpPrice 返回管理员为该货币单独配置的 1 美元额度售价
+func GetTopUpPrice(currency string) (float64, bool) {
+	currencySetting.RLock()
+	price, ok := currencySetting.TopUpPrices[strings.ToUpper(currency)]
+	currencySetting.RUnlock()
+	return price, ok && price > 0
+}
+
+// LoadExchangeRateFile 读取汇率文件，支持 {"CNY": 7.2} 与 {"rates": {"CNY": 7.2}} 两种格式
+func LoadExchangeRateFile() error {
+	currencySetting.RLock()
+	path := currencySetting.RateFile
+	currencySetting.RUnlock()
+	if path == "" {
+		fileRatesMutex.Lock()
+		fileRates = nil
+		fileRatesMutex.Unlock()
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var wrapped struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	rates := make(map[string]float64)
+	if err = json.Unmarshal(data, &wrapped); err == nil && len(wrapped.Rates) > 0 {
+		rates = wrapped.Rates
+	} else if err = json.Unmarshal(data, &rates); err != nil {
+		return fmt.Errorf("汇率文件格式错误: %w", err)
+	}
+	loaded := make(map[string]float64, len(rates))
+	for currency, rate := range rates {
+		if rate > 0 {
+			loaded[strings.ToUpper(currency)] = rate
+		}
+	}
+	fileRatesMutex.Lock()
+	fileRates = loaded
+	fileRatesMutex.Unlock()
+	return nil
+}
@@ -0,0 +1,48 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	MediaStorageBackendLocal = "local"
+	MediaStorageBackendS3    = "s3"
+)
+
+// MediaStorageSetting 生成结果（图片、视频等）转存设置，开启后响应中返回网关签名的访问地址，不再暴露上游地址
+type MediaStorageSetting struct {
+	Enabled           bool   `json:"enabled"`
+	Backend           string `json:"backend"` // local、s3，local 仅适用于单节点部署，多节点部署必须使用 s3
+	LocalDir          string `json:"local_dir"`
+	S3Endpoint        string `json:"s3_endpoint"`
+	S3Region          string `json:"s3_region"`
+	S3Bucket          string `json:"s3_bucket"`
+	S3AccessKeyId     string `json:"s3_access_key_id"`
+	S3SecretAccessKey string `json:"s3_secret_access_key"`
+	S3Prefix          string `json:"s3_prefix"`
+	S3PathStyle       bool   `json:"s3_path_style"`
+	RetentionDays     int    `json:"retention_days"`      // 保留天数，到期后删除，0 表示永久保留
+	UrlExpireSeconds  int    `json:"url_expire_seconds"`  // 签名地址的有效期
+	MaxFileSizeMB     int    `json:"max_file_size_mb"`    // 单个文件大小上限
+	UserStorageMB     int    `json:"user_storage_mb"`     // 每个用户的存储上限，超出后不再转存，0 表示不限制
+	StoreImageResults bool   `json:"store_image_results"` // 是否转存图片生成接口返回的 url
+}
+
+// 默认配置
+var mediaStorageSetting = MediaStorageSetting{
+	Backend:           MediaStorageBackendLocal,
+	LocalDir:          "media",
+	S3Region:          "us-east-1",
+	S3PathStyle:       true,
+	RetentionDays:     7,
+	UrlExpireSeconds:  3600,
+	MaxFileSizeMB:     200,
+	StoreImageResults: true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("media_storage_setting", &mediaStorageSetting)
+}
+
+func GetMediaStorageSetting() *MediaStorageSetting {
+	return &mediaStorageSetting
+}
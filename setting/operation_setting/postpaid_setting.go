@@ -0,0 +1,23 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+type PostpaidSetting struct {
+	BillingDay      int `json:"billing_day"`       // 每月出账日
+	PaymentTermDays int `json:"payment_term_days"` // 出账后的付款期限（天），逾期后锁定账户
+}
+
+// 默认配置
+var postpaidSetting = PostpaidSetting{
+	BillingDay:      1,
+	PaymentTermDays: 15,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("postpaid_setting", &postpaidSetting)
+}
+
+func GetPostpaidSetting() *PostpaidSetting {
+	return &postpaidSetting
+}
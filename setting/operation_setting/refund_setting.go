@@ -0,0 +1,28 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// 退款或撤销兑换码时，用户余额不足以扣回额度的处理策略
+const (
+	RefundPolicyReject        = "reject"         // 拒绝操作
+	RefundPolicyClamp         = "clamp"          // 只扣到余额为 0
+	RefundPolicyAllowNegative = "allow_negative" // 全额扣回，允许余额为负
+)
+
+type RefundSetting struct {
+	NegativeBalancePolicy string `json:"negative_balance_policy"`
+}
+
+// 默认配置
+var refundSetting = RefundSetting{
+	NegativeBalancePolicy: RefundPolicyReject,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("refund_setting", &refundSetting)
+}
+
+func GetRefundSetting() *RefundSetting {
+	return &refundSetting
+}
@@ -0,0 +1,27 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+type TaskCallbackSetting struct {
+	Enabled            bool `json:"enabled"`              // 是否允许提交异步任务时指定 callback_url
+	MaxAttempts        int  `json:"max_attempts"`         // 单次回调的最大投递次数（含首次）
+	BackoffBaseSeconds int  `json:"backoff_base_seconds"` // 重试间隔基数，第 n 次重试等待 base * 2^(n-1) 秒
+	TimeoutSeconds     int  `json:"timeout_seconds"`      // 单次投递的超时时间
+}
+
+// 默认配置
+var taskCallbackSetting = TaskCallbackSetting{
+	Enabled:            true,
+	MaxAttempts:        6,
+	BackoffBaseSeconds: 10,
+	TimeoutSeconds:     10,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("task_callback_setting", &taskCallbackSetting)
+}
+
+func GetTaskCallbackSetting() *TaskCallbackSetting {
+	return &taskCallbackSetting
+}
@@ -0,0 +1,40 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+type TaskPollSetting struct {
+	IntervalSeconds         int            `json:"interval_seconds"`          // 默认轮询间隔
+	PlatformIntervalSeconds map[string]int `json:"platform_interval_seconds"` // 按平台覆盖默认轮询间隔
+	MaxIntervalSeconds      int            `json:"max_interval_seconds"`      // 进度长时间无变化时退避的上限
+	TimeoutMinutes          int            `json:"timeout_minutes"`           // 提交后超过该时间仍未完成的任务判定失败并退还额度，0 表示不限制
+	BatchSize               int            `json:"batch_size"`                // 每轮最多轮询的任务数
+}
+
+// 默认配置
+var taskPollSetting = TaskPollSetting{
+	IntervalSeconds:         15,
+	PlatformIntervalSeconds: map[string]int{},
+	MaxIntervalSeconds:      300,
+	TimeoutMinutes:          60,
+	BatchSize:               500,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("task_poll_setting", &taskPollSetting)
+}
+
+func GetTaskPollSetting() *TaskPollSetting {
+	return &taskPollSetting
+}
+
+// GetTaskPollInterval 获取平台的基础轮询间隔（秒）
+func (s *TaskPollSetting) GetTaskPollInterval(platform string) int {
+	if interval, ok := s.PlatformIntervalSeconds[platform]; ok && interval > 0 {
+		return interval
+	}
+	if s.IntervalSeconds > 0 {
+		return s.IntervalSeconds
+	}
+	return 15
+}
@@ -0,0 +1,41 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+type TaskQueueSetting struct {
+	Enabled            bool           `json:"enabled"`             // 是否启用网关任务队列
+	UserConcurrency    int            `json:"user_concurrency"`    // 每个用户同时执行的异步任务上限，0 表示不限制
+	ChannelConcurrency int            `json:"channel_concurrency"` // 每个渠道同时执行的异步任务上限，0 表示不限制
+	MaxQueuedPerUser   int            `json:"max_queued_per_user"` // 每个用户最多排队的任务数，超出后直接拒绝，0 表示不限制
+	MaxWaitMinutes     int            `json:"max_wait_minutes"`    // 排队超过该时间仍未提交的任务判定失败，0 表示不限制
+	GroupPriority      map[string]int `json:"group_priority"`      // 分组优先级，数值越大越先调度，未配置的分组为 0
+}
+
+// 默认配置
+var taskQueueSetting = TaskQueueSetting{
+	Enabled:            false,
+	UserConcurrency:    3,
+	ChannelConcurrency: 0,
+	MaxQueuedPerUser:   20,
+	MaxWaitMinutes:     30,
+	GroupPriority:      map[string]int{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("task_queue_setting", &taskQueueSetting)
+}
+
+func GetTaskQueueSetting() *TaskQueueSetting {
+	return &taskQueueSetting
+}
+
+// GetGroupPriority 获取分组的调度优先级
+func (s *TaskQueueSetting) GetGroupPriority(group string) int {
+	return s.GroupPriority[group]
+}
+
+// Limited 是否配置了任一并发上限，未配置时无需排队
+func (s *TaskQueueSetting) Limited() bool {
+	return s.Enabled && (s.UserConcurrency > 0 || s.ChannelConcurrency > 0)
+}
@@ -0,0 +1,43 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	UsageReportDestinationLocal = "local"
+	UsageReportDestinationS3    = "s3"
+)
+
+// UsageReportSetting 每日用量报表设置，报表覆盖前一个自然日，写入本地目录或 S3 兼容存储
+type UsageReportSetting struct {
+	Enabled           bool     `json:"enabled"`
+	Datasets          []string `json:"datasets"` // logs、quota_data
+	Format            string   `json:"format"`   // csv、ndjson、parquet
+	Destination       string   `json:"destination"`
+	LocalDir          string   `json:"local_dir"`
+	S3Endpoint        string   `json:"s3_endpoint"`
+	S3Region          string   `json:"s3_region"`
+	S3Bucket          string   `json:"s3_bucket"`
+	S3AccessKeyId     string   `json:"s3_access_key_id"`
+	S3SecretAccessKey string   `json:"s3_secret_access_key"`
+	S3Prefix          string   `json:"s3_prefix"`
+	S3PathStyle       bool     `json:"s3_path_style"`
+}
+
+// 默认配置
+var usageReportSetting = UsageReportSetting{
+	Datasets:    []string{"logs", "quota_data"},
+	Format:      "csv",
+	Destination: UsageReportDestinationLocal,
+	LocalDir:    "reports",
+	S3Region:    "us-east-1",
+	S3PathStyle: true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("usage_report_setting", &usageReportSetting)
+}
+
+func GetUsageReportSetting() *UsageReportSetting {
+	return &usageReportSetting
+}
@@ -0,0 +1,9 @@
+package setting
+
+var PayPalClientId = ""
+var PayPalClientSecret = ""
+var PayPalWebhookId = ""
+var PayPalSandbox = false
+var PayPalCurrency = "USD" // 可用的结算货币，逗号分隔，第一个为默认货币
+var PayPalUnitPrice = 1.0
+var PayPalMinTopUp = 1
@@ -6,3 +6,6 @@ var StripePriceId = ""
 var StripeUnitPrice = 8.0
 var StripeMinTopUp = 1
 var StripePromotionCodesEnabled = false
+
+// StripeCurrencies 可用的结算货币，逗号分隔，第一个为默认货币；扣费金额由网关按充值售价或汇率计算，无需在 Stripe Price 中配置 currency_options
+var StripeCurrencies = "USD"
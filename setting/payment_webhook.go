@@ -0,0 +1,10 @@
+package setting
+
+// 通用支付网关：下单与查单请求、支付回调均使用 PaymentWebhookSecret 做 HMAC-SHA256 签名
+var PaymentWebhookName = ""
+var PaymentWebhookCreateUrl = ""
+var PaymentWebhookQueryUrl = ""
+var PaymentWebhookSecret = ""
+var PaymentWebhookUnitPrice = 1.0
+var PaymentWebhookMinTopUp = 1
+var PaymentWebhookCurrencies = "USD" // 可用的结算货币，逗号分隔，第一个为默认货币
@@ -42,10 +42,14 @@ func GetExposedData() gin.H {
 		return cloneGinH(c.data)
 	}
 	newData := gin.H{
-		"model_ratio":      GetModelRatioCopy(),
-		"completion_ratio": GetCompletionRatioCopy(),
-		"cache_ratio":      GetCacheRatioCopy(),
-		"model_price":      GetModelPriceCopy(),
+		"model_ratio":         GetModelRatioCopy(),
+		"completion_ratio":    GetCompletionRatioCopy(),
+		"cache_ratio":         GetCacheRatioCopy(),
+		"model_price":         GetModelPriceCopy(),
+		"tiered_ratio":        GetTieredRatioCopy(),
+		"video_price":         GetVideoPriceCopy(),
+		"image_size_ratio":    GetImageSizeRatioCopy(),
+		"image_quality_ratio": GetImageQualityRatioCopy(),
 	}
 	exposedData.Store(&exposedCache{
 		data:      newData,
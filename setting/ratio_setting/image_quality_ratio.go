@@ -0,0 +1,85 @@
+package ratio_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"strings"
+	"sync"
+)
+
+// defaultImageQualityRatio 按次计费的图片模型在不同 quality 下的价格倍率
+// Ideogram 将 quality 映射为渲染速度：low 为 TURBO，hd、high 为 QUALITY，其余为 DEFAULT，模型价格按 DEFAULT 配置
+var defaultImageQualityRatio = map[string]map[string]float64{
+	"ideogram-v3": {"low": 0.5, "hd": 1.5, "high": 1.5},
+}
+
+var imageQualityRatioMap map[string]map[string]float64
+var imageQualityRatioMapMutex sync.RWMutex
+
+func ImageQualityRatio2JSONString() string {
+	imageQualityRatioMapMutex.RLock()
+	defer imageQualityRatioMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(imageQualityRatioMap)
+	if err != nil {
+		common.SysLog("error marshalling image quality ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+// CheckImageQualityRatio 校验图片质量倍率配置，倍率必须大于 0
+func CheckImageQualityRatio(jsonStr string) error {
+	var ratios map[string]map[string]float64
+	if err := json.Unmarshal([]byte(jsonStr), &ratios); err != nil {
+		return err
+	}
+	for name, qualities := range ratios {
+		for quality, ratio := range qualities {
+			if ratio <= 0 {
+				return fmt.Errorf("模型 %s 质量 %s 的倍率必须大于 0", name, quality)
+			}
+		}
+	}
+	return nil
+}
+
+func UpdateImageQualityRatioByJSONString(jsonStr string) error {
+	if err := CheckImageQualityRatio(jsonStr); err != nil {
+		return err
+	}
+	ratios := make(map[string]map[string]float64)
+	if err := json.Unmarshal([]byte(jsonStr), &ratios); err != nil {
+		return err
+	}
+	imageQualityRatioMapMutex.Lock()
+	imageQualityRatioMap = ratios
+	imageQualityRatioMapMutex.Unlock()
+	InvalidateExposedDataCache()
+	return nil
+}
+
+func GetImageQualityRatioCopy() map[string]map[string]float64 {
+	imageQualityRatioMapMutex.RLock()
+	defer imageQualityRatioMapMutex.RUnlock()
+	copyMap := make(map[string]map[string]float64, len(imageQualityRatioMap))
+	for k, v := range imageQualityRatioMap {
+		copyMap[k] = v
+	}
+	return copyMap
+}
+
+// GetImageQualityRatio 获取模型在指定 quality 下的价格倍率，quality 不区分大小写，未配置时返回 1
+func GetImageQualityRatio(name string, quality string) (float64, bool) {
+	imageQualityRatioMapMutex.RLock()
+	defer imageQualityRatioMapMutex.RUnlock()
+	qualities, ok := imageQualityRatioMap[FormatMatchingModelName(name)]
+	if !ok || quality == "" {
+		return 1, false
+	}
+	for key, ratio := range qualities {
+		if strings.EqualFold(key, quality) {
+			return ratio, true
+		}
+	}
+	return 1, false
+}
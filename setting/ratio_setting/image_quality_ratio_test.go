@@ -0,0 +1,32 @@
+package ratio_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetImageQualityRatio(t *testing.T) {
+	InitRatioSettings()
+	defer InitRatioSettings()
+
+	ratio, ok := GetImageQualityRatio("ideogram-v3", "HD")
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, ratio)
+	ratio, ok = GetImageQualityRatio("ideogram-v3", "low")
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, ratio)
+	// 未配置的 quality 按模型价格计费
+	ratio, ok = GetImageQualityRatio("ideogram-v3", "standard")
+	assert.False(t, ok)
+	assert.Equal(t, 1.0, ratio)
+
+	require.NoError(t, UpdateImageQualityRatioByJSONString(`{"flux-dev":{"high":2}}`))
+	_, ok = GetImageQualityRatio("ideogram-v3", "hd")
+	assert.False(t, ok)
+	ratio, _ = GetImageQualityRatio("flux-dev", "high")
+	assert.Equal(t, 2.0, ratio)
+
+	assert.Error(t, CheckImageQualityRatio(`{"m":{"hd":0}}`))
+}
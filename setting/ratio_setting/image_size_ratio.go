@@ -0,0 +1,129 @@
+package ratio_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultImageSizeRatio 按次计费的图片模型在不同尺寸下的价格倍率，尺寸可以是 1024x1024 形式或 16:9 形式的宽高比
+var defaultImageSizeRatio = map[string]map[string]float64{
+	"flux-dev":     {"512x512": 0.5, "1024x1024": 1, "1440x1440": 2},
+	"flux-pro-1.1": {"512x512": 0.5, "1024x1024": 1, "1440x1440": 2},
+	"ideogram-v3":  {"512x1536": 0.75, "1024x1024": 1, "1536x1536": 1.5},
+}
+
+var imageSizeRatioMap map[string]map[string]float64
+var imageSizeRatioMapMutex sync.RWMutex
+
+func ImageSizeRatio2JSONString() string {
+	imageSizeRatioMapMutex.RLock()
+	defer imageSizeRatioMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(imageSizeRatioMap)
+	if err != nil {
+		common.SysLog("error marshalling image size ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+// CheckImageSizeRatio 校验图片尺寸倍率配置，倍率必须大于 0
+func CheckImageSizeRatio(jsonStr string) error {
+	var ratios map[string]map[string]float64
+	if err := json.Unmarshal([]byte(jsonStr), &ratios); err != nil {
+		return err
+	}
+	for name, sizes := range ratios {
+		for size, ratio := range sizes {
+			if ratio <= 0 {
+				return fmt.Errorf("模型 %s 尺寸 %s 的倍率必须大于 0", name, size)
+			}
+		}
+	}
+	return nil
+}
+
+func UpdateImageSizeRatioByJSONString(jsonStr string) error {
+	if err := CheckImageSizeRatio(jsonStr); err != nil {
+		return err
+	}
+	ratios := make(map[string]map[string]float64)
+	if err := json.Unmarshal([]byte(jsonStr), &ratios); err != nil {
+		return err
+	}
+	imageSizeRatioMapMutex.Lock()
+	imageSizeRatioMap = ratios
+	imageSizeRatioMapMutex.Unlock()
+	InvalidateExposedDataCache()
+	return nil
+}
+
+func GetImageSizeRatioCopy() map[string]map[string]float64 {
+	imageSizeRatioMapMutex.RLock()
+	defer imageSizeRatioMapMutex.RUnlock()
+	copyMap := make(map[string]map[string]float64, len(imageSizeRatioMap))
+	for k, v := range imageSizeRatioMap {
+		copyMap[k] = v
+	}
+	return copyMap
+}
+
+// GetImageSizeRatio 获取模型在指定尺寸下的价格倍率，尺寸不区分大小写，模型未配置时返回 1
+// 未配置的宽x高尺寸按像素面积匹配：取面积不小于该尺寸的最小已配置尺寸，超过所有已配置尺寸时按最大尺寸的倍率与面积比例计算
+func GetImageSizeRatio(name string, size string) (float64, bool) {
+	imageSizeRatioMapMutex.RLock()
+	defer imageSizeRatioMapMutex.RUnlock()
+	sizes, ok := imageSizeRatioMap[FormatMatchingModelName(name)]
+	if !ok || size == "" {
+		return 1, false
+	}
+	for key, ratio := range sizes {
+		if strings.EqualFold(key, size) {
+			return ratio, true
+		}
+	}
+	area, ok := imageSizeArea(size)
+	if !ok {
+		return 1, false
+	}
+	var ceilArea, maxArea int
+	var ceilRatio, maxRatio float64
+	for key, ratio := range sizes {
+		keyArea, ok := imageSizeArea(key)
+		if !ok {
+			continue
+		}
+		if keyArea >= area && (ceilArea == 0 || keyArea < ceilArea) {
+			ceilArea, ceilRatio = keyArea, ratio
+		}
+		if keyArea > maxArea {
+			maxArea, maxRatio = keyArea, ratio
+		}
+	}
+	if ceilArea > 0 {
+		return ceilRatio, true
+	}
+	if maxArea > 0 {
+		return maxRatio * float64(area) / float64(maxArea), true
+	}
+	return 1, false
+}
+
+// imageSizeArea 计算 1024x1024 形式尺寸的像素面积
+func imageSizeArea(size string) (int, bool) {
+	w, h, found := strings.Cut(strings.ToLower(size), "x")
+	if !found {
+		return 0, false
+	}
+	width, err := strconv.Atoi(strings.TrimSpace(w))
+	if err != nil || width <= 0 {
+		return 0, false
+	}
+	height, err := strconv.Atoi(strings.TrimSpace(h))
+	if err != nil || height <= 0 {
+		return 0, false
+	}
+	return width * height, true
+}
@@ -0,0 +1,42 @@
+package ratio_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetImageSizeRatio(t *testing.T) {
+	InitRatioSettings()
+	defer InitRatioSettings()
+	require.NoError(t, UpdateImageSizeRatioByJSONString(`{"flux-dev":{"1024x1024":1,"1440X1440":2,"16:9":1.5}}`))
+
+	ratio, ok := GetImageSizeRatio("flux-dev", "1440x1440")
+	require.True(t, ok)
+	assert.Equal(t, 2.0, ratio)
+
+	ratio, ok = GetImageSizeRatio("flux-dev", "16:9")
+	require.True(t, ok)
+	assert.Equal(t, 1.5, ratio)
+
+	// 未配置的尺寸按面积匹配不小于它的最小尺寸，超过所有尺寸时按面积比例计算
+	ratio, ok = GetImageSizeRatio("flux-dev", "512x512")
+	require.True(t, ok)
+	assert.Equal(t, 1.0, ratio)
+	ratio, ok = GetImageSizeRatio("flux-dev", "1280x1280")
+	require.True(t, ok)
+	assert.Equal(t, 2.0, ratio)
+	ratio, ok = GetImageSizeRatio("flux-dev", "2880x1440")
+	require.True(t, ok)
+	assert.Equal(t, 4.0, ratio)
+
+	// 未配置的宽高比或模型按原价计费
+	ratio, ok = GetImageSizeRatio("flux-dev", "4:3")
+	assert.False(t, ok)
+	assert.Equal(t, 1.0, ratio)
+	_, ok = GetImageSizeRatio("unknown-model", "1024x1024")
+	assert.False(t, ok)
+
+	assert.Error(t, CheckImageSizeRatio(`{"m":{"1024x1024":0}}`))
+}
@@ -256,6 +256,7 @@ var defaultModelRatio = map[string]float64{
 var defaultModelPrice = map[string]float64{
 	"suno_music":              0.1,
 	"suno_lyrics":             0.01,
+	"music-u":                 0.1,
 	"dall-e-3":                0.04,
 	"imagen-3.0-generate-002": 0.03,
 	"gpt-4-gizmo-*":           0.1,
@@ -277,6 +278,24 @@ var defaultModelPrice = map[string]float64{
 	"mj_upscale":              0.05,
 	"swap_face":               0.05,
 	"mj_upload":               0.05,
+	"flux-dev":                0.025,
+	"flux-pro-1.1":            0.04,
+	"flux-pro-1.1-ultra":      0.06,
+	"flux-kontext-pro":        0.04,
+	"flux-kontext-max":        0.08,
+	"flux-pro-1.0-fill":       0.05,
+	"sd3.5-large":             0.065,
+	"sd3.5-large-turbo":       0.04,
+	"sd3.5-medium":            0.035,
+	"stable-image-ultra":      0.08,
+	"stable-image-core":       0.03,
+	"stable-image-inpaint":    0.03,
+	"ideogram-v3":             0.06,
+
+	// Stability 放大接口按次计费，价格按官方积分（1 积分 = 0.01 美元）换算
+	"stable-image-upscale-fast":         0.02,
+	"stable-image-upscale-conservative": 0.4,
+	"stable-image-upscale-creative":     0.6,
 }
 
 var defaultAudioRatio = map[string]float64{
@@ -348,6 +367,26 @@ func InitRatioSettings() {
 	audioCompletionRatioMapMutex.Lock()
 	audioCompletionRatioMap = defaultAudioCompletionRatio
 	audioCompletionRatioMapMutex.Unlock()
+
+	// initialize tieredRatioMap
+	tieredRatioMapMutex.Lock()
+	tieredRatioMap = defaultTieredRatio
+	tieredRatioMapMutex.Unlock()
+
+	// initialize videoPriceMap
+	videoPriceMapMutex.Lock()
+	videoPriceMap = defaultVideoPrice
+	videoPriceMapMutex.Unlock()
+
+	// initialize imageSizeRatioMap
+	imageSizeRatioMapMutex.Lock()
+	imageSizeRatioMap = defaultImageSizeRatio
+	imageSizeRatioMapMutex.Unlock()
+
+	// initialize imageQualityRatioMap
+	imageQualityRatioMapMutex.Lock()
+	imageQualityRatioMap = defaultImageQualityRatio
+	imageQualityRatioMapMutex.Unlock()
 }
 
 func GetModelPriceMap() map[string]float64 {
@@ -395,6 +434,14 @@ func GetModelPrice(name string, printErr bool) (float64, bool) {
 	return price, true
 }
 
+// MusicPerSongPriceSuffix 音乐模型按首计费的价格键后缀，例如 suno_music-per-song
+const MusicPerSongPriceSuffix = "-per-song"
+
+// GetMusicPricePerSong 返回音乐模型按首计费的单价，未单独配置时 ok 为 false，此时使用模型价格按次计费
+func GetMusicPricePerSong(name string) (float64, bool) {
+	return GetModelPrice(name+MusicPerSongPriceSuffix, false)
+}
+
 func UpdateModelRatioByJSONString(jsonStr string) error {
 	modelRatioMapMutex.Lock()
 	defer modelRatioMapMutex.Unlock()
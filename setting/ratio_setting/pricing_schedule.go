@@ -0,0 +1,176 @@
+package ratio_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PricingSchedule 分时定价规则，在时间窗口内将模型倍率与分组倍率的乘积再乘以 Multiplier
+// Start、End 为 HH:MM 格式，End 小于 Start 时表示跨越零点；Weekdays 按 Timezone 的星期几判断，0 为周日
+// Models 支持以 * 结尾的前缀匹配，Models、Groups、Weekdays 为空表示不限
+type PricingSchedule struct {
+	Name       string   `json:"name"`
+	Timezone   string   `json:"timezone,omitempty"`
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Weekdays   []int    `json:"weekdays,omitempty"`
+	Models     []string `json:"models,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+	Multiplier float64  `json:"multiplier"`
+
+	location *time.Location
+	startMin int
+	endMin   int
+}
+
+var pricingSchedules []*PricingSchedule
+var pricingSchedulesMutex sync.RWMutex
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式错误: %s", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (s *PricingSchedule) init() error {
+	if s.Multiplier <= 0 {
+		return fmt.Errorf("分时定价 %s 的倍率必须大于 0", s.Name)
+	}
+	var err error
+	if s.startMin, err = parseClock(s.Start); err != nil {
+		return err
+	}
+	if s.endMin, err = parseClock(s.End); err != nil {
+		return err
+	}
+	if s.startMin == s.endMin {
+		return fmt.Errorf("分时定价 %s 的开始与结束时间不能相同", s.Name)
+	}
+	s.location = time.Local
+	if s.Timezone != "" {
+		if s.location, err = time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("分时定价 %s 的时区无效: %s", s.Name, s.Timezone)
+		}
+	}
+	for _, weekday := range s.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return fmt.Errorf("分时定价 %s 的星期取值应为 0-6", s.Name)
+		}
+	}
+	return nil
+}
+
+// IsActive 判断给定时间是否处于规则的时间窗口内，跨零点的窗口以开始时刻所在的日期判断星期
+func (s *PricingSchedule) IsActive(now time.Time) bool {
+	local := now.In(s.location)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+	if s.startMin < s.endMin {
+		if minute < s.startMin || minute >= s.endMin {
+			return false
+		}
+	} else {
+		if minute >= s.endMin && minute < s.startMin {
+			return false
+		}
+		if minute < s.endMin {
+			day = local.AddDate(0, 0, -1).Weekday()
+		}
+	}
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+	for _, weekday := range s.Weekdays {
+		if time.Weekday(weekday) == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PricingSchedule) matches(modelName string, group string) bool {
+	if len(s.Groups) > 0 && !common.StringsContains(s.Groups, group) {
+		return false
+	}
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, pattern := range s.Models {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(modelName, prefix) {
+				return true
+			}
+		} else if pattern == modelName {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePricingSchedules(jsonStr string) ([]*PricingSchedule, error) {
+	var schedules []*PricingSchedule
+	if err := json.Unmarshal([]byte(jsonStr), &schedules); err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		if err := schedule.init(); err != nil {
+			return nil, err
+		}
+	}
+	return schedules, nil
+}
+
+func CheckPricingSchedule(jsonStr string) error {
+	_, err := parsePricingSchedules(jsonStr)
+	return err
+}
+
+func UpdatePricingScheduleByJSONString(jsonStr string) error {
+	schedules, err := parsePricingSchedules(jsonStr)
+	if err != nil {
+		return err
+	}
+	pricingSchedulesMutex.Lock()
+	pricingSchedules = schedules
+	pricingSchedulesMutex.Unlock()
+	InvalidateExposedDataCache()
+	return nil
+}
+
+func PricingSchedule2JSONString() string {
+	pricingSchedulesMutex.RLock()
+	defer pricingSchedulesMutex.RUnlock()
+	if pricingSchedules == nil {
+		return "[]"
+	}
+	jsonBytes, err := json.Marshal(pricingSchedules)
+	if err != nil {
+		common.SysLog("error marshalling pricing schedule: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+// GetPricingSchedules 返回全部分时定价规则
+func GetPricingSchedules() []*PricingSchedule {
+	pricingSchedulesMutex.RLock()
+	defer pricingSchedulesMutex.RUnlock()
+	return append([]*PricingSchedule(nil), pricingSchedules...)
+}
+
+// GetPricingScheduleMultiplier 返回当前时间第一条匹配规则的倍率，没有匹配时返回 1
+func GetPricingScheduleMultiplier(modelName string, group string, now time.Time) (float64, *PricingSchedule) {
+	pricingSchedulesMutex.RLock()
+	defer pricingSchedulesMutex.RUnlock()
+	for _, schedule := range pricingSchedules {
+		if schedule.matches(modelName, group) && schedule.IsActive(now) {
+			return schedule.Multiplier, schedule
+		}
+	}
+	return 1, nil
+}
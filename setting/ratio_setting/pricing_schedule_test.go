@@ -0,0 +1,46 @@
+package ratio_setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPricingScheduleMultiplier(t *testing.T) {
+	require.NoError(t, UpdatePricingScheduleByJSONString(`[
+		{"name":"deepseek-off-peak","timezone":"Asia/Shanghai","start":"00:30","end":"08:30","models":["deepseek-*"],"multiplier":0.5},
+		{"name":"weekend-night","timezone":"UTC","start":"22:00","end":"06:00","weekdays":[6],"groups":["batch"],"multiplier":0.8}
+	]`))
+	defer UpdatePricingScheduleByJSONString("[]")
+
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	multiplier, schedule := GetPricingScheduleMultiplier("deepseek-chat", "default", time.Date(2025, 3, 3, 1, 0, 0, 0, shanghai))
+	require.NotNil(t, schedule)
+	assert.Equal(t, "deepseek-off-peak", schedule.Name)
+	assert.Equal(t, 0.5, multiplier)
+
+	multiplier, schedule = GetPricingScheduleMultiplier("deepseek-chat", "default", time.Date(2025, 3, 3, 8, 30, 0, 0, shanghai))
+	assert.Nil(t, schedule)
+	assert.Equal(t, 1.0, multiplier)
+
+	// 周六 22:00 开始的窗口跨越零点，周日凌晨仍按周六计算
+	_, schedule = GetPricingScheduleMultiplier("gpt-4o", "batch", time.Date(2025, 3, 9, 3, 0, 0, 0, time.UTC))
+	require.NotNil(t, schedule)
+	assert.Equal(t, "weekend-night", schedule.Name)
+
+	_, schedule = GetPricingScheduleMultiplier("gpt-4o", "batch", time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC))
+	assert.Nil(t, schedule)
+
+	_, schedule = GetPricingScheduleMultiplier("gpt-4o", "default", time.Date(2025, 3, 9, 3, 0, 0, 0, time.UTC))
+	assert.Nil(t, schedule)
+}
+
+func TestCheckPricingSchedule(t *testing.T) {
+	assert.Error(t, CheckPricingSchedule(`[{"name":"bad","start":"25:00","end":"06:00","multiplier":0.5}]`))
+	assert.Error(t, CheckPricingSchedule(`[{"name":"bad","start":"01:00","end":"06:00","multiplier":0}]`))
+	assert.Error(t, CheckPricingSchedule(`[{"name":"bad","timezone":"Mars/Olympus","start":"01:00","end":"06:00","multiplier":0.5}]`))
+}
@@ -0,0 +1,161 @@
+package ratio_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"sort"
+	"sync"
+)
+
+// PricingTier 按输入 token 数分段的倍率，命中某一档时整个请求按该档计费
+// MaxTokens 为该档输入 token 上限（含），0 表示无上限；未填写的补全、缓存倍率沿用模型的基础配置
+type PricingTier struct {
+	MaxTokens          int      `json:"max_tokens"`
+	ModelRatio         float64  `json:"model_ratio"`
+	CompletionRatio    *float64 `json:"completion_ratio,omitempty"`
+	CacheRatio         *float64 `json:"cache_ratio,omitempty"`
+	CacheCreationRatio *float64 `json:"cache_creation_ratio,omitempty"`
+}
+
+// TieredRatios 命中分段后的完整倍率，Tier 从 1 开始计数
+type TieredRatios struct {
+	Tier               int
+	MaxTokens          int
+	ModelRatio         float64
+	CompletionRatio    float64
+	CacheRatio         float64
+	CacheCreationRatio float64
+}
+
+// defaultTieredRatio 默认不配置任何分段：分段倍率会直接替换模型倍率，
+// 内置的数值无法随管理员自定义的模型倍率调整，需要分段计费的模型由管理员自行配置
+var defaultTieredRatio = map[string][]PricingTier{}
+
+var tieredRatioMap map[string][]PricingTier
+var tieredRatioMapMutex sync.RWMutex
+
+func TieredRatio2JSONString() string {
+	tieredRatioMapMutex.RLock()
+	defer tieredRatioMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(tieredRatioMap)
+	if err != nil {
+		common.SysLog("error marshalling tiered ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+// sortPricingTiers 按上限升序排列，无上限的一档放在最后
+func sortPricingTiers(tiers []PricingTier) {
+	sort.SliceStable(tiers, func(i, j int) bool {
+		if tiers[i].MaxTokens == 0 {
+			return false
+		}
+		if tiers[j].MaxTokens == 0 {
+			return true
+		}
+		return tiers[i].MaxTokens < tiers[j].MaxTokens
+	})
+}
+
+// CheckTieredRatio 校验分段配置：每个模型至少一档，上限不重复，且必须有且只有一档无上限
+func CheckTieredRatio(jsonStr string) error {
+	var tiered map[string][]PricingTier
+	if err := json.Unmarshal([]byte(jsonStr), &tiered); err != nil {
+		return err
+	}
+	for name, tiers := range tiered {
+		if len(tiers) == 0 {
+			return fmt.Errorf("模型 %s 未配置分段", name)
+		}
+		seen := make(map[int]bool)
+		for _, tier := range tiers {
+			if tier.MaxTokens < 0 || tier.ModelRatio < 0 {
+				return fmt.Errorf("模型 %s 的分段上限或倍率不能为负数", name)
+			}
+			if seen[tier.MaxTokens] {
+				return fmt.Errorf("模型 %s 的分段上限 %d 重复", name, tier.MaxTokens)
+			}
+			seen[tier.MaxTokens] = true
+		}
+		if !seen[0] {
+			return fmt.Errorf("模型 %s 缺少无上限（max_tokens 为 0）的分段", name)
+		}
+	}
+	return nil
+}
+
+func UpdateTieredRatioByJSONString(jsonStr string) error {
+	if err := CheckTieredRatio(jsonStr); err != nil {
+		return err
+	}
+	tiered := make(map[string][]PricingTier)
+	if err := json.Unmarshal([]byte(jsonStr), &tiered); err != nil {
+		return err
+	}
+	for _, tiers := range tiered {
+		sortPricingTiers(tiers)
+	}
+	tieredRatioMapMutex.Lock()
+	tieredRatioMap = tiered
+	tieredRatioMapMutex.Unlock()
+	InvalidateExposedDataCache()
+	return nil
+}
+
+func GetTieredRatioCopy() map[string][]PricingTier {
+	tieredRatioMapMutex.RLock()
+	defer tieredRatioMapMutex.RUnlock()
+	copyMap := make(map[string][]PricingTier, len(tieredRatioMap))
+	for k, v := range tieredRatioMap {
+		copyMap[k] = append([]PricingTier(nil), v...)
+	}
+	return copyMap
+}
+
+func HasTieredRatio(name string) bool {
+	tieredRatioMapMutex.RLock()
+	defer tieredRatioMapMutex.RUnlock()
+	_, ok := tieredRatioMap[FormatMatchingModelName(name)]
+	return ok
+}
+
+// GetTieredRatios 按输入 token 数查找命中的分段，未配置分段时 ok 为 false；
+// 超过所有上限时按最高一档计费，避免回落到模型的基础倍率
+func GetTieredRatios(name string, promptTokens int) (TieredRatios, bool) {
+	tieredRatioMapMutex.RLock()
+	tiers, ok := tieredRatioMap[FormatMatchingModelName(name)]
+	tieredRatioMapMutex.RUnlock()
+	if !ok || len(tiers) == 0 {
+		return TieredRatios{}, false
+	}
+	i := len(tiers) - 1
+	for index, tier := range tiers {
+		if tier.MaxTokens == 0 || promptTokens <= tier.MaxTokens {
+			i = index
+			break
+		}
+	}
+	tier := tiers[i]
+	ratios := TieredRatios{
+		Tier:       i + 1,
+		MaxTokens:  tier.MaxTokens,
+		ModelRatio: tier.ModelRatio,
+	}
+	if tier.CompletionRatio != nil {
+		ratios.CompletionRatio = *tier.CompletionRatio
+	} else {
+		ratios.CompletionRatio = GetCompletionRatio(name)
+	}
+	if tier.CacheRatio != nil {
+		ratios.CacheRatio = *tier.CacheRatio
+	} else {
+		ratios.CacheRatio, _ = GetCacheRatio(name)
+	}
+	if tier.CacheCreationRatio != nil {
+		ratios.CacheCreationRatio = *tier.CacheCreationRatio
+	} else {
+		ratios.CacheCreationRatio, _ = GetCreateCacheRatio(name)
+	}
+	return ratios, true
+}
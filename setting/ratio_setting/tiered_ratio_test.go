@@ -0,0 +1,67 @@
+package ratio_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTieredRatios(t *testing.T) {
+	InitRatioSettings()
+	defer InitRatioSettings()
+	require.NoError(t, UpdateTieredRatioByJSONString(`{"test-model":[
+		{"max_tokens":0,"model_ratio":2,"completion_ratio":3},
+		{"max_tokens":1000,"model_ratio":1,"cache_ratio":0.5}
+	]}`))
+
+	low, ok := GetTieredRatios("test-model", 1000)
+	require.True(t, ok)
+	assert.Equal(t, 1, low.Tier)
+	assert.Equal(t, 1.0, low.ModelRatio)
+	assert.Equal(t, 0.5, low.CacheRatio)
+	assert.Equal(t, GetCompletionRatio("test-model"), low.CompletionRatio)
+
+	high, ok := GetTieredRatios("test-model", 1001)
+	require.True(t, ok)
+	assert.Equal(t, 2, high.Tier)
+	assert.Equal(t, 2.0, high.ModelRatio)
+	assert.Equal(t, 3.0, high.CompletionRatio)
+
+	_, ok = GetTieredRatios("unknown-model", 10)
+	assert.False(t, ok)
+}
+
+func TestCheckTieredRatio(t *testing.T) {
+	assert.NoError(t, CheckTieredRatio(`{"m":[{"max_tokens":200000,"model_ratio":1},{"max_tokens":0,"model_ratio":2}]}`))
+	assert.Error(t, CheckTieredRatio(`{"m":[]}`))
+	assert.Error(t, CheckTieredRatio(`{"m":[{"max_tokens":0,"model_ratio":1},{"max_tokens":0,"model_ratio":2}]}`))
+	assert.Error(t, CheckTieredRatio(`{"m":[{"max_tokens":-1,"model_ratio":1}]}`))
+	// 必须有一档无上限，否则超出所有上限的请求无处计费
+	assert.Error(t, CheckTieredRatio(`{"m":[{"max_tokens":1000,"model_ratio":1},{"max_tokens":2000,"model_ratio":2}]}`))
+}
+
+func TestGetTieredRatios_AboveAllTiers(t *testing.T) {
+	InitRatioSettings()
+	defer InitRatioSettings()
+	// 早期保存的配置可能没有无上限的一档，超出所有上限时按最高一档计费
+	tieredRatioMapMutex.Lock()
+	tieredRatioMap = map[string][]PricingTier{"test-model": {
+		{MaxTokens: 1000, ModelRatio: 1},
+		{MaxTokens: 2000, ModelRatio: 2},
+	}}
+	tieredRatioMapMutex.Unlock()
+
+	ratios, ok := GetTieredRatios("test-model", 5000)
+	require.True(t, ok)
+	assert.Equal(t, 2, ratios.Tier)
+	assert.Equal(t, 2000, ratios.MaxTokens)
+	assert.Equal(t, 2.0, ratios.ModelRatio)
+}
+
+func TestGetTieredRatios_NoDefaultTiers(t *testing.T) {
+	InitRatioSettings()
+	_, ok := GetTieredRatios("gemini-2.5-pro", 1000)
+	assert.False(t, ok)
+	assert.False(t, HasTieredRatio("claude-sonnet-4-20250514"))
+}
@@ -0,0 +1,110 @@
+package ratio_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"strings"
+	"sync"
+)
+
+// VideoPrice 视频模型按生成时长计费的单价（美元/秒），Resolutions 按分辨率（如 720p）覆盖默认单价
+type VideoPrice struct {
+	PerSecond   float64            `json:"per_second"`
+	Resolutions map[string]float64 `json:"resolutions,omitempty"`
+}
+
+var defaultVideoPrice = map[string]VideoPrice{
+	"gen4_turbo":  {PerSecond: 0.05},
+	"gen3a_turbo": {PerSecond: 0.05},
+	"ray-2": {
+		PerSecond:   0.14,
+		Resolutions: map[string]float64{"540p": 0.08, "720p": 0.14, "1080p": 0.32},
+	},
+	"ray-flash-2": {
+		PerSecond:   0.05,
+		Resolutions: map[string]float64{"540p": 0.028, "720p": 0.05, "1080p": 0.11},
+	},
+	"MiniMax-Hailuo-02": {
+		PerSecond:   0.047,
+		Resolutions: map[string]float64{"512p": 0.017, "768p": 0.047, "1080p": 0.082},
+	},
+	"T2V-01": {PerSecond: 0.072},
+	"I2V-01": {PerSecond: 0.072},
+}
+
+var videoPriceMap map[string]VideoPrice
+var videoPriceMapMutex sync.RWMutex
+
+func VideoPrice2JSONString() string {
+	videoPriceMapMutex.RLock()
+	defer videoPriceMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(videoPriceMap)
+	if err != nil {
+		common.SysLog("error marshalling video price: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+// CheckVideoPrice 校验按秒计费配置：单价不能为负数，且至少配置一个单价
+func CheckVideoPrice(jsonStr string) error {
+	var prices map[string]VideoPrice
+	if err := json.Unmarshal([]byte(jsonStr), &prices); err != nil {
+		return err
+	}
+	for name, price := range prices {
+		if price.PerSecond < 0 {
+			return fmt.Errorf("模型 %s 的单价不能为负数", name)
+		}
+		if price.PerSecond == 0 && len(price.Resolutions) == 0 {
+			return fmt.Errorf("模型 %s 未配置单价", name)
+		}
+		for resolution, perSecond := range price.Resolutions {
+			if perSecond < 0 {
+				return fmt.Errorf("模型 %s 分辨率 %s 的单价不能为负数", name, resolution)
+			}
+		}
+	}
+	return nil
+}
+
+func UpdateVideoPriceByJSONString(jsonStr string) error {
+	if err := CheckVideoPrice(jsonStr); err != nil {
+		return err
+	}
+	prices := make(map[string]VideoPrice)
+	if err := json.Unmarshal([]byte(jsonStr), &prices); err != nil {
+		return err
+	}
+	videoPriceMapMutex.Lock()
+	videoPriceMap = prices
+	videoPriceMapMutex.Unlock()
+	InvalidateExposedDataCache()
+	return nil
+}
+
+func GetVideoPriceCopy() map[string]VideoPrice {
+	videoPriceMapMutex.RLock()
+	defer videoPriceMapMutex.RUnlock()
+	copyMap := make(map[string]VideoPrice, len(videoPriceMap))
+	for k, v := range videoPriceMap {
+		copyMap[k] = v
+	}
+	return copyMap
+}
+
+// GetVideoPricePerSecond 获取模型在指定分辨率下的每秒单价，分辨率不区分大小写，未单独配置时使用默认单价
+func GetVideoPricePerSecond(name string, resolution string) (float64, bool) {
+	videoPriceMapMutex.RLock()
+	price, ok := videoPriceMap[FormatMatchingModelName(name)]
+	videoPriceMapMutex.RUnlock()
+	if !ok {
+		return 0, false
+	}
+	for key, perSecond := range price.Resolutions {
+		if strings.EqualFold(key, resolution) {
+			return perSecond, true
+		}
+	}
+	return price.PerSecond, price.PerSecond > 0
+}
@@ -0,0 +1,35 @@
+package ratio_setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVideoPricePerSecond(t *testing.T) {
+	InitRatioSettings()
+	defer InitRatioSettings()
+	require.NoError(t, UpdateVideoPriceByJSONString(`{
+		"flat-model":{"per_second":0.05},
+		"res-model":{"resolutions":{"720p":0.1,"1080P":0.2}}
+	}`))
+
+	price, ok := GetVideoPricePerSecond("flat-model", "1280:720")
+	require.True(t, ok)
+	assert.Equal(t, 0.05, price)
+
+	// 分辨率不区分大小写
+	price, ok = GetVideoPricePerSecond("res-model", "1080p")
+	require.True(t, ok)
+	assert.Equal(t, 0.2, price)
+
+	// 未配置的分辨率且无默认单价时不按秒计费
+	_, ok = GetVideoPricePerSecond("res-model", "540p")
+	assert.False(t, ok)
+	_, ok = GetVideoPricePerSecond("unknown-model", "720p")
+	assert.False(t, ok)
+
+	assert.Error(t, CheckVideoPrice(`{"m":{}}`))
+	assert.Error(t, CheckVideoPrice(`{"m":{"resolutions":{"720p":-1}}}`))
+}
@@ -6,6 +6,17 @@ type GroupRatioInfo struct {
 	GroupRatio        float64
 	GroupSpecialRatio float64
 	HasSpecialRatio   bool
+	TimeMultiplier    float64 // 分时定价倍率，已计入 GroupRatio 与 GroupSpecialRatio
+	PricingSchedule   string
+	PriceOverride     *PriceOverride // 命中的用户协议价
+}
+
+// PriceOverride 用户协议价的计费快照
+type PriceOverride struct {
+	Id        int     `json:"id"`
+	ModelName string  `json:"model_name"`
+	Type      string  `json:"type"`
+	Value     float64 `json:"value"`
 }
 
 type PriceData struct {
@@ -18,6 +29,8 @@ type PriceData struct {
 	AudioRatio             float64
 	AudioCompletionRatio   float64
 	UsePrice               bool
+	PricingTier            int // 命中的分段序号，0 表示未使用分段倍率
+	PricingTierMaxTokens   int
 	ShouldPreConsumedQuota int
 	GroupRatioInfo         GroupRatioInfo
 }
@@ -29,5 +42,5 @@ type PerCallPriceData struct {
 }
 
 func (p PriceData) ToSetting() string {
-	return fmt.Sprintf("ModelPrice: %f, ModelRatio: %f, CompletionRatio: %f, CacheRatio: %f, GroupRatio: %f, UsePrice: %t, CacheCreationRatio: %f, ShouldPreConsumedQuota: %d, ImageRatio: %f, AudioRatio: %f, AudioCompletionRatio: %f", p.ModelPrice, p.ModelRatio, p.CompletionRatio, p.CacheRatio, p.GroupRatioInfo.GroupRatio, p.UsePrice, p.CacheCreationRatio, p.ShouldPreConsumedQuota, p.ImageRatio, p.AudioRatio, p.AudioCompletionRatio)
+	return fmt.Sprintf("ModelPrice: %f, ModelRatio: %f, CompletionRatio: %f, CacheRatio: %f, GroupRatio: %f, UsePrice: %t, CacheCreationRatio: %f, ShouldPreConsumedQuota: %d, ImageRatio: %f, AudioRatio: %f, AudioCompletionRatio: %f, PricingTier: %d", p.ModelPrice, p.ModelRatio, p.CompletionRatio, p.CacheRatio, p.GroupRatioInfo.GroupRatio, p.UsePrice, p.CacheCreationRatio, p.ShouldPreConsumedQuota, p.ImageRatio, p.AudioRatio, p.AudioCompletionRatio, p.PricingTier)
 }
@@ -159,6 +159,36 @@ export const CHANNEL_OPTIONS = [
     color: 'purple',
     label: 'Vidu',
   },
+  {
+    value: 53,
+    color: 'grey',
+    label: 'Runway',
+  },
+  {
+    value: 54,
+    color: 'indigo',
+    label: 'Luma',
+  },
+  {
+    value: 55,
+    color: 'grey',
+    label: 'Black Forest Labs',
+  },
+  {
+    value: 56,
+    color: 'purple',
+    label: 'Stability AI',
+  },
+  {
+    value: 57,
+    color: 'blue',
+    label: 'Ideogram',
+  },
+  {
+    value: 58,
+    color: 'pink',
+    label: 'Udio',
+  },
 ];
 
 export const MODEL_TABLE_PAGE_SIZE = 10;